@@ -0,0 +1,67 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// Operation is the kind of access that is authorized, see Authorize.
+type Operation string
+
+// The operations that are authorized.
+const (
+	OpRead   Operation = "read"   // reading one record (key) or all the records of a bucket (AnyKey)
+	OpWrite  Operation = "write"  // storing a record
+	OpDelete Operation = "delete" // deleting a record
+	OpAdmin  Operation = "admin"  // maintenance of the whole database (like Defrag), the bucket is empty
+)
+
+// AnyKey is the key that is authorized for operations on a whole bucket (or on the database).
+const AnyKey = -1
+
+/*
+AuthorizeFunc decides if an operation is allowed, it returns an error to deny it.
+The context is the one of the caller, e.g. of the HTTP request, so it can hold the identity of the user.
+*/
+type AuthorizeFunc func(ctx context.Context, operation Operation, bucket string, key int) error
+
+// ErrDenied is returned when an operation isn't authorized.
+var ErrDenied = errors.New("access denied")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+OnAuthorize adds a function that decides if an operation is allowed, see Authorize.
+*/
+func (fdb *DB) OnAuthorize(hook AuthorizeFunc) {
+	defer fdb.lockUnlock()()
+
+	fdb.hooks.authorize = append(fdb.hooks.authorize, hook)
+}
+
+/*
+Authorize checks with the authorization hooks if an operation on a bucket (and key) is allowed.
+It is called by the HTTP, gRPC and Redis servers before every operation, and can be called
+by other modules to enforce the same permissions. Without hooks everything is allowed.
+The hooks are called without locking the database, so they can use it.
+A denied operation returns an error that wraps ErrDenied and the error of the hook.
+*/
+func (fdb *DB) Authorize(ctx context.Context, operation Operation, bucket string, key int) error {
+	readLock := fdb.mu.RLock()
+	hooks := fdb.hooks.authorize // hooks are only appended, so the slice can be used after unlocking
+	readLock.RUnlock()
+
+	for _, hook := range hooks {
+		err := hook(ctx, operation, bucket, key)
+		if err != nil {
+			return fmt.Errorf("authorize (%s %s_%d) error: %w: %w", operation, bucket, key, ErrDenied, err)
+		}
+	}
+
+	return nil
+}
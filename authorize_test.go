@@ -0,0 +1,63 @@
+package fastdb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type userKey struct{}
+
+func Test_Authorize(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	ctx := context.Background()
+
+	// without hooks everything is allowed
+	err = store.Authorize(ctx, fastdb.OpAdmin, "", fastdb.AnyKey)
+	require.NoError(t, err)
+
+	errNotAllowed := errors.New("not allowed")
+
+	store.OnAuthorize(func(ctx context.Context, operation fastdb.Operation, bucket string, key int) error {
+		if ctx.Value(userKey{}) == "admin" {
+			return nil
+		}
+
+		if operation != fastdb.OpRead || bucket == "secret" || key == 13 {
+			return errNotAllowed
+		}
+
+		// the hooks can use the database
+		_, _ = store.Get(bucket, key)
+
+		return nil
+	})
+
+	err = store.Authorize(ctx, fastdb.OpRead, "user", 1)
+	require.NoError(t, err)
+
+	err = store.Authorize(ctx, fastdb.OpRead, "user", 13)
+	require.ErrorIs(t, err, fastdb.ErrDenied)
+	require.ErrorIs(t, err, errNotAllowed)
+	assert.Equal(t, "authorize (read user_13) error: access denied: not allowed", err.Error())
+
+	err = store.Authorize(ctx, fastdb.OpRead, "secret", fastdb.AnyKey)
+	require.ErrorIs(t, err, fastdb.ErrDenied)
+
+	err = store.Authorize(ctx, fastdb.OpWrite, "user", 1)
+	require.ErrorIs(t, err, fastdb.ErrDenied)
+
+	err = store.Authorize(context.WithValue(ctx, userKey{}, "admin"), fastdb.OpDelete, "secret", 1)
+	require.NoError(t, err)
+}
@@ -0,0 +1,213 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// backupTimeFormat is the time in the name of a backup, names in this format sort in time order.
+const backupTimeFormat = "20060102T150405.000Z"
+
+/*
+BackupSink stores the backups that BackupTo and AutoBackup make, like a directory (see DirSink)
+or a bucket of an object storage (like S3 or GCS).
+*/
+type BackupSink interface {
+	// Store reads one backup (a database file, see Backup) from the reader, and stores it under the name.
+	// The context is cancelled when the AutoBackup is stopped or the database is closed.
+	Store(ctx context.Context, name string, backup io.Reader) error
+}
+
+// DirSink is a BackupSink that stores the backups as files in a directory.
+type DirSink struct {
+	Dir  string // the directory, it's created when it doesn't exist
+	Keep int    // the number of backups (of the same database) to keep, 0 keeps all of them
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+BackupTo makes a backup (see Backup) and stores it in the sink, under a name with the name of the file
+and the time (like "fast-20261016T093000.000Z.db"). The backup is streamed to the sink while it's written.
+*/
+func (fdb *DB) BackupTo(ctx context.Context, sink BackupSink) error {
+	name := fdb.backupName(time.Now())
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(fdb.Backup(writer))
+	}()
+
+	err := sink.Store(ctx, name, reader)
+
+	// a sink that stopped reading mustn't leave the backup waiting
+	_ = reader.Close()
+
+	if err != nil {
+		return fmt.Errorf("backupTo (%s) error: %w", name, err)
+	}
+
+	return nil
+}
+
+/*
+AutoBackup stores a backup in the sink after every interval (see BackupTo), until the returned function
+is called or the database is closed. A failed backup is logged, the next one tries again.
+*/
+func (fdb *DB) AutoBackup(interval time.Duration, sink BackupSink) (func(), error) {
+	defer fdb.lockUnlock()()
+
+	// after a Close, no tasks are started anymore
+	if fdb.closed || fdb.stopTasks == nil {
+		return nil, fmt.Errorf("autoBackup error: %w", ErrClosed)
+	}
+
+	if interval <= 0 {
+		return nil, fmt.Errorf("autoBackup error: invalid interval %s", interval)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopBackups := make(chan struct{})
+	stopTasks := fdb.stopTasks
+
+	go func() {
+		select {
+		case <-stopBackups:
+		case <-stopTasks:
+		}
+
+		cancel()
+	}()
+
+	fdb.runEvery(interval, stopBackups, func() {
+		err := fdb.BackupTo(ctx, sink)
+		if err != nil && ctx.Err() == nil {
+			fdb.logger().Error("auto backup error", "error", err)
+		}
+	})
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			close(stopBackups)
+		})
+	}, nil
+}
+
+/*
+backupName returns the name of a backup of the moment.
+*/
+func (fdb *DB) backupName(moment time.Time) string {
+	base := "memory"
+
+	if fdb.aof != nil {
+		base = strings.TrimSuffix(filepath.Base(fdb.aof.Path()), filepath.Ext(fdb.aof.Path()))
+	}
+
+	return base + "-" + moment.UTC().Format(backupTimeFormat) + ".db"
+}
+
+/*
+Store writes the backup to a file in the directory, via a temporary file that is synced and then moved in place,
+and removes the oldest backups of the same database above Keep.
+*/
+func (sink DirSink) Store(_ context.Context, name string, backup io.Reader) error {
+	err := os.MkdirAll(sink.Dir, 0o750)
+	if err != nil {
+		return fmt.Errorf("dirSink error: %w", err)
+	}
+
+	path := filepath.Join(sink.Dir, filepath.Base(name))
+
+	err = writeFileFrom(path, backup)
+	if err != nil {
+		return fmt.Errorf("dirSink error: %w", err)
+	}
+
+	if sink.Keep > 0 {
+		err = sink.prune(name)
+		if err != nil {
+			return fmt.Errorf("dirSink error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+/*
+prune removes the oldest backups with the same prefix (the name of the database) as the name,
+so Keep of them are left.
+*/
+func (sink DirSink) prune(name string) error {
+	prefix := name[:strings.LastIndexByte(name, '-')+1]
+
+	entries, err := os.ReadDir(sink.Dir)
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	var backups []string
+
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) && strings.HasSuffix(entry.Name(), ".db") {
+			backups = append(backups, entry.Name())
+		}
+	}
+
+	slices.Sort(backups)
+
+	for len(backups) > sink.Keep {
+		err = os.Remove(filepath.Join(sink.Dir, backups[0]))
+		if err != nil {
+			return err //nolint:wrapcheck // it is wrapped by the caller
+		}
+
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+/*
+writeFileFrom writes what is read to a temporary file, which is synced and then moved to the path.
+*/
+func writeFileFrom(path string, reader io.Reader) error {
+	tmpPath := path + ".tmp"
+
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600) //nolint:gosec // the path is given by the caller
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	_, err = io.Copy(file, reader)
+	if err == nil {
+		err = file.Sync()
+	}
+
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmpPath)
+	}
+
+	return err //nolint:wrapcheck // it is wrapped by the caller
+}
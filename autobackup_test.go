@@ -0,0 +1,138 @@
+package fastdb_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingSink is a BackupSink that remembers the names and sizes of what it stored.
+type countingSink struct {
+	err   error
+	sizes map[string]int64
+	mu    sync.Mutex
+}
+
+func (sink *countingSink) Store(_ context.Context, name string, backup io.Reader) error {
+	if sink.err != nil {
+		return sink.err
+	}
+
+	size, err := io.Copy(io.Discard, backup)
+	if err != nil {
+		return err
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	sink.sizes[name] = size
+
+	return nil
+}
+
+func (sink *countingSink) count() int {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	return len(sink.sizes)
+}
+
+func Test_BackupTo_DirSink(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := fastdb.Open(filepath.Join(dir, "fast.db"), fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	err = store.Set("text", 1, []byte("one"))
+	require.NoError(t, err)
+
+	sink := fastdb.DirSink{Dir: filepath.Join(dir, "backups"), Keep: 2}
+
+	for range 3 {
+		err = store.BackupTo(context.Background(), sink)
+		require.NoError(t, err)
+
+		time.Sleep(2 * time.Millisecond) // a new name for every backup
+	}
+
+	entries, err := os.ReadDir(sink.Dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	for _, entry := range entries {
+		assert.True(t, strings.HasPrefix(entry.Name(), "fast-"))
+		assert.True(t, strings.HasSuffix(entry.Name(), ".db"))
+	}
+
+	restored, err := fastdb.Open(filepath.Join(sink.Dir, entries[1].Name()), fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	value, ok := restored.Get("text", 1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("one"), value)
+
+	err = restored.Close()
+	require.NoError(t, err)
+
+	failing := &countingSink{err: errors.New("no space")}
+	err = store.BackupTo(context.Background(), failing)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no space")
+}
+
+func Test_AutoBackup(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("one"))
+	require.NoError(t, err)
+
+	_, err = store.AutoBackup(0, &countingSink{})
+	require.Error(t, err)
+
+	sink := &countingSink{sizes: map[string]int64{}}
+
+	stop, err := store.AutoBackup(10*time.Millisecond, sink)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return sink.count() >= 2
+	}, time.Second, 5*time.Millisecond)
+
+	stop()
+	stop() // stopping twice is fine
+
+	time.Sleep(30 * time.Millisecond)
+
+	count := sink.count()
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, count, sink.count())
+
+	for name, size := range sink.sizes {
+		assert.True(t, strings.HasPrefix(name, "memory-"))
+		assert.Positive(t, size)
+	}
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	_, err = store.AutoBackup(time.Second, sink)
+	require.ErrorIs(t, err, fastdb.ErrClosed)
+}
@@ -0,0 +1,54 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"time"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+runEvery starts a background routine that runs the task after every interval,
+until the database is closed, or the (optional) stop channel is closed.
+*/
+func (fdb *DB) runEvery(interval time.Duration, stopTask <-chan struct{}, task func()) {
+	stop := fdb.stopTasks
+
+	fdb.tasks.Add(1)
+
+	go func() {
+		defer fdb.tasks.Done()
+
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-stopTask:
+				return
+			case <-tick.C:
+				task()
+			}
+		}
+	}()
+}
+
+/*
+stopBackground stops all background routines and waits until they have finished.
+*/
+func (fdb *DB) stopBackground() {
+	fdb.mu.Lock()
+	stop := fdb.stopTasks
+	fdb.stopTasks = nil
+	fdb.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	fdb.tasks.Wait()
+}
@@ -0,0 +1,98 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+var errOwnFile = errors.New("the backup can't be written to the file of the database")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Backup writes all the records and meta data of the moment it's called to a stream, in the format of
+a database file (with the format and compression of the database), so what is written can be opened with Open.
+The lock is only held to take a (shallow) copy of the data, so writers aren't blocked while it's written.
+*/
+func (fdb *DB) Backup(writer io.Writer) error {
+	keys, meta, opts, err := fdb.backupState()
+	if err != nil {
+		return fmt.Errorf("backup error: %w", err)
+	}
+
+	err = persist.WriteRecords(writer, keys, meta, opts...)
+	if err != nil {
+		return fmt.Errorf("backup error: %w", err)
+	}
+
+	return nil
+}
+
+/*
+BackupToFile writes a backup (see Backup) to a database file, which is replaced when it exists.
+The file is written under a temporary name and synced before it's moved in place,
+so there's never a half written backup. It can't be the file of the database itself.
+*/
+func (fdb *DB) BackupToFile(path string) error {
+	keys, meta, opts, err := fdb.backupState()
+	if err != nil {
+		return fmt.Errorf("backupToFile error: %w", err)
+	}
+
+	if fdb.aof != nil && samePath(path, fdb.aof.Path()) {
+		return fmt.Errorf("backupToFile (%s) error: %w", path, errOwnFile)
+	}
+
+	err = persist.WriteFile(path, keys, meta, opts...)
+	if err != nil {
+		return fmt.Errorf("backupToFile error: %w", err)
+	}
+
+	return nil
+}
+
+/*
+backupState returns a (shallow) copy of the records and the meta data, with the options to write them.
+*/
+func (fdb *DB) backupState() (map[string]map[int][]byte, map[string]string, []persist.Option, error) {
+	defer fdb.mu.RLock().RUnlock()
+
+	if fdb.closed {
+		return nil, nil, nil, ErrClosed
+	}
+
+	keys := make(map[string]map[int][]byte, len(fdb.keys))
+	for bucket, records := range fdb.keys {
+		keys[bucket] = maps.Clone(records)
+	}
+
+	opts := []persist.Option{persist.WithFormat(fdb.cfg.format)}
+	if fdb.cfg.compression != 0 {
+		opts = append(opts, persist.WithCompression(fdb.cfg.compression))
+	}
+
+	return keys, maps.Clone(fdb.meta), opts, nil
+}
+
+/*
+samePath tells if the two paths are the same file path.
+*/
+func samePath(path, other string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	otherAbs, err := filepath.Abs(other)
+
+	return err == nil && abs == otherAbs
+}
@@ -0,0 +1,95 @@
+package fastdb_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Backup(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "backup.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithCompression(1))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	big := bytes.Repeat([]byte("compress me "), 20)
+
+	err = store.SetMulti("text", map[int][]byte{1: []byte("one"), 2: big})
+	require.NoError(t, err)
+
+	err = store.SetDescription("text", "the texts")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	err = store.Backup(&buf)
+	require.NoError(t, err)
+
+	// later writes aren't in the backup
+	err = store.Set("text", 3, []byte("three"))
+	require.NoError(t, err)
+
+	streamPath := filepath.Join(dir, "stream.db")
+	err = os.WriteFile(streamPath, buf.Bytes(), 0o600)
+	require.NoError(t, err)
+
+	restored, err := fastdb.Open(streamPath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	records, err := restored.GetAll("text")
+	require.NoError(t, err)
+	assert.Equal(t, map[int][]byte{1: []byte("one"), 2: big}, records)
+	assert.Equal(t, "the texts", restored.Description("text"))
+
+	err = restored.Close()
+	require.NoError(t, err)
+
+	backupPath := filepath.Join(dir, "copy.db")
+
+	err = store.BackupToFile(backupPath)
+	require.NoError(t, err)
+
+	restored, err = fastdb.Open(backupPath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.Equal(t, 3, restored.Count("text"))
+
+	err = restored.Close()
+	require.NoError(t, err)
+
+	_, err = os.Stat(backupPath + ".tmp")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	err = store.BackupToFile(filePath)
+	require.Error(t, err)
+}
+
+func Test_Backup_closed(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("one"))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	err = store.Backup(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, fileHeader(buf.String())+"set\ntext_1\none\n", buf.String())
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	err = store.Backup(&buf)
+	require.ErrorIs(t, err, fastdb.ErrClosed)
+}
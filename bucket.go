@@ -0,0 +1,335 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// ErrBucketExists is returned by RenameBucket and CopyBucket when the new bucket already exists.
+var ErrBucketExists = errors.New("bucket already exists")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+DeleteBucket deletes a bucket with all its records, with one instruction in the file
+(plus the removal of their expiry times and labels), instead of a del instruction per record.
+Deleting a bucket that doesn't exist is not an error.
+*/
+func (fdb *DB) DeleteBucket(bucket string) error {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return fmt.Errorf("deleteBucket error: %w", err)
+	}
+
+	defer unlock()
+
+	if fdb.readOnly() {
+		return fmt.Errorf("deleteBucket error: %w", ErrReadOnly)
+	}
+
+	_, found := fdb.keys[bucket]
+	if !found {
+		return nil
+	}
+
+	keys := fdb.sortedKeys(bucket)
+	instructions := []persist.Instruction{persist.DelBucketInstruction(bucket)}
+
+	for _, key := range keys {
+		err = fdb.beforeDel(bucket, key)
+		if err != nil {
+			return err
+		}
+
+		instructions = append(instructions, fdb.expiryInstructions(bucket, key, 0)...)
+		instructions = append(instructions, fdb.labelInstructions(bucket, key)...)
+	}
+
+	err = fdb.write(instructions...)
+	if err != nil {
+		return fmt.Errorf("deleteBucket->write error: %w", err)
+	}
+
+	for _, key := range keys {
+		fdb.liveBytes -= fdb.recordSize(bucket, key)
+		fdb.setExpiry(bucket, key, 0)
+		delete(fdb.meta, labelsName(bucket, key))
+	}
+
+	delete(fdb.keys, bucket)
+	fdb.touch(bucket)
+
+	for _, key := range keys {
+		fdb.notify(Event{Type: EventDel, Bucket: bucket, Key: key})
+		fdb.afterDel(bucket, key)
+	}
+
+	return nil
+}
+
+/*
+Truncate deletes all the buckets, with their records and meta data (like expiry times, labels,
+descriptions and sequences, which start over), and rewrites the file empty, like a Defrag does.
+Only the write sequence (see Position) continues, and WithWriteSequence the deletion of the buckets
+is written as one write, so Changes reports it. It's meant for test suites and a "factory reset".
+*/
+func (fdb *DB) Truncate() error {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return fmt.Errorf("truncate error: %w", err)
+	}
+
+	defer unlock()
+
+	if fdb.readOnly() {
+		return fmt.Errorf("truncate error: %w", ErrReadOnly)
+	}
+
+	buckets := slices.Sorted(maps.Keys(fdb.keys))
+
+	for _, bucket := range buckets {
+		for _, key := range fdb.sortedKeys(bucket) {
+			err = fdb.beforeDel(bucket, key)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// the meta data is shared with the file, which writes what is left of it
+	meta := maps.Clone(fdb.meta)
+	lsn, found := meta[lsnMeta]
+	clear(fdb.meta)
+
+	if found {
+		fdb.meta[lsnMeta] = lsn
+	}
+
+	if fdb.aof != nil {
+		err = fdb.aof.Defrag(map[string]map[int][]byte{})
+		if err != nil {
+			maps.Copy(fdb.meta, meta)
+
+			return fmt.Errorf("truncate error: %w", err)
+		}
+
+		fdb.lastDefrag = time.Now()
+		fdb.fileRecords = 0
+
+		err = fdb.truncated(buckets)
+		if err != nil {
+			return fmt.Errorf("truncate error: %w", err)
+		}
+	}
+
+	fdb.resetGrowth()
+
+	keys := fdb.keys
+	fdb.keys = map[string]map[int][]byte{}
+	fdb.expiries = map[string]map[int]int64{}
+	fdb.liveBytes = 0
+
+	for _, bucket := range buckets {
+		fdb.touch(bucket)
+
+		for _, key := range slices.Sorted(maps.Keys(keys[bucket])) {
+			fdb.notify(Event{Type: EventDel, Bucket: bucket, Key: key})
+			fdb.afterDel(bucket, key)
+		}
+	}
+
+	return nil
+}
+
+/*
+truncated marks the file that Truncate rewrote as the base of the changes, and (WithWriteSequence)
+writes the deletion of the buckets after it as one write, so Changes reports them.
+The caller must hold the write lock.
+*/
+func (fdb *DB) truncated(buckets []string) error {
+	err := fdb.markBase()
+	if err != nil || !fdb.cfg.writeSequence || len(buckets) == 0 {
+		return err
+	}
+
+	instructions := make([]persist.Instruction, 0, len(buckets))
+	for _, bucket := range buckets {
+		instructions = append(instructions, persist.DelBucketInstruction(bucket))
+	}
+
+	return fdb.write(instructions...)
+}
+
+/*
+RenameBucket moves all the records of a bucket (with their expiry times, labels and the description
+of the bucket) to a new bucket, with one instruction in the file, so the records aren't written again.
+The new bucket must not exist yet. The records are moved as they are, so the hooks aren't called,
+but the watchers get the events of both buckets.
+*/
+func (fdb *DB) RenameBucket(oldBucket, newBucket string) error {
+	return fdb.copyBucket("renameBucket", oldBucket, newBucket, true)
+}
+
+/*
+CopyBucket copies all the records of a bucket (with their expiry times and labels) to a new bucket,
+with one instruction in the file, so the records aren't written again.
+The new bucket must not exist yet. The records are copied as they are, so the hooks aren't called,
+but the watchers get the events of the new bucket.
+*/
+func (fdb *DB) CopyBucket(srcBucket, dstBucket string) error {
+	return fdb.copyBucket("copyBucket", srcBucket, dstBucket, false)
+}
+
+/*
+copyBucket copies the records of a bucket to a new bucket, and removes the source bucket if it's a move.
+*/
+func (fdb *DB) copyBucket(operation, src, dst string, move bool) error {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return fmt.Errorf("%s error: %w", operation, err)
+	}
+
+	defer unlock()
+
+	if fdb.readOnly() {
+		return fmt.Errorf("%s error: %w", operation, ErrReadOnly)
+	}
+
+	records, found := fdb.keys[src]
+	if !found {
+		return fmt.Errorf("%s error: %w (%s)", operation, ErrBucketNotFound, src)
+	}
+
+	_, found = fdb.keys[dst]
+	if found {
+		return fmt.Errorf("%s error: %w (%s)", operation, ErrBucketExists, dst)
+	}
+
+	err = fdb.checkLimit(dst, len(records))
+	if err != nil {
+		return fmt.Errorf("%s->%w", operation, err)
+	}
+
+	keys := fdb.sortedKeys(src)
+
+	err = fdb.write(fdb.copyInstructions(src, dst, keys, move)...)
+	if err != nil {
+		return fmt.Errorf("%s->write error: %w", operation, err)
+	}
+
+	fdb.keys[dst] = maps.Clone(records)
+
+	for _, key := range keys {
+		fdb.liveBytes += fdb.recordSize(dst, key)
+		fdb.moveMeta(labelsName(src, key), labelsName(dst, key), move)
+
+		expiry, hasExpiry := fdb.expiries[src][key]
+		if hasExpiry {
+			fdb.setExpiry(dst, key, expiry)
+		}
+
+		if move {
+			fdb.liveBytes -= fdb.recordSize(src, key)
+			fdb.setExpiry(src, key, 0)
+		}
+	}
+
+	if move {
+		delete(fdb.keys, src)
+		fdb.moveMeta(descriptionPrefix+src, descriptionPrefix+dst, true)
+	}
+
+	fdb.touch(dst)
+
+	if move {
+		fdb.touch(src)
+	}
+
+	for _, key := range keys {
+		fdb.notify(Event{Type: EventSet, Bucket: dst, Key: key, Value: records[key]})
+
+		if move {
+			fdb.notify(Event{Type: EventDel, Bucket: src, Key: key})
+		}
+	}
+
+	return nil
+}
+
+/*
+copyInstructions returns the instructions to copy (or move) the records of a bucket,
+with their meta data. With stripes, the buckets can be in different files,
+so the records are written to the new bucket one by one.
+The caller must hold the write lock.
+*/
+func (fdb *DB) copyInstructions(src, dst string, keys []int, move bool) []persist.Instruction {
+	var instructions []persist.Instruction
+
+	switch {
+	case fdb.aof != nil && fdb.aof.Striped():
+		for _, key := range keys {
+			instructions = append(instructions, persist.SetInstruction(dst, key, fdb.keys[src][key]))
+		}
+
+		if move {
+			instructions = append(instructions, persist.DelBucketInstruction(src))
+		}
+	case move:
+		instructions = append(instructions, persist.RenameBucketInstruction(src, dst))
+	default:
+		instructions = append(instructions, persist.CopyBucketInstruction(src, dst))
+	}
+
+	names := make([][2]string, 0, len(keys))
+
+	for _, key := range keys {
+		names = append(names,
+			[2]string{ttlName(src, key), ttlName(dst, key)},
+			[2]string{labelsName(src, key), labelsName(dst, key)})
+	}
+
+	if move {
+		names = append(names, [2]string{descriptionPrefix + src, descriptionPrefix + dst})
+	}
+
+	for _, name := range names {
+		value, found := fdb.meta[name[0]]
+		if !found {
+			continue
+		}
+
+		instructions = append(instructions, persist.MetaInstruction(name[1], value))
+
+		if move {
+			instructions = append(instructions, persist.DelMetaInstruction(name[0]))
+		}
+	}
+
+	return instructions
+}
+
+/*
+moveMeta copies (or moves) a meta data value to another name, if it exists.
+The caller must hold the write lock.
+*/
+func (fdb *DB) moveMeta(from, to string, move bool) {
+	value, found := fdb.meta[from]
+	if !found {
+		return
+	}
+
+	fdb.meta[to] = value
+
+	if move {
+		delete(fdb.meta, from)
+	}
+}
@@ -0,0 +1,317 @@
+package fastdb_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DeleteBucket(t *testing.T) {
+	path := "data/fastdb_delete_bucket.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	deleted := 0
+
+	store.OnAfterDel(func(bucket string, _ int) {
+		assert.Equal(t, "text", bucket)
+
+		deleted++
+	})
+
+	for key := 1; key <= 3; key++ {
+		err = store.Set("text", key, []byte("value"))
+		require.NoError(t, err)
+	}
+
+	err = store.Set("user", 1, []byte("value"))
+	require.NoError(t, err)
+
+	err = store.DeleteBucket("text")
+	require.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+	assert.False(t, store.HasBucket("text"))
+
+	// deleting it again does nothing
+	err = store.DeleteBucket("text")
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// one instruction for the whole bucket
+	checkFileLines(t, filePath, 14)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.False(t, store.HasBucket("text"))
+	assert.Equal(t, 1, store.Count("user"))
+}
+
+func Test_Truncate(t *testing.T) {
+	path := "data/fastdb_truncate.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		err = os.Remove(filePath + ".bak")
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	for key := 1; key <= 3; key++ {
+		err = store.Set("text", key, []byte("value"))
+		require.NoError(t, err)
+	}
+
+	err = store.SetWithTTL("user", 1, []byte("value"), time.Hour)
+	require.NoError(t, err)
+
+	err = store.SetDescription("user", "the users")
+	require.NoError(t, err)
+
+	_, err = store.Sequence("order").Next()
+	require.NoError(t, err)
+
+	err = store.Truncate()
+	require.NoError(t, err)
+	assert.Equal(t, 0, store.TotalCount())
+	assert.Empty(t, store.Descriptions())
+	assert.Equal(t, 0, store.Sequence("order").Current())
+
+	checkFileLines(t, filePath, 0)
+
+	err = store.Set("text", 1, []byte("new value"))
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.Equal(t, 1, store.TotalCount())
+
+	value, found := store.Get("text", 1)
+	assert.True(t, found)
+	assert.Equal(t, []byte("new value"), value)
+}
+
+func Test_Truncate_memory(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	err = store.Set("text", 1, []byte("value"))
+	require.NoError(t, err)
+
+	events, stop := store.Watch("text")
+	defer stop()
+
+	err = store.Truncate()
+	require.NoError(t, err)
+	assert.False(t, store.HasBucket("text"))
+
+	event := <-events
+	assert.Equal(t, fastdb.EventDel, event.Type)
+	assert.Equal(t, 1, event.Key)
+}
+
+func Test_RenameBucket(t *testing.T) {
+	path := "data/fastdb_rename_bucket.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	for key := 1; key <= 3; key++ {
+		err = store.Set("text", key, []byte("value"))
+		require.NoError(t, err)
+	}
+
+	err = store.SetWithTTL("text", 4, []byte("value"), time.Hour)
+	require.NoError(t, err)
+
+	err = store.SetDescription("text", "some texts")
+	require.NoError(t, err)
+
+	err = store.Set("user", 1, []byte("value"))
+	require.NoError(t, err)
+
+	err = store.RenameBucket("text", "user")
+	require.ErrorIs(t, err, fastdb.ErrBucketExists)
+
+	err = store.RenameBucket("unknown", "other")
+	require.Error(t, err)
+
+	events, stop := store.Watch("")
+	defer stop()
+
+	err = store.RenameBucket("text", "message")
+	require.NoError(t, err)
+	assert.False(t, store.HasBucket("text"))
+	assert.Equal(t, 4, store.Count("message"))
+	assert.Equal(t, "some texts", store.Description("message"))
+	assert.Empty(t, store.Description("text"))
+
+	event := <-events
+	assert.Equal(t, fastdb.EventSet, event.Type)
+	assert.Equal(t, "message", event.Bucket)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.False(t, store.HasBucket("text"))
+	assert.Equal(t, 4, store.Count("message"))
+	assert.Equal(t, "some texts", store.Description("message"))
+
+	_, found := store.TTL("message", 4)
+	assert.True(t, found)
+
+	_, found = store.TTL("text", 4)
+	assert.False(t, found)
+}
+
+func Test_CopyBucket(t *testing.T) {
+	path := "data/fastdb_copy_bucket.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	for key := 1; key <= 3; key++ {
+		err = store.Set("text", key, []byte("value"))
+		require.NoError(t, err)
+	}
+
+	err = store.CopyBucket("text", "backup")
+	require.NoError(t, err)
+
+	err = store.CopyBucket("text", "backup")
+	require.ErrorIs(t, err, fastdb.ErrBucketExists)
+
+	// the copy is independent of the original
+	err = store.Set("text", 1, []byte("changed"))
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// one instruction for the whole copy
+	checkFileLines(t, filePath, 15)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.Equal(t, 3, store.Count("text"))
+	assert.Equal(t, 3, store.Count("backup"))
+
+	value, _ := store.Get("text", 1)
+	assert.Equal(t, []byte("changed"), value)
+
+	value, _ = store.Get("backup", 1)
+	assert.Equal(t, []byte("value"), value)
+}
+
+func Test_RenameBucket_WithStripes(t *testing.T) {
+	path := "data/fastdb_rename_stripes.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		matches, err := filepath.Glob(filePath + "*")
+		require.NoError(t, err)
+
+		for _, match := range matches {
+			require.NoError(t, os.Remove(match))
+		}
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithStripes(4))
+	require.NoError(t, err)
+
+	for key := 1; key <= 3; key++ {
+		err = store.Set("bucket1", key, []byte("value"))
+		require.NoError(t, err)
+	}
+
+	// the buckets end up in different files
+	for _, bucket := range []string{"bucket2", "bucket3", "bucket4", "bucket5"} {
+		err = store.RenameBucket("bucket1", bucket)
+		require.NoError(t, err)
+
+		err = store.RenameBucket(bucket, "bucket1")
+		require.NoError(t, err)
+	}
+
+	err = store.CopyBucket("bucket1", "bucket2")
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithStripes(4))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.Equal(t, 3, store.Count("bucket1"))
+	assert.Equal(t, 3, store.Count("bucket2"))
+	assert.Equal(t, 0, store.Count("bucket3"))
+}
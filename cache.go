@@ -0,0 +1,68 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"maps"
+	"slices"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// sortCache holds the sorted keys of a bucket, valid for one generation.
+type sortCache struct {
+	keys       []int
+	generation uint64
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+touch bumps the generation of a bucket, so cached data of that bucket becomes stale,
+and checks the watermark of the bucket.
+The caller must hold the write lock.
+*/
+func (fdb *DB) touch(bucket string) {
+	fdb.generations[bucket]++
+	fdb.debug.written(bucket)
+	fdb.checkWatermark(bucket)
+}
+
+/*
+sortedKeys returns the sorted keys of a bucket.
+When the bucket didn't change since the last call, the cached keys are returned,
+otherwise they are sorted again and cached (unless the cache is turned off).
+The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) sortedKeys(bucket string) []int {
+	if fdb.cfg.noSortCache {
+		return slices.Sorted(maps.Keys(fdb.keys[bucket]))
+	}
+
+	generation := fdb.generations[bucket]
+
+	fdb.cacheMu.Lock()
+	defer fdb.cacheMu.Unlock()
+
+	cache, found := fdb.sortCaches[bucket]
+	if found && cache.generation == generation {
+		return cache.keys
+	}
+
+	keys := slices.Sorted(maps.Keys(fdb.keys[bucket]))
+	fdb.sortCaches[bucket] = &sortCache{keys: keys, generation: generation}
+
+	return keys
+}
+
+/*
+resetCaches clears all the cached data.
+The caller must hold the write lock.
+*/
+func (fdb *DB) resetCaches() {
+	fdb.cacheMu.Lock()
+	defer fdb.cacheMu.Unlock()
+
+	fdb.sortCaches = map[string]*sortCache{}
+	fdb.generations = map[string]uint64{}
+}
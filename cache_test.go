@@ -0,0 +1,152 @@
+package fastdb_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetAllSorted_cacheInvalidation(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.NotNil(t, store)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	for _, key := range []int{3, 1, 2} {
+		err = store.Set("bucket", key, []byte("value"))
+		require.NoError(t, err)
+	}
+
+	records, err := store.GetAllSorted("bucket")
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, 1, records[0].SortField)
+	assert.Equal(t, 3, records[2].SortField)
+
+	// served from the cache
+	records, err = store.GetAllSorted("bucket")
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+
+	// a set must invalidate the cache
+	err = store.Set("bucket", 0, []byte("new value"))
+	require.NoError(t, err)
+
+	records, err = store.GetAllSorted("bucket")
+	require.NoError(t, err)
+	assert.Len(t, records, 4)
+	assert.Equal(t, 0, records[0].SortField)
+	assert.Equal(t, []byte("new value"), records[0].Data)
+
+	// an update of an existing key must be visible
+	err = store.Set("bucket", 0, []byte("updated value"))
+	require.NoError(t, err)
+
+	records, err = store.GetAllSorted("bucket")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("updated value"), records[0].Data)
+
+	// a del must invalidate the cache
+	ok, err := store.Del("bucket", 3)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	records, err = store.GetAllSorted("bucket")
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, 2, records[2].SortField)
+
+	// deleting and recreating the bucket must not return stale keys
+	for _, key := range []int{0, 1, 2} {
+		_, err = store.Del("bucket", key)
+		require.NoError(t, err)
+	}
+
+	_, err = store.GetAllSorted("bucket")
+	require.Error(t, err)
+
+	err = store.Set("bucket", 5, []byte("value"))
+	require.NoError(t, err)
+
+	records, err = store.GetAllSorted("bucket")
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, 5, records[0].SortField)
+}
+
+func Test_GetAllSorted_consistent(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	total := 100
+	done := make(chan struct{})
+
+	// every round changes all the records at once
+	go func() {
+		defer close(done)
+
+		for round := range 200 {
+			items := make(map[int][]byte, total)
+			for key := 1; key <= total; key++ {
+				items[key] = []byte(strconv.Itoa(round))
+			}
+
+			assert.NoError(t, store.SetMulti("bucket", items))
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		records, err := store.GetAllSorted("bucket")
+		if err != nil {
+			continue
+		}
+
+		require.Len(t, records, total)
+
+		for _, record := range records {
+			require.Equal(t, records[0].Data, record.Data)
+		}
+	}
+}
+
+func Test_GetAllSorted_expired(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	err = store.Set("bucket", 1, []byte("stays"))
+	require.NoError(t, err)
+
+	err = store.SetWithTTL("bucket", 2, []byte("expires"), time.Millisecond)
+	require.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+
+	records, err := store.GetAllSorted("bucket")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, 1, records[0].SortField)
+}
@@ -0,0 +1,41 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bytes"
+	"fmt"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+CompareAndSwap stores the new value (like Set), but only if the current value of the key is still old,
+so concurrent writers can't silently overwrite each other's changes (optimistic concurrency).
+An old value of nil means that the key may not exist yet (an expired key doesn't exist).
+It returns false (without an error) if the current value is different.
+*/
+func (fdb *DB) CompareAndSwap(bucket string, key int, old, value []byte) (bool, error) {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return false, fmt.Errorf("compareAndSwap error: %w", err)
+	}
+
+	defer unlock()
+
+	current, found := fdb.keys[bucket][key]
+	if found && fdb.expired(bucket, key) {
+		found = false
+	}
+
+	if found != (old != nil) || !bytes.Equal(current, old) {
+		return false, nil
+	}
+
+	err = fdb.set(bucket, key, value, 0)
+	if err != nil {
+		return false, fmt.Errorf("compareAndSwap->%w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,78 @@
+package fastdb_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CompareAndSwap(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	swapped, err := store.CompareAndSwap("text", 1, []byte("one"), []byte("two"))
+	require.NoError(t, err)
+	assert.False(t, swapped)
+
+	swapped, err = store.CompareAndSwap("text", 1, nil, []byte("one"))
+	require.NoError(t, err)
+	assert.True(t, swapped)
+
+	swapped, err = store.CompareAndSwap("text", 1, nil, []byte("other"))
+	require.NoError(t, err)
+	assert.False(t, swapped)
+
+	swapped, err = store.CompareAndSwap("text", 1, []byte("one"), []byte("two"))
+	require.NoError(t, err)
+	assert.True(t, swapped)
+
+	value, _ := store.Get("text", 1)
+	assert.Equal(t, []byte("two"), value)
+
+	swapped, err = store.CompareAndSwap("text", -1, nil, []byte("one"))
+	require.Error(t, err)
+	assert.False(t, swapped)
+
+	// concurrent increments don't lose updates
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range 10 {
+				for {
+					current, found := store.Get("count", 1)
+
+					counter := 0
+					if found {
+						counter, _ = strconv.Atoi(string(current))
+					}
+
+					swapped, err := store.CompareAndSwap("count", 1, current, []byte(strconv.Itoa(counter+1)))
+					assert.NoError(t, err)
+
+					if swapped {
+						break
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	value, _ = store.Get("count", 1)
+	assert.Equal(t, []byte("100"), value)
+}
@@ -0,0 +1,240 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// lsnBaseMeta is the meta data name under which is persisted up to which write sequence
+// the file only holds the state (instead of the changes), after it was rewritten.
+const lsnBaseMeta = "lsnbase"
+
+// ChangeType tells what kind of change a Change is about.
+type ChangeType int
+
+const (
+	// ChangeSet is a record that was stored.
+	ChangeSet ChangeType = iota + 1
+	// ChangeDel is a record that was deleted (or has expired).
+	ChangeDel
+	// ChangeDelBucket is a bucket that was deleted, with all its records.
+	ChangeDelBucket
+	// ChangeRenameBucket is a bucket that was renamed to the target bucket.
+	ChangeRenameBucket
+	// ChangeCopyBucket is a bucket that was copied to the target bucket.
+	ChangeCopyBucket
+)
+
+// Change describes one change of the records, as it is read back from the file by Changes.
+type Change struct {
+	Time     time.Time // when it was written, only WithTimestamps
+	Bucket   string
+	Target   string // the new bucket, only for ChangeRenameBucket and ChangeCopyBucket
+	Value    []byte // the new value, only for ChangeSet
+	Sequence uint64 // the write the change belongs to (one write can make more changes)
+	Type     ChangeType
+	Key      int // only for ChangeSet and ChangeDel
+}
+
+// ErrChangesGone is returned by Changes when the file doesn't hold all the changes after the sequence anymore.
+var ErrChangesGone = errors.New("changes are no longer in the file")
+
+var errNoWriteSequence = errors.New("the database isn't opened WithWriteSequence")
+
+// changeFeed groups the instructions of the file into the writes they belong to.
+type changeFeed struct {
+	fn      func(change Change) error
+	group   []Change // the changes of the write that is being read
+	pending []Change // the changes of the last complete write, which aren't delivered yet
+	cursor  uint64   // the last write that was delivered (or skipped)
+	next    uint64   // the sequence of the pending write
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Changes reads the changes of the records that were written after the write with the given sequence
+(see Position) back from the file, and calls fn for every one of them, in the order they were written.
+It returns the sequence of the last write that was delivered completely, so a consumer
+(like an indexer) can store it, and continue where it left off with the next call, even after a restart.
+If fn returns an error, Changes stops and returns it (the write it was delivering comes again the next time).
+
+The database must be opened WithWriteSequence (from the start), because the sequence of every write
+is persisted with it. A Defrag, a Checkpoint and a Truncate remove the changes from the file, so a consumer
+that is behind gets ErrChangesGone, and has to read all the records again (starting at Position).
+Changes to meta data (like expiry times and labels) aren't reported, and stripes aren't supported.
+The read lock is held while reading, so fn can't change the database.
+*/
+func (fdb *DB) Changes(from uint64, fn func(change Change) error) (uint64, error) {
+	defer fdb.mu.RLock().RUnlock()
+
+	if fdb.closed {
+		return from, fmt.Errorf("changes error: %w", ErrClosed)
+	}
+
+	if !fdb.cfg.writeSequence || fdb.aof == nil {
+		return from, fmt.Errorf("changes error: %w", errNoWriteSequence)
+	}
+
+	feed := &changeFeed{fn: fn, cursor: from}
+
+	err := fdb.aof.Replay(feed.read)
+	if err == nil {
+		err = feed.deliver()
+	}
+
+	if err != nil {
+		return feed.cursor, fmt.Errorf("changes error: %w", err)
+	}
+
+	return feed.cursor, nil
+}
+
+/*
+read handles one instruction of the file: the changes of records are gathered until the sequence
+of the write (which comes last) tells to which write they belong.
+*/
+func (feed *changeFeed) read(ins persist.Instruction) error {
+	modified, _ := ins.Modified()
+
+	switch ins.Name {
+	case "set", "del":
+		bucket, key, ok := ins.BucketKey()
+		if !ok {
+			return nil
+		}
+
+		change := Change{Type: ChangeSet, Bucket: bucket, Key: key, Value: ins.Value, Time: modified}
+		if ins.Name == "del" {
+			change = Change{Type: ChangeDel, Bucket: bucket, Key: key, Time: modified}
+		}
+
+		feed.group = append(feed.group, change)
+	case "delbucket":
+		feed.group = append(feed.group, Change{Type: ChangeDelBucket, Bucket: ins.Key, Time: modified})
+	case "renamebucket", "copybucket":
+		change := Change{Type: ChangeRenameBucket, Bucket: ins.Key, Target: string(ins.Value), Time: modified}
+		if ins.Name == "copybucket" {
+			change.Type = ChangeCopyBucket
+		}
+
+		feed.group = append(feed.group, change)
+	case "meta":
+		switch ins.Key {
+		case lsnMeta:
+			return feed.endWrite(string(ins.Value))
+		case lsnBaseMeta:
+			return feed.endBase(string(ins.Value))
+		}
+	}
+
+	return nil
+}
+
+/*
+endWrite handles the end of a write: the previous write is delivered, and this one waits,
+because when a base follows, it was part of the state after a rewrite instead of a write.
+*/
+func (feed *changeFeed) endWrite(value string) error {
+	sequence, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		feed.group = nil
+
+		return nil //nolint:nilerr // it's not a write
+	}
+
+	err = feed.deliver()
+	if err != nil {
+		return err
+	}
+
+	group := feed.group
+	feed.group = nil
+
+	switch {
+	case sequence <= feed.cursor:
+		// the consumer already has this write
+	case sequence == feed.cursor+1:
+		feed.pending = group
+		feed.next = sequence
+	default:
+		return fmt.Errorf("%w (after %d, the next is %d)", ErrChangesGone, feed.cursor, sequence)
+	}
+
+	return nil
+}
+
+/*
+endBase handles the end of the state that a rewrite of the file wrote:
+what was read before it aren't changes, and the changes up to the sequence are gone.
+*/
+func (feed *changeFeed) endBase(value string) error {
+	sequence, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil //nolint:nilerr // it's not a base
+	}
+
+	feed.group = nil
+	feed.pending = nil
+	feed.next = 0
+
+	if sequence > feed.cursor {
+		return fmt.Errorf("%w (after %d, the file starts at %d)", ErrChangesGone, feed.cursor, sequence)
+	}
+
+	return nil
+}
+
+/*
+deliver calls the function of the consumer for the changes of the pending write.
+*/
+func (feed *changeFeed) deliver() error {
+	if feed.next == 0 {
+		return nil
+	}
+
+	for _, change := range feed.pending {
+		change.Sequence = feed.next
+
+		err := feed.fn(change)
+		if err != nil {
+			return err
+		}
+	}
+
+	feed.cursor = feed.next
+	feed.pending = nil
+	feed.next = 0
+
+	return nil
+}
+
+/*
+markBase persists that the file, which was just rewritten, holds the state up to the current write sequence,
+so Changes knows that the changes before it are gone (WithWriteSequence).
+The caller must hold the write lock.
+*/
+func (fdb *DB) markBase() error {
+	if !fdb.cfg.writeSequence || fdb.aof == nil {
+		return nil
+	}
+
+	base := strconv.FormatUint(fdb.sequence, 10)
+
+	err := fdb.aof.WriteBatch([]persist.Instruction{persist.MetaInstruction(lsnBaseMeta, base)})
+	if err != nil {
+		return fmt.Errorf("markBase error: %w", err)
+	}
+
+	fdb.meta[lsnBaseMeta] = base
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package fastdb_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Changes(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "changes.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithWriteSequence())
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("one"))
+	require.NoError(t, err)
+
+	err = store.SetMulti("text", map[int][]byte{2: []byte("two"), 3: []byte("three")})
+	require.NoError(t, err)
+
+	_, err = store.Del("text", 1)
+	require.NoError(t, err)
+
+	err = store.RenameBucket("text", "words")
+	require.NoError(t, err)
+
+	var changes []fastdb.Change
+
+	collect := func(change fastdb.Change) error {
+		changes = append(changes, change)
+
+		return nil
+	}
+
+	last, err := store.Changes(0, collect)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), last)
+	require.Len(t, changes, 5)
+	assert.Equal(t, fastdb.Change{Type: fastdb.ChangeSet, Bucket: "text", Key: 1, Value: []byte("one"), Sequence: 1}, changes[0])
+	assert.Equal(t, uint64(2), changes[1].Sequence)
+	assert.Equal(t, uint64(2), changes[2].Sequence)
+	assert.Equal(t, fastdb.Change{Type: fastdb.ChangeDel, Bucket: "text", Key: 1, Sequence: 3}, changes[3])
+	assert.Equal(t, fastdb.Change{Type: fastdb.ChangeRenameBucket, Bucket: "text", Target: "words", Sequence: 4}, changes[4])
+
+	// a consumer continues where it left off, also after a restart
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithWriteSequence())
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	err = store.Set("words", 4, []byte("four"))
+	require.NoError(t, err)
+
+	changes = nil
+	last, err = store.Changes(3, collect)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), last)
+	require.Len(t, changes, 2)
+	assert.Equal(t, fastdb.ChangeRenameBucket, changes[0].Type)
+	assert.Equal(t, fastdb.Change{Type: fastdb.ChangeSet, Bucket: "words", Key: 4, Value: []byte("four"), Sequence: 5}, changes[1])
+
+	// an error of the consumer stops before the write it was delivering
+	errStop := errors.New("stop")
+	last, err = store.Changes(3, func(fastdb.Change) error { return errStop })
+	require.ErrorIs(t, err, errStop)
+	assert.Equal(t, uint64(3), last)
+
+	// a defrag removes the changes, a consumer that is up to date continues
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	_, err = store.Changes(4, collect)
+	require.ErrorIs(t, err, fastdb.ErrChangesGone)
+
+	err = store.Set("words", 5, []byte("five"))
+	require.NoError(t, err)
+
+	changes = nil
+	last, err = store.Changes(5, collect)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), last)
+	require.Len(t, changes, 1)
+	assert.Equal(t, 5, changes[0].Key)
+
+	// a truncate is reported as the deletion of the buckets
+	err = store.Truncate()
+	require.NoError(t, err)
+
+	changes = nil
+	last, err = store.Changes(6, collect)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), last)
+	assert.Equal(t, []fastdb.Change{{Type: fastdb.ChangeDelBucket, Bucket: "words", Sequence: 7}}, changes)
+
+	// a checkpoint removes the changes too
+	err = store.Checkpoint()
+	require.NoError(t, err)
+
+	_, err = store.Changes(6, collect)
+	require.ErrorIs(t, err, fastdb.ErrChangesGone)
+
+	last, err = store.Changes(7, collect)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), last)
+}
+
+func Test_Changes_firstWriteAfterDefrag(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "changes.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithWriteSequence())
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	err = store.Set("text", 1, []byte("one"))
+	require.NoError(t, err)
+
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	// the state that the defrag wrote isn't the first write
+	_, err = store.Changes(0, func(fastdb.Change) error { return nil })
+	require.ErrorIs(t, err, fastdb.ErrChangesGone)
+
+	last, err := store.Changes(1, func(fastdb.Change) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), last)
+}
+
+func Test_Changes_error(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	_, err = store.Changes(0, func(fastdb.Change) error { return nil })
+	require.Error(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	_, err = store.Changes(0, func(fastdb.Change) error { return nil })
+	require.ErrorIs(t, err, fastdb.ErrClosed)
+}
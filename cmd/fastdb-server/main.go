@@ -0,0 +1,95 @@
+/*
+Package main is a tiny server that makes a fastdb database available over the Redis protocol (RESP),
+so existing Redis clients can use it. The Redis keys are mapped onto the buckets as "<bucket>:<key>",
+where key is a number. The supported commands are PING, AUTH, GET, SET (with EX or PX), DEL, EXISTS, SCAN and QUIT.
+
+Usage:
+
+	fastdb-server -addr :6380 -path data/fast.db
+
+To expose it on a network, use TLS (-tls-cert and -tls-key), and only accept known clients
+with client certificates (-tls-client-ca) and/or a token that clients send with AUTH
+(-token, or the environment variable FASTDB_TOKEN, which isn't visible in the process list):
+
+	FASTDB_TOKEN=secret fastdb-server -tls-cert cert.pem -tls-key key.pem -tls-client-ca clients.pem
+*/
+package main
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"crypto/tls"
+	"flag"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/marcelloh/fastdb"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+main is the bootstrap of the application.
+*/
+func main() {
+	addr := flag.String("addr", ":6380", "the address to listen on")
+	dbPath := flag.String("path", "data/fastdb.db", "the database file, or :memory:")
+	syncTime := flag.Int("sync", 100, "the time (in milliseconds) between two syncs to disk")
+	certFile := flag.String("tls-cert", "", "the certificate (PEM file) to serve TLS with")
+	keyFile := flag.String("tls-key", "", "the key (PEM file) of the certificate")
+	clientCAFile := flag.String("tls-client-ca", "", "the CA certificates (PEM file) that client certificates need")
+	token := flag.String("token", os.Getenv("FASTDB_TOKEN"), "the token that clients have to send with AUTH")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	store, err := fastdb.Open(*dbPath, fastdb.WithSyncTime(*syncTime), fastdb.WithLogger(logger))
+	if err != nil {
+		logger.Error("open error", "error", err)
+		os.Exit(1)
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err == nil && *certFile != "" {
+		var config *tls.Config
+
+		config, err = fastdb.ServerTLSConfig(*certFile, *keyFile, *clientCAFile)
+		if err == nil {
+			listener = tls.NewListener(listener, config)
+		} else {
+			_ = listener.Close()
+		}
+	}
+
+	if err != nil {
+		logger.Error("listen error", "error", err)
+		_ = store.Close()
+		os.Exit(1) //nolint:gocritic // the store is closed
+	}
+
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+
+		_ = listener.Close()
+	}()
+
+	logger.Info("listening", "addr", listener.Addr().String(), "path", *dbPath, "tls", *certFile != "")
+
+	srv := &server{store: store, logger: logger, token: *token}
+
+	err = srv.serve(listener)
+	if err != nil {
+		logger.Error("serve error", "error", err)
+	}
+
+	err = store.Close()
+	if err != nil {
+		logger.Error("close error", "error", err)
+		os.Exit(1)
+	}
+}
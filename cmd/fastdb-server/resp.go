@@ -0,0 +1,179 @@
+package main
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// limits are the maximum sizes of a command that is read, so a client can't make the server allocate too much.
+type limits struct {
+	args  int // the number of arguments of a command
+	bulk  int // the size of one argument (or an inline command)
+	total int // the size of all the arguments of a command together
+}
+
+// commandLimits are the limits of a command, an argument is as big as the body of a PUT of the HTTP API.
+var commandLimits = limits{args: 1024 * 1024, bulk: 32 * 1024 * 1024, total: 64 * 1024 * 1024}
+
+// authLimits are the limits before a connection is authenticated (with a token), enough for AUTH [username] token.
+var authLimits = limits{args: 8, bulk: 4096, total: 8192}
+
+var errProtocol = errors.New("protocol error")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+readCommand reads one command in the RESP format: an array of bulk strings,
+or an inline command (a line with space separated arguments, as telnet sends it).
+Nothing is allocated for the lengths the client sends, only for the data that really arrives.
+*/
+func readCommand(reader *bufio.Reader, lim limits) ([]string, error) {
+	line, err := readLine(reader, lim.bulk)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil || count < 0 || count > lim.args {
+		return nil, fmt.Errorf("%w: wrong array length '%s'", errProtocol, line[1:])
+	}
+
+	var (
+		args  []string
+		total int
+	)
+
+	for range count {
+		arg, err := readBulk(reader, min(lim.bulk, lim.total-total))
+		if err != nil {
+			return nil, err
+		}
+
+		total += len(arg)
+		args = append(args, arg)
+	}
+
+	return args, nil
+}
+
+/*
+readBulk reads one bulk string of at most size bytes.
+*/
+func readBulk(reader *bufio.Reader, size int) (string, error) {
+	line, err := readLine(reader, size)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasPrefix(line, "$") {
+		return "", fmt.Errorf("%w: expected '$', got '%s'", errProtocol, line)
+	}
+
+	length, err := strconv.Atoi(line[1:])
+	if err != nil || length < 0 || length > size {
+		return "", fmt.Errorf("%w: wrong bulk length '%s'", errProtocol, line[1:])
+	}
+
+	// the buffer grows with the data that arrives, not with the length that was announced
+	var data bytes.Buffer
+
+	_, err = io.CopyN(&data, reader, int64(length)+2)
+	if err != nil {
+		return "", err //nolint:wrapcheck // the connection is closed anyway
+	}
+
+	if !bytes.HasSuffix(data.Bytes(), []byte("\r\n")) {
+		return "", fmt.Errorf("%w: bulk string doesn't end with CRLF", errProtocol)
+	}
+
+	return string(data.Bytes()[:length]), nil
+}
+
+/*
+readLine reads one line (of at most size bytes) without the line ending.
+*/
+func readLine(reader *bufio.Reader, size int) (string, error) {
+	var line []byte
+
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+
+		if len(line) > size+2 {
+			return "", fmt.Errorf("%w: line too long", errProtocol)
+		}
+
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return "", err //nolint:wrapcheck // the connection is closed anyway
+		}
+	}
+
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+/*
+writeSimple writes a simple string, like OK.
+*/
+func writeSimple(writer *bufio.Writer, text string) {
+	_, _ = writer.WriteString("+" + text + "\r\n")
+}
+
+/*
+writeError writes an error reply.
+*/
+func writeError(writer *bufio.Writer, text string) {
+	_, _ = writer.WriteString("-ERR " + strings.ReplaceAll(text, "\n", " ") + "\r\n")
+}
+
+/*
+writeErrorCode writes an error reply with another code than ERR (like NOAUTH).
+*/
+func writeErrorCode(writer *bufio.Writer, code, text string) {
+	_, _ = writer.WriteString("-" + code + " " + strings.ReplaceAll(text, "\n", " ") + "\r\n")
+}
+
+/*
+writeInt writes an integer reply.
+*/
+func writeInt(writer *bufio.Writer, value int) {
+	_, _ = writer.WriteString(":" + strconv.Itoa(value) + "\r\n")
+}
+
+/*
+writeBulk writes a bulk string, or the null bulk string for nil.
+*/
+func writeBulk(writer *bufio.Writer, data []byte) {
+	if data == nil {
+		_, _ = writer.WriteString("$-1\r\n")
+
+		return
+	}
+
+	_, _ = writer.WriteString("$" + strconv.Itoa(len(data)) + "\r\n")
+	_, _ = writer.Write(data)
+	_, _ = writer.WriteString("\r\n")
+}
+
+/*
+writeArrayHeader writes the start of an array, the elements have to follow.
+*/
+func writeArrayHeader(writer *bufio.Writer, count int) {
+	_, _ = writer.WriteString("*" + strconv.Itoa(count) + "\r\n")
+}
@@ -0,0 +1,460 @@
+package main
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const defaultScanCount = 10
+
+// server serves a database over the Redis protocol (RESP).
+// Redis keys are mapped onto the buckets as "<bucket>:<key>", where key is a number.
+// Every command is checked with the authorization hooks of the database, with a context per connection.
+// With a token, a connection has to authenticate (AUTH token) before it can execute other commands,
+// and until then only small commands are read (see authLimits).
+type server struct {
+	store  *fastdb.DB
+	logger *slog.Logger
+	token  string
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+serve accepts connections until the listener is closed.
+*/
+func (srv *server) serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+
+			return err //nolint:wrapcheck // it is logged by the caller
+		}
+
+		go srv.handle(conn)
+	}
+}
+
+/*
+handle executes the commands of one connection until it's closed.
+*/
+func (srv *server) handle(conn net.Conn) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer func() {
+		cancel()
+
+		_ = conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	authenticated := srv.token == ""
+
+	for {
+		// a client without the token can't send big commands
+		lim := authLimits
+		if authenticated {
+			lim = commandLimits
+		}
+
+		args, err := readCommand(reader, lim)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				srv.logger.Debug("connection error", "remote", conn.RemoteAddr(), "error", err)
+				writeError(writer, err.Error())
+				_ = writer.Flush()
+			}
+
+			return
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := false
+
+		switch command := strings.ToUpper(args[0]); {
+		case command == "AUTH":
+			authenticated = srv.auth(writer, args[1:]) || authenticated
+		case !authenticated && command != "QUIT":
+			writeErrorCode(writer, "NOAUTH", "Authentication required.")
+		default:
+			quit = srv.execute(ctx, writer, args)
+		}
+
+		err = writer.Flush()
+		if err != nil || quit {
+			return
+		}
+	}
+}
+
+/*
+execute executes one command and writes the reply.
+It returns true if the connection should be closed.
+*/
+func (srv *server) execute(ctx context.Context, writer *bufio.Writer, args []string) bool {
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		if len(args) > 1 {
+			writeBulk(writer, []byte(args[1]))
+		} else {
+			writeSimple(writer, "PONG")
+		}
+	case "GET":
+		srv.get(ctx, writer, args[1:])
+	case "SET":
+		srv.set(ctx, writer, args[1:])
+	case "DEL":
+		srv.del(ctx, writer, args[1:])
+	case "EXISTS":
+		srv.exists(ctx, writer, args[1:])
+	case "SCAN":
+		srv.scan(ctx, writer, args[1:])
+	case "COMMAND":
+		writeArrayHeader(writer, 0)
+	case "QUIT":
+		writeSimple(writer, "OK")
+
+		return true
+	default:
+		writeError(writer, "unknown command '"+args[0]+"'")
+	}
+
+	return false
+}
+
+/*
+auth handles: AUTH [username] token
+It returns true if the token is right, the username is ignored.
+*/
+func (srv *server) auth(writer *bufio.Writer, args []string) bool {
+	if len(args) != 1 && len(args) != 2 {
+		writeError(writer, "wrong number of arguments for 'auth' command")
+
+		return false
+	}
+
+	if srv.token == "" {
+		writeError(writer, "AUTH called without any token configured")
+
+		return false
+	}
+
+	if subtle.ConstantTimeCompare([]byte(args[len(args)-1]), []byte(srv.token)) != 1 {
+		writeErrorCode(writer, "WRONGPASS", "invalid token")
+
+		return false
+	}
+
+	writeSimple(writer, "OK")
+
+	return true
+}
+
+/*
+get handles: GET key
+*/
+func (srv *server) get(ctx context.Context, writer *bufio.Writer, args []string) {
+	if len(args) != 1 {
+		writeError(writer, "wrong number of arguments for 'get' command")
+
+		return
+	}
+
+	bucket, key, ok := parseKey(args[0])
+	if !ok {
+		writeKeyError(writer, args[0])
+
+		return
+	}
+
+	err := srv.store.Authorize(ctx, fastdb.OpRead, bucket, key)
+	if err != nil {
+		writeError(writer, err.Error())
+
+		return
+	}
+
+	data, found := srv.store.Get(bucket, key)
+	if found && data == nil {
+		data = []byte{}
+	}
+
+	writeBulk(writer, data)
+}
+
+/*
+set handles: SET key value [EX seconds | PX milliseconds]
+*/
+func (srv *server) set(ctx context.Context, writer *bufio.Writer, args []string) {
+	if len(args) != 2 && len(args) != 4 {
+		writeError(writer, "wrong number of arguments for 'set' command")
+
+		return
+	}
+
+	bucket, key, ok := parseKey(args[0])
+	if !ok {
+		writeKeyError(writer, args[0])
+
+		return
+	}
+
+	err := srv.store.Authorize(ctx, fastdb.OpWrite, bucket, key)
+	if err != nil {
+		writeError(writer, err.Error())
+
+		return
+	}
+
+	var ttl time.Duration
+
+	if len(args) == 4 {
+		amount, err := strconv.Atoi(args[3])
+		if err != nil || amount <= 0 {
+			writeError(writer, "invalid expire time in 'set' command")
+
+			return
+		}
+
+		switch strings.ToUpper(args[2]) {
+		case "EX":
+			ttl = time.Duration(amount) * time.Second
+		case "PX":
+			ttl = time.Duration(amount) * time.Millisecond
+		default:
+			writeError(writer, "syntax error")
+
+			return
+		}
+	}
+
+	if ttl > 0 {
+		err = srv.store.SetWithTTL(bucket, key, []byte(args[1]), ttl)
+	} else {
+		err = srv.store.Set(bucket, key, []byte(args[1]))
+	}
+
+	if err != nil {
+		writeError(writer, err.Error())
+
+		return
+	}
+
+	writeSimple(writer, "OK")
+}
+
+/*
+del handles: DEL key [key ...]
+*/
+func (srv *server) del(ctx context.Context, writer *bufio.Writer, args []string) {
+	if len(args) == 0 {
+		writeError(writer, "wrong number of arguments for 'del' command")
+
+		return
+	}
+
+	count := 0
+
+	for _, arg := range args {
+		bucket, key, ok := parseKey(arg)
+		if !ok {
+			continue
+		}
+
+		err := srv.store.Authorize(ctx, fastdb.OpDelete, bucket, key)
+		if err != nil {
+			writeError(writer, err.Error())
+
+			return
+		}
+
+		deleted, err := srv.store.Del(bucket, key)
+		if err != nil {
+			writeError(writer, err.Error())
+
+			return
+		}
+
+		if deleted {
+			count++
+		}
+	}
+
+	writeInt(writer, count)
+}
+
+/*
+exists handles: EXISTS key [key ...]
+*/
+func (srv *server) exists(ctx context.Context, writer *bufio.Writer, args []string) {
+	if len(args) == 0 {
+		writeError(writer, "wrong number of arguments for 'exists' command")
+
+		return
+	}
+
+	count := 0
+
+	for _, arg := range args {
+		bucket, key, ok := parseKey(arg)
+		if !ok {
+			continue
+		}
+
+		err := srv.store.Authorize(ctx, fastdb.OpRead, bucket, key)
+		if err != nil {
+			writeError(writer, err.Error())
+
+			return
+		}
+
+		_, found := srv.store.Get(bucket, key)
+		if found {
+			count++
+		}
+	}
+
+	writeInt(writer, count)
+}
+
+/*
+scan handles: SCAN cursor [MATCH pattern] [COUNT count]
+The cursor is the position in the sorted list of all the keys.
+*/
+func (srv *server) scan(ctx context.Context, writer *bufio.Writer, args []string) {
+	if len(args) == 0 || len(args)%2 == 0 {
+		writeError(writer, "wrong number of arguments for 'scan' command")
+
+		return
+	}
+
+	cursor, err := strconv.Atoi(args[0])
+	if err != nil || cursor < 0 {
+		writeError(writer, "invalid cursor")
+
+		return
+	}
+
+	pattern := "*"
+	count := defaultScanCount
+
+	for i := 1; i < len(args); i += 2 {
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			count, err = strconv.Atoi(args[i+1])
+			if err != nil || count <= 0 {
+				writeError(writer, "value is not an integer or out of range")
+
+				return
+			}
+		default:
+			writeError(writer, "syntax error")
+
+			return
+		}
+	}
+
+	keys := srv.allKeys(ctx)
+	end := min(cursor+count, len(keys))
+	next := end
+
+	if end >= len(keys) {
+		next = 0
+	}
+
+	var matches []string
+
+	for _, key := range keys[min(cursor, end):end] {
+		matched, err := path.Match(pattern, key)
+		if err != nil {
+			writeError(writer, "invalid pattern")
+
+			return
+		}
+
+		if matched {
+			matches = append(matches, key)
+		}
+	}
+
+	writeArrayHeader(writer, 2)
+	writeBulk(writer, []byte(strconv.Itoa(next)))
+	writeArrayHeader(writer, len(matches))
+
+	for _, key := range matches {
+		writeBulk(writer, []byte(key))
+	}
+}
+
+/*
+allKeys returns all the keys (as "<bucket>:<key>") sorted by bucket and key.
+The buckets that may not be read are left out.
+*/
+func (srv *server) allKeys(ctx context.Context) []string {
+	var keys []string
+
+	for _, bucket := range srv.store.Buckets() {
+		if srv.store.Authorize(ctx, fastdb.OpRead, bucket, fastdb.AnyKey) != nil {
+			continue
+		}
+
+		records, err := srv.store.GetAllSorted(bucket)
+		if err != nil {
+			continue // the bucket was removed in the meantime
+		}
+
+		for _, record := range records {
+			keys = append(keys, bucket+":"+strconv.Itoa(record.SortField.(int))) //nolint:forcetypeassert // always an int
+		}
+	}
+
+	return keys
+}
+
+/*
+parseKey splits a Redis key in the format "<bucket>:<key>" into the bucket and the key.
+*/
+func parseKey(redisKey string) (string, int, bool) {
+	cPos := strings.LastIndex(redisKey, ":")
+	if cPos < 1 {
+		return "", 0, false
+	}
+
+	key, err := strconv.Atoi(redisKey[cPos+1:])
+	if err != nil || key < 0 {
+		return "", 0, false
+	}
+
+	return redisKey[:cPos], key, true
+}
+
+/*
+writeKeyError writes the error for a key in the wrong format.
+*/
+func writeKeyError(writer *bufio.Writer, redisKey string) {
+	writeError(writer, "key '"+redisKey+"' should be in the format <bucket>:<number>")
+}
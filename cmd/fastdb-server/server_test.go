@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_server(t *testing.T) {
+	store, err := fastdb.Open(":memory:")
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := &server{store: store, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	done := make(chan error)
+
+	go func() {
+		done <- srv.serve(listener)
+	}()
+
+	defer func() {
+		require.NoError(t, listener.Close())
+		require.NoError(t, <-done)
+		require.NoError(t, store.Close())
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+
+	send := func(command, expected string) {
+		t.Helper()
+
+		_, err := conn.Write([]byte(command))
+		require.NoError(t, err)
+
+		reply := make([]byte, len(expected))
+		_, err = io.ReadFull(reader, reply)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(reply))
+	}
+
+	send("PING\r\n", "+PONG\r\n")
+	send("*3\r\n$3\r\nSET\r\n$6\r\nuser:1\r\n$8\r\nline 1\r\n\r\n", "+OK\r\n")
+	send("*2\r\n$3\r\nGET\r\n$6\r\nuser:1\r\n", "$8\r\nline 1\r\n\r\n")
+	send("SET user:2 second\r\n", "+OK\r\n")
+	send("SET user:3 third EX 60\r\n", "+OK\r\n")
+	send("SET other:10 value\r\n", "+OK\r\n")
+	send("GET user:4\r\n", "$-1\r\n")
+	send("GET user\r\n", "-ERR key 'user' should be in the format <bucket>:<number>\r\n")
+	send("EXISTS user:1 user:4 other:10\r\n", ":2\r\n")
+	send("SCAN 0 COUNT 2\r\n", "*2\r\n$1\r\n2\r\n*2\r\n$8\r\nother:10\r\n$6\r\nuser:1\r\n")
+	send("SCAN 2 COUNT 2\r\n", "*2\r\n$1\r\n0\r\n*2\r\n$6\r\nuser:2\r\n$6\r\nuser:3\r\n")
+	send("SCAN 0 MATCH other:* COUNT 100\r\n", "*2\r\n$1\r\n0\r\n*1\r\n$8\r\nother:10\r\n")
+	send("DEL user:1 user:4 user:2\r\n", ":2\r\n")
+
+	store.OnAuthorize(func(_ context.Context, _ fastdb.Operation, bucket string, _ int) error {
+		if bucket == "other" {
+			return errors.New("not allowed")
+		}
+
+		return nil
+	})
+
+	send("SET other:11 value\r\n", "-ERR authorize (write other_11) error: access denied: not allowed\r\n")
+	send("SCAN 0 COUNT 100\r\n", "*2\r\n$1\r\n0\r\n*1\r\n$6\r\nuser:3\r\n")
+	send("FLUSHALL\r\n", "-ERR unknown command 'FLUSHALL'\r\n")
+	send("QUIT\r\n", "+OK\r\n")
+
+	_, err = reader.ReadByte()
+	require.ErrorIs(t, err, io.EOF)
+
+	ttl, ok := store.TTL("user", 3)
+	assert.True(t, ok)
+	assert.Positive(t, ttl)
+	assert.Equal(t, "2 record(s) in 2 bucket(s)", store.Info())
+}
+
+func Test_serverToken(t *testing.T) {
+	store, err := fastdb.Open(":memory:")
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := &server{store: store, logger: slog.New(slog.NewTextHandler(io.Discard, nil)), token: "secret"}
+	done := make(chan error)
+
+	go func() {
+		done <- srv.serve(listener)
+	}()
+
+	defer func() {
+		require.NoError(t, listener.Close())
+		require.NoError(t, <-done)
+		require.NoError(t, store.Close())
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+
+	send := func(command, expected string) {
+		t.Helper()
+
+		_, err := conn.Write([]byte(command))
+		require.NoError(t, err)
+
+		reply := make([]byte, len(expected))
+		_, err = io.ReadFull(reader, reply)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(reply))
+	}
+
+	send("SET user:1 value\r\n", "-NOAUTH Authentication required.\r\n")
+	send("AUTH wrong\r\n", "-WRONGPASS invalid token\r\n")
+	send("GET user:1\r\n", "-NOAUTH Authentication required.\r\n")
+	send("AUTH default secret\r\n", "+OK\r\n")
+	send("SET user:1 value\r\n", "+OK\r\n")
+	send("AUTH wrong\r\n", "-WRONGPASS invalid token\r\n")
+	send("GET user:1\r\n", "$5\r\nvalue\r\n")
+	send("QUIT\r\n", "+OK\r\n")
+
+	// before AUTH, a big command is refused before it is read
+	conn, err = net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+
+	reader = bufio.NewReader(conn)
+
+	send("*1048576\r\n", "-ERR protocol error: wrong array length '1048576'\r\n")
+
+	_, err = reader.ReadByte()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func Test_readCommand_limits(t *testing.T) {
+	read := func(input string) ([]string, error) {
+		return readCommand(bufio.NewReader(strings.NewReader(input)), limits{args: 4, bulk: 16, total: 24})
+	}
+
+	args, err := read("*2\r\n$3\r\nGET\r\n$6\r\nuser:1\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GET", "user:1"}, args)
+
+	for _, input := range []string{
+		"*9223372036854775807\r\n",
+		"*-1\r\n",
+		"*5\r\n",
+		"*1\r\n$17\r\n",
+		"*2\r\n$16\r\n0123456789abcdef\r\n$16\r\n0123456789abcdef\r\n",
+		strings.Repeat("x", 100) + "\r\n",
+	} {
+		_, err = read(input)
+		require.ErrorIs(t, err, errProtocol, input)
+	}
+
+	// a bulk that is announced but never sent allocates nothing
+	_, err = read("*1\r\n$16\r\nshort")
+	require.ErrorIs(t, err, io.EOF)
+}
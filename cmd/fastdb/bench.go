@@ -0,0 +1,593 @@
+package main
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const benchBucket = "bench"
+
+// benchOptions holds the workload of the benchmark.
+type benchOptions struct {
+	token       string
+	mix         []benchOp
+	ops         int
+	minSize     int
+	maxSize     int
+	concurrency int
+	keys        int
+	syncTime    int
+	prefill     bool
+}
+
+// benchOp is one kind of operation, with its weight in the mix.
+type benchOp struct {
+	name   string
+	weight int
+}
+
+// benchClient executes the operations of the benchmark on a target.
+type benchClient interface {
+	get(key int) error
+	set(key int, value []byte) error
+	del(key int) error
+	close() error
+}
+
+// fileClient runs the benchmark on a database file (or :memory:), shared by all the workers.
+type fileClient struct {
+	store *fastdb.DB
+}
+
+// httpClient runs the benchmark on the HTTP API (see fastdbhttp).
+type httpClient struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+// respClient runs the benchmark on a Redis server (like fastdb-server), with a connection per worker.
+type respClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+var (
+	errBenchTarget = errors.New("unexpected reply")
+
+	// letters are the bytes of the values, so they are valid in every format.
+	letters = bytes.Repeat([]byte("abcdefghijklmnopqrstuvwxyz"), 1024)
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+bench runs a workload against a file or a server and prints the latency percentiles per operation.
+The records are stored in the bucket "bench".
+*/
+func bench(args []string, stdout io.Writer) error {
+	target := args[0]
+
+	opts, err := parseBenchOptions(args[1:])
+	if err != nil {
+		return err
+	}
+
+	newClient, cleanup, err := openTarget(target, opts)
+	if err != nil {
+		return fmt.Errorf("bench error: %w", err)
+	}
+
+	defer func() {
+		_ = cleanup()
+	}()
+
+	if opts.prefill {
+		err = prefill(newClient, opts)
+		if err != nil {
+			return fmt.Errorf("bench->prefill error: %w", err)
+		}
+	}
+
+	start := time.Now()
+
+	latencies, err := runWorkers(newClient, opts)
+	if err != nil {
+		return fmt.Errorf("bench error: %w", err)
+	}
+
+	elapsed := time.Since(start)
+
+	err = cleanup()
+	if err != nil {
+		return fmt.Errorf("bench error: %w", err)
+	}
+
+	return printBench(stdout, target, opts, latencies, elapsed)
+}
+
+/*
+parseBenchOptions parses the flags of the benchmark.
+*/
+func parseBenchOptions(args []string) (*benchOptions, error) {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	opts := &benchOptions{}
+	flags.IntVar(&opts.ops, "ops", 10000, "the number of operations")
+	flags.IntVar(&opts.concurrency, "concurrency", 4, "the number of concurrent workers")
+	flags.IntVar(&opts.keys, "keys", 10000, "the number of different keys")
+	flags.IntVar(&opts.syncTime, "sync", 100, "the sync time (in milliseconds) of a file")
+	flags.BoolVar(&opts.prefill, "prefill", true, "store all the keys before the benchmark")
+	flags.StringVar(&opts.token, "token", os.Getenv("FASTDB_TOKEN"), "the token of the server")
+	mix := flags.String("mix", "get:80,set:15,del:5", "the ratio of the operations")
+	size := flags.String("size", "100", "the size of the values in bytes, or a range like 100-1000")
+
+	err := flags.Parse(args)
+	if err != nil || flags.NArg() > 0 || opts.ops <= 0 || opts.concurrency <= 0 || opts.keys <= 0 {
+		return nil, errUsage
+	}
+
+	opts.mix, err = parseMix(*mix)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.minSize, opts.maxSize, err = parseSize(*size)
+	if err != nil {
+		return nil, err
+	}
+
+	return opts, nil
+}
+
+/*
+parseMix parses the ratio of the operations, like get:80,set:15,del:5.
+*/
+func parseMix(mix string) ([]benchOp, error) {
+	var ops []benchOp
+
+	for _, part := range strings.Split(mix, ",") {
+		name, weight, found := strings.Cut(part, ":")
+		amount, err := strconv.Atoi(weight)
+
+		if !found || err != nil || amount < 0 || !slices.Contains([]string{"get", "set", "del"}, name) {
+			return nil, fmt.Errorf("mix (%s) should be like get:80,set:15,del:5", mix)
+		}
+
+		ops = append(ops, benchOp{name: name, weight: amount})
+	}
+
+	if !slices.ContainsFunc(ops, func(op benchOp) bool { return op.weight > 0 }) {
+		return nil, fmt.Errorf("mix (%s) has no operations", mix)
+	}
+
+	return ops, nil
+}
+
+/*
+parseSize parses the size of the values, a number or a range like 100-1000.
+*/
+func parseSize(size string) (int, int, error) {
+	minText, maxText, isRange := strings.Cut(size, "-")
+	if !isRange {
+		maxText = minText
+	}
+
+	minSize, err := strconv.Atoi(minText)
+	if err == nil {
+		var maxSize int
+
+		maxSize, err = strconv.Atoi(maxText)
+		if err == nil && minSize >= 0 && maxSize >= minSize && maxSize <= len(letters) {
+			return minSize, maxSize, nil
+		}
+	}
+
+	return 0, 0, fmt.Errorf("size (%s) should be a number or a range like 100-1000 (up to %d)", size, len(letters))
+}
+
+/*
+openTarget returns the function that creates a client for a worker, and the function that cleans up.
+The target is a file, an http(s):// URL of the HTTP API or a redis:// address.
+*/
+func openTarget(target string, opts *benchOptions) (func() (benchClient, error), func() error, error) {
+	switch {
+	case strings.HasPrefix(target, "http://"), strings.HasPrefix(target, "https://"):
+		client := &httpClient{client: &http.Client{Timeout: 10 * time.Second}, baseURL: strings.TrimSuffix(target, "/"), token: opts.token}
+
+		return func() (benchClient, error) { return client, nil }, func() error { return nil }, nil
+	case strings.HasPrefix(target, "redis://"):
+		newClient := func() (benchClient, error) {
+			return dialRESP(strings.TrimPrefix(target, "redis://"), opts.token)
+		}
+
+		return newClient, func() error { return nil }, nil
+	default:
+		store, err := fastdb.Open(target, fastdb.WithSyncTime(opts.syncTime))
+		if err != nil {
+			return nil, nil, err //nolint:wrapcheck // it is wrapped by the caller
+		}
+
+		client := &fileClient{store: store}
+		once := sync.OnceValue(store.Close)
+
+		return func() (benchClient, error) { return client, nil }, once, nil
+	}
+}
+
+/*
+prefill stores all the keys, so the gets and dels find something.
+*/
+func prefill(newClient func() (benchClient, error), opts *benchOptions) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = client.close()
+	}()
+
+	rnd := rand.New(rand.NewPCG(0, 0)) //nolint:gosec // it's not about security
+
+	for key := range opts.keys {
+		err = client.set(key, opts.value(rnd))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+runWorkers runs the operations with the workers, and returns the latencies per operation.
+*/
+func runWorkers(newClient func() (benchClient, error), opts *benchOptions) (map[string][]time.Duration, error) {
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		latencies = map[string][]time.Duration{}
+		errs      []error
+	)
+
+	for worker := range opts.concurrency {
+		count := opts.ops / opts.concurrency
+		if worker < opts.ops%opts.concurrency {
+			count++
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			measured, err := runWorker(newClient, opts, uint64(worker), count) //nolint:gosec // worker is never negative
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			for name, durations := range measured {
+				latencies[name] = append(latencies[name], durations...)
+			}
+
+			errs = append(errs, err)
+		}()
+	}
+
+	wg.Wait()
+
+	return latencies, errors.Join(errs...)
+}
+
+/*
+runWorker runs a number of random operations with its own client, and returns the latencies per operation.
+*/
+func runWorker(newClient func() (benchClient, error), opts *benchOptions, seed uint64, count int) (map[string][]time.Duration, error) {
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = client.close()
+	}()
+
+	rnd := rand.New(rand.NewPCG(seed, seed)) //nolint:gosec // it's not about security
+	latencies := map[string][]time.Duration{}
+
+	total := 0
+	for _, op := range opts.mix {
+		total += op.weight
+	}
+
+	for range count {
+		name := opts.pick(rnd.IntN(total))
+		key := rnd.IntN(opts.keys)
+
+		start := time.Now()
+
+		switch name {
+		case "get":
+			err = client.get(key)
+		case "set":
+			err = client.set(key, opts.value(rnd))
+		default:
+			err = client.del(key)
+		}
+
+		if err != nil {
+			return latencies, fmt.Errorf("%s (%d) error: %w", name, key, err)
+		}
+
+		latencies[name] = append(latencies[name], time.Since(start))
+	}
+
+	return latencies, nil
+}
+
+/*
+pick returns the operation for a number below the total weight of the mix.
+*/
+func (opts *benchOptions) pick(number int) string {
+	for _, op := range opts.mix {
+		if number < op.weight {
+			return op.name
+		}
+
+		number -= op.weight
+	}
+
+	return opts.mix[len(opts.mix)-1].name
+}
+
+/*
+value returns a value with a random size within the range.
+*/
+func (opts *benchOptions) value(rnd *rand.Rand) []byte {
+	size := opts.minSize + rnd.IntN(opts.maxSize-opts.minSize+1)
+	start := rnd.IntN(len(letters) - size + 1)
+
+	return letters[start : start+size]
+}
+
+/*
+printBench prints the throughput and the latency percentiles per operation.
+*/
+func printBench(stdout io.Writer, target string, opts *benchOptions, latencies map[string][]time.Duration,
+	elapsed time.Duration,
+) error {
+	writer := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	_, _ = fmt.Fprintf(stdout, "target: %s, concurrency: %d, values: %d-%d bytes\n",
+		target, opts.concurrency, opts.minSize, opts.maxSize)
+	_, _ = fmt.Fprintf(stdout, "%d operations in %s (%.0f ops/s)\n",
+		opts.ops, elapsed.Round(time.Millisecond), float64(opts.ops)/elapsed.Seconds())
+	_, _ = fmt.Fprintln(writer, "op\tcount\tp50\tp90\tp99\tp99.9\tmax\t")
+
+	for _, op := range opts.mix {
+		durations := latencies[op.name]
+		if len(durations) == 0 {
+			continue
+		}
+
+		slices.Sort(durations)
+
+		_, _ = fmt.Fprintf(writer, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t\n", op.name, len(durations),
+			percentile(durations, 0.5), percentile(durations, 0.9), percentile(durations, 0.99),
+			percentile(durations, 0.999), durations[len(durations)-1])
+	}
+
+	return writer.Flush() //nolint:wrapcheck // it is the output
+}
+
+/*
+percentile returns the percentile of the sorted durations.
+*/
+func percentile(sorted []time.Duration, fraction float64) time.Duration {
+	return sorted[int(fraction*float64(len(sorted)-1))]
+}
+
+/*
+get reads a key from the file.
+*/
+func (cln *fileClient) get(key int) error {
+	_, _ = cln.store.Get(benchBucket, key)
+
+	return nil
+}
+
+/*
+set stores a key in the file.
+*/
+func (cln *fileClient) set(key int, value []byte) error {
+	return cln.store.Set(benchBucket, key, value) //nolint:wrapcheck // it is already wrapped
+}
+
+/*
+del deletes a key from the file.
+*/
+func (cln *fileClient) del(key int) error {
+	_, err := cln.store.Del(benchBucket, key)
+
+	return err //nolint:wrapcheck // it is already wrapped
+}
+
+/*
+close does nothing, the file is shared by the workers.
+*/
+func (*fileClient) close() error {
+	return nil
+}
+
+/*
+get reads a key with the HTTP API.
+*/
+func (cln *httpClient) get(key int) error {
+	return cln.call(http.MethodGet, key, nil)
+}
+
+/*
+set stores a key with the HTTP API.
+*/
+func (cln *httpClient) set(key int, value []byte) error {
+	return cln.call(http.MethodPut, key, value)
+}
+
+/*
+del deletes a key with the HTTP API.
+*/
+func (cln *httpClient) del(key int) error {
+	return cln.call(http.MethodDelete, key, nil)
+}
+
+/*
+close does nothing, the connections are reused by the workers.
+*/
+func (*httpClient) close() error {
+	return nil
+}
+
+/*
+call executes one request of the HTTP API, a missing key is no error.
+*/
+func (cln *httpClient) call(method string, key int, value []byte) error {
+	url := cln.baseURL + "/buckets/" + benchBucket + "/keys/" + strconv.Itoa(key)
+
+	request, err := http.NewRequest(method, url, bytes.NewReader(value)) //nolint:noctx // every request has a timeout
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	if cln.token != "" {
+		request.Header.Set("Authorization", "Bearer "+cln.token)
+	}
+
+	response, err := cln.client.Do(request)
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	_, _ = io.Copy(io.Discard, response.Body)
+	_ = response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest && response.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("%w: %s", errBenchTarget, response.Status)
+	}
+
+	return nil
+}
+
+/*
+dialRESP connects to a Redis server, and authenticates with the token (if any).
+*/
+func dialRESP(addr, token string) (*respClient, error) {
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	cln := &respClient{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}
+
+	if token != "" {
+		err = cln.call("AUTH", token)
+		if err != nil {
+			_ = conn.Close()
+
+			return nil, err
+		}
+	}
+
+	return cln, nil
+}
+
+/*
+get reads a key from the Redis server.
+*/
+func (cln *respClient) get(key int) error {
+	return cln.call("GET", benchBucket+":"+strconv.Itoa(key))
+}
+
+/*
+set stores a key at the Redis server.
+*/
+func (cln *respClient) set(key int, value []byte) error {
+	return cln.call("SET", benchBucket+":"+strconv.Itoa(key), string(value))
+}
+
+/*
+del deletes a key from the Redis server.
+*/
+func (cln *respClient) del(key int) error {
+	return cln.call("DEL", benchBucket+":"+strconv.Itoa(key))
+}
+
+/*
+close closes the connection.
+*/
+func (cln *respClient) close() error {
+	return cln.conn.Close() //nolint:wrapcheck // it is ignored
+}
+
+/*
+call sends one command and reads its reply.
+*/
+func (cln *respClient) call(args ...string) error {
+	_, _ = cln.writer.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+
+	for _, arg := range args {
+		_, _ = cln.writer.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+
+	err := cln.writer.Flush()
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	line, err := cln.reader.ReadString('\n')
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	line = strings.TrimSuffix(line, "\r\n")
+
+	switch {
+	case strings.HasPrefix(line, "-"):
+		return fmt.Errorf("%w: %s", errBenchTarget, line[1:])
+	case strings.HasPrefix(line, "$"):
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return fmt.Errorf("%w: %s", errBenchTarget, line)
+		}
+
+		if size >= 0 {
+			_, err = cln.reader.Discard(size + 2)
+		}
+
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	default:
+		return nil
+	}
+}
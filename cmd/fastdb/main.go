@@ -0,0 +1,391 @@
+/*
+Package main is a command line tool to inspect and change a fastdb database file.
+
+Usage:
+
+	fastdb get      <file> <bucket> <key>
+	fastdb set      <file> <bucket> <key> <value>   (a value of - is read from stdin)
+	fastdb del      <file> <bucket> <key>
+	fastdb list     <file> [bucket]
+	fastdb describe <file> <bucket> [description]   (without one, it prints the current description)
+	fastdb info     <file>
+	fastdb defrag   <file>
+	fastdb verify   <file>
+	fastdb check    <file>                          (a report of every problem as JSON, without opening the file)
+	fastdb advise   <file>                          (how well the values compress, and the advised level)
+	fastdb modified <file> <bucket> <key>           (when the key was last changed, see WithTimestamps)
+	fastdb bench    <file|http://host/path|redis://host:port> [flags]
+
+The bench command runs a workload (in the bucket "bench") and prints the latency percentiles
+per operation, to size the sync time and the hardware. Its flags are:
+
+	-ops 10000                  the number of operations
+	-mix get:80,set:15,del:5    the ratio of the operations
+	-size 100                   the size of the values in bytes, or a range like 100-1000
+	-concurrency 4              the number of concurrent workers
+	-keys 10000                 the number of different keys
+	-sync 100                   the sync time (in milliseconds) of a file
+	-prefill=true               store all the keys before the benchmark
+	-token ...                  the token of the server (or the environment variable FASTDB_TOKEN)
+*/
+package main
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// adviseSampleSize is the number of values per bucket that advise compresses.
+const adviseSampleSize = 1000
+
+const usage = `usage:
+  fastdb get      <file> <bucket> <key>
+  fastdb set      <file> <bucket> <key> <value>   (a value of - is read from stdin)
+  fastdb del      <file> <bucket> <key>
+  fastdb list     <file> [bucket]
+  fastdb describe <file> <bucket> [description]
+  fastdb info     <file>
+  fastdb defrag   <file>
+  fastdb verify   <file>
+  fastdb check    <file>
+  fastdb advise   <file>
+  fastdb modified <file> <bucket> <key>
+  fastdb bench    <file|http://host/path|redis://host:port> [-ops 10000] [-mix get:80,set:15,del:5]
+                  [-size 100[-1000]] [-concurrency 4] [-keys 10000] [-sync 100] [-prefill=true] [-token ...]
+`
+
+// command is one subcommand of the tool.
+type command struct {
+	run      func(store *fastdb.DB, args []string, stdin io.Reader, stdout io.Writer) error
+	minArgs  int
+	maxArgs  int
+	readOnly bool
+}
+
+var (
+	commands = map[string]command{
+		"get":      {run: get, minArgs: 2, maxArgs: 2, readOnly: true},
+		"set":      {run: set, minArgs: 3, maxArgs: 3},
+		"del":      {run: del, minArgs: 2, maxArgs: 2},
+		"list":     {run: list, minArgs: 0, maxArgs: 1, readOnly: true},
+		"describe": {run: describe, minArgs: 1, maxArgs: 2},
+		"info":     {run: info, readOnly: true},
+		"defrag":   {run: defrag},
+		"verify":   {run: info, readOnly: true},
+		"advise":   {run: advise, readOnly: true},
+		"modified": {run: modified, minArgs: 2, maxArgs: 2, readOnly: true},
+	}
+
+	errUsage    = errors.New("wrong usage")
+	errNotFound = errors.New("not found")
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+main is the bootstrap of the application.
+*/
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+/*
+run runs the command of the arguments, and returns the exit code.
+*/
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	err := execute(args, stdin, stdout)
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		_, _ = fmt.Fprint(stderr, usage)
+
+		return 2
+	default:
+		_, _ = fmt.Fprintln(stderr, "fastdb:", err)
+
+		return 1
+	}
+}
+
+/*
+execute opens the file and runs the command on it.
+*/
+func execute(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+
+	if args[0] == "bench" {
+		return bench(args[1:], stdout)
+	}
+
+	if args[0] == "check" {
+		if len(args) != 2 {
+			return errUsage
+		}
+
+		return check(args[1], stdout)
+	}
+
+	cmd, found := commands[args[0]]
+	if !found || len(args)-2 < cmd.minArgs || len(args)-2 > cmd.maxArgs {
+		return errUsage
+	}
+
+	path := args[1]
+
+	if cmd.readOnly {
+		// don't create a file that isn't there
+		_, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("%s error: %w", args[0], err)
+		}
+	}
+
+	opts := []fastdb.Option{fastdb.WithSyncTime(0)}
+	if cmd.readOnly {
+		opts = append(opts, fastdb.WithReadOnly())
+	}
+
+	store, err := fastdb.Open(path, opts...)
+	if err != nil {
+		return fmt.Errorf("%s error: %w", args[0], err)
+	}
+
+	err = cmd.run(store, args[2:], stdin, stdout)
+
+	return errors.Join(err, store.Close())
+}
+
+/*
+get prints the value of a key.
+*/
+func get(store *fastdb.DB, args []string, _ io.Reader, stdout io.Writer) error {
+	key, err := parseKey(args[1])
+	if err != nil {
+		return err
+	}
+
+	value, found := store.Get(args[0], key)
+	if !found {
+		return fmt.Errorf("get error: key %s_%d %w", args[0], key, errNotFound)
+	}
+
+	_, err = fmt.Fprintf(stdout, "%s\n", value)
+
+	return err //nolint:wrapcheck // it is the output
+}
+
+/*
+modified prints when a key was last changed, if the file holds the time.
+*/
+func modified(store *fastdb.DB, args []string, _ io.Reader, stdout io.Writer) error {
+	key, err := parseKey(args[1])
+	if err != nil {
+		return err
+	}
+
+	modTime, found, err := store.LastModified(args[0], key)
+	if err != nil {
+		return err //nolint:wrapcheck // it is already wrapped
+	}
+
+	if !found {
+		return fmt.Errorf("modified error: time of key %s_%d %w", args[0], key, errNotFound)
+	}
+
+	_, err = fmt.Fprintf(stdout, "%s\n", modTime.Format(time.RFC3339Nano))
+
+	return err //nolint:wrapcheck // it is the output
+}
+
+/*
+set stores the value of a key.
+*/
+func set(store *fastdb.DB, args []string, stdin io.Reader, _ io.Writer) error {
+	key, err := parseKey(args[1])
+	if err != nil {
+		return err
+	}
+
+	value := []byte(args[2])
+
+	if args[2] == "-" {
+		value, err = io.ReadAll(stdin)
+		if err != nil {
+			return fmt.Errorf("set->read error: %w", err)
+		}
+	}
+
+	return store.Set(args[0], key, value) //nolint:wrapcheck // it is already wrapped
+}
+
+/*
+del deletes a key.
+*/
+func del(store *fastdb.DB, args []string, _ io.Reader, _ io.Writer) error {
+	key, err := parseKey(args[1])
+	if err != nil {
+		return err
+	}
+
+	deleted, err := store.Del(args[0], key)
+	if err != nil {
+		return err //nolint:wrapcheck // it is already wrapped
+	}
+
+	if !deleted {
+		return fmt.Errorf("del error: key %s_%d %w", args[0], key, errNotFound)
+	}
+
+	return nil
+}
+
+/*
+list prints the buckets with their number of records (and description),
+or (for a bucket) all the keys with their values.
+*/
+func list(store *fastdb.DB, args []string, _ io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		descriptions := store.Descriptions()
+
+		for _, bucket := range store.Buckets() {
+			records, err := store.GetAll(bucket)
+			if err != nil {
+				return err //nolint:wrapcheck // it is already wrapped
+			}
+
+			line := fmt.Sprintf("%s\t%d", bucket, len(records))
+			if descriptions[bucket] != "" {
+				line += "\t" + descriptions[bucket]
+			}
+
+			_, err = fmt.Fprintln(stdout, line)
+			if err != nil {
+				return err //nolint:wrapcheck // it is the output
+			}
+		}
+
+		return nil
+	}
+
+	records, err := store.GetAllSorted(args[0])
+	if err != nil {
+		return err //nolint:wrapcheck // it is already wrapped
+	}
+
+	for _, record := range records {
+		_, err = fmt.Fprintf(stdout, "%d\t%s\n", record.SortField, record.Data)
+		if err != nil {
+			return err //nolint:wrapcheck // it is the output
+		}
+	}
+
+	return nil
+}
+
+/*
+describe stores the description of a bucket, or prints it.
+*/
+func describe(store *fastdb.DB, args []string, _ io.Reader, stdout io.Writer) error {
+	if len(args) == 2 {
+		return store.SetDescription(args[0], args[1]) //nolint:wrapcheck // it is already wrapped
+	}
+
+	_, err := fmt.Fprintln(stdout, store.Description(args[0]))
+
+	return err //nolint:wrapcheck // it is the output
+}
+
+/*
+info prints info about the storage. For verify, opening the file already checked every entry.
+*/
+func info(store *fastdb.DB, _ []string, _ io.Reader, stdout io.Writer) error {
+	_, err := fmt.Fprintln(stdout, store.Info())
+
+	return err //nolint:wrapcheck // it is the output
+}
+
+/*
+check prints the report of persist.Verify as JSON, and fails when it found a problem.
+The file isn't opened (or locked), so the file of a database that is in use can be checked.
+*/
+func check(path string, stdout io.Writer) error {
+	report, err := persist.Verify(path)
+	if err != nil {
+		return fmt.Errorf("check error: %w", err)
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+
+	err = encoder.Encode(report)
+	if err != nil {
+		return err //nolint:wrapcheck // it is the output
+	}
+
+	if !report.OK() {
+		return fmt.Errorf("check error: %d problem(s), %d torn byte(s) at the end", len(report.Problems), report.TornBytes)
+	}
+
+	return nil
+}
+
+/*
+defrag optimises the file.
+*/
+func defrag(store *fastdb.DB, _ []string, _ io.Reader, _ io.Writer) error {
+	return store.Defrag() //nolint:wrapcheck // it is already wrapped
+}
+
+/*
+advise prints how well the values of every bucket compress (sampled), and the advised compression level.
+*/
+func advise(store *fastdb.DB, _ []string, _ io.Reader, stdout io.Writer) error {
+	report := store.AnalyzeCompression(adviseSampleSize)
+	writer := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(writer, "bucket	records	sampled	avg size	compressible	saved (fastest)	saved (default)")
+
+	for _, bucket := range report.Buckets {
+		_, _ = fmt.Fprintf(writer, "%s\t%d\t%d\t%.0f\t%.0f%%\t%.0f%%\t%.0f%%\n", bucket.Bucket, bucket.Records,
+			bucket.Sampled, bucket.AvgValueSize, bucket.Compressible*100, (1-bucket.RatioFastest)*100,
+			(1-bucket.RatioDefault)*100)
+	}
+
+	err := writer.Flush()
+	if err != nil {
+		return err //nolint:wrapcheck // it is the output
+	}
+
+	_, err = fmt.Fprintf(stdout, "advice: %s (WithCompression(%d))\n", report.Advice, report.Level)
+
+	return err //nolint:wrapcheck // it is the output
+}
+
+/*
+parseKey parses the key argument.
+*/
+func parseKey(arg string) (int, error) {
+	key, err := strconv.Atoi(arg)
+	if err != nil || key < 0 {
+		return 0, fmt.Errorf("key (%s) should be a positive number", arg)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/marcelloh/fastdb/fastdbhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_run(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "fast.db")
+
+	call := func(stdin string, args ...string) (int, string, string) {
+		t.Helper()
+
+		stdout := &bytes.Buffer{}
+		stderr := &bytes.Buffer{}
+		code := run(args, strings.NewReader(stdin), stdout, stderr)
+
+		return code, stdout.String(), stderr.String()
+	}
+
+	code, _, stderr := call("", "get", path, "user", "1")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "no such file")
+
+	code, _, _ = call("", "set", path, "user", "2", "second")
+	assert.Equal(t, 0, code)
+
+	code, _, _ = call("first line", "set", path, "user", "1", "-")
+	assert.Equal(t, 0, code)
+
+	code, _, _ = call("", "set", path, "other", "1", "value")
+	assert.Equal(t, 0, code)
+
+	code, stdout, _ := call("", "get", path, "user", "2")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "second\n", stdout)
+
+	code, _, stderr = call("", "get", path, "user", "3")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "not found")
+
+	code, stdout, _ = call("", "list", path)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "other\t1\nuser\t2\n", stdout)
+
+	code, _, _ = call("", "describe", path, "user", "the registered users")
+	assert.Equal(t, 0, code)
+
+	code, stdout, _ = call("", "describe", path, "user")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "the registered users\n", stdout)
+
+	code, stdout, _ = call("", "list", path)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "other\t1\nuser\t2\tthe registered users\n", stdout)
+
+	code, stdout, _ = call("", "list", path, "user")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "1\tfirst line\n2\tsecond\n", stdout)
+
+	code, _, _ = call("", "del", path, "other", "1")
+	assert.Equal(t, 0, code)
+
+	code, _, _ = call("", "del", path, "other", "1")
+	assert.Equal(t, 1, code)
+
+	code, _, _ = call("", "defrag", path)
+	assert.Equal(t, 0, code)
+
+	code, stdout, _ = call("", "info", path)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "2 record(s) in 1 bucket(s)\n", stdout)
+
+	code, stdout, _ = call("", "verify", path)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "2 record(s) in 1 bucket(s)\n", stdout)
+
+	code, stdout, _ = call("", "advise", path)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "compressible")
+	assert.Contains(t, stdout, "advice: don't compress")
+
+	code, _, stderr = call("", "get", path, "user", "abc")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "positive number")
+
+	code, stdout, _ = call("", "check", path)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, `"problems": []`)
+	assert.Contains(t, stdout, `"live": 2`)
+
+	err := os.WriteFile(path, []byte("set\nuser_1\nvalue\nbroken\n"), 0o600)
+	assert.NoError(t, err)
+
+	code, stdout, stderr = call("", "check", path)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, `"reason": "wrong instruction format 'broken'"`)
+	assert.Contains(t, stderr, "1 problem(s)")
+
+	code, _, stderr = call("", "verify", path)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "wrong instruction format")
+
+	code, _, stderr = call("", "unknown", path)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "usage")
+
+	code, _, _ = call("", "get", path, "user")
+	assert.Equal(t, 2, code)
+}
+
+func Test_modified(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fast.db")
+
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(0), fastdb.WithTimestamps())
+	require.NoError(t, err)
+
+	before := time.Now()
+
+	err = store.Set("user", 1, []byte("first"))
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	stdout := &bytes.Buffer{}
+	code := run([]string{"modified", path, "user", "1"}, nil, stdout, &bytes.Buffer{})
+	assert.Equal(t, 0, code)
+
+	modTime, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(stdout.String()))
+	require.NoError(t, err)
+	assert.False(t, modTime.Before(before))
+
+	stderr := &bytes.Buffer{}
+	code = run([]string{"modified", path, "user", "2"}, nil, &bytes.Buffer{}, stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "not found")
+}
+
+func Test_bench(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bench.db")
+
+	call := func(args ...string) (int, string, string) {
+		t.Helper()
+
+		stdout := &bytes.Buffer{}
+		stderr := &bytes.Buffer{}
+		code := run(args, strings.NewReader(""), stdout, stderr)
+
+		return code, stdout.String(), stderr.String()
+	}
+
+	code, stdout, stderr := call("bench", path, "-ops", "1000", "-keys", "100", "-size", "10-200", "-sync", "0")
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "1000 operations")
+	assert.Contains(t, stdout, "p99")
+	assert.Regexp(t, `get +\d+`, stdout)
+	assert.Regexp(t, `set +\d+`, stdout)
+
+	code, stdout, _ = call("info", path)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "in 1 bucket(s)")
+
+	store, err := fastdb.Open(":memory:")
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, store.Close())
+	}()
+
+	server := httptest.NewServer(fastdbhttp.NewHandler(store, fastdbhttp.WithToken("secret")))
+	defer server.Close()
+
+	code, stdout, stderr = call("bench", server.URL, "-ops", "200", "-keys", "20", "-mix", "get:1,del:1", "-token", "secret")
+	assert.Equal(t, 0, code, stderr)
+	assert.Regexp(t, `del +\d+`, stdout)
+	assert.NotContains(t, stdout, "set ")
+
+	code, _, stderr = call("bench", server.URL, "-ops", "10", "-prefill=false", "-token", "wrong")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "401")
+
+	code, _, stderr = call("bench", path, "-mix", "get:80,put:20")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "mix")
+
+	code, _, stderr = call("bench", path, "-size", "100-10")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "size")
+
+	code, _, _ = call("bench", path, "-ops", "0")
+	assert.Equal(t, 2, code)
+}
@@ -0,0 +1,123 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// Diff describes one record that is different in the two files of Compare.
+type Diff struct {
+	Bucket string
+	ValueA []byte // the value in file A (nil if it isn't there)
+	ValueB []byte // the value in file B (nil if it isn't there)
+	Key    int
+	InA    bool // the record exists in file A
+	InB    bool // the record exists in file B
+}
+
+// DiffReport holds the differences between two files, see Compare.
+type DiffReport struct {
+	OnlyInA []string // the buckets that only exist in file A
+	OnlyInB []string // the buckets that only exist in file B
+	Diffs   []Diff   // the records that are different, sorted by bucket and key
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Compare reads two database files and reports the buckets and records that are different,
+e.g. to check a replica, a backup or a migration.
+The files are only read (nothing is locked or changed), so they may belong to databases that are in use.
+*/
+func Compare(pathA, pathB string) (DiffReport, error) {
+	keysA, _, err := persist.ReadFile(pathA)
+	if err != nil {
+		return DiffReport{}, fmt.Errorf("compare error: %w", err)
+	}
+
+	keysB, _, err := persist.ReadFile(pathB)
+	if err != nil {
+		return DiffReport{}, fmt.Errorf("compare error: %w", err)
+	}
+
+	return diffKeys(keysA, keysB), nil
+}
+
+/*
+Equal returns true if no differences were found.
+*/
+func (rep DiffReport) Equal() bool {
+	return len(rep.OnlyInA) == 0 && len(rep.OnlyInB) == 0 && len(rep.Diffs) == 0
+}
+
+/*
+diffKeys returns the differences between two sets of buckets.
+Empty buckets are the same as buckets that don't exist.
+*/
+func diffKeys(keysA, keysB map[string]map[int][]byte) DiffReport {
+	report := DiffReport{}
+	buckets := slices.Collect(maps.Keys(keysA))
+
+	for bucket := range keysB {
+		if _, found := keysA[bucket]; !found {
+			buckets = append(buckets, bucket)
+		}
+	}
+
+	slices.Sort(buckets)
+
+	for _, bucket := range buckets {
+		recordsA, recordsB := keysA[bucket], keysB[bucket]
+
+		switch {
+		case len(recordsA) == 0 && len(recordsB) == 0:
+			continue
+		case len(recordsB) == 0:
+			report.OnlyInA = append(report.OnlyInA, bucket)
+		case len(recordsA) == 0:
+			report.OnlyInB = append(report.OnlyInB, bucket)
+		}
+
+		report.Diffs = append(report.Diffs, diffRecords(bucket, recordsA, recordsB)...)
+	}
+
+	return report
+}
+
+/*
+diffRecords returns the differences between the records of one bucket, sorted by key.
+*/
+func diffRecords(bucket string, recordsA, recordsB map[int][]byte) []Diff {
+	var diffs []Diff
+
+	keys := slices.Collect(maps.Keys(recordsA))
+
+	for key := range recordsB {
+		if _, found := recordsA[key]; !found {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		valueA, inA := recordsA[key]
+		valueB, inB := recordsB[key]
+
+		if inA && inB && bytes.Equal(valueA, valueB) {
+			continue
+		}
+
+		diffs = append(diffs, Diff{Bucket: bucket, Key: key, ValueA: valueA, ValueB: valueB, InA: inA, InB: inB})
+	}
+
+	return diffs
+}
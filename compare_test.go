@@ -0,0 +1,78 @@
+package fastdb_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Compare(t *testing.T) {
+	pathA := filepath.Clean("data/fastdb_compare_a.db")
+	pathB := filepath.Clean("data/fastdb_compare_b.db")
+	_ = os.Remove(pathA)
+	_ = os.Remove(pathB)
+
+	defer func() {
+		for _, path := range []string{pathA, pathB, pathB + ".snapshot"} {
+			err := os.Remove(path)
+			require.NoError(t, err)
+		}
+	}()
+
+	storeA, err := fastdb.Open(pathA, fastdb.WithSyncTime(0))
+	require.NoError(t, err)
+
+	storeB, err := fastdb.Open(pathB, fastdb.WithSyncTime(0), fastdb.WithFormat(fastdb.FormatBinary))
+	require.NoError(t, err)
+
+	for _, store := range []*fastdb.DB{storeA, storeB} {
+		err = store.SetMulti("text", map[int][]byte{1: []byte("one"), 2: []byte("two"), 3: []byte("three")})
+		require.NoError(t, err)
+	}
+
+	// a checkpoint moves the data of B into the snapshot, which is read too
+	err = storeB.Checkpoint()
+	require.NoError(t, err)
+
+	report, err := fastdb.Compare(pathA, pathB)
+	require.NoError(t, err)
+	assert.True(t, report.Equal())
+
+	err = storeA.Set("user", 1, []byte("alice"))
+	require.NoError(t, err)
+
+	err = storeA.Set("text", 2, []byte("TWO"))
+	require.NoError(t, err)
+
+	_, err = storeA.Del("text", 3)
+	require.NoError(t, err)
+
+	err = storeB.Set("text", 4, []byte("four"))
+	require.NoError(t, err)
+
+	// the databases are still open
+	report, err = fastdb.Compare(pathA, pathB)
+	require.NoError(t, err)
+	assert.False(t, report.Equal())
+	assert.Equal(t, []string{"user"}, report.OnlyInA)
+	assert.Empty(t, report.OnlyInB)
+	assert.Equal(t, []fastdb.Diff{
+		{Bucket: "text", Key: 2, ValueA: []byte("TWO"), ValueB: []byte("two"), InA: true, InB: true},
+		{Bucket: "text", Key: 3, ValueB: []byte("three"), InB: true},
+		{Bucket: "text", Key: 4, ValueB: []byte("four"), InB: true},
+		{Bucket: "user", Key: 1, ValueA: []byte("alice"), InA: true},
+	}, report.Diffs)
+
+	require.NoError(t, storeA.Close())
+	require.NoError(t, storeB.Close())
+
+	_, err = fastdb.Compare(pathA, "data/non_existing.db")
+	require.Error(t, err)
+
+	_, err = os.Stat("data/non_existing.db")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
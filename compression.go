@@ -0,0 +1,160 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const (
+	// minCompressSize is the size from which values are compressed (the same as in persist).
+	minCompressSize = 64
+	// worthCompressing is the ratio below which compression saves enough to be worth its CPU time.
+	worthCompressing = 0.8
+	// worthBetterLevel is the extra saving from which the default level is worth its CPU time.
+	worthBetterLevel = 0.1
+)
+
+// BucketCompression holds the compression numbers of one bucket, see AnalyzeCompression.
+type BucketCompression struct {
+	Bucket       string
+	Records      int
+	Sampled      int     // the number of values that were compressed
+	AvgValueSize float64 // in bytes, of the sampled values
+	Compressible float64 // the part of the sampled values that is big enough to be compressed (0-1)
+	RatioFastest float64 // the compressed size divided by the original size, at the fastest level
+	RatioDefault float64 // the compressed size divided by the original size, at the default level
+}
+
+// CompressionReport holds the compressibility of the data and the advised setting, see AnalyzeCompression.
+type CompressionReport struct {
+	Advice       string // explains the advised level
+	Buckets      []BucketCompression
+	RatioFastest float64 // over all the sampled values
+	RatioDefault float64 // over all the sampled values
+	Level        int     // the advised level for WithCompression, 0 means no compression
+}
+
+// bucketSample holds the sampled values of a bucket.
+type bucketSample struct {
+	values  [][]byte
+	records int
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+AnalyzeCompression compresses a sample of the values of every bucket (at most sampleSize per bucket,
+spread over the keys), and reports how well they compress, and which level of WithCompression
+is advised. Values below 64 bytes are never compressed, and a value is only stored compressed if
+that makes it smaller, just like the real compression does.
+Only the sample is collected under the read lock, the compression runs without blocking writers.
+*/
+func (fdb *DB) AnalyzeCompression(sampleSize int) CompressionReport {
+	samples := fdb.sampleValues(max(sampleSize, 1))
+	report := CompressionReport{}
+
+	var total, fastest, best int
+
+	for _, bucket := range slices.Sorted(maps.Keys(samples)) {
+		stats := BucketCompression{Bucket: bucket, Records: samples[bucket].records, Sampled: len(samples[bucket].values)}
+
+		var size, sizeFastest, sizeDefault, compressible int
+
+		for _, value := range samples[bucket].values {
+			size += len(value)
+			sizeFastest += compressedSize(value, gzip.BestSpeed)
+			sizeDefault += compressedSize(value, gzip.DefaultCompression)
+
+			if len(value) >= minCompressSize {
+				compressible++
+			}
+		}
+
+		if stats.Sampled > 0 {
+			stats.AvgValueSize = float64(size) / float64(stats.Sampled)
+			stats.Compressible = float64(compressible) / float64(stats.Sampled)
+		}
+
+		stats.RatioFastest, stats.RatioDefault = ratio(sizeFastest, size), ratio(sizeDefault, size)
+		total, fastest, best = total+size, fastest+sizeFastest, best+sizeDefault
+
+		report.Buckets = append(report.Buckets, stats)
+	}
+
+	report.RatioFastest, report.RatioDefault = ratio(fastest, total), ratio(best, total)
+
+	switch {
+	case total == 0:
+		report.Advice = "no values to analyze"
+	case report.RatioFastest > worthCompressing:
+		report.Advice = fmt.Sprintf("don't compress: it only saves %.0f%%", (1-report.RatioFastest)*100)
+	case report.RatioFastest-report.RatioDefault >= worthBetterLevel:
+		report.Level = gzip.DefaultCompression
+		report.Advice = fmt.Sprintf("compress with the default level: it saves %.0f%% (the fastest level %.0f%%)",
+			(1-report.RatioDefault)*100, (1-report.RatioFastest)*100)
+	default:
+		report.Level = gzip.BestSpeed
+		report.Advice = fmt.Sprintf("compress with the fastest level: it saves %.0f%% (the default level %.0f%%)",
+			(1-report.RatioFastest)*100, (1-report.RatioDefault)*100)
+	}
+
+	return report
+}
+
+/*
+sampleValues returns at most sampleSize values per bucket, spread over the sorted keys.
+*/
+func (fdb *DB) sampleValues(sampleSize int) map[string]bucketSample {
+	defer fdb.mu.RLock().RUnlock()
+
+	samples := make(map[string]bucketSample, len(fdb.keys))
+
+	for bucket, records := range fdb.keys {
+		sortedKeys := fdb.sortedKeys(bucket)
+		step := max(len(sortedKeys)/sampleSize, 1)
+		sample := bucketSample{records: len(records)}
+
+		for pos := 0; pos < len(sortedKeys) && len(sample.values) < sampleSize; pos += step {
+			sample.values = append(sample.values, records[sortedKeys[pos]])
+		}
+
+		samples[bucket] = sample
+	}
+
+	return samples
+}
+
+/*
+compressedSize returns the size a value takes in the file with compression at the level.
+*/
+func compressedSize(value []byte, level int) int {
+	if len(value) < minCompressSize {
+		return len(value)
+	}
+
+	var buf bytes.Buffer
+
+	writer, _ := gzip.NewWriterLevel(&buf, level) // the level is always valid
+	_, _ = writer.Write(value)
+	_ = writer.Close()
+
+	return min(buf.Len(), len(value))
+}
+
+/*
+ratio returns part divided by total, or 1 without a total.
+*/
+func ratio(part, total int) float64 {
+	if total == 0 {
+		return 1
+	}
+
+	return float64(part) / float64(total)
+}
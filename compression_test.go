@@ -0,0 +1,79 @@
+package fastdb_test
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AnalyzeCompression(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	report := store.AnalyzeCompression(10)
+	assert.Equal(t, 0, report.Level)
+	assert.Equal(t, "no values to analyze", report.Advice)
+
+	rnd := rand.New(rand.NewPCG(1, 2)) //nolint:gosec // it's a test
+
+	for key := 1; key <= 100; key++ {
+		json := `{"name":"John","city":"Amsterdam","tags":["` + strings.Repeat("fastdb,", 20) + `"],"id":` + strconv.Itoa(key) + `}`
+		err = store.Set("json", key, []byte(json))
+		require.NoError(t, err)
+
+		random := make([]byte, 200)
+		for i := range random {
+			random[i] = byte(rnd.IntN(256))
+		}
+
+		err = store.Set("random", key, random)
+		require.NoError(t, err)
+
+		err = store.Set("small", key, []byte("value"))
+		require.NoError(t, err)
+	}
+
+	report = store.AnalyzeCompression(10)
+	require.Len(t, report.Buckets, 3)
+
+	jsonStats := report.Buckets[0]
+	assert.Equal(t, "json", jsonStats.Bucket)
+	assert.Equal(t, 100, jsonStats.Records)
+	assert.Equal(t, 10, jsonStats.Sampled)
+	assert.InDelta(t, 1.0, jsonStats.Compressible, 0.001)
+	assert.Less(t, jsonStats.RatioFastest, 0.5)
+
+	randomStats := report.Buckets[1]
+	assert.Greater(t, randomStats.RatioFastest, 0.95)
+
+	smallStats := report.Buckets[2]
+	assert.InDelta(t, 5.0, smallStats.AvgValueSize, 0.001)
+	assert.InDelta(t, 0.0, smallStats.Compressible, 0.001)
+	assert.InDelta(t, 1.0, smallStats.RatioDefault, 0.001)
+
+	assert.NotEqual(t, 0, report.Level)
+	assert.Contains(t, report.Advice, "compress with the")
+
+	// random data doesn't compress well enough
+	for key := 1; key <= 100; key++ {
+		_, err = store.Del("json", key)
+		require.NoError(t, err)
+	}
+
+	report = store.AnalyzeCompression(1000)
+	require.Len(t, report.Buckets, 2)
+	assert.Equal(t, 100, report.Buckets[0].Sampled)
+
+	assert.Equal(t, 0, report.Level)
+	assert.Contains(t, report.Advice, "don't compress")
+}
@@ -0,0 +1,242 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bytes"
+	"fmt"
+	"hash/maphash"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// getAllWindow is the time after a GetAll in which a write from another goroutine is reported.
+const getAllWindow = time.Second
+
+// Misuse describes a wrong use of the database that the debug mode detected, see WithDebug.
+type Misuse struct {
+	Problem string
+	Stack   string // where the misuse was detected
+	Origin  string // where the misused value or map came from (empty if not applicable)
+}
+
+// debugger detects misuse of the database, see WithDebug.
+type debugger struct {
+	report   func(Misuse)
+	handouts map[string]map[int]handout
+	getAlls  map[string]getAll
+	closed   string // the stack of the first Close
+	seed     maphash.Seed
+	mu       sync.Mutex
+}
+
+// handout is a value that Get returned.
+type handout struct {
+	data     *byte // the start of the value, to know it's still the same value
+	stack    string
+	checksum uint64
+	size     int
+}
+
+// getAll is the map of a bucket that GetAll returned.
+type getAll struct {
+	at        time.Time
+	stack     string
+	goroutine string
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithDebug turns on the detection of misuse, for debug builds and tests (it costs speed and memory):
+  - changing a value that Get returned (it's the value in the database, not a copy),
+    detected at the next Get of the key, and at Close
+  - writing to a bucket from another goroutine, shortly after GetAll returned its map
+    (which is the map in the database, so iterating it while it's written is a data race)
+  - closing the database twice
+
+Every misuse is reported with stack traces. A nil report logs them as errors with the logger (WithLogger).
+*/
+func WithDebug(report func(Misuse)) Option {
+	return func(cfg *config) {
+		cfg.debug = true
+		cfg.debugReport = report
+	}
+}
+
+/*
+newDebugger returns the debugger for the settings, or nil when debug mode is off.
+*/
+func newDebugger(cfg config) *debugger {
+	if !cfg.debug {
+		return nil
+	}
+
+	report := cfg.debugReport
+	if report == nil {
+		report = func(misuse Misuse) {
+			cfg.logger.Error("misuse: "+misuse.Problem, "stack", misuse.Stack, "origin", misuse.Origin)
+		}
+	}
+
+	return &debugger{
+		report:   report,
+		handouts: map[string]map[int]handout{},
+		getAlls:  map[string]getAll{},
+		seed:     maphash.MakeSeed(),
+	}
+}
+
+/*
+handOut remembers a value that Get returns, after checking that the value it returned before wasn't changed.
+*/
+func (dbg *debugger) handOut(bucket string, key int, data []byte) {
+	if dbg == nil || len(data) == 0 {
+		return
+	}
+
+	dbg.mu.Lock()
+	defer dbg.mu.Unlock()
+
+	dbg.check(bucket, key, data)
+
+	if dbg.handouts[bucket] == nil {
+		dbg.handouts[bucket] = map[int]handout{}
+	}
+
+	dbg.handouts[bucket][key] = handout{
+		data:     &data[0],
+		size:     len(data),
+		checksum: maphash.Bytes(dbg.seed, data),
+		stack:    string(debug.Stack()),
+	}
+}
+
+/*
+checkHandouts checks (in debug mode) that none of the values that Get returned was changed.
+*/
+func (fdb *DB) checkHandouts() {
+	if fdb.debug == nil {
+		return
+	}
+
+	defer fdb.mu.RLock().RUnlock()
+
+	fdb.debug.checkAll(fdb.keys)
+}
+
+/*
+checkAll checks that none of the values that Get returned was changed.
+*/
+func (dbg *debugger) checkAll(keys map[string]map[int][]byte) {
+	if dbg == nil {
+		return
+	}
+
+	dbg.mu.Lock()
+	defer dbg.mu.Unlock()
+
+	for bucket, handouts := range dbg.handouts {
+		for key := range handouts {
+			dbg.check(bucket, key, keys[bucket][key])
+		}
+	}
+
+	dbg.handouts = map[string]map[int]handout{}
+}
+
+/*
+check reports if the value that Get returned was changed, while it's still the value of the key.
+The caller must hold the lock of the debugger.
+*/
+func (dbg *debugger) check(bucket string, key int, data []byte) {
+	out, found := dbg.handouts[bucket][key]
+	if !found || len(data) != out.size || &data[0] != out.data || maphash.Bytes(dbg.seed, data) == out.checksum {
+		return
+	}
+
+	dbg.report(Misuse{
+		Problem: fmt.Sprintf("the value of %s_%d was changed after Get returned it, copy it before changing it", bucket, key),
+		Stack:   string(debug.Stack()),
+		Origin:  out.stack,
+	})
+}
+
+/*
+gotAll remembers that GetAll returned the map of a bucket.
+*/
+func (dbg *debugger) gotAll(bucket string) {
+	if dbg == nil {
+		return
+	}
+
+	dbg.mu.Lock()
+	defer dbg.mu.Unlock()
+
+	dbg.getAlls[bucket] = getAll{at: time.Now(), stack: string(debug.Stack()), goroutine: goroutine()}
+}
+
+/*
+written reports a write to a bucket from another goroutine, shortly after GetAll returned its map.
+*/
+func (dbg *debugger) written(bucket string) {
+	if dbg == nil {
+		return
+	}
+
+	dbg.mu.Lock()
+	defer dbg.mu.Unlock()
+
+	got, found := dbg.getAlls[bucket]
+	if !found {
+		return
+	}
+
+	delete(dbg.getAlls, bucket)
+
+	if time.Since(got.at) > getAllWindow || got.goroutine == goroutine() {
+		return
+	}
+
+	dbg.report(Misuse{
+		Problem: fmt.Sprintf("bucket (%s) was written while the map that GetAll returned (to another goroutine) "+
+			"may still be iterated, use ForEach, All or GetAllSorted instead", bucket),
+		Stack:  string(debug.Stack()),
+		Origin: got.stack,
+	})
+}
+
+/*
+closing reports if the database was closed before.
+*/
+func (dbg *debugger) closing() {
+	if dbg == nil {
+		return
+	}
+
+	dbg.mu.Lock()
+	defer dbg.mu.Unlock()
+
+	if dbg.closed == "" {
+		dbg.closed = string(debug.Stack())
+
+		return
+	}
+
+	dbg.report(Misuse{Problem: "the database was closed twice", Stack: string(debug.Stack()), Origin: dbg.closed})
+}
+
+/*
+goroutine returns the id of the current goroutine (from its stack trace), only for debugging.
+*/
+func goroutine() string {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf, _, _ = bytes.Cut(bytes.TrimPrefix(buf, []byte("goroutine ")), []byte(" "))
+
+	return string(buf)
+}
@@ -0,0 +1,88 @@
+package fastdb_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithDebug(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		misuse []fastdb.Misuse
+	)
+
+	report := func(found fastdb.Misuse) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		misuse = append(misuse, found)
+	}
+
+	store, err := fastdb.Open(memory, fastdb.WithDebug(report))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("value"))
+	require.NoError(t, err)
+
+	// reading and replacing values is fine
+	value, _ := store.Get("text", 1)
+	assert.Equal(t, []byte("value"), value)
+
+	err = store.Set("text", 1, []byte("other"))
+	require.NoError(t, err)
+
+	_, _ = store.Get("text", 1)
+	assert.Empty(t, misuse)
+
+	// changing a value that Get returned
+	value, _ = store.Get("text", 1)
+	value[0] = 'O'
+
+	_, _ = store.Get("text", 1)
+	require.Len(t, misuse, 1)
+	assert.Contains(t, misuse[0].Problem, "text_1 was changed")
+	assert.Contains(t, misuse[0].Origin, "Test_WithDebug")
+
+	// writing from another goroutine, while the map of GetAll may be iterated
+	all, err := store.GetAll("text")
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+
+	err = store.Set("text", 2, []byte("value"))
+	require.NoError(t, err)
+	assert.Len(t, misuse, 1)
+
+	_, err = store.GetAll("text")
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		err := store.Set("text", 3, []byte("value"))
+		assert.NoError(t, err)
+	}()
+
+	<-done
+	require.Len(t, misuse, 2)
+	assert.Contains(t, misuse[1].Problem, "GetAll")
+
+	// a changed value is also detected at Close, and closing twice too
+	value, _ = store.Get("text", 2)
+	value[0] = 'V'
+
+	err = store.Close()
+	require.NoError(t, err)
+	require.Len(t, misuse, 3)
+	assert.Contains(t, misuse[2].Problem, "text_2 was changed")
+
+	_ = store.Close()
+	require.Len(t, misuse, 4)
+	assert.Equal(t, "the database was closed twice", misuse[3].Problem)
+	assert.Contains(t, misuse[3].Origin, "Test_WithDebug")
+}
@@ -0,0 +1,88 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const descriptionPrefix = "description:"
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+SetDescription stores a human readable description of what a bucket holds, an empty one removes it.
+It is persisted, and stays when the bucket is emptied (a bucket doesn't have to exist to be described).
+*/
+func (fdb *DB) SetDescription(bucket, description string) error {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return fmt.Errorf("setDescription error: %w", err)
+	}
+
+	defer unlock()
+
+	if fdb.readOnly() {
+		return fmt.Errorf("setDescription error: %w", ErrReadOnly)
+	}
+
+	name := descriptionPrefix + bucket
+
+	if description == "" {
+		_, found := fdb.meta[name]
+		if !found {
+			return nil
+		}
+
+		err = fdb.write(persist.DelMetaInstruction(name))
+		if err != nil {
+			return fmt.Errorf("setDescription->write error: %w", err)
+		}
+
+		delete(fdb.meta, name)
+
+		return nil
+	}
+
+	return fdb.setMeta(name, description)
+}
+
+/*
+Description returns the description of a bucket (empty if it has none).
+*/
+func (fdb *DB) Description(bucket string) string {
+	defer fdb.mu.RLock().RUnlock()
+
+	return fdb.meta[descriptionPrefix+bucket]
+}
+
+/*
+Descriptions returns the descriptions of all the buckets that have one.
+*/
+func (fdb *DB) Descriptions() map[string]string {
+	defer fdb.mu.RLock().RUnlock()
+
+	return fdb.descriptions()
+}
+
+/*
+descriptions returns the descriptions of all the buckets that have one.
+The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) descriptions() map[string]string {
+	descriptions := map[string]string{}
+
+	for name, description := range fdb.meta {
+		bucket, found := strings.CutPrefix(name, descriptionPrefix)
+		if found {
+			descriptions[bucket] = description
+		}
+	}
+
+	return descriptions
+}
@@ -0,0 +1,73 @@
+package fastdb_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SetDescription(t *testing.T) {
+	path := "data/fastdb_description.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	assert.Empty(t, store.Description("tmp2"))
+
+	err = store.Set("tmp2", 1, []byte("value"))
+	require.NoError(t, err)
+
+	err = store.SetDescription("tmp2", "import of the old\nuser table")
+	require.NoError(t, err)
+
+	err = store.SetDescription("next", "filled by the next import")
+	require.NoError(t, err)
+
+	// the description stays when the bucket is emptied
+	_, err = store.Del("tmp2", 1)
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.Equal(t, "import of the old\nuser table", store.Description("tmp2"))
+	assert.Equal(t, map[string]string{"tmp2": "import of the old\nuser table", "next": "filled by the next import"},
+		store.Stats().Descriptions)
+
+	err = store.SetDescription("next", "")
+	require.NoError(t, err)
+
+	err = store.SetDescription("unknown", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"tmp2": "import of the old\nuser table"}, store.Descriptions())
+}
+
+func Test_SetDescription_readOnly(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithReadOnly())
+	require.NoError(t, err)
+
+	err = store.SetDescription("text", "a description")
+	require.ErrorIs(t, err, fastdb.ErrReadOnly)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
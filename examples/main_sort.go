@@ -37,7 +37,7 @@ type record struct {
 main is the bootstrap of the application.
 */
 func main() {
-	store, err := fastdb.Open(":memory:", 100)
+	store, err := fastdb.Open(":memory:", fastdb.WithSyncTime(100))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,304 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+ExportJSON writes all the records as one JSON document: {"bucket": {"key": value}}.
+Buckets and keys are sorted, so the same data always results in the same document.
+Values that are JSON objects or arrays are embedded as they are, all others as a string
+(so binary values that aren't valid UTF-8 can't be exported without loss).
+Expired records and the meta data (like sequences and expiry times) aren't exported.
+*/
+func (fdb *DB) ExportJSON(writer io.Writer) error {
+	defer fdb.mu.RLock().RUnlock()
+
+	selection := make(map[string][]int, len(fdb.keys))
+	for bucket := range fdb.keys {
+		selection[bucket] = fdb.sortedKeys(bucket)
+	}
+
+	err := fdb.writeJSON(writer, selection)
+	if err != nil {
+		return fmt.Errorf("exportJSON error: %w", err)
+	}
+
+	return nil
+}
+
+/*
+writeJSON writes the selected records (the sorted keys per bucket) as one JSON document, see ExportJSON.
+The expired records are left out. The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) writeJSON(writer io.Writer, selection map[string][]int) error {
+	bufWriter := bufio.NewWriter(writer)
+	buf := []byte("{")
+	now := time.Now().UnixNano()
+
+	for bCount, bucket := range slices.Sorted(maps.Keys(selection)) {
+		if bCount > 0 {
+			buf = append(buf, ',')
+		}
+
+		buf = append(buf, "\n  "...)
+		buf = strconv.AppendQuote(buf, bucket)
+		buf = append(buf, ": {"...)
+
+		kCount := 0
+
+		for _, key := range selection[bucket] {
+			if fdb.expiredAt(bucket, key, now) {
+				continue
+			}
+
+			if kCount > 0 {
+				buf = append(buf, ',')
+			}
+
+			kCount++
+
+			buf = append(buf, "\n    \""...)
+			buf = strconv.AppendInt(buf, int64(key), 10)
+			buf = append(buf, "\": "...)
+			buf = appendJSONValue(buf, fdb.keys[bucket][key])
+
+			_, err := bufWriter.Write(buf)
+			if err != nil {
+				return err //nolint:wrapcheck // it is wrapped by the caller
+			}
+
+			buf = buf[:0]
+		}
+
+		buf = append(buf, "\n  }"...)
+	}
+
+	buf = append(buf, "\n}\n"...)
+
+	_, err := bufWriter.Write(buf)
+	if err == nil {
+		err = bufWriter.Flush()
+	}
+
+	return err //nolint:wrapcheck // it is wrapped by the caller
+}
+
+/*
+ExportCSV writes the records of a bucket as CSV, in Key sorted order, to open it in a spreadsheet.
+The first column is the key, followed by a column per field: the value at that gjson path
+(objects and arrays as JSON, and an empty cell if the path doesn't exist).
+The first row holds the column names.
+*/
+func (fdb *DB) ExportCSV(bucket string, writer io.Writer, fields []string) error {
+	records, err := fdb.GetAllSorted(bucket)
+	if err != nil {
+		return fmt.Errorf("exportCSV error: %w", err)
+	}
+
+	csvWriter := csv.NewWriter(writer)
+	row := make([]string, len(fields)+1)
+
+	err = csvWriter.Write(append([]string{"key"}, fields...))
+
+	for _, record := range records {
+		if err != nil {
+			break
+		}
+
+		row[0] = strconv.Itoa(record.SortField.(int)) //nolint:forcetypeassert // always an int
+
+		for i, result := range gjson.GetManyBytes(record.Data, fields...) {
+			row[i+1] = result.String()
+		}
+
+		err = csvWriter.Write(row)
+	}
+
+	if err == nil {
+		csvWriter.Flush()
+		err = csvWriter.Error()
+	}
+
+	if err != nil {
+		return fmt.Errorf("exportCSV error: %w", err)
+	}
+
+	return nil
+}
+
+/*
+ExportCanonical writes all the records as text, one line per record: bucket_key = value,
+sorted by bucket and key. JSON objects and arrays are written compact with their keys sorted,
+all other values as a JSON string. The same data always gives the same text, so it can be
+committed (e.g. for small configuration datasets), and every change shows up as a readable diff.
+Expired records and the meta data aren't exported.
+*/
+func (fdb *DB) ExportCanonical(writer io.Writer) error {
+	defer fdb.mu.RLock().RUnlock()
+
+	bufWriter := bufio.NewWriter(writer)
+	now := time.Now().UnixNano()
+
+	var buf []byte
+
+	for _, bucket := range slices.Sorted(maps.Keys(fdb.keys)) {
+		for _, key := range fdb.sortedKeys(bucket) {
+			if fdb.expiredAt(bucket, key, now) {
+				continue
+			}
+
+			buf = append(buf[:0], bucket...)
+			buf = append(buf, '_')
+			buf = strconv.AppendInt(buf, int64(key), 10)
+			buf = append(buf, " = "...)
+			buf = appendCanonicalValue(buf, fdb.keys[bucket][key])
+			buf = append(buf, '\n')
+
+			_, err := bufWriter.Write(buf)
+			if err != nil {
+				return fmt.Errorf("exportCanonical error: %w", err)
+			}
+		}
+	}
+
+	err := bufWriter.Flush()
+	if err != nil {
+		return fmt.Errorf("exportCanonical error: %w", err)
+	}
+
+	return nil
+}
+
+/*
+ImportJSON opens (or creates) the database at the path, and stores all the records
+of a JSON document (as written by ExportJSON) in it, in one go.
+For keys that already exist, resolve decides what is stored (nil means KeepIncoming), like with Restore.
+*/
+func ImportJSON(reader io.Reader, path string, resolve ConflictFunc, opts ...Option) (*DB, error) {
+	incoming, err := readJSON(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	fdb, err := Open(path, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("importJSON error: %w", err)
+	}
+
+	err = fdb.restore(incoming, resolve)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("importJSON error: %w", err), fdb.Close())
+	}
+
+	return fdb, nil
+}
+
+/*
+readJSON reads the records from a JSON document.
+*/
+func readJSON(reader io.Reader) (map[string]map[int][]byte, error) {
+	var document map[string]map[string]json.RawMessage
+
+	err := json.NewDecoder(reader).Decode(&document)
+	if err != nil {
+		return nil, fmt.Errorf("importJSON->decode error: %w", err)
+	}
+
+	keys := make(map[string]map[int][]byte, len(document))
+
+	for bucket, records := range document {
+		keys[bucket] = make(map[int][]byte, len(records))
+
+		for keyText, raw := range records {
+			key, err := strconv.Atoi(keyText)
+			if err != nil || key < 0 {
+				return nil, fmt.Errorf("importJSON error: key (%s) of bucket (%s) should be a positive number", keyText, bucket)
+			}
+
+			value, err := jsonValue(raw)
+			if err != nil {
+				return nil, fmt.Errorf("importJSON->value of %s_%d error: %w", bucket, key, err)
+			}
+
+			keys[bucket][key] = value
+		}
+	}
+
+	return keys, nil
+}
+
+/*
+appendJSONValue appends a value to the JSON document:
+JSON objects and arrays as they are, everything else as a string.
+*/
+func appendJSONValue(buf, value []byte) []byte {
+	if len(value) > 0 && (value[0] == '{' || value[0] == '[') && json.Valid(value) {
+		return append(buf, value...)
+	}
+
+	data, _ := json.Marshal(string(value)) //nolint:errchkjson // a string always marshals
+
+	return append(buf, data...)
+}
+
+/*
+appendCanonicalValue appends a value in its canonical form: JSON objects and arrays compact,
+with the object keys sorted (and the numbers as they were), everything else as a JSON string.
+*/
+func appendCanonicalValue(buf, value []byte) []byte {
+	var data any = string(value)
+
+	if len(value) > 0 && (value[0] == '{' || value[0] == '[') {
+		var object any
+
+		decoder := json.NewDecoder(bytes.NewReader(value))
+		decoder.UseNumber()
+
+		if decoder.Decode(&object) == nil && !decoder.More() {
+			data = object
+		}
+	}
+
+	out := &bytes.Buffer{}
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+
+	_ = encoder.Encode(data) //nolint:errchkjson // decoded JSON and strings always encode
+
+	return append(buf, bytes.TrimSuffix(out.Bytes(), []byte("\n"))...)
+}
+
+/*
+jsonValue returns the value of a JSON document, which is the reverse of appendJSONValue.
+*/
+func jsonValue(raw json.RawMessage) ([]byte, error) {
+	if len(raw) > 0 && (raw[0] == '{' || raw[0] == '[') {
+		return raw, nil
+	}
+
+	var text string
+
+	err := json.Unmarshal(raw, &text)
+	if err != nil {
+		return nil, fmt.Errorf("value should be an object, an array or a string: %w", err)
+	}
+
+	return []byte(text), nil
+}
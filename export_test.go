@@ -0,0 +1,218 @@
+package fastdb_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ExportJSON_ImportJSON(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	values := map[int][]byte{
+		1: []byte(`{"name":"John", "tags":["a","b"]}`),
+		2: []byte("plain text with \"quotes\""),
+		3: []byte(`"a json string"`),
+		4: []byte(`[1,2,3]`),
+		5: []byte("12"),
+	}
+
+	err = store.SetMulti("user", values)
+	require.NoError(t, err)
+
+	err = store.Set("other", 10, []byte("x"))
+	require.NoError(t, err)
+
+	// an expired record (that isn't reaped yet) doesn't come back
+	err = store.SetWithTTL("other", 11, []byte("gone"), time.Nanosecond)
+	require.NoError(t, err)
+
+	time.Sleep(time.Millisecond)
+
+	document := &bytes.Buffer{}
+	err = store.ExportJSON(document)
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	expected := `{
+  "other": {
+    "10": "x"
+  },
+  "user": {
+    "1": {"name":"John", "tags":["a","b"]},
+    "2": "plain text with \"quotes\"",
+    "3": "\"a json string\"",
+    "4": [1,2,3],
+    "5": "12"
+  }
+}
+`
+	assert.Equal(t, expected, document.String())
+
+	path := "data/fastdb_import.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err = fastdb.ImportJSON(document, filePath, nil, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	records, err := store.GetAll("user")
+	require.NoError(t, err)
+	assert.Equal(t, values, records)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// the import is persisted
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.Equal(t, "6 record(s) in 2 bucket(s)", store.Info())
+
+	exported := &bytes.Buffer{}
+	err = store.ExportJSON(exported)
+	require.NoError(t, err)
+	assert.Equal(t, expected, exported.String())
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// an empty database
+	store, err = fastdb.Open(memory)
+	require.NoError(t, err)
+
+	exported.Reset()
+	err = store.ExportJSON(exported)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n}\n", exported.String())
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_ImportJSON_conflict(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "fastdb_import_conflict.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	err = store.Set("user", 1, []byte("John"))
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	document := `{"user": {"1": "Jane", "2": "Joe"}}`
+
+	store, err = fastdb.ImportJSON(strings.NewReader(document), filePath, fastdb.KeepExisting, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	records, err := store.GetAll("user")
+	require.NoError(t, err)
+	assert.Equal(t, map[int][]byte{1: []byte("John"), 2: []byte("Joe")}, records)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// nil overwrites, like with Restore
+	store, err = fastdb.ImportJSON(strings.NewReader(document), filePath, nil, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	records, err = store.GetAll("user")
+	require.NoError(t, err)
+	assert.Equal(t, map[int][]byte{1: []byte("Jane"), 2: []byte("Joe")}, records)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_ImportJSON_wrong(t *testing.T) {
+	for _, document := range []string{
+		`not json`,
+		`{"user": {"abc": "value"}}`,
+		`{"user": {"-1": "value"}}`,
+		`{"user": {"1": 12}}`,
+	} {
+		_, err := fastdb.ImportJSON(strings.NewReader(document), memory, nil)
+		require.Error(t, err, document)
+	}
+}
+
+func Test_ExportCSV(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	err = store.SetMulti("user", map[int][]byte{
+		2: []byte(`{"name":"Jane, Doe","address":{"city":"Amsterdam"},"tags":["a","b"]}`),
+		1: []byte(`{"name":"John","age":42,"address":{"city":"New \"York\""}}`),
+		3: []byte(`not json`),
+	})
+	require.NoError(t, err)
+
+	output := &bytes.Buffer{}
+	err = store.ExportCSV("user", output, []string{"name", "age", "address.city", "tags"})
+	require.NoError(t, err)
+
+	expected := `key,name,age,address.city,tags
+1,John,42,"New ""York""",
+2,"Jane, Doe",,Amsterdam,"[""a"",""b""]"
+3,,,,
+`
+	assert.Equal(t, expected, output.String())
+
+	err = store.ExportCSV("unknown", output, []string{"name"})
+	require.Error(t, err)
+}
+
+func Test_ExportCanonical(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	err = store.SetMulti("user", map[int][]byte{
+		10: []byte(`{ "name": "John", "age": 12345678901234567890, "tags": ["b", "a"], "html": "<b>" }`),
+		2:  []byte("plain text with \"quotes\"\nand a newline"),
+		3:  []byte(`{"broken":`),
+	})
+	require.NoError(t, err)
+
+	err = store.Set("config", 1, []byte(`[{"z":1,"a":2}]`))
+	require.NoError(t, err)
+
+	err = store.SetWithTTL("config", 2, []byte("gone"), time.Nanosecond)
+	require.NoError(t, err)
+
+	time.Sleep(time.Millisecond)
+
+	output := &bytes.Buffer{}
+	err = store.ExportCanonical(output)
+	require.NoError(t, err)
+
+	expected := `config_1 = [{"a":2,"z":1}]
+user_2 = "plain text with \"quotes\"\nand a newline"
+user_3 = "{\"broken\":"
+user_10 = {"age":12345678901234567890,"html":"<b>","name":"John","tags":["b","a"]}
+`
+	assert.Equal(t, expected, output.String())
+
+	err = store.Close()
+	require.NoError(t, err)
+}
@@ -6,9 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"os"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/marcelloh/fastdb/persist"
 )
@@ -17,11 +21,74 @@ import (
 
 // DB represents a collection of key-value pairs that persist on disk or memory.
 type DB struct {
-	aof  *persist.AOF
-	keys map[string]map[int][]byte
-	mu   sync.RWMutex
+	aof             *persist.AOF
+	cfg             config
+	keys            map[string]map[int][]byte
+	meta            map[string]string
+	expiries        map[string]map[int]int64
+	idGenerators    map[string]IDGenerator
+	watermarks      map[string]*watermarkState
+	sequence        uint64 // the number of the last write
+	liveBytes       int64  // the size of the records in the file, see Stats
+	fileRecords     int    // the record instructions (stores and deletes) in the files, see Stats
+	growthBase      int64  // the size of the files when the growth measuring started
+	growthSince     time.Time
+	lastDefrag      time.Time // see Stats
+	overMemory      bool      // only used by the memory check, see WithMemoryLimit
+	closed          bool
+	stopTasks       chan struct{}
+	stopCheckpoints chan struct{}
+	stopScrub       chan struct{}
+	frozen          chan struct{} // not nil while frozen, closed by Thaw
+	tasks           sync.WaitGroup
+	generations     map[string]uint64
+	sortCaches      map[string]*sortCache
+	watchers        map[*watcher]struct{}
+	hooks           hooks
+	debug           *debugger             // nil unless WithDebug
+	trace           TraceFunc             // nil unless WithTracing
+	follower        *persist.Follower     // nil unless OpenFollower
+	followErr       error                 // the last error of the follower, see followTask
+	replicas        map[*replica]struct{} // the connected followers, see ServeReplication
+	replicating     atomic.Bool           // true while ReplicateFrom runs, its own writes are refused then
+	health          Health
+	mu              rwLock
+	cacheMu         sync.Mutex
+	watchMu         sync.Mutex
+	scrubMu         sync.Mutex
+	replicaMu       sync.Mutex
 }
 
+// ErrReadOnly is returned for writes to a database that was opened with WithReadOnly (or that is a follower).
+var ErrReadOnly = persist.ErrReadOnly
+
+// ErrBucketNotFound is returned by the reads and writes of a whole bucket, when the bucket doesn't exist.
+var ErrBucketNotFound = errors.New("bucket not found")
+
+// ErrClosed is returned for writes to a database that was closed.
+var ErrClosed = errors.New("database is closed")
+
+// ErrCorrupted is returned by Open when the file has a damaged entry, the error is a *CorruptionError.
+var ErrCorrupted = persist.ErrCorrupted
+
+// ErrLocked is returned by Open when the file is already opened, by another process or in this one.
+var ErrLocked = persist.ErrLocked
+
+// ErrNewerFileVersion is returned by Open when the file is written in a newer format than this version can read.
+var ErrNewerFileVersion = persist.ErrNewerFileVersion
+
+// ErrTooBig is returned when a record is too big (over 10 MB, after compression) to be read back from the file.
+var ErrTooBig = persist.ErrTooBig
+
+// ErrSnapshotMismatch is returned by OpenFromBackup when the file was rewritten after the snapshot was taken.
+var ErrSnapshotMismatch = persist.ErrSnapshotMismatch
+
+// CorruptionReport holds the bad entries that were skipped while opening the file.
+type CorruptionReport = persist.CorruptionReport
+
+// CorruptionError describes a damaged entry in the file: where it is and what is wrong with it.
+type CorruptionError = persist.CorruptionError
+
 // SortRecord represents a record from a sorted collection of sliced records
 type SortRecord struct {
 	SortField any
@@ -34,46 +101,158 @@ type SortRecord struct {
 Open opens a database at the provided path.
 If the file doesn't exist, it will be created automatically.
 If the path is ':memory:' then the database will be opened in memory only.
+The options (like WithSyncTime) change the default behaviour.
 */
-func Open(path string, syncIime int) (*DB, error) {
+func Open(path string, opts ...Option) (*DB, error) {
 	var (
 		aof *persist.AOF
 		err error
 	)
 
+	cfg := newConfig(opts)
 	keys := map[string]map[int][]byte{}
+	meta := map[string]string{}
+
+	if path != ":memory:" && cfg.dirPerm != 0 {
+		err = os.MkdirAll(filepath.Dir(filepath.Clean(path)), cfg.dirPerm)
+		if err != nil {
+			return nil, fmt.Errorf("open (%s) error: %w", path, err)
+		}
+	}
 
 	if path != ":memory:" {
-		aof, keys, err = persist.OpenPersister(path, syncIime)
+		aof, keys, err = persist.OpenPersister(path, cfg.syncTime, cfg.persistOptions()...)
+		if err == nil {
+			meta = aof.Meta()
+		}
+	}
+
+	return newDB(aof, keys, meta, cfg), err //nolint:wrapcheck // it is already wrapped
+}
+
+/*
+newDB creates the database around the data that was read.
+*/
+func newDB(aof *persist.AOF, keys map[string]map[int][]byte, meta map[string]string, cfg config) *DB {
+	fdb := &DB{aof: aof, keys: keys, meta: meta, cfg: cfg, idGenerators: map[string]IDGenerator{}, mu: newRWLock()}
+	fdb.debug = newDebugger(cfg)
+	fdb.trace = cfg.trace
+	fdb.resetCaches()
+	fdb.loadExpiries()
+	fdb.loadSequence()
+	fdb.loadLiveBytes()
+	fdb.resetGrowth()
+
+	if aof != nil {
+		fdb.fileRecords = aof.RecordsRead()
+	}
+
+	fdb.stopTasks = make(chan struct{})
+
+	if !cfg.readOnly {
+		fdb.runEvery(reapInterval, nil, fdb.reapExpired)
+
+		if aof != nil {
+			fdb.restartCheckpoints()
+			fdb.runEvery(autoDefragInterval, nil, fdb.autoDefrag)
+		}
+	}
+
+	if cfg.memoryLimit > 0 {
+		fdb.runEvery(memoryCheckInterval, nil, fdb.checkMemory)
+	}
+
+	if aof != nil {
+		fdb.restartScrub()
+
+		if cfg.paranoid && cfg.paranoidInterval > 0 {
+			fdb.runEvery(cfg.paranoidInterval, nil, fdb.compareFile)
+		}
 	}
 
-	return &DB{aof: aof, keys: keys}, err //nolint:wrapcheck // it is already wrapped
+	return fdb
 }
 
 /*
 Defrag optimises the file to reflect the latest state.
+For a database in memory, it does nothing.
 */
-func (fdb *DB) Defrag() error {
-	defer fdb.lockUnlock()()
+func (fdb *DB) Defrag() (err error) {
+	end := fdb.startTrace("defrag", "", 0)
 
-	var err error
+	defer func() {
+		end(err)
+	}()
+
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return fmt.Errorf("defrag error: %w", err)
+	}
+
+	defer unlock()
+
+	if fdb.aof == nil {
+		return nil
+	}
+
+	if fdb.cfg.readOnly {
+		return fmt.Errorf("defrag error: %w", ErrReadOnly)
+	}
+
+	start := time.Now()
 
 	err = fdb.aof.Defrag(fdb.keys)
+	if err == nil {
+		err = fdb.markBase()
+	}
+
 	if err != nil {
-		err = fmt.Errorf("defrag error: %w", err)
+		return fmt.Errorf("defrag error: %w", err)
 	}
 
-	return err
+	fdb.fileRecords = fdb.totalCount()
+
+	fdb.lastDefrag = time.Now()
+
+	if fdb.cfg.observer.Defragged != nil {
+		fdb.cfg.observer.Defragged(fdb.lastDefrag.Sub(start))
+	}
+	fdb.resetGrowth()
+
+	return nil
 }
 
 /*
 Del deletes one map value in a bucket.
 */
-func (fdb *DB) Del(bucket string, key int) (bool, error) {
-	defer fdb.lockUnlock()()
+func (fdb *DB) Del(bucket string, key int) (deleted bool, err error) {
+	end := fdb.startTrace("del", bucket, key)
+
+	defer func() {
+		end(err)
+	}()
+
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return false, fmt.Errorf("del error: %w", err)
+	}
 
+	defer unlock()
+
+	return fdb.del(bucket, key)
+}
+
+/*
+del deletes one map value in a bucket.
+The caller must hold the write lock.
+*/
+func (fdb *DB) del(bucket string, key int) (bool, error) {
 	var err error
 
+	if fdb.readOnly() {
+		return false, fmt.Errorf("del error: %w", ErrReadOnly)
+	}
+
 	// bucket exists?
 	_, found := fdb.keys[bucket]
 	if !found {
@@ -86,97 +265,311 @@ func (fdb *DB) Del(bucket string, key int) (bool, error) {
 		return found, nil
 	}
 
-	if fdb.aof != nil {
-		lines := "del\n" + bucket + "_" + strconv.Itoa(key) + "\n"
+	err = fdb.delKeys(bucket, []int{key})
+	if err != nil {
+		return false, fmt.Errorf("del->%w", err)
+	}
+
+	return true, nil
+}
+
+/*
+delKeys deletes existing keys of a bucket, with one write to the file.
+The caller must hold the write lock.
+*/
+func (fdb *DB) delKeys(bucket string, keys []int) error {
+	instructions := make([]persist.Instruction, 0, len(keys))
 
-		err = fdb.aof.Write(lines)
+	for _, key := range keys {
+		err := fdb.beforeDel(bucket, key)
 		if err != nil {
-			return false, fmt.Errorf("del->write error: %w", err)
+			return err
 		}
+
+		instructions = append(instructions, persist.DelInstruction(bucket, key))
+		instructions = append(instructions, fdb.expiryInstructions(bucket, key, 0)...)
+		instructions = append(instructions, fdb.labelInstructions(bucket, key)...)
 	}
 
-	delete(fdb.keys[bucket], key)
+	err := fdb.write(instructions...)
+	if err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+
+	for _, key := range keys {
+		fdb.removeRecord(bucket, key)
+	}
+
+	fdb.touch(bucket)
 
 	if len(fdb.keys[bucket]) == 0 {
 		delete(fdb.keys, bucket)
 	}
 
-	return true, nil
+	return nil
+}
+
+/*
+removeRecord removes a deleted record (with its expiry time and labels) from memory,
+after its deletion was written. The caller must hold the write lock, and touch the bucket.
+*/
+func (fdb *DB) removeRecord(bucket string, key int) {
+	fdb.liveBytes -= fdb.recordSize(bucket, key)
+	delete(fdb.keys[bucket], key)
+	fdb.setExpiry(bucket, key, 0)
+	delete(fdb.meta, labelsName(bucket, key))
+	fdb.notify(Event{Type: EventDel, Bucket: bucket, Key: key})
+	fdb.afterDel(bucket, key)
 }
 
 /*
 Get returns one map value from a bucket.
 */
 func (fdb *DB) Get(bucket string, key int) ([]byte, bool) {
-	fdb.mu.RLock()
-	defer fdb.mu.RUnlock()
+	defer fdb.startTrace("get", bucket, key)(nil)
+	defer fdb.mu.RLock().RUnlock()
 
 	data, ok := fdb.keys[bucket][key]
+	if ok && fdb.expired(bucket, key) {
+		return nil, false
+	}
+
+	fdb.debug.handOut(bucket, key, data)
+	fdb.observeGet(bucket, ok)
 
 	return data, ok
 }
 
 /*
-GetAll returns all map values from a bucket in random order.
+GetSize returns the size (in bytes) of one map value from a bucket, without copying it.
+*/
+func (fdb *DB) GetSize(bucket string, key int) (int, bool) {
+	data, ok := fdb.Get(bucket, key)
+
+	return len(data), ok
+}
+
+/*
+Exists tells if a bucket has the key (that didn't expire), without handing out the value.
+*/
+func (fdb *DB) Exists(bucket string, key int) bool {
+	defer fdb.mu.RLock().RUnlock()
+
+	_, ok := fdb.keys[bucket][key]
+
+	return ok && !fdb.expired(bucket, key)
+}
+
+/*
+HasBucket tells if the bucket exists (a bucket exists as long as it has records).
+*/
+func (fdb *DB) HasBucket(bucket string) bool {
+	defer fdb.mu.RLock().RUnlock()
+
+	_, ok := fdb.keys[bucket]
+
+	return ok
+}
+
+/*
+GetPrefix returns (a copy of) the first n bytes of one map value from a bucket.
+If the value is shorter, the whole value is returned.
+*/
+func (fdb *DB) GetPrefix(bucket string, key, n int) ([]byte, bool) {
+	data, ok := fdb.Get(bucket, key)
+	if !ok {
+		return nil, false
+	}
+
+	return slices.Clone(data[:min(max(n, 0), len(data))]), true
+}
+
+/*
+GetAll returns all map values from a bucket in random order, without the expired ones.
 */
 func (fdb *DB) GetAll(bucket string) (map[int][]byte, error) {
-	fdb.mu.RLock()
-	defer fdb.mu.RUnlock()
+	defer fdb.mu.RLock().RUnlock()
 
 	bmap, found := fdb.keys[bucket]
 	if !found {
-		return nil, fmt.Errorf("bucket (%s) not found", bucket)
+		return nil, fmt.Errorf("%w (%s)", ErrBucketNotFound, bucket)
 	}
 
-	return bmap, nil
+	fdb.debug.gotAll(bucket)
+
+	return fdb.unexpired(bucket, bmap), nil
 }
 
 /*
 GetAllSorted returns all map values from a bucket in Key sorted order.
+The records are collected under one lock and checked for expiry at one moment,
+so the result reflects one instant in time, even during heavy writes.
+The sorted keys are cached until the bucket changes.
 */
 func (fdb *DB) GetAllSorted(bucket string) ([]*SortRecord, error) {
-	memRecords, err := fdb.GetAll(bucket)
-	if err != nil {
-		return nil, err
+	defer fdb.mu.RLock().RUnlock()
+
+	memRecords, found := fdb.keys[bucket]
+	if !found {
+		return nil, fmt.Errorf("%w (%s)", ErrBucketNotFound, bucket)
 	}
 
-	sortedKeys := slices.Sorted(maps.Keys(memRecords))
+	sortedKeys := fdb.sortedKeys(bucket)
+	now := time.Now().UnixNano()
 
-	sortedRecords := make([]*SortRecord, len(memRecords))
+	sortedRecords := make([]*SortRecord, 0, len(memRecords))
+
+	for _, key := range sortedKeys {
+		if fdb.expiredAt(bucket, key, now) {
+			continue
+		}
 
-	for count, key := range sortedKeys {
-		sortedRecords[count] = &SortRecord{SortField: key, Data: memRecords[key]}
-		// count++
+		sortedRecords = append(sortedRecords, &SortRecord{SortField: key, Data: memRecords[key]})
 	}
 
 	return sortedRecords, nil
 }
 
 /*
-GetNewIndex returns the next available index for a bucket.
+GetRange returns the map values from a bucket whose keys lie between minKey and maxKey
+(both included), in Key sorted order. Only the records in the range are collected.
 */
-func (fdb *DB) GetNewIndex(bucket string) (newKey int) {
-	memRecords, err := fdb.GetAll(bucket)
-	if err != nil {
-		return 1
+func (fdb *DB) GetRange(bucket string, minKey, maxKey int) ([]*SortRecord, error) {
+	defer fdb.mu.RLock().RUnlock()
+
+	memRecords, found := fdb.keys[bucket]
+	if !found {
+		return nil, fmt.Errorf("%w (%s)", ErrBucketNotFound, bucket)
 	}
 
-	lkey := 0
-	for key := range memRecords {
-		if key > lkey {
-			lkey = key
+	sortedKeys := fdb.sortedKeys(bucket)
+	start, _ := slices.BinarySearch(sortedKeys, minKey)
+	end, found := slices.BinarySearch(sortedKeys, maxKey)
+
+	if found {
+		end++
+	}
+
+	end = max(end, start)
+	now := time.Now().UnixNano()
+
+	sortedRecords := make([]*SortRecord, 0, end-start)
+
+	for _, key := range sortedKeys[start:end] {
+		if fdb.expiredAt(bucket, key, now) {
+			continue
 		}
+
+		sortedRecords = append(sortedRecords, &SortRecord{SortField: key, Data: memRecords[key]})
 	}
 
-	newKey = lkey + 1
+	return sortedRecords, nil
+}
+
+/*
+Buckets returns the names of all the buckets in sorted order.
+*/
+func (fdb *DB) Buckets() []string {
+	defer fdb.mu.RLock().RUnlock()
+
+	return slices.Sorted(maps.Keys(fdb.keys))
+}
+
+/*
+Count returns the number of records in a bucket (0 if it doesn't exist).
+Records that expired, but weren't removed yet, aren't counted.
+*/
+func (fdb *DB) Count(bucket string) int {
+	defer fdb.mu.RLock().RUnlock()
+
+	return len(fdb.keys[bucket]) - fdb.expiredCount(bucket, time.Now().UnixNano())
+}
+
+/*
+TotalCount returns the number of records in all the buckets.
+Records that expired, but weren't removed yet, aren't counted.
+*/
+func (fdb *DB) TotalCount() int {
+	defer fdb.mu.RLock().RUnlock()
+
+	now := time.Now().UnixNano()
+	total := fdb.totalCount()
+
+	for bucket := range fdb.expiries {
+		total -= fdb.expiredCount(bucket, now)
+	}
+
+	return total
+}
+
+/*
+totalCount returns the number of records in all the buckets, with the expired ones that are still there.
+The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) totalCount() int {
+	total := 0
+	for _, records := range fdb.keys {
+		total += len(records)
+	}
+
+	return total
+}
+
+/*
+GetNewIndex returns the next available index for a bucket.
+Another caller can get the same index before it is used,
+so use Insert to allocate the key and store the value in one go.
+*/
+func (fdb *DB) GetNewIndex(bucket string) (newKey int) {
+	defer fdb.mu.RLock().RUnlock()
+
+	newKey = highestKey(fdb.keys[bucket]) + 1
 
 	return newKey
 }
 
+/*
+CorruptionReport returns the bad entries that were skipped (and quarantined) while opening
+the database with WithQuarantine, or nil if nothing was skipped.
+*/
+func (fdb *DB) CorruptionReport() *CorruptionReport {
+	if fdb.aof == nil {
+		return nil
+	}
+
+	return fdb.aof.CorruptionReport()
+}
+
+/*
+RestoredFromBackup returns the path to which the corrupt file was moved when Open restored
+the backup (see WithBackupFallback), or an empty string if the file itself was opened.
+*/
+func (fdb *DB) RestoredFromBackup() string {
+	if fdb.aof == nil {
+		return ""
+	}
+
+	return fdb.aof.CorruptFile()
+}
+
+/*
+DiscardedBytes returns the number of bytes of a torn write that Open cut off the end of the file
+(see WithRecover), 0 when the file was complete.
+*/
+func (fdb *DB) DiscardedBytes() int64 {
+	if fdb.aof == nil {
+		return 0
+	}
+
+	return fdb.aof.DiscardedBytes()
+}
+
 /*
 Info returns info about the storage.
 */
 func (fdb *DB) Info() string {
+	defer fdb.mu.RLock().RUnlock()
+
 	count := 0
 	for i := range fdb.keys {
 		count += len(fdb.keys[i])
@@ -188,39 +581,128 @@ func (fdb *DB) Info() string {
 /*
 Set stores one map value in a bucket.
 */
-func (fdb *DB) Set(bucket string, key int, value []byte) error {
-	defer fdb.lockUnlock()()
+func (fdb *DB) Set(bucket string, key int, value []byte) (err error) {
+	end := fdb.startTrace("set", bucket, key)
+
+	defer func() {
+		end(err)
+	}()
 
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return fmt.Errorf("set error: %w", err)
+	}
+
+	defer unlock()
+
+	return fdb.set(bucket, key, value, 0)
+}
+
+/*
+SetPresent stores a key without a value (an empty value) in a bucket, for buckets that are used
+as a set of keys (like the IDs of the users that saw a message). Check a key with Exists.
+Records with an empty value are written without a value line, which halves their size in the file.
+*/
+func (fdb *DB) SetPresent(bucket string, key int) error {
+	return fdb.Set(bucket, key, []byte{})
+}
+
+/*
+set stores one map value in a bucket, with an optional expiry time (in unix nanoseconds).
+The caller must hold the write lock.
+*/
+func (fdb *DB) set(bucket string, key int, value []byte, expiry int64) error {
+	if fdb.readOnly() {
+		return fmt.Errorf("set error: %w", ErrReadOnly)
+	}
+
+	value, err := fdb.checkSet(bucket, key, value)
+	if err != nil {
+		return fmt.Errorf("set->%w", err)
+	}
+
+	instructions := append([]persist.Instruction{persist.SetInstruction(bucket, key, value)},
+		fdb.expiryInstructions(bucket, key, expiry)...)
+
+	err = fdb.write(instructions...)
+	if err != nil {
+		return fmt.Errorf("set->write error: %w", err)
+	}
+
+	fdb.storeRecord(bucket, key, value, expiry)
+
+	return nil
+}
+
+/*
+checkSet checks if the value can be stored (the key, the hooks, the size and the limit of the bucket),
+and returns the value as the hooks made it.
+The caller must hold the write lock.
+*/
+func (fdb *DB) checkSet(bucket string, key int, value []byte) ([]byte, error) {
 	if key < 0 {
-		return errors.New("set->key should be positive")
+		return nil, errors.New("key should be positive")
 	}
 
-	if fdb.aof != nil {
-		lines := "set\n" + bucket + "_" + strconv.Itoa(key) + "\n" + string(value) + "\n"
+	value, err := fdb.beforeSet(bucket, key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if fdb.cfg.maxValueSize > 0 && len(value) > fdb.cfg.maxValueSize {
+		return nil, fmt.Errorf("value size (%d) exceeds the maximum (%d)", len(value), fdb.cfg.maxValueSize)
+	}
 
-		err := fdb.aof.Write(lines)
+	_, exists := fdb.keys[bucket][key]
+	if !exists {
+		err = fdb.checkLimit(bucket, 1)
 		if err != nil {
-			return fmt.Errorf("set->write error: %w", err)
+			return nil, err
 		}
 	}
 
+	return value, nil
+}
+
+/*
+storeRecord stores a record (with its expiry time) in memory, after it was written.
+The caller must hold the write lock.
+*/
+func (fdb *DB) storeRecord(bucket string, key int, value []byte, expiry int64) {
 	_, found := fdb.keys[bucket]
 	if !found {
 		fdb.keys[bucket] = map[int][]byte{}
 	}
 
+	fdb.liveBytes -= fdb.recordSize(bucket, key)
 	fdb.keys[bucket][key] = value
-
-	return nil
+	fdb.liveBytes += fdb.recordSize(bucket, key)
+	fdb.setExpiry(bucket, key, expiry)
+	fdb.touch(bucket)
+	fdb.notify(Event{Type: EventSet, Bucket: bucket, Key: key, Value: value})
+	fdb.afterSet(bucket, key, value)
 }
 
 /*
-Close closes the database.
+Close closes the database, the writes after it (and closing it again) return ErrClosed.
 */
 func (fdb *DB) Close() error {
-	if fdb.aof != nil {
-		defer fdb.lockUnlock()()
+	fdb.debug.closing()
+	fdb.checkHandouts()
+	fdb.Thaw()
+	fdb.stopBackground()
+	fdb.stopWatchers()
+	fdb.stopReplicas()
+
+	defer fdb.lockUnlock()()
+
+	if fdb.closed {
+		return fmt.Errorf("close error: %w", ErrClosed)
+	}
 
+	fdb.closed = true
+
+	if fdb.aof != nil {
 		err := fdb.aof.Close()
 		if err != nil {
 			return fmt.Errorf("close error: %w", err)
@@ -228,6 +710,47 @@ func (fdb *DB) Close() error {
 	}
 
 	fdb.keys = map[string]map[int][]byte{}
+	fdb.meta = map[string]string{}
+	fdb.expiries = map[string]map[int]int64{}
+	fdb.liveBytes = 0
+	fdb.resetCaches()
+
+	return nil
+}
+
+/*
+write writes the instructions to the file (if there is one) in one go,
+and gives them the next write sequence (which is persisted too, WithWriteSequence).
+The caller must hold the write lock.
+*/
+func (fdb *DB) write(instructions ...persist.Instruction) error {
+	if fdb.closed {
+		return ErrClosed
+	}
+
+	sequence := fdb.sequence + 1
+	lsn := strconv.FormatUint(sequence, 10)
+
+	if fdb.cfg.writeSequence {
+		instructions = append(instructions, persist.MetaInstruction(lsnMeta, lsn))
+	}
+
+	if fdb.aof != nil {
+		err := fdb.writeBatch(instructions)
+		if err != nil {
+			return err
+		}
+
+		fdb.fileRecords += recordInstructions(instructions)
+	}
+
+	fdb.sequence = sequence
+
+	if fdb.cfg.writeSequence {
+		fdb.meta[lsnMeta] = lsn
+	}
+
+	fdb.replicate(instructions)
 
 	return nil
 }
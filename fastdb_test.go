@@ -7,6 +7,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sync"
 	"testing"
 	"time"
@@ -37,7 +38,7 @@ func Test_Open_File_noData(t *testing.T) {
 		require.NoError(t, err)
 	}()
 
-	store, err := fastdb.Open(path, syncIime)
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime))
 	require.NoError(t, err)
 	assert.NotNil(t, store)
 
@@ -45,12 +46,16 @@ func Test_Open_File_noData(t *testing.T) {
 		err = store.Close()
 		require.NoError(t, err)
 	}()
+
+	// there is no file to defrag
+	err = store.Defrag()
+	require.NoError(t, err)
 }
 
 func Test_Open_Memory(t *testing.T) {
 	path := memory
 
-	store, err := fastdb.Open(path, syncIime)
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime))
 	require.NoError(t, err)
 	assert.NotNil(t, store)
 
@@ -64,7 +69,7 @@ func Test_SetGetDel_oneRecord(t *testing.T) {
 	path := "data/fastdb_set.db"
 	filePath := filepath.Clean(path)
 
-	store, err := fastdb.Open(filePath, syncIime)
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
 	require.NoError(t, err)
 	assert.NotNil(t, store)
 
@@ -137,7 +142,7 @@ func Fuzz_SetGetDel_oneRecord(f *testing.F) {
 	filePath := filepath.Clean(path)
 	_ = os.Remove(filePath)
 
-	store, err := fastdb.Open(filePath, 1000)
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(1000))
 	require.NoError(f, err)
 	assert.NotNil(f, store)
 
@@ -220,7 +225,7 @@ func Fuzz_SetGetDel_oneRecord(f *testing.F) {
 func Test_Get_wrongRecord(t *testing.T) {
 	path := memory
 
-	store, err := fastdb.Open(path, syncIime)
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime))
 	require.NoError(t, err)
 	assert.NotNil(t, store)
 
@@ -249,11 +254,160 @@ func Test_Get_wrongRecord(t *testing.T) {
 	assert.Nil(t, memData)
 }
 
+func Test_Exists_HasBucket(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.False(t, store.HasBucket("bucket"))
+	assert.False(t, store.Exists("bucket", 1))
+
+	err = store.Set("bucket", 1, []byte("a text"))
+	require.NoError(t, err)
+
+	err = store.SetWithTTL("bucket", 2, []byte("a text"), time.Nanosecond)
+	require.NoError(t, err)
+
+	time.Sleep(time.Millisecond)
+
+	assert.True(t, store.HasBucket("bucket"))
+	assert.True(t, store.Exists("bucket", 1))
+	assert.False(t, store.Exists("bucket", 2))
+	assert.False(t, store.Exists("bucket", 3))
+
+	_, err = store.Del("bucket", 1)
+	require.NoError(t, err)
+
+	_, err = store.Del("bucket", 2)
+	require.NoError(t, err)
+
+	assert.False(t, store.HasBucket("bucket"))
+}
+
+func Test_HasBucket_reopen(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "fastdb_hasbucket.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	err = store.Set("bucket", 1, []byte("a text"))
+	require.NoError(t, err)
+
+	err = store.Set("other", 1, []byte("a text"))
+	require.NoError(t, err)
+
+	_, err = store.Del("bucket", 1)
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.False(t, store.HasBucket("bucket"))
+	assert.True(t, store.HasBucket("other"))
+	assert.Equal(t, []string{"other"}, store.Buckets())
+}
+
+func Test_Count_TotalCount(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.Equal(t, 0, store.Count("bucket"))
+	assert.Equal(t, 0, store.TotalCount())
+
+	err = store.SetMulti("bucket", map[int][]byte{1: []byte("one"), 2: []byte("two")})
+	require.NoError(t, err)
+
+	err = store.Set("other", 1, []byte("one"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, store.Count("bucket"))
+	assert.Equal(t, 1, store.Count("other"))
+	assert.Equal(t, 3, store.TotalCount())
+
+	_, err = store.Del("bucket", 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, store.Count("bucket"))
+	assert.Equal(t, 2, store.TotalCount())
+
+	// an expired record isn't counted, also before the reaper removed it
+	err = store.SetWithTTL("other", 2, []byte("two"), time.Nanosecond)
+	require.NoError(t, err)
+
+	err = store.SetWithTTL("other", 3, []byte("three"), time.Hour)
+	require.NoError(t, err)
+
+	time.Sleep(time.Millisecond)
+
+	assert.Equal(t, 2, store.Count("other"))
+	assert.Equal(t, 3, store.TotalCount())
+}
+
+func Test_GetSize_GetPrefix(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.NotNil(t, store)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	err = store.Set("bucket", 1, []byte("a text"))
+	require.NoError(t, err)
+
+	size, ok := store.GetSize("bucket", 1)
+	assert.True(t, ok)
+	assert.Equal(t, 6, size)
+
+	size, ok = store.GetSize("bucket", 2)
+	assert.False(t, ok)
+	assert.Equal(t, 0, size)
+
+	prefix, ok := store.GetPrefix("bucket", 1, 2)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("a "), prefix)
+
+	// the prefix is a copy
+	prefix[0] = 'b'
+	memData, _ := store.Get("bucket", 1)
+	assert.Equal(t, []byte("a text"), memData)
+
+	prefix, ok = store.GetPrefix("bucket", 1, 100)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("a text"), prefix)
+
+	prefix, ok = store.GetPrefix("bucket", 1, -1)
+	assert.True(t, ok)
+	assert.Empty(t, prefix)
+
+	prefix, ok = store.GetPrefix("wrong_bucket", 1, 2)
+	assert.False(t, ok)
+	assert.Nil(t, prefix)
+}
+
 func Test_Defrag_1000lines(t *testing.T) {
 	path := "data/fastdb_defrag1000.db"
 	filePath := filepath.Clean(path)
 
-	store, err := fastdb.Open(path, syncIime)
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime))
 	require.NoError(t, err)
 	assert.NotNil(t, store)
 
@@ -302,7 +456,7 @@ func Test_Defrag_1000000lines(t *testing.T) {
 	path := "data/fastdb_defrag1000000.db"
 	filePath := filepath.Clean(path)
 
-	store, err := fastdb.Open(filePath, 250)
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(250))
 	require.NoError(t, err)
 	assert.NotNil(t, store)
 
@@ -350,7 +504,7 @@ func Test_GetAllFromMemory_1000(t *testing.T) {
 	total := 1000
 	path := memory
 
-	store, err := fastdb.Open(path, syncIime)
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime))
 	require.NoError(t, err)
 	assert.NotNil(t, store)
 
@@ -393,7 +547,7 @@ func Test_GetAllFromFile_1000(t *testing.T) {
 	filePath := filepath.Clean(path)
 	_ = os.Remove(filePath)
 
-	store, err := fastdb.Open(path, syncIime)
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime))
 	require.NoError(t, err)
 	assert.NotNil(t, store)
 
@@ -442,7 +596,7 @@ func Test_GetAllSortedFromFile_10000(t *testing.T) {
 		require.NoError(t, err)
 	}()
 
-	store, err := fastdb.Open(path, syncIime)
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime))
 	require.NoError(t, err)
 	assert.NotNil(t, store)
 
@@ -485,11 +639,56 @@ func Test_GetAllSortedFromFile_10000(t *testing.T) {
 	assert.Len(t, records, total)
 }
 
+func Test_GetRange(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	for _, key := range []int{1700000000, 1700000060, 1700000120, 1700000180} {
+		err = store.Set("events", key, []byte("event"))
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name     string
+		expected []int
+		minKey   int
+		maxKey   int
+	}{
+		{name: "inclusive", minKey: 1700000060, maxKey: 1700000120, expected: []int{1700000060, 1700000120}},
+		{name: "between", minKey: 1700000001, maxKey: 1700000179, expected: []int{1700000060, 1700000120}},
+		{name: "all", minKey: 0, maxKey: 2000000000, expected: []int{1700000000, 1700000060, 1700000120, 1700000180}},
+		{name: "none", minKey: 1700000001, maxKey: 1700000059, expected: []int{}},
+		{name: "reversed", minKey: 1700000180, maxKey: 1700000000, expected: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := store.GetRange("events", tt.minKey, tt.maxKey)
+			require.NoError(t, err)
+
+			keys := []int{}
+			for _, record := range records {
+				keys = append(keys, record.SortField.(int)) //nolint:forcetypeassert // always an int
+			}
+
+			assert.Equal(t, tt.expected, keys)
+		})
+	}
+
+	_, err = store.GetRange("wrong_bucket", 0, 1)
+	require.Error(t, err)
+}
+
 func Test_GetAllSortedFromMemory_10000(t *testing.T) {
 	total := 10000
 	path := memory
 
-	store, err := fastdb.Open(path, syncIime)
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime))
 	require.NoError(t, err)
 	assert.NotNil(t, store)
 
@@ -539,7 +738,7 @@ func Test_Set_error(t *testing.T) {
 	path := "data/fastdb_set_error.db"
 	filePath := filepath.Clean(path)
 
-	store, err := fastdb.Open(filePath, syncIime)
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
 	require.NoError(t, err)
 	assert.NotNil(t, store)
 
@@ -553,7 +752,59 @@ func Test_Set_error(t *testing.T) {
 
 	// store a record
 	err = store.Set("bucket", 1, []byte("a text"))
-	require.Error(t, err)
+	require.ErrorIs(t, err, fastdb.ErrClosed)
+
+	err = store.Close()
+	require.ErrorIs(t, err, fastdb.ErrClosed)
+}
+
+func Test_sentinelErrors(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	_, err = store.GetAll("nobucket")
+	require.ErrorIs(t, err, fastdb.ErrBucketNotFound)
+
+	err = store.RenameBucket("nobucket", "other")
+	require.ErrorIs(t, err, fastdb.ErrBucketNotFound)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	err = store.Set("bucket", 1, []byte("value"))
+	require.ErrorIs(t, err, fastdb.ErrClosed)
+
+	filePath := filepath.Join(t.TempDir(), "fastdb_corrupted.db")
+	err = os.WriteFile(filePath, []byte("set\nbucket_1\nvalue\nset\nbucket_x\nvalue\n"), 0o600)
+	require.NoError(t, err)
+
+	_, err = fastdb.Open(filePath)
+	require.ErrorIs(t, err, fastdb.ErrCorrupted)
+
+	var corrupt *fastdb.CorruptionError
+
+	require.ErrorAs(t, err, &corrupt)
+	assert.Equal(t, 4, corrupt.Line)
+	assert.Equal(t, int64(19), corrupt.Offset)
+}
+
+func Test_Open_locked(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "fastdb_locked.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	_, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.ErrorIs(t, err, fastdb.ErrLocked)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
 }
 
 func Test_Set_wrongBucket(t *testing.T) {
@@ -566,7 +817,7 @@ func Test_Set_wrongBucket(t *testing.T) {
 		require.NoError(t, err)
 	}()
 
-	store, err := fastdb.Open(path, syncIime)
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime))
 	require.NoError(t, err)
 	assert.NotNil(t, store)
 
@@ -580,7 +831,7 @@ func Test_Set_wrongBucket(t *testing.T) {
 	err = store.Close()
 	require.NoError(t, err)
 
-	store2, err := fastdb.Open(path, syncIime)
+	store2, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime))
 	require.NoError(t, err)
 	assert.NotNil(t, store2)
 
@@ -600,7 +851,7 @@ func TestConcurrentOperationsWithDelete(t *testing.T) {
 		require.NoError(t, err)
 	}()
 
-	store, err := fastdb.Open(path, syncIime)
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime))
 	require.NoError(t, err)
 
 	defer func() {
@@ -682,7 +933,7 @@ func Benchmark_Get_File_1000(b *testing.B) {
 		require.NoError(b, err)
 	}()
 
-	store, err := fastdb.Open(path, syncIime)
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime))
 	require.NoError(b, err)
 	assert.NotNil(b, store)
 
@@ -723,7 +974,7 @@ func Benchmark_Get_Memory_1000(b *testing.B) {
 	path := memory
 	total := 1000
 
-	store, err := fastdb.Open(path, syncIime)
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime))
 	require.NoError(b, err)
 	assert.NotNil(b, store)
 
@@ -760,6 +1011,32 @@ func Benchmark_Get_Memory_1000(b *testing.B) {
 	require.NoError(b, err)
 }
 
+func Benchmark_Get_Memory_Parallel(b *testing.B) {
+	store, err := fastdb.Open(memory)
+	require.NoError(b, err)
+
+	for key := 1; key <= 1000; key++ {
+		err = store.Set("bench_bucket", key, []byte("a value"))
+		require.NoError(b, err)
+	}
+
+	b.ResetTimer()
+
+	// the readers are spread over the shards of the lock, so they don't slow each other down
+	b.RunParallel(func(pb *testing.PB) {
+		key := 0
+		for pb.Next() {
+			key = key%1000 + 1
+			_, _ = store.Get("bench_bucket", key)
+		}
+	})
+
+	b.StopTimer()
+
+	err = store.Close()
+	require.NoError(b, err)
+}
+
 func Benchmark_Set_File_NoSyncTime(b *testing.B) {
 	path := "data/bench-set.db"
 
@@ -771,7 +1048,7 @@ func Benchmark_Set_File_NoSyncTime(b *testing.B) {
 		require.NoError(b, err)
 	}()
 
-	store, err := fastdb.Open(path, 0)
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(0))
 	require.NoError(b, err)
 	assert.NotNil(b, store)
 
@@ -812,7 +1089,7 @@ func Benchmark_Set_File_WithSyncTime(b *testing.B) {
 		require.NoError(b, err)
 	}()
 
-	store, err := fastdb.Open(path, syncIime)
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime))
 	require.NoError(b, err)
 	assert.NotNil(b, store)
 
@@ -847,23 +1124,40 @@ func checkFileLines(t *testing.T, filePath string, checkCount int) {
 	require.NoError(t, err)
 	assert.NotNil(t, readFile)
 
-	count := 0
+	var lines []string
 
 	scanner := bufio.NewScanner(readFile)
 	for scanner.Scan() {
-		count++
+		lines = append(lines, scanner.Text())
 	}
 
 	err = readFile.Close()
 	require.NoError(t, err)
 
-	assert.Equal(t, checkCount, count)
+	// the header with the file version (and the generation) isn't counted
+	if len(lines) >= 3 && lines[0] == "meta" && lines[1] == "fileversion" {
+		lines = lines[3:]
+	}
+
+	if len(lines) >= 3 && lines[0] == "meta" && lines[1] == "filegeneration" {
+		lines = lines[3:]
+	}
+
+	assert.Len(t, lines, checkCount)
+}
+
+// headerText is a header of a file in the text format, every header has the same size.
+const headerText = "meta\nfileversion\n2\nmeta\nfilegeneration\n0123456789abcdef\n"
+
+// fileHeader returns the header at the start of the content of a file: the version and the generation.
+func fileHeader(content string) string {
+	return regexp.MustCompile("^meta\nfileversion\n2\n(meta\nfilegeneration\n[0-9a-f]{16}\n)?").FindString(content)
 }
 
 func Benchmark_Set_Memory(b *testing.B) {
 	path := memory
 
-	store, err := fastdb.Open(path, syncIime)
+	store, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime))
 	require.NoError(b, err)
 	assert.NotNil(b, store)
 
@@ -892,3 +1186,52 @@ func Benchmark_Set_Memory(b *testing.B) {
 	err = store.Close()
 	require.NoError(b, err)
 }
+
+func Test_SetPresent(t *testing.T) {
+	path := "data/fastdb_present.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	for key := 1; key <= 3; key++ {
+		err = store.SetPresent("seen", key)
+		require.NoError(t, err)
+	}
+
+	_, err = store.Del("seen", 2)
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// only the keys are written
+	checkFileLines(t, filePath, 8)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.True(t, store.Exists("seen", 1))
+	assert.False(t, store.Exists("seen", 2))
+	assert.True(t, store.Exists("seen", 3))
+
+	value, found := store.Get("seen", 3)
+	assert.True(t, found)
+	assert.Empty(t, value)
+
+	// a defrag keeps them compact
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	checkFileLines(t, filePath, 4)
+}
@@ -0,0 +1,80 @@
+package fastdbgrpc
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// tokenCredentials sends a static token with every call.
+type tokenCredentials struct {
+	token string
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+TokenInterceptor returns the server option that requires every call to have the metadata
+"authorization: Bearer <token>", other calls get the code Unauthenticated.
+It applies to all the services of the server. Use it together with TLS (and client certificates for mutual TLS):
+
+	config, err := fastdb.ServerTLSConfig("cert.pem", "key.pem", "clients.pem")
+	server := grpc.NewServer(fastdbgrpc.ServerOption(), fastdbgrpc.TokenInterceptor(token),
+		grpc.Creds(credentials.NewTLS(config)))
+*/
+func TokenInterceptor(token string) grpc.ServerOption {
+	return grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		if !validToken(ctx, token) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		return handler(ctx, req)
+	})
+}
+
+/*
+TokenCredentials returns the dial option for clients that sends the token with every call.
+The token is only sent over TLS.
+*/
+func TokenCredentials(token string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(tokenCredentials{token: token})
+}
+
+/*
+GetRequestMetadata returns the metadata with the token.
+*/
+func (creds tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + creds.token}, nil
+}
+
+/*
+RequireTransportSecurity returns true, so the token is never sent unencrypted.
+*/
+func (tokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+/*
+validToken returns true if the metadata of the call has the token.
+*/
+func validToken(ctx context.Context, token string) bool {
+	for _, value := range metadata.ValueFromIncomingContext(ctx, "authorization") {
+		callToken, found := strings.CutPrefix(value, "Bearer ")
+		if found && subtle.ConstantTimeCompare([]byte(callToken), []byte(token)) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
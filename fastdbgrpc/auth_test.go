@@ -0,0 +1,64 @@
+package fastdbgrpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/marcelloh/fastdb/fastdbgrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func Test_TokenInterceptor(t *testing.T) {
+	store, err := fastdb.Open(":memory:")
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(fastdbgrpc.ServerOption(), fastdbgrpc.TokenInterceptor("secret"))
+	fastdbgrpc.Register(server, store)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	defer func() {
+		server.Stop()
+		require.NoError(t, store.Close())
+	}()
+
+	dialer := grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	})
+
+	// the token is never sent without TLS
+	_, err = grpc.NewClient("passthrough:///bufnet", dialer,
+		grpc.WithTransportCredentials(insecure.NewCredentials()), fastdbgrpc.TokenCredentials("secret"))
+	require.Error(t, err)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet", dialer, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+
+	client := fastdbgrpc.NewClient(conn)
+	ctx := context.Background()
+
+	_, err = client.Info(ctx, &fastdbgrpc.InfoRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.Info(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer wrong"), &fastdbgrpc.InfoRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.Info(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret"), &fastdbgrpc.InfoRequest{})
+	require.NoError(t, err)
+}
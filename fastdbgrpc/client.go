@@ -0,0 +1,111 @@
+package fastdbgrpc
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// Client calls the FastDB service.
+type Client struct {
+	conn grpc.ClientConnInterface
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+NewClient returns the client of the FastDB service on the connection.
+*/
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{conn: conn}
+}
+
+/*
+Set stores one value in a bucket.
+*/
+func (cln *Client) Set(ctx context.Context, req *SetRequest, opts ...grpc.CallOption) (*SetResponse, error) {
+	return invoke(ctx, cln, "Set", req, &SetResponse{}, opts)
+}
+
+/*
+Get returns one value from a bucket.
+*/
+func (cln *Client) Get(ctx context.Context, req *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	return invoke(ctx, cln, "Get", req, &GetResponse{}, opts)
+}
+
+/*
+Del deletes one value from a bucket.
+*/
+func (cln *Client) Del(ctx context.Context, req *DelRequest, opts ...grpc.CallOption) (*DelResponse, error) {
+	return invoke(ctx, cln, "Del", req, &DelResponse{}, opts)
+}
+
+/*
+GetAll returns all the records of a bucket, in key sorted order.
+*/
+func (cln *Client) GetAll(ctx context.Context, req *GetAllRequest, opts ...grpc.CallOption) (*GetAllResponse, error) {
+	return invoke(ctx, cln, "GetAll", req, &GetAllResponse{}, opts)
+}
+
+/*
+Defrag optimises the file to reflect the latest state.
+*/
+func (cln *Client) Defrag(ctx context.Context, req *DefragRequest, opts ...grpc.CallOption) (*DefragResponse, error) {
+	return invoke(ctx, cln, "Defrag", req, &DefragResponse{}, opts)
+}
+
+/*
+Info returns info about the storage.
+*/
+func (cln *Client) Info(ctx context.Context, req *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error) {
+	return invoke(ctx, cln, "Info", req, &InfoResponse{}, opts)
+}
+
+/*
+Backup returns a snapshot of the database.
+*/
+func (cln *Client) Backup(ctx context.Context, req *BackupRequest, opts ...grpc.CallOption) (*BackupResponse, error) {
+	return invoke(ctx, cln, "Backup", req, &BackupResponse{}, opts)
+}
+
+/*
+Verify checks that every entry of the file can still be read.
+*/
+func (cln *Client) Verify(ctx context.Context, req *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error) {
+	return invoke(ctx, cln, "Verify", req, &VerifyResponse{}, opts)
+}
+
+/*
+Stats returns the numbers about the storage.
+*/
+func (cln *Client) Stats(ctx context.Context, req *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	return invoke(ctx, cln, "Stats", req, &StatsResponse{}, opts)
+}
+
+/*
+invoke calls one method of the service.
+*/
+func invoke[Resp message](
+	ctx context.Context,
+	cln *Client,
+	name string,
+	req message,
+	resp Resp,
+	opts []grpc.CallOption,
+) (Resp, error) {
+	opts = append([]grpc.CallOption{grpc.ForceCodec(codec{})}, opts...)
+
+	err := cln.conn.Invoke(ctx, "/"+serviceName+"/"+name, req, resp, opts...)
+	if err != nil {
+		var zero Resp
+
+		return zero, err //nolint:wrapcheck // it is the grpc status
+	}
+
+	return resp, nil
+}
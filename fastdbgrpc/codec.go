@@ -0,0 +1,65 @@
+package fastdbgrpc
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// codec encodes the messages of fastdb.proto in the protobuf wire format,
+// and hands all other (generated) protobuf messages to the protobuf library.
+type codec struct{}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+ServerOption returns the option that a grpc server needs to serve the FastDB service:
+
+	server := grpc.NewServer(fastdbgrpc.ServerOption())
+	fastdbgrpc.Register(server, store)
+
+Other (generated) protobuf services on the same server keep working.
+*/
+func ServerOption() grpc.ServerOption {
+	return grpc.ForceServerCodec(codec{})
+}
+
+/*
+Marshal encodes a message.
+*/
+func (codec) Marshal(value any) ([]byte, error) {
+	switch msg := value.(type) {
+	case message:
+		return msg.marshal(), nil
+	case proto.Message:
+		return proto.Marshal(msg) //nolint:wrapcheck // it is the protobuf error
+	default:
+		return nil, fmt.Errorf("marshal error: %T is not a protobuf message", value)
+	}
+}
+
+/*
+Unmarshal decodes a message.
+*/
+func (codec) Unmarshal(data []byte, value any) error {
+	switch msg := value.(type) {
+	case message:
+		return msg.unmarshal(data)
+	case proto.Message:
+		return proto.Unmarshal(data, msg) //nolint:wrapcheck // it is the protobuf error
+	default:
+		return fmt.Errorf("unmarshal error: %T is not a protobuf message", value)
+	}
+}
+
+/*
+Name returns the name of the content subtype, which is the one of protobuf.
+*/
+func (codec) Name() string {
+	return "proto"
+}
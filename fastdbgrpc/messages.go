@@ -0,0 +1,353 @@
+package fastdbgrpc
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// message is a message of fastdb.proto, which encodes itself in the protobuf wire format.
+type message interface {
+	marshal() []byte
+	unmarshal(data []byte) error
+}
+
+// SetRequest is the request of Set.
+type SetRequest struct {
+	Bucket string
+	Value  []byte
+	Key    int64
+}
+
+// SetResponse is the response of Set.
+type SetResponse struct{}
+
+// GetRequest is the request of Get.
+type GetRequest struct {
+	Bucket string
+	Key    int64
+}
+
+// GetResponse is the response of Get.
+type GetResponse struct {
+	Value []byte
+	Found bool
+}
+
+// DelRequest is the request of Del.
+type DelRequest struct {
+	Bucket string
+	Key    int64
+}
+
+// DelResponse is the response of Del.
+type DelResponse struct {
+	Deleted bool
+}
+
+// GetAllRequest is the request of GetAll.
+type GetAllRequest struct {
+	Bucket string
+}
+
+// Record is one record of a GetAllResponse.
+type Record struct {
+	Value []byte
+	Key   int64
+}
+
+// GetAllResponse is the response of GetAll.
+type GetAllResponse struct {
+	Records []*Record
+}
+
+// DefragRequest is the request of Defrag.
+type DefragRequest struct{}
+
+// DefragResponse is the response of Defrag.
+type DefragResponse struct{}
+
+// InfoRequest is the request of Info.
+type InfoRequest struct{}
+
+// InfoResponse is the response of Info.
+type InfoResponse struct {
+	Info string
+}
+
+// BackupRequest is the request of Backup.
+type BackupRequest struct{}
+
+// BackupResponse is the response of Backup.
+type BackupResponse struct {
+	Snapshot []byte
+}
+
+// VerifyRequest is the request of Verify.
+type VerifyRequest struct{}
+
+// VerifyResponse is the response of Verify.
+type VerifyResponse struct{}
+
+// StatsRequest is the request of Stats.
+type StatsRequest struct{}
+
+// StatsResponse is the response of Stats, see fastdb.Stats.
+type StatsResponse struct {
+	Records            int64
+	Buckets            int64
+	LiveBytes          int64
+	FileBytes          int64
+	WriteAmplification float64
+	GrowthPerDay       float64
+	DaysUntilFull      float64
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+func (msg *SetRequest) marshal() []byte {
+	buf := appendString(nil, 1, msg.Bucket)
+	buf = appendInt(buf, 2, msg.Key)
+
+	return appendBytes(buf, 3, msg.Value)
+}
+
+func (msg *SetRequest) unmarshal(data []byte) error {
+	return decodeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) int {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			return consumeString(data, &msg.Bucket)
+		case num == 2 && typ == protowire.VarintType:
+			return consumeInt(data, &msg.Key)
+		case num == 3 && typ == protowire.BytesType:
+			return consumeBytes(data, &msg.Value)
+		default:
+			return skipField
+		}
+	})
+}
+
+func (*SetResponse) marshal() []byte { return nil }
+
+func (*SetResponse) unmarshal(data []byte) error { return decodeFields(data, skipAll) }
+
+func (msg *GetRequest) marshal() []byte {
+	return appendInt(appendString(nil, 1, msg.Bucket), 2, msg.Key)
+}
+
+func (msg *GetRequest) unmarshal(data []byte) error {
+	return decodeFields(data, bucketKeyFields(&msg.Bucket, &msg.Key))
+}
+
+func (msg *GetResponse) marshal() []byte {
+	return appendBool(appendBytes(nil, 1, msg.Value), 2, msg.Found)
+}
+
+func (msg *GetResponse) unmarshal(data []byte) error {
+	return decodeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) int {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			return consumeBytes(data, &msg.Value)
+		case num == 2 && typ == protowire.VarintType:
+			return consumeBool(data, &msg.Found)
+		default:
+			return skipField
+		}
+	})
+}
+
+func (msg *DelRequest) marshal() []byte {
+	return appendInt(appendString(nil, 1, msg.Bucket), 2, msg.Key)
+}
+
+func (msg *DelRequest) unmarshal(data []byte) error {
+	return decodeFields(data, bucketKeyFields(&msg.Bucket, &msg.Key))
+}
+
+func (msg *DelResponse) marshal() []byte {
+	return appendBool(nil, 1, msg.Deleted)
+}
+
+func (msg *DelResponse) unmarshal(data []byte) error {
+	return decodeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) int {
+		if num == 1 && typ == protowire.VarintType {
+			return consumeBool(data, &msg.Deleted)
+		}
+
+		return skipField
+	})
+}
+
+func (msg *GetAllRequest) marshal() []byte {
+	return appendString(nil, 1, msg.Bucket)
+}
+
+func (msg *GetAllRequest) unmarshal(data []byte) error {
+	return decodeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) int {
+		if num == 1 && typ == protowire.BytesType {
+			return consumeString(data, &msg.Bucket)
+		}
+
+		return skipField
+	})
+}
+
+func (msg *Record) marshal() []byte {
+	return appendBytes(appendInt(nil, 1, msg.Key), 2, msg.Value)
+}
+
+func (msg *Record) unmarshal(data []byte) error {
+	return decodeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) int {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			return consumeInt(data, &msg.Key)
+		case num == 2 && typ == protowire.BytesType:
+			return consumeBytes(data, &msg.Value)
+		default:
+			return skipField
+		}
+	})
+}
+
+func (msg *GetAllResponse) marshal() []byte {
+	var buf []byte
+
+	for _, record := range msg.Records {
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, record.marshal())
+	}
+
+	return buf
+}
+
+func (msg *GetAllResponse) unmarshal(data []byte) error {
+	return decodeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) int {
+		if num != 1 || typ != protowire.BytesType {
+			return skipField
+		}
+
+		var recordData []byte
+
+		size := consumeBytes(data, &recordData)
+		if size < 0 {
+			return size
+		}
+
+		record := &Record{}
+		if record.unmarshal(recordData) != nil {
+			return -1
+		}
+
+		msg.Records = append(msg.Records, record)
+
+		return size
+	})
+}
+
+func (*DefragRequest) marshal() []byte { return nil }
+
+func (*DefragRequest) unmarshal(data []byte) error { return decodeFields(data, skipAll) }
+
+func (*DefragResponse) marshal() []byte { return nil }
+
+func (*DefragResponse) unmarshal(data []byte) error { return decodeFields(data, skipAll) }
+
+func (*InfoRequest) marshal() []byte { return nil }
+
+func (*InfoRequest) unmarshal(data []byte) error { return decodeFields(data, skipAll) }
+
+func (msg *InfoResponse) marshal() []byte {
+	return appendString(nil, 1, msg.Info)
+}
+
+func (msg *InfoResponse) unmarshal(data []byte) error {
+	return decodeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) int {
+		if num == 1 && typ == protowire.BytesType {
+			return consumeString(data, &msg.Info)
+		}
+
+		return skipField
+	})
+}
+
+func (*BackupRequest) marshal() []byte { return nil }
+
+func (*BackupRequest) unmarshal(data []byte) error { return decodeFields(data, skipAll) }
+
+func (msg *BackupResponse) marshal() []byte {
+	return appendBytes(nil, 1, msg.Snapshot)
+}
+
+func (msg *BackupResponse) unmarshal(data []byte) error {
+	return decodeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) int {
+		if num == 1 && typ == protowire.BytesType {
+			return consumeBytes(data, &msg.Snapshot)
+		}
+
+		return skipField
+	})
+}
+
+func (*VerifyRequest) marshal() []byte { return nil }
+
+func (*VerifyRequest) unmarshal(data []byte) error { return decodeFields(data, skipAll) }
+
+func (*VerifyResponse) marshal() []byte { return nil }
+
+func (*VerifyResponse) unmarshal(data []byte) error { return decodeFields(data, skipAll) }
+
+func (*StatsRequest) marshal() []byte { return nil }
+
+func (*StatsRequest) unmarshal(data []byte) error { return decodeFields(data, skipAll) }
+
+func (msg *StatsResponse) marshal() []byte {
+	buf := appendInt(nil, 1, msg.Records)
+	buf = appendInt(buf, 2, msg.Buckets)
+	buf = appendInt(buf, 3, msg.LiveBytes)
+	buf = appendInt(buf, 4, msg.FileBytes)
+	buf = appendDouble(buf, 5, msg.WriteAmplification)
+	buf = appendDouble(buf, 6, msg.GrowthPerDay)
+
+	return appendDouble(buf, 7, msg.DaysUntilFull)
+}
+
+func (msg *StatsResponse) unmarshal(data []byte) error {
+	return decodeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) int {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			return consumeInt(data, &msg.Records)
+		case num == 2 && typ == protowire.VarintType:
+			return consumeInt(data, &msg.Buckets)
+		case num == 3 && typ == protowire.VarintType:
+			return consumeInt(data, &msg.LiveBytes)
+		case num == 4 && typ == protowire.VarintType:
+			return consumeInt(data, &msg.FileBytes)
+		case num == 5 && typ == protowire.Fixed64Type:
+			return consumeDouble(data, &msg.WriteAmplification)
+		case num == 6 && typ == protowire.Fixed64Type:
+			return consumeDouble(data, &msg.GrowthPerDay)
+		case num == 7 && typ == protowire.Fixed64Type:
+			return consumeDouble(data, &msg.DaysUntilFull)
+		default:
+			return skipField
+		}
+	})
+}
+
+/*
+bucketKeyFields returns the field decoder of the requests with a bucket and a key.
+*/
+func bucketKeyFields(bucket *string, key *int64) fieldDecoder {
+	return func(num protowire.Number, typ protowire.Type, data []byte) int {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			return consumeString(data, bucket)
+		case num == 2 && typ == protowire.VarintType:
+			return consumeInt(data, key)
+		default:
+			return skipField
+		}
+	}
+}
@@ -0,0 +1,280 @@
+/*
+Package fastdbgrpc serves a fastdb database as a gRPC service (see fastdb.proto),
+so services in other languages can talk to a central fastdb instance.
+The messages are encoded in the protobuf wire format, so any protobuf client can use it.
+Every call is checked with the authorization hooks of the database (see fastdb.DB.OnAuthorize),
+with the context of the call. A denied call gets the code PermissionDenied.
+The admin calls (Defrag, Info, Backup, Verify and Stats) are checked as fastdb.OpAdmin, with an empty bucket.
+*/
+package fastdbgrpc
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/marcelloh/fastdb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const serviceName = "fastdb.v1.FastDB"
+
+// Server implements the FastDB service around a database.
+type Server struct {
+	store *fastdb.DB
+}
+
+// fastDBServer is the interface that the service needs, which Server implements.
+type fastDBServer interface {
+	Set(ctx context.Context, req *SetRequest) (message, error)
+	Get(ctx context.Context, req *GetRequest) (message, error)
+	Del(ctx context.Context, req *DelRequest) (message, error)
+	GetAll(ctx context.Context, req *GetAllRequest) (message, error)
+	Defrag(ctx context.Context, req *DefragRequest) (message, error)
+	Info(ctx context.Context, req *InfoRequest) (message, error)
+	Backup(ctx context.Context, req *BackupRequest) (message, error)
+	Verify(ctx context.Context, req *VerifyRequest) (message, error)
+	Stats(ctx context.Context, req *StatsRequest) (message, error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*fastDBServer)(nil),
+	Methods: []grpc.MethodDesc{
+		method("Set", fastDBServer.Set),
+		method("Get", fastDBServer.Get),
+		method("Del", fastDBServer.Del),
+		method("GetAll", fastDBServer.GetAll),
+		method("Defrag", fastDBServer.Defrag),
+		method("Info", fastDBServer.Info),
+		method("Backup", fastDBServer.Backup),
+		method("Verify", fastDBServer.Verify),
+		method("Stats", fastDBServer.Stats),
+	},
+	Metadata: "fastdb.proto",
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Register registers the FastDB service for the database at a grpc server,
+which has to be created with ServerOption.
+*/
+func Register(registrar grpc.ServiceRegistrar, store *fastdb.DB) {
+	registrar.RegisterService(&serviceDesc, &Server{store: store})
+}
+
+/*
+Set stores one value in a bucket.
+*/
+func (srv *Server) Set(ctx context.Context, req *SetRequest) (message, error) {
+	err := srv.store.Authorize(ctx, fastdb.OpWrite, req.Bucket, int(req.Key))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	err = srv.store.Set(req.Bucket, int(req.Key), req.Value)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &SetResponse{}, nil
+}
+
+/*
+Get returns one value from a bucket.
+*/
+func (srv *Server) Get(ctx context.Context, req *GetRequest) (message, error) {
+	err := srv.store.Authorize(ctx, fastdb.OpRead, req.Bucket, int(req.Key))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	value, found := srv.store.Get(req.Bucket, int(req.Key))
+
+	return &GetResponse{Value: value, Found: found}, nil
+}
+
+/*
+Del deletes one value from a bucket.
+*/
+func (srv *Server) Del(ctx context.Context, req *DelRequest) (message, error) {
+	err := srv.store.Authorize(ctx, fastdb.OpDelete, req.Bucket, int(req.Key))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	deleted, err := srv.store.Del(req.Bucket, int(req.Key))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &DelResponse{Deleted: deleted}, nil
+}
+
+/*
+GetAll returns all the records of a bucket, in key sorted order.
+*/
+func (srv *Server) GetAll(ctx context.Context, req *GetAllRequest) (message, error) {
+	err := srv.store.Authorize(ctx, fastdb.OpRead, req.Bucket, fastdb.AnyKey)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	sortedRecords, err := srv.store.GetAllSorted(req.Bucket)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	resp := &GetAllResponse{Records: make([]*Record, len(sortedRecords))}
+
+	for i, sortRecord := range sortedRecords {
+		resp.Records[i] = &Record{Key: int64(sortRecord.SortField.(int)), Value: sortRecord.Data} //nolint:forcetypeassert // always an int
+	}
+
+	return resp, nil
+}
+
+/*
+Defrag optimises the file to reflect the latest state.
+*/
+func (srv *Server) Defrag(ctx context.Context, _ *DefragRequest) (message, error) {
+	err := srv.store.Authorize(ctx, fastdb.OpAdmin, "", fastdb.AnyKey)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	err = srv.store.Defrag()
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &DefragResponse{}, nil
+}
+
+/*
+Info returns info about the storage.
+*/
+func (srv *Server) Info(ctx context.Context, _ *InfoRequest) (message, error) {
+	err := srv.store.Authorize(ctx, fastdb.OpAdmin, "", fastdb.AnyKey)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &InfoResponse{Info: srv.store.Info()}, nil
+}
+
+/*
+Backup returns a snapshot of the database, which can be restored with fastdb.OpenFromBackup.
+The whole snapshot is one message, so the maximum message size of the client limits its size.
+*/
+func (srv *Server) Backup(ctx context.Context, _ *BackupRequest) (message, error) {
+	err := srv.store.Authorize(ctx, fastdb.OpAdmin, "", fastdb.AnyKey)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	var buf bytes.Buffer
+
+	err = srv.store.Snapshot(&buf)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &BackupResponse{Snapshot: buf.Bytes()}, nil
+}
+
+/*
+Verify checks that every entry of the file can still be read. A corruption gets the code DataLoss.
+*/
+func (srv *Server) Verify(ctx context.Context, _ *VerifyRequest) (message, error) {
+	err := srv.store.Authorize(ctx, fastdb.OpAdmin, "", fastdb.AnyKey)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	err = srv.store.Verify()
+	if err != nil {
+		return nil, status.Error(codes.DataLoss, err.Error())
+	}
+
+	return &VerifyResponse{}, nil
+}
+
+/*
+Stats returns the numbers about the storage.
+*/
+func (srv *Server) Stats(ctx context.Context, _ *StatsRequest) (message, error) {
+	err := srv.store.Authorize(ctx, fastdb.OpAdmin, "", fastdb.AnyKey)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	stats := srv.store.Stats()
+
+	return &StatsResponse{
+		Records:            int64(stats.Records),
+		Buckets:            int64(stats.Buckets),
+		LiveBytes:          stats.LiveBytes,
+		FileBytes:          stats.FileBytes,
+		WriteAmplification: stats.WriteAmplification,
+		GrowthPerDay:       stats.GrowthPerDay,
+		DaysUntilFull:      stats.DaysUntilFull,
+	}, nil
+}
+
+/*
+method returns the description of one method of the service.
+*/
+func method[Req any, PReq interface {
+	*Req
+	message
+}](name string, call func(fastDBServer, context.Context, PReq) (message, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := PReq(new(Req))
+
+			err := dec(req)
+			if err != nil {
+				return nil, err
+			}
+
+			handler := func(ctx context.Context, req any) (any, error) {
+				return call(srv.(fastDBServer), ctx, req.(PReq)) //nolint:forcetypeassert // guaranteed by grpc
+			}
+
+			if interceptor == nil {
+				return handler(ctx, req)
+			}
+
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/" + name}
+
+			return interceptor(ctx, req, info, handler)
+		},
+	}
+}
+
+/*
+statusError returns the grpc status for an error of the database.
+*/
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, fastdb.ErrDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, fastdb.ErrReadOnly):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, fastdb.ErrFrozen), errors.Is(err, fastdb.ErrClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, fastdb.ErrBucketNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
@@ -0,0 +1,126 @@
+package fastdbgrpc_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/marcelloh/fastdb/fastdbgrpc"
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func Test_Server(t *testing.T) {
+	store, err := fastdb.Open(":memory:")
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(fastdbgrpc.ServerOption())
+	fastdbgrpc.Register(server, store)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	defer func() {
+		server.Stop()
+		require.NoError(t, store.Close())
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+
+	client := fastdbgrpc.NewClient(conn)
+	ctx := context.Background()
+
+	_, err = client.Set(ctx, &fastdbgrpc.SetRequest{Bucket: "user", Key: 2, Value: []byte("second")})
+	require.NoError(t, err)
+
+	_, err = client.Set(ctx, &fastdbgrpc.SetRequest{Bucket: "user", Key: 1, Value: []byte("first")})
+	require.NoError(t, err)
+
+	_, err = client.Set(ctx, &fastdbgrpc.SetRequest{Bucket: "user", Key: -1, Value: []byte("wrong")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	getResp, err := client.Get(ctx, &fastdbgrpc.GetRequest{Bucket: "user", Key: 1})
+	require.NoError(t, err)
+	assert.True(t, getResp.Found)
+	assert.Equal(t, []byte("first"), getResp.Value)
+
+	getResp, err = client.Get(ctx, &fastdbgrpc.GetRequest{Bucket: "user", Key: 3})
+	require.NoError(t, err)
+	assert.False(t, getResp.Found)
+
+	allResp, err := client.GetAll(ctx, &fastdbgrpc.GetAllRequest{Bucket: "user"})
+	require.NoError(t, err)
+	require.Len(t, allResp.Records, 2)
+	assert.Equal(t, int64(1), allResp.Records[0].Key)
+	assert.Equal(t, []byte("second"), allResp.Records[1].Value)
+
+	_, err = client.GetAll(ctx, &fastdbgrpc.GetAllRequest{Bucket: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	delResp, err := client.Del(ctx, &fastdbgrpc.DelRequest{Bucket: "user", Key: 2})
+	require.NoError(t, err)
+	assert.True(t, delResp.Deleted)
+
+	infoResp, err := client.Info(ctx, &fastdbgrpc.InfoRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "1 record(s) in 1 bucket(s)", infoResp.Info)
+
+	_, err = client.Defrag(ctx, &fastdbgrpc.DefragRequest{})
+	require.NoError(t, err)
+
+	_, err = client.Verify(ctx, &fastdbgrpc.VerifyRequest{})
+	require.NoError(t, err)
+
+	statsResp, err := client.Stats(ctx, &fastdbgrpc.StatsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), statsResp.Records)
+	assert.Equal(t, int64(1), statsResp.Buckets)
+	assert.Equal(t, -1.0, statsResp.DaysUntilFull)
+
+	backupResp, err := client.Backup(ctx, &fastdbgrpc.BackupRequest{})
+	require.NoError(t, err)
+
+	keys, err := persist.ReadSnapshot(bytes.NewReader(backupResp.Snapshot))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("first"), keys["user"][1])
+
+	store.OnAuthorize(func(_ context.Context, operation fastdb.Operation, bucket string, _ int) error {
+		if bucket == "secret" || operation == fastdb.OpAdmin {
+			return errors.New("not allowed")
+		}
+
+		return nil
+	})
+
+	_, err = client.Get(ctx, &fastdbgrpc.GetRequest{Bucket: "secret", Key: 1})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.Defrag(ctx, &fastdbgrpc.DefragRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.Backup(ctx, &fastdbgrpc.BackupRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.Get(ctx, &fastdbgrpc.GetRequest{Bucket: "user", Key: 1})
+	require.NoError(t, err)
+}
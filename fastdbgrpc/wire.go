@@ -0,0 +1,140 @@
+package fastdbgrpc
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// skipField is returned by a field decoder for fields it doesn't know, so they are skipped.
+const skipField = -1 << 31
+
+// fieldDecoder decodes the value of one field, and returns its size (negative on an error).
+type fieldDecoder func(num protowire.Number, typ protowire.Type, data []byte) int
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+decodeFields decodes all the fields of a message with the field decoder.
+Unknown fields are skipped, like protobuf does.
+*/
+func decodeFields(data []byte, decode fieldDecoder) error {
+	for len(data) > 0 {
+		num, typ, size := protowire.ConsumeTag(data)
+		if size < 0 {
+			return fmt.Errorf("decode error: %w", protowire.ParseError(size))
+		}
+
+		data = data[size:]
+
+		size = decode(num, typ, data)
+		if size == skipField {
+			size = protowire.ConsumeFieldValue(num, typ, data)
+		}
+
+		if size < 0 {
+			return fmt.Errorf("decode (field %d) error: %w", num, protowire.ParseError(size))
+		}
+
+		data = data[size:]
+	}
+
+	return nil
+}
+
+/*
+skipAll is the field decoder of messages without fields.
+*/
+func skipAll(protowire.Number, protowire.Type, []byte) int {
+	return skipField
+}
+
+func appendString(buf []byte, num protowire.Number, value string) []byte {
+	if value == "" {
+		return buf
+	}
+
+	buf = protowire.AppendTag(buf, num, protowire.BytesType)
+
+	return protowire.AppendString(buf, value)
+}
+
+func appendBytes(buf []byte, num protowire.Number, value []byte) []byte {
+	if len(value) == 0 {
+		return buf
+	}
+
+	buf = protowire.AppendTag(buf, num, protowire.BytesType)
+
+	return protowire.AppendBytes(buf, value)
+}
+
+func appendInt(buf []byte, num protowire.Number, value int64) []byte {
+	if value == 0 {
+		return buf
+	}
+
+	buf = protowire.AppendTag(buf, num, protowire.VarintType)
+
+	return protowire.AppendVarint(buf, uint64(value)) //nolint:gosec // int64 is encoded as two's complement
+}
+
+func appendBool(buf []byte, num protowire.Number, value bool) []byte {
+	if !value {
+		return buf
+	}
+
+	buf = protowire.AppendTag(buf, num, protowire.VarintType)
+
+	return protowire.AppendVarint(buf, 1)
+}
+
+func appendDouble(buf []byte, num protowire.Number, value float64) []byte {
+	if value == 0 {
+		return buf
+	}
+
+	buf = protowire.AppendTag(buf, num, protowire.Fixed64Type)
+
+	return protowire.AppendFixed64(buf, math.Float64bits(value))
+}
+
+func consumeString(data []byte, value *string) int {
+	str, size := protowire.ConsumeString(data)
+	*value = str
+
+	return size
+}
+
+func consumeBytes(data []byte, value *[]byte) int {
+	bytes, size := protowire.ConsumeBytes(data)
+	*value = append([]byte(nil), bytes...)
+
+	return size
+}
+
+func consumeInt(data []byte, value *int64) int {
+	varint, size := protowire.ConsumeVarint(data)
+	*value = int64(varint) //nolint:gosec // int64 is encoded as two's complement
+
+	return size
+}
+
+func consumeBool(data []byte, value *bool) int {
+	varint, size := protowire.ConsumeVarint(data)
+	*value = varint != 0
+
+	return size
+}
+
+func consumeDouble(data []byte, value *float64) int {
+	fixed, size := protowire.ConsumeFixed64(data)
+	*value = math.Float64frombits(fixed)
+
+	return size
+}
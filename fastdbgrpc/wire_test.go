@@ -0,0 +1,41 @@
+package fastdbgrpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the encoding must be the one of protobuf, so other languages can use the service.
+func Test_wireFormat(t *testing.T) {
+	req := &SetRequest{Bucket: "a", Key: 1, Value: []byte("b")}
+	data := req.marshal()
+	assert.Equal(t, []byte{0x0a, 0x01, 'a', 0x10, 0x01, 0x1a, 0x01, 'b'}, data)
+
+	// unknown fields are skipped
+	decoded := &SetRequest{}
+	err := decoded.unmarshal(append([]byte{0x20, 0x05}, data...))
+	require.NoError(t, err)
+	assert.Equal(t, req, decoded)
+
+	err = decoded.unmarshal([]byte{0x0a, 0x05, 'a'})
+	require.Error(t, err)
+
+	resp := &GetAllResponse{Records: []*Record{{Key: 1, Value: []byte("x")}, {Key: 2}}}
+	assert.Equal(t, []byte{0x0a, 0x05, 0x08, 0x01, 0x12, 0x01, 'x', 0x0a, 0x02, 0x08, 0x02}, resp.marshal())
+
+	decodedResp := &GetAllResponse{}
+	err = decodedResp.unmarshal(resp.marshal())
+	require.NoError(t, err)
+	assert.Equal(t, resp, decodedResp)
+
+	// a double is a fixed64 (field 7, wire type 1)
+	stats := &StatsResponse{Records: 2, DaysUntilFull: -1}
+	assert.Equal(t, []byte{0x08, 0x02, 0x39, 0, 0, 0, 0, 0, 0, 0xf0, 0xbf}, stats.marshal())
+
+	decodedStats := &StatsResponse{}
+	err = decodedStats.unmarshal(stats.marshal())
+	require.NoError(t, err)
+	assert.Equal(t, stats, decodedStats)
+}
@@ -0,0 +1,449 @@
+/*
+Package fastdbhttp exposes a fastdb database as a small HTTP/JSON REST API,
+which can be mounted in an existing http.ServeMux, for debugging and light remote access:
+
+	mux.Handle("/db/", http.StripPrefix("/db", fastdbhttp.NewHandler(store)))
+
+The routes are:
+
+	GET    /buckets/{bucket}             all records of a bucket, in Key sorted order
+	GET    /buckets/{bucket}/keys/{key}  the value of one record
+	PUT    /buckets/{bucket}/keys/{key}  stores the request body as the value of a record
+	DELETE /buckets/{bucket}/keys/{key}  deletes a record
+	GET    /buckets/{bucket}/description the description of a bucket
+	PUT    /buckets/{bucket}/description stores the request body as the description of a bucket
+
+Only WithAdmin mounts the admin routes:
+
+	POST   /admin/defrag                 optimises the file (see fastdb.DB.Defrag)
+	GET    /admin/backup                 a snapshot of the database (see fastdb.DB.Snapshot)
+	POST   /admin/verify                 checks every entry of the file (see fastdb.DB.Verify)
+	GET    /admin/stats                  the numbers about the storage, as JSON (see fastdb.DB.Stats)
+
+Every request is checked with the authorization hooks of the database (see fastdb.DB.OnAuthorize),
+with the context of the request. A denied request gets the status 403 Forbidden.
+The admin routes are checked as fastdb.OpAdmin, with an empty bucket.
+
+To only accept known clients, the handler can require a static token (WithToken) and/or a client
+certificate (WithClientCert), on a server with the TLS configuration of fastdb.ServerTLSConfig:
+
+	config, err := fastdb.ServerTLSConfig("cert.pem", "key.pem", "clients.pem")
+	server := &http.Server{Addr: ":8443", Handler: handler, TLSConfig: config}
+	err = server.ListenAndServeTLS("", "")
+*/
+package fastdbhttp
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/marcelloh/fastdb"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// MaxBodySize is the maximum size (in bytes) of a value that can be stored with PUT.
+const MaxBodySize = 32 << 20
+
+// record is one record in the response of a bucket.
+type record struct {
+	Value any `json:"value"`
+	Key   int `json:"key"`
+}
+
+// Option configures the handler.
+type Option func(*handler)
+
+// countingWriter counts the bytes that are written, to know if the response has started.
+type countingWriter struct {
+	writer  io.Writer
+	written int64
+}
+
+// handler serves the routes of the database.
+type handler struct {
+	store      *fastdb.DB
+	token      string
+	clientCert bool
+	admin      bool
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+NewHandler returns the handler with the REST API of the database.
+*/
+func NewHandler(store *fastdb.DB, options ...Option) http.Handler {
+	hdl := &handler{store: store}
+
+	for _, option := range options {
+		option(hdl)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /buckets/{bucket}", hdl.getBucket)
+	mux.HandleFunc("GET /buckets/{bucket}/description", hdl.getDescription)
+	mux.HandleFunc("PUT /buckets/{bucket}/description", hdl.setDescription)
+	mux.HandleFunc("GET /buckets/{bucket}/keys/{key}", hdl.get)
+	mux.HandleFunc("PUT /buckets/{bucket}/keys/{key}", hdl.set)
+	mux.HandleFunc("DELETE /buckets/{bucket}/keys/{key}", hdl.del)
+
+	if hdl.admin {
+		mux.HandleFunc("POST /admin/defrag", hdl.defrag)
+		mux.HandleFunc("GET /admin/backup", hdl.backup)
+		mux.HandleFunc("POST /admin/verify", hdl.verify)
+		mux.HandleFunc("GET /admin/stats", hdl.stats)
+	}
+
+	if hdl.token == "" && !hdl.clientCert {
+		return mux
+	}
+
+	return hdl.authenticate(mux)
+}
+
+/*
+WithAdmin mounts the admin routes (defrag, backup, verify and stats), which aren't there by default.
+Protect them with WithToken and/or WithClientCert, and with an authorization hook for fastdb.OpAdmin.
+*/
+func WithAdmin() Option {
+	return func(hdl *handler) {
+		hdl.admin = true
+	}
+}
+
+/*
+WithToken requires every request to have the header "Authorization: Bearer <token>".
+*/
+func WithToken(token string) Option {
+	return func(hdl *handler) {
+		hdl.token = token
+	}
+}
+
+/*
+WithClientCert requires every request to come over TLS, with a client certificate that was verified.
+*/
+func WithClientCert() Option {
+	return func(hdl *handler) {
+		hdl.clientCert = true
+	}
+}
+
+/*
+authenticate returns a handler that only passes the requests with the token and/or client certificate.
+Other requests get the status 401 Unauthorized.
+*/
+func (hdl *handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if hdl.clientCert && (request.TLS == nil || len(request.TLS.VerifiedChains) == 0) {
+			http.Error(writer, "client certificate required", http.StatusUnauthorized)
+
+			return
+		}
+
+		if hdl.token != "" {
+			token, found := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+			if !found || subtle.ConstantTimeCompare([]byte(token), []byte(hdl.token)) != 1 {
+				writer.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(writer, "invalid token", http.StatusUnauthorized)
+
+				return
+			}
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+/*
+getBucket writes all the records of a bucket as a JSON array, in Key sorted order.
+Values that are valid JSON are embedded as they are, all others as a string.
+*/
+func (hdl *handler) getBucket(writer http.ResponseWriter, request *http.Request) {
+	if !hdl.authorize(writer, request, fastdb.OpRead, fastdb.AnyKey) {
+		return
+	}
+
+	sortedRecords, err := hdl.store.GetAllSorted(request.PathValue("bucket"))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusNotFound)
+
+		return
+	}
+
+	records := make([]record, len(sortedRecords))
+
+	for i, sortRecord := range sortedRecords {
+		records[i] = record{Key: sortRecord.SortField.(int), Value: jsonValue(sortRecord.Data)} //nolint:forcetypeassert // always an int
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(writer).Encode(records)
+}
+
+/*
+getDescription writes the description of a bucket (as text), see SetDescription.
+*/
+func (hdl *handler) getDescription(writer http.ResponseWriter, request *http.Request) {
+	if !hdl.authorize(writer, request, fastdb.OpRead, fastdb.AnyKey) {
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	_, _ = io.WriteString(writer, hdl.store.Description(request.PathValue("bucket")))
+}
+
+/*
+setDescription stores the request body as the description of a bucket, an empty body removes it.
+*/
+func (hdl *handler) setDescription(writer http.ResponseWriter, request *http.Request) {
+	if !hdl.authorize(writer, request, fastdb.OpWrite, fastdb.AnyKey) {
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, MaxBodySize))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusRequestEntityTooLarge)
+
+		return
+	}
+
+	err = hdl.store.SetDescription(request.PathValue("bucket"), string(data))
+	if err != nil {
+		http.Error(writer, err.Error(), errorStatus(err))
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+/*
+get writes the value of one record.
+*/
+func (hdl *handler) get(writer http.ResponseWriter, request *http.Request) {
+	key, ok := parseKey(writer, request)
+	if !ok || !hdl.authorize(writer, request, fastdb.OpRead, key) {
+		return
+	}
+
+	data, found := hdl.store.Get(request.PathValue("bucket"), key)
+	if !found {
+		http.Error(writer, "key not found", http.StatusNotFound)
+
+		return
+	}
+
+	contentType := "application/octet-stream"
+	if json.Valid(data) {
+		contentType = "application/json"
+	}
+
+	writer.Header().Set("Content-Type", contentType)
+
+	_, _ = writer.Write(data)
+}
+
+/*
+set stores the request body as the value of one record.
+*/
+func (hdl *handler) set(writer http.ResponseWriter, request *http.Request) {
+	key, ok := parseKey(writer, request)
+	if !ok || !hdl.authorize(writer, request, fastdb.OpWrite, key) {
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, MaxBodySize))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusRequestEntityTooLarge)
+
+		return
+	}
+
+	err = hdl.store.Set(request.PathValue("bucket"), key, data)
+	if err != nil {
+		http.Error(writer, err.Error(), errorStatus(err))
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+/*
+del deletes one record.
+*/
+func (hdl *handler) del(writer http.ResponseWriter, request *http.Request) {
+	key, ok := parseKey(writer, request)
+	if !ok || !hdl.authorize(writer, request, fastdb.OpDelete, key) {
+		return
+	}
+
+	deleted, err := hdl.store.Del(request.PathValue("bucket"), key)
+	if err != nil {
+		http.Error(writer, err.Error(), errorStatus(err))
+
+		return
+	}
+
+	if !deleted {
+		http.Error(writer, "key not found", http.StatusNotFound)
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+/*
+defrag optimises the file to reflect the latest state.
+*/
+func (hdl *handler) defrag(writer http.ResponseWriter, request *http.Request) {
+	if !hdl.authorize(writer, request, fastdb.OpAdmin, fastdb.AnyKey) {
+		return
+	}
+
+	err := hdl.store.Defrag()
+	if err != nil {
+		http.Error(writer, err.Error(), errorStatus(err))
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+/*
+backup writes a snapshot of the database, which can be restored with fastdb.OpenFromBackup.
+The snapshot is written into a buffer first, so a failure can still be reported with its status.
+*/
+func (hdl *handler) backup(writer http.ResponseWriter, request *http.Request) {
+	if !hdl.authorize(writer, request, fastdb.OpAdmin, fastdb.AnyKey) {
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/octet-stream")
+	writer.Header().Set("Content-Disposition", `attachment; filename="fastdb.snapshot"`)
+
+	body := &countingWriter{writer: writer}
+
+	err := hdl.store.Snapshot(body)
+	if err == nil {
+		return
+	}
+
+	if body.written == 0 {
+		writer.Header().Del("Content-Disposition")
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	// the status is already sent, so the client has to notice the broken connection
+	panic(http.ErrAbortHandler)
+}
+
+/*
+Write writes to the underlying writer and counts the bytes.
+*/
+func (counter *countingWriter) Write(data []byte) (int, error) {
+	written, err := counter.writer.Write(data)
+	counter.written += int64(written)
+
+	return written, err //nolint:wrapcheck // it is wrapped by the caller
+}
+
+/*
+verify checks that every entry of the file can still be read.
+A corruption gets the status 500 Internal Server Error, with the corruption as the message.
+*/
+func (hdl *handler) verify(writer http.ResponseWriter, request *http.Request) {
+	if !hdl.authorize(writer, request, fastdb.OpAdmin, fastdb.AnyKey) {
+		return
+	}
+
+	err := hdl.store.Verify()
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+/*
+stats writes the numbers about the storage as JSON.
+*/
+func (hdl *handler) stats(writer http.ResponseWriter, request *http.Request) {
+	if !hdl.authorize(writer, request, fastdb.OpAdmin, fastdb.AnyKey) {
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(writer).Encode(hdl.store.Stats())
+}
+
+/*
+authorize checks if the operation on the bucket of the path is allowed. If it isn't, the error is written.
+*/
+func (hdl *handler) authorize(writer http.ResponseWriter, request *http.Request, operation fastdb.Operation, key int) bool {
+	err := hdl.store.Authorize(request.Context(), operation, request.PathValue("bucket"), key)
+	if err != nil {
+		http.Error(writer, err.Error(), errorStatus(err))
+
+		return false
+	}
+
+	return true
+}
+
+/*
+parseKey returns the key of the path. If it isn't a number, the error is written.
+*/
+func parseKey(writer http.ResponseWriter, request *http.Request) (int, bool) {
+	key, err := strconv.Atoi(request.PathValue("key"))
+	if err != nil || key < 0 {
+		http.Error(writer, "key should be a positive number", http.StatusBadRequest)
+
+		return 0, false
+	}
+
+	return key, true
+}
+
+/*
+errorStatus returns the HTTP status for an error of the database.
+*/
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, fastdb.ErrReadOnly), errors.Is(err, fastdb.ErrDenied):
+		return http.StatusForbidden
+	case errors.Is(err, fastdb.ErrFrozen):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+/*
+jsonValue returns the value as raw JSON if it's valid JSON, and otherwise as a string.
+*/
+func jsonValue(data []byte) any {
+	if json.Valid(data) {
+		return json.RawMessage(data)
+	}
+
+	return string(data)
+}
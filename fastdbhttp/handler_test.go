@@ -0,0 +1,225 @@
+package fastdbhttp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/marcelloh/fastdb/fastdbhttp"
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Handler(t *testing.T) {
+	store, err := fastdb.Open(":memory:")
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/db/", http.StripPrefix("/db", fastdbhttp.NewHandler(store)))
+
+	call := func(method, path, body string) (int, string, string) {
+		t.Helper()
+
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, request)
+
+		data, err := io.ReadAll(recorder.Body)
+		require.NoError(t, err)
+
+		return recorder.Code, recorder.Header().Get("Content-Type"), string(data)
+	}
+
+	code, _, _ := call(http.MethodPut, "/db/buckets/user/keys/2", `{"name":"John"}`)
+	assert.Equal(t, http.StatusNoContent, code)
+
+	code, _, _ = call(http.MethodPut, "/db/buckets/user/keys/1", "plain text")
+	assert.Equal(t, http.StatusNoContent, code)
+
+	code, contentType, body := call(http.MethodGet, "/db/buckets/user/keys/2", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "application/json", contentType)
+	assert.JSONEq(t, `{"name":"John"}`, body)
+
+	code, contentType, body = call(http.MethodGet, "/db/buckets/user/keys/1", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "application/octet-stream", contentType)
+	assert.Equal(t, "plain text", body)
+
+	code, _, body = call(http.MethodGet, "/db/buckets/user", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `[{"key":1,"value":"plain text"},{"key":2,"value":{"name":"John"}}]`, body)
+
+	code, _, _ = call(http.MethodGet, "/db/buckets/user/keys/abc", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _, _ = call(http.MethodDelete, "/db/buckets/user/keys/1", "")
+	assert.Equal(t, http.StatusNoContent, code)
+
+	code, _, _ = call(http.MethodDelete, "/db/buckets/user/keys/1", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _, _ = call(http.MethodGet, "/db/buckets/user/keys/1", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _, _ = call(http.MethodGet, "/db/buckets/unknown", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _, _ = call(http.MethodPost, "/db/buckets/user/keys/1", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+	code, _, _ = call(http.MethodPut, "/db/buckets/user/description", "the registered users")
+	assert.Equal(t, http.StatusNoContent, code)
+
+	code, _, body = call(http.MethodGet, "/db/buckets/user/description", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "the registered users", body)
+
+	// only reads are allowed on the secret bucket
+	store.OnAuthorize(func(_ context.Context, operation fastdb.Operation, bucket string, _ int) error {
+		if bucket == "secret" && operation != fastdb.OpRead {
+			return errors.New("read only bucket")
+		}
+
+		return nil
+	})
+
+	code, _, _ = call(http.MethodPut, "/db/buckets/secret/keys/1", "text")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _, _ = call(http.MethodDelete, "/db/buckets/secret/keys/1", "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _, _ = call(http.MethodPut, "/db/buckets/secret/description", "text")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _, _ = call(http.MethodGet, "/db/buckets/secret/keys/1", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _, _ = call(http.MethodPut, "/db/buckets/user/keys/1", "text")
+	assert.Equal(t, http.StatusNoContent, code)
+}
+
+func Test_HandlerAuthentication(t *testing.T) {
+	store, err := fastdb.Open(":memory:")
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	call := func(handler http.Handler, authorization string) int {
+		t.Helper()
+
+		request := httptest.NewRequest(http.MethodPut, "/buckets/user/keys/1", strings.NewReader("text"))
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder.Code
+	}
+
+	handler := fastdbhttp.NewHandler(store, fastdbhttp.WithToken("secret"))
+	assert.Equal(t, http.StatusUnauthorized, call(handler, ""))
+	assert.Equal(t, http.StatusUnauthorized, call(handler, "Bearer wrong"))
+	assert.Equal(t, http.StatusUnauthorized, call(handler, "secret"))
+	assert.Equal(t, http.StatusNoContent, call(handler, "Bearer secret"))
+
+	// the test requests don't use TLS
+	handler = fastdbhttp.NewHandler(store, fastdbhttp.WithClientCert())
+	assert.Equal(t, http.StatusUnauthorized, call(handler, ""))
+}
+
+func Test_HandlerAdmin(t *testing.T) {
+	store, err := fastdb.Open(":memory:")
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	handler := fastdbhttp.NewHandler(store)
+
+	call := func(method, path string) (int, []byte) {
+		t.Helper()
+
+		request := httptest.NewRequest(method, path, nil)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder.Code, recorder.Body.Bytes()
+	}
+
+	err = store.Set("user", 1, []byte("John"))
+	require.NoError(t, err)
+
+	// without WithAdmin, the admin routes aren't there
+	code, _ := call(http.MethodPost, "/admin/defrag")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = call(http.MethodGet, "/admin/backup")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	handler = fastdbhttp.NewHandler(store, fastdbhttp.WithAdmin())
+
+	code, _ = call(http.MethodPost, "/admin/defrag")
+	assert.Equal(t, http.StatusNoContent, code)
+
+	code, _ = call(http.MethodPost, "/admin/verify")
+	assert.Equal(t, http.StatusNoContent, code)
+
+	code, body := call(http.MethodGet, "/admin/stats")
+	assert.Equal(t, http.StatusOK, code)
+
+	var stats fastdb.Stats
+
+	err = json.Unmarshal(body, &stats)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Records)
+
+	code, body = call(http.MethodGet, "/admin/backup")
+	assert.Equal(t, http.StatusOK, code)
+
+	keys, err := persist.ReadSnapshot(bytes.NewReader(body))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("John"), keys["user"][1])
+
+	code, _ = call(http.MethodGet, "/admin/defrag")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+	store.OnAuthorize(func(_ context.Context, operation fastdb.Operation, _ string, _ int) error {
+		if operation == fastdb.OpAdmin {
+			return errors.New("no admin")
+		}
+
+		return nil
+	})
+
+	for _, path := range []string{"/admin/defrag", "/admin/verify"} {
+		code, _ = call(http.MethodPost, path)
+		assert.Equal(t, http.StatusForbidden, code)
+	}
+
+	for _, path := range []string{"/admin/backup", "/admin/stats"} {
+		code, _ = call(http.MethodGet, path)
+		assert.Equal(t, http.StatusForbidden, code)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Package fastdbmetrics exposes the measurements of a fastdb database (see fastdb.Observer) as Prometheus metrics:
+  - the gets (hits and misses), sets and deletes per bucket, and the sizes of the stored values
+  - the bytes written to the files, and the time the syncs take
+  - the time the Defrags take
+
+The metrics are collected with a prometheus.Collector, and fed by the observer of the database:
+
+	metrics := fastdbmetrics.New("fastdb")
+	store, err := fastdb.Open(path, fastdb.WithObserver(metrics.Observer()))
+	prometheus.MustRegister(metrics)
+
+It is a separate module, so the core has no Prometheus dependency.
+*/
+package fastdbmetrics
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// Metrics holds the Prometheus metrics of a database, it is a prometheus.Collector.
+type Metrics struct {
+	gets          *prometheus.CounterVec
+	sets          *prometheus.CounterVec
+	dels          *prometheus.CounterVec
+	valueBytes    prometheus.Histogram
+	writtenBytes  prometheus.Counter
+	syncSeconds   prometheus.Histogram
+	defragSeconds prometheus.Histogram
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+New returns the metrics, with names that start with the namespace (like "fastdb_gets_total").
+*/
+func New(namespace string) *Metrics {
+	return &Metrics{
+		gets: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "gets_total", Help: "The number of Gets, by bucket and result (hit or miss).",
+		}, []string{"bucket", "result"}),
+		sets: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "sets_total", Help: "The number of values that were stored, by bucket.",
+		}, []string{"bucket"}),
+		dels: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "dels_total", Help: "The number of records that were deleted, by bucket.",
+		}, []string{"bucket"}),
+		valueBytes: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "value_bytes", Help: "The sizes of the values that were stored.",
+			Buckets: prometheus.ExponentialBuckets(16, 4, 8), // 16 bytes to 256 KB
+		}),
+		writtenBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "written_bytes_total", Help: "The number of bytes written to the files.",
+		}),
+		syncSeconds: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "sync_duration_seconds", Help: "The time the syncs of the files take.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 9), // 100µs to 6.5s
+		}),
+		defragSeconds: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "defrag_duration_seconds", Help: "The time the Defrags take.",
+			Buckets: prometheus.DefBuckets,
+		}),
+	}
+}
+
+/*
+Observer returns the observer that feeds the metrics, to open the database with (see fastdb.WithObserver).
+*/
+func (mtr *Metrics) Observer() fastdb.Observer {
+	return fastdb.Observer{
+		Get: func(bucket string, found bool) {
+			result := "miss"
+			if found {
+				result = "hit"
+			}
+
+			mtr.gets.WithLabelValues(bucket, result).Inc()
+		},
+		Set: func(bucket string, size int) {
+			mtr.sets.WithLabelValues(bucket).Inc()
+			mtr.valueBytes.Observe(float64(size))
+		},
+		Del: func(bucket string) {
+			mtr.dels.WithLabelValues(bucket).Inc()
+		},
+		Written: func(bytes int) {
+			mtr.writtenBytes.Add(float64(bytes))
+		},
+		Synced: func(duration time.Duration) {
+			mtr.syncSeconds.Observe(duration.Seconds())
+		},
+		Defragged: func(duration time.Duration) {
+			mtr.defragSeconds.Observe(duration.Seconds())
+		},
+	}
+}
+
+/*
+Describe sends the descriptions of all the metrics (for prometheus.Collector).
+*/
+func (mtr *Metrics) Describe(descs chan<- *prometheus.Desc) {
+	for _, collector := range mtr.collectors() {
+		collector.Describe(descs)
+	}
+}
+
+/*
+Collect sends the current values of all the metrics (for prometheus.Collector).
+*/
+func (mtr *Metrics) Collect(metrics chan<- prometheus.Metric) {
+	for _, collector := range mtr.collectors() {
+		collector.Collect(metrics)
+	}
+}
+
+/*
+collectors returns all the metrics.
+*/
+func (mtr *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		mtr.gets, mtr.sets, mtr.dels, mtr.valueBytes, mtr.writtenBytes, mtr.syncSeconds, mtr.defragSeconds,
+	}
+}
@@ -0,0 +1,74 @@
+package fastdbmetrics_test
+
+import (
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/marcelloh/fastdb/fastdbmetrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Metrics(t *testing.T) {
+	metrics := fastdbmetrics.New("fastdb")
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(metrics))
+
+	store, err := fastdb.Open(t.TempDir()+"/fastdb_metrics.db", fastdb.WithSyncTime(0),
+		fastdb.WithObserver(metrics.Observer()))
+	require.NoError(t, err)
+
+	err = store.Set("user", 1, []byte("value"))
+	require.NoError(t, err)
+	err = store.Set("user", 2, []byte("value"))
+	require.NoError(t, err)
+
+	_, _ = store.Get("user", 1)
+	_, _ = store.Get("user", 3)
+
+	_, err = store.Del("user", 2)
+	require.NoError(t, err)
+
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	values := map[string]float64{}
+
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			switch {
+			case metric.GetCounter() != nil:
+				name := family.GetName()
+				for _, label := range metric.GetLabel() {
+					name += "/" + label.GetValue()
+				}
+
+				values[name] = metric.GetCounter().GetValue()
+			case metric.GetHistogram() != nil:
+				values[family.GetName()] = float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	assert.InDelta(t, 2, values["fastdb_sets_total/user"], 0)
+	assert.InDelta(t, 1, values["fastdb_gets_total/user/hit"], 0)
+	assert.InDelta(t, 1, values["fastdb_gets_total/user/miss"], 0)
+	assert.InDelta(t, 1, values["fastdb_dels_total/user"], 0)
+	assert.InDelta(t, 2, values["fastdb_value_bytes"], 0)
+	assert.InDelta(t, 2*len("set\nuser_1\nvalue\n")+len("del\nuser_2\n"), values["fastdb_written_bytes_total"], 0)
+	assert.GreaterOrEqual(t, values["fastdb_sync_duration_seconds"], 3.0)
+	assert.InDelta(t, 1, values["fastdb_defrag_duration_seconds"], 0)
+
+	problems, err := testutil.GatherAndLint(registry)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
@@ -0,0 +1,63 @@
+/*
+Package fastdbotel makes OpenTelemetry spans around the storage operations of a fastdb database:
+Set, Get, Del and Defrag (with the bucket and key as attributes), and every write and sync of the files,
+so tail latency (like a slow fsync) shows up in the traces:
+
+	store, err := fastdb.Open(path, fastdbotel.WithTracerProvider(provider))
+
+The operations of fastdb have no context, so the spans have no parent.
+It is a separate module, so the core has no OpenTelemetry dependency.
+*/
+package fastdbotel
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"context"
+
+	"github.com/marcelloh/fastdb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// instrumentationName is the name of the tracer.
+const instrumentationName = "github.com/marcelloh/fastdb/fastdbotel"
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithTracerProvider is the option that makes the database emit spans with a tracer of the provider.
+*/
+func WithTracerProvider(provider trace.TracerProvider) fastdb.Option {
+	return fastdb.WithTracing(Trace(provider))
+}
+
+/*
+Trace returns the trace function (see fastdb.WithTracing) that makes a span per operation,
+named "fastdb." + the operation. A failed operation records its error on the span.
+*/
+func Trace(provider trace.TracerProvider) fastdb.TraceFunc {
+	tracer := provider.Tracer(instrumentationName)
+
+	return func(operation, bucket string, key int) func(err error) {
+		attributes := []attribute.KeyValue{attribute.String("db.system", "fastdb")}
+		if bucket != "" {
+			attributes = append(attributes, attribute.String("fastdb.bucket", bucket), attribute.Int("fastdb.key", key))
+		}
+
+		_, span := tracer.Start(context.Background(), "fastdb."+operation,
+			trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(attributes...))
+
+		return func(err error) {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+
+			span.End()
+		}
+	}
+}
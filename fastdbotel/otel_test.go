@@ -0,0 +1,48 @@
+package fastdbotel_test
+
+import (
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/marcelloh/fastdb/fastdbotel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_WithTracerProvider(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	store, err := fastdb.Open(t.TempDir()+"/fastdb_otel.db", fastdb.WithSyncTime(0),
+		fastdb.WithMaxValueSize(10), fastdbotel.WithTracerProvider(provider))
+	require.NoError(t, err)
+
+	err = store.Set("user", 1, []byte("value"))
+	require.NoError(t, err)
+
+	_, _ = store.Get("user", 1)
+
+	err = store.Set("user", 2, []byte("a value that is too big"))
+	require.Error(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+
+	assert.Equal(t, []string{"fastdb.write", "fastdb.set", "fastdb.get", "fastdb.set"}, names)
+
+	assert.Contains(t, spans[1].Attributes(), attribute.String("fastdb.bucket", "user"))
+	assert.Contains(t, spans[1].Attributes(), attribute.Int("fastdb.key", 1))
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Equal(t, codes.Error, spans[3].Status().Code)
+}
@@ -0,0 +1,172 @@
+/*
+Package fastdbparquet exports the records of a fastdb bucket as a Parquet file,
+so the data can be analysed with tools like DuckDB or Spark without intermediate scripts.
+The JSON values are flattened into columns, with a schema that is given or inferred (see InferSchema).
+It is a separate module, so the core has no Parquet dependency.
+*/
+package fastdbparquet
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/parquet-go/parquet-go"
+	"github.com/tidwall/gjson"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// keyColumn is the name of the first column, which holds the key of the record.
+const keyColumn = "key"
+
+// rowBatch is the number of rows that are written at once.
+const rowBatch = 1000
+
+var errColumnName = errors.New("column name is used twice (or is the key column)")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Export writes the records of a bucket as a Parquet file, in key order.
+It has the column "key", and a column per field of the schema (nil infers it, see InferSchema).
+A value that doesn't exist at the path, or that doesn't fit the type of its column, is null.
+*/
+func Export(store *fastdb.DB, bucket string, writer io.Writer, schema Schema) error {
+	var err error
+
+	if schema == nil {
+		schema, err = InferSchema(store, bucket)
+		if err != nil {
+			return fmt.Errorf("export error: %w", err)
+		}
+	}
+
+	parquetSchema, err := newParquetSchema(bucket, schema)
+	if err != nil {
+		return fmt.Errorf("export error: %w", err)
+	}
+
+	records, err := store.GetAllSorted(bucket)
+	if err != nil {
+		return fmt.Errorf("export error: %w", err)
+	}
+
+	columns := make([]int, len(schema))
+	for i, field := range schema {
+		leaf, _ := parquetSchema.Lookup(field.Name)
+		columns[i] = leaf.ColumnIndex
+	}
+
+	keyLeaf, _ := parquetSchema.Lookup(keyColumn)
+
+	parquetWriter := parquet.NewWriter(writer, parquetSchema)
+	rows := make([]parquet.Row, 0, rowBatch)
+
+	for _, record := range records {
+		key := int64(record.SortField.(int)) //nolint:forcetypeassert // always an int
+		row := make(parquet.Row, len(schema)+1)
+		row[keyLeaf.ColumnIndex] = parquet.Int64Value(key).Level(0, 0, keyLeaf.ColumnIndex)
+
+		for i, field := range schema {
+			value := fieldValue(record.Data, field)
+
+			definition := 1
+			if value.IsNull() {
+				definition = 0
+			}
+
+			row[columns[i]] = value.Level(0, definition, columns[i])
+		}
+
+		rows = append(rows, row)
+
+		if len(rows) == rowBatch {
+			_, err = parquetWriter.WriteRows(rows)
+			if err != nil {
+				return fmt.Errorf("export error: %w", err)
+			}
+
+			rows = rows[:0]
+		}
+	}
+
+	_, err = parquetWriter.WriteRows(rows)
+	if err == nil {
+		err = parquetWriter.Close()
+	}
+
+	if err != nil {
+		return fmt.Errorf("export error: %w", err)
+	}
+
+	return nil
+}
+
+/*
+newParquetSchema returns the Parquet schema with the key column and an optional column per field.
+*/
+func newParquetSchema(bucket string, schema Schema) (*parquet.Schema, error) {
+	group := parquet.Group{keyColumn: parquet.Int(64)}
+
+	for _, field := range schema {
+		if _, found := group[field.Name]; found {
+			return nil, fmt.Errorf("%w: %s", errColumnName, field.Name)
+		}
+
+		group[field.Name] = parquet.Optional(columnNode(field.Type))
+	}
+
+	return parquet.NewSchema(bucket, group), nil
+}
+
+/*
+columnNode returns the Parquet node for a type.
+*/
+func columnNode(typ Type) parquet.Node {
+	switch typ {
+	case Int64:
+		return parquet.Int(64)
+	case Float64:
+		return parquet.Leaf(parquet.DoubleType)
+	case Bool:
+		return parquet.Leaf(parquet.BooleanType)
+	default:
+		return parquet.String()
+	}
+}
+
+/*
+fieldValue returns the value of a field in the data, a null value if it doesn't exist or doesn't fit the type.
+*/
+func fieldValue(data []byte, field Field) parquet.Value {
+	result := gjson.GetBytes(data, field.Path)
+
+	// the column of the values that aren't JSON objects
+	if field.Path == thisPath {
+		switch {
+		case !gjson.ValidBytes(data):
+			result = gjson.Result{Type: gjson.String, Str: string(data)}
+		case result.IsObject():
+			return parquet.NullValue()
+		}
+	}
+
+	switch {
+	case !result.Exists() || result.Type == gjson.Null:
+		return parquet.NullValue()
+	case field.Type == Int64 && result.Type == gjson.Number && typeOf(result) == Int64:
+		return parquet.Int64Value(result.Int())
+	case field.Type == Float64 && result.Type == gjson.Number:
+		return parquet.DoubleValue(result.Float())
+	case field.Type == Bool && (result.Type == gjson.True || result.Type == gjson.False):
+		return parquet.BooleanValue(result.Bool())
+	case field.Type == String || field.Type == 0:
+		return parquet.ByteArrayValue([]byte(result.String()))
+	default:
+		return parquet.NullValue()
+	}
+}
@@ -0,0 +1,148 @@
+package fastdbparquet_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/marcelloh/fastdb/fastdbparquet"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_InferSchema(t *testing.T) {
+	store := newStore(t)
+
+	schema, err := fastdbparquet.InferSchema(store, "user")
+	require.NoError(t, err)
+
+	assert.Equal(t, fastdbparquet.Schema{
+		{Name: "active", Path: "active", Type: fastdbparquet.Bool},
+		{Name: "address.city", Path: "address.city", Type: fastdbparquet.String},
+		{Name: "age", Path: "age", Type: fastdbparquet.Int64},
+		{Name: "name", Path: "name", Type: fastdbparquet.String},
+		{Name: "score", Path: "score", Type: fastdbparquet.Float64},
+		{Name: "tags", Path: "tags", Type: fastdbparquet.String},
+		{Name: "value", Path: "@this", Type: fastdbparquet.String},
+	}, schema)
+
+	_, err = fastdbparquet.InferSchema(store, "nothing")
+	require.Error(t, err)
+}
+
+func Test_Export(t *testing.T) {
+	store := newStore(t)
+
+	var buf bytes.Buffer
+
+	err := fastdbparquet.Export(store, "user", &buf, nil)
+	require.NoError(t, err)
+
+	rows := readRows(t, buf.Bytes())
+	require.Len(t, rows, 3)
+
+	assert.Equal(t, int64(1), rows[0]["key"])
+	assert.Equal(t, "John", rows[0]["name"])
+	assert.Equal(t, int64(42), rows[0]["age"])
+	assert.Equal(t, "Paris", rows[0]["address.city"])
+	assert.Equal(t, `["a","b"]`, rows[0]["tags"])
+	assert.Equal(t, true, rows[0]["active"])
+	assert.InDelta(t, 1.5, rows[0]["score"], 0.001)
+	assert.Nil(t, rows[0]["value"])
+
+	assert.Nil(t, rows[1]["age"])
+	assert.InDelta(t, 2.0, rows[1]["score"], 0.001)
+
+	assert.Equal(t, "plain text", rows[2]["value"])
+	assert.Nil(t, rows[2]["name"])
+
+	// a given schema
+	buf.Reset()
+
+	err = fastdbparquet.Export(store, "user", &buf, fastdbparquet.Schema{
+		{Name: "city", Path: "address.city", Type: fastdbparquet.String},
+		{Name: "age", Path: "age", Type: fastdbparquet.Float64},
+	})
+	require.NoError(t, err)
+
+	rows = readRows(t, buf.Bytes())
+	require.Len(t, rows, 3)
+	assert.Equal(t, map[string]any{"key": int64(1), "city": "Paris", "age": 42.0}, rows[0])
+
+	err = fastdbparquet.Export(store, "user", &buf, fastdbparquet.Schema{{Name: "key", Path: "name"}})
+	require.Error(t, err)
+}
+
+func newStore(t *testing.T) *fastdb.DB {
+	t.Helper()
+
+	store, err := fastdb.Open(":memory:")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+
+	err = store.SetMulti("user", map[int][]byte{
+		1: []byte(`{"name":"John","age":42,"score":1.5,"active":true,"tags":["a","b"],"address":{"city":"Paris"}}`),
+		2: []byte(`{"name":"Jane","age":null,"score":2}`),
+		3: []byte("plain text"),
+	})
+	require.NoError(t, err)
+
+	return store
+}
+
+/*
+readRows reads the rows of a Parquet file as maps from the column name to the value (nil for null).
+*/
+func readRows(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+
+	reader := parquet.NewReader(bytes.NewReader(data))
+
+	defer func() {
+		require.NoError(t, reader.Close())
+	}()
+
+	columns := reader.Schema().Columns()
+
+	var result []map[string]any
+
+	rows := make([]parquet.Row, 10)
+
+	for {
+		count, err := reader.ReadRows(rows)
+
+		for _, row := range rows[:count] {
+			values := map[string]any{}
+
+			for _, value := range row {
+				name := columns[value.Column()][0]
+
+				switch {
+				case value.IsNull():
+					values[name] = nil
+				case value.Kind() == parquet.Int64:
+					values[name] = value.Int64()
+				case value.Kind() == parquet.Double:
+					values[name] = value.Double()
+				case value.Kind() == parquet.Boolean:
+					values[name] = value.Boolean()
+				default:
+					values[name] = string(value.ByteArray())
+				}
+			}
+
+			result = append(result, values)
+		}
+
+		if err != nil {
+			require.ErrorIs(t, err, io.EOF)
+
+			return result
+		}
+	}
+}
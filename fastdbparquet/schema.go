@@ -0,0 +1,160 @@
+package fastdbparquet
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/tidwall/gjson"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// Type is the type of a column.
+type Type int
+
+const (
+	// String is a text column, objects and arrays are written as JSON.
+	String Type = iota + 1
+	// Int64 is a column of whole numbers.
+	Int64
+	// Float64 is a column of numbers.
+	Float64
+	// Bool is a column of true and false.
+	Bool
+)
+
+// thisPath is the gjson path of the whole value, for values that aren't JSON objects.
+const thisPath = "@this"
+
+// Field is a column of the export: the value at a gjson path of the JSON values.
+type Field struct {
+	Name string // the name of the column
+	Path string // the gjson path of the value (like "address.city")
+	Type Type
+}
+
+// Schema holds the columns of the export (besides the key, which is always the first column).
+type Schema []Field
+
+// pathEscaper escapes the characters that have a meaning in a gjson path.
+var pathEscaper = strings.NewReplacer(".", `\.`, "*", `\*`, "?", `\?`, "|", `\|`, "#", `\#`, "@", `\@`)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+InferSchema returns the schema of the values of a bucket: a column for every field that occurs in them.
+Nested objects are flattened into columns named by their path (like "address.city"), arrays become
+String columns with the JSON. A field with whole numbers is an Int64, with other numbers a Float64,
+and a field that holds different types is a String. Values that aren't JSON objects are put in the column "value".
+*/
+func InferSchema(store *fastdb.DB, bucket string) (Schema, error) {
+	records, err := store.GetAllSorted(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("inferSchema error: %w", err)
+	}
+
+	types := map[string]Type{}
+
+	for _, record := range records {
+		if !gjson.ValidBytes(record.Data) {
+			addType(types, thisPath, String)
+
+			continue
+		}
+
+		value := gjson.ParseBytes(record.Data)
+		if !value.IsObject() {
+			addType(types, thisPath, typeOf(value))
+
+			continue
+		}
+
+		addFields(types, "", value)
+	}
+
+	schema := make(Schema, 0, len(types))
+
+	for path, typ := range types {
+		if typ == 0 {
+			typ = String // only nulls
+		}
+
+		schema = append(schema, Field{Name: columnName(path), Path: path, Type: typ})
+	}
+
+	// the same order as the columns in the file
+	slices.SortFunc(schema, func(a, b Field) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return schema, nil
+}
+
+/*
+addFields adds the types of the fields of an object, with the nested objects flattened.
+*/
+func addFields(types map[string]Type, prefix string, object gjson.Result) {
+	object.ForEach(func(key, value gjson.Result) bool {
+		path := prefix + pathEscaper.Replace(key.String())
+
+		if value.IsObject() {
+			addFields(types, path+".", value)
+		} else {
+			addType(types, path, typeOf(value))
+		}
+
+		return true
+	})
+}
+
+/*
+addType merges the type of a value into the type of the path.
+*/
+func addType(types map[string]Type, path string, typ Type) {
+	known, found := types[path]
+
+	switch {
+	case !found || known == 0:
+		types[path] = typ
+	case typ == 0 || typ == known:
+	case (known == Int64 && typ == Float64) || (known == Float64 && typ == Int64):
+		types[path] = Float64
+	default:
+		types[path] = String
+	}
+}
+
+/*
+typeOf returns the type of a JSON value, 0 for null.
+*/
+func typeOf(value gjson.Result) Type {
+	switch value.Type {
+	case gjson.Null:
+		return 0
+	case gjson.True, gjson.False:
+		return Bool
+	case gjson.Number:
+		if strings.ContainsAny(value.Raw, ".eE") {
+			return Float64
+		}
+
+		return Int64
+	default:
+		return String
+	}
+}
+
+/*
+columnName returns the name of the column of a path: the path without the escapes.
+*/
+func columnName(path string) string {
+	if path == thisPath {
+		return "value"
+	}
+
+	return strings.ReplaceAll(path, `\`, "")
+}
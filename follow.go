@@ -0,0 +1,206 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// defaultFollowInterval is the time between two reads of a follower, when no interval is given.
+const defaultFollowInterval = 100 * time.Millisecond
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+OpenFollower opens the file of a database that another process (the leader) writes, as a read-only copy
+in memory that follows the leader: after every interval, the new records are read (like "tail -f").
+It is a cheap read replica on the same host (for reporting), without a network protocol.
+The file isn't locked and never written, the options work like with Open (WithReadOnly is implied),
+and Watch reports the changes as they are read.
+After a Defrag of the leader, the whole file is read again (without events), and until the leader
+has written it completely, records can be missing.
+*/
+func OpenFollower(path string, interval time.Duration, opts ...Option) (*DB, error) {
+	cfg := newConfig(append(opts, WithReadOnly()))
+
+	follower, err := persist.Follow(path, cfg.persistOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("openFollower error: %w", err)
+	}
+
+	fdb := newDB(nil, map[string]map[int][]byte{}, map[string]string{}, cfg)
+	fdb.follower = follower
+
+	err = fdb.follow()
+	if err != nil {
+		_ = fdb.Close()
+
+		return nil, fmt.Errorf("openFollower error: %w", err)
+	}
+
+	if interval <= 0 {
+		interval = defaultFollowInterval
+	}
+
+	fdb.runEvery(interval, nil, fdb.followTask)
+
+	return fdb, nil
+}
+
+/*
+followTask is the background task of a follower, that reads what the leader wrote.
+A failure is logged once (until the next one is different), the next read tries again.
+*/
+func (fdb *DB) followTask() {
+	err := fdb.follow()
+
+	defer fdb.lockUnlock()()
+
+	if err != nil && (fdb.followErr == nil || fdb.followErr.Error() != err.Error()) {
+		fdb.cfg.logger.Error("follow error", "error", err)
+	}
+
+	fdb.followErr = err
+}
+
+/*
+follow reads the instructions that the leader wrote since the last time, and applies them.
+*/
+func (fdb *DB) follow() error {
+	defer fdb.lockUnlock()()
+
+	notify := true
+	metaChanged := false
+
+	apply := func(ins persist.Instruction) {
+		metaChanged = fdb.applyInstruction(ins, notify) || metaChanged
+	}
+
+	replaced, err := fdb.follower.Poll(apply)
+	if err == nil && replaced {
+		// the leader rewrote the file, so everything is read again
+		fdb.keys = map[string]map[int][]byte{}
+		fdb.meta = map[string]string{}
+		fdb.resetCaches()
+
+		notify = false
+		metaChanged = true
+
+		_, err = fdb.follower.Poll(apply)
+	}
+
+	if metaChanged {
+		fdb.loadExpiries()
+		fdb.loadSequence()
+	}
+
+	if replaced {
+		fdb.loadLiveBytes()
+	}
+
+	if err != nil {
+		return fmt.Errorf("follow error: %w", err)
+	}
+
+	return nil
+}
+
+/*
+applyInstruction changes the memory like the instruction changed the file of the leader,
+and returns true if it changed the meta data.
+The caller must hold the write lock.
+*/
+func (fdb *DB) applyInstruction(ins persist.Instruction, notify bool) bool {
+	switch ins.Name {
+	case "set", "del":
+		bucket, key, ok := ins.BucketKey()
+		if !ok {
+			return false
+		}
+
+		if ins.Name == "set" {
+			fdb.followSet(bucket, key, ins.Value, notify)
+		} else {
+			fdb.followDel(bucket, key, notify)
+		}
+	case "delbucket":
+		for _, key := range slices.Sorted(maps.Keys(fdb.keys[ins.Key])) {
+			fdb.followDel(ins.Key, key, notify)
+		}
+
+		delete(fdb.keys, ins.Key)
+	case "renamebucket", "copybucket":
+		src, dst := ins.Key, string(ins.Value)
+		if _, found := fdb.keys[src]; !found || src == dst {
+			return false
+		}
+
+		for _, key := range slices.Sorted(maps.Keys(fdb.keys[dst])) {
+			fdb.followDel(dst, key, notify)
+		}
+
+		for _, key := range slices.Sorted(maps.Keys(fdb.keys[src])) {
+			fdb.followSet(dst, key, fdb.keys[src][key], notify)
+		}
+
+		if ins.Name == "renamebucket" {
+			fdb.applyInstruction(persist.DelBucketInstruction(src), notify)
+		}
+	case "meta":
+		fdb.meta[ins.Key] = string(ins.Value)
+
+		return true
+	case "delmeta":
+		delete(fdb.meta, ins.Key)
+
+		return true
+	}
+
+	return false
+}
+
+/*
+followSet stores a record that the leader wrote.
+The caller must hold the write lock.
+*/
+func (fdb *DB) followSet(bucket string, key int, value []byte, notify bool) {
+	_, found := fdb.keys[bucket]
+	if !found {
+		fdb.keys[bucket] = map[int][]byte{}
+	}
+
+	fdb.liveBytes -= fdb.recordSize(bucket, key)
+	fdb.keys[bucket][key] = value
+	fdb.liveBytes += fdb.recordSize(bucket, key)
+	fdb.touch(bucket)
+
+	if notify {
+		fdb.notify(Event{Type: EventSet, Bucket: bucket, Key: key, Value: value})
+	}
+}
+
+/*
+followDel removes a record that the leader deleted.
+The caller must hold the write lock.
+*/
+func (fdb *DB) followDel(bucket string, key int, notify bool) {
+	_, found := fdb.keys[bucket][key]
+	if !found {
+		return
+	}
+
+	fdb.liveBytes -= fdb.recordSize(bucket, key)
+	delete(fdb.keys[bucket], key)
+	fdb.touch(bucket)
+
+	if notify {
+		fdb.notify(Event{Type: EventDel, Bucket: bucket, Key: key})
+	}
+}
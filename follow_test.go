@@ -0,0 +1,90 @@
+package fastdb_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenFollower(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "fastdb_follower.db")
+
+	leader, err := fastdb.Open(filePath, fastdb.WithSyncTime(0))
+	require.NoError(t, err)
+
+	defer func() {
+		err = leader.Close()
+		require.NoError(t, err)
+	}()
+
+	err = leader.Set("text", 1, []byte("one"))
+	require.NoError(t, err)
+
+	follower, err := fastdb.OpenFollower(filePath, 5*time.Millisecond)
+	require.NoError(t, err)
+
+	defer func() {
+		err = follower.Close()
+		require.NoError(t, err)
+	}()
+
+	value, ok := follower.Get("text", 1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("one"), value)
+
+	// the follower is read-only
+	err = follower.Set("text", 2, []byte("two"))
+	require.ErrorIs(t, err, fastdb.ErrReadOnly)
+
+	events, stop := follower.Watch("text")
+	defer stop()
+
+	err = leader.Set("text", 2, []byte("two"))
+	require.NoError(t, err)
+
+	_, err = leader.Del("text", 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, fastdb.Event{Type: fastdb.EventSet, Bucket: "text", Key: 2, Value: []byte("two")}, <-events)
+	assert.Equal(t, fastdb.Event{Type: fastdb.EventDel, Bucket: "text", Key: 1}, <-events)
+
+	err = leader.SetWithTTL("text", 3, []byte("three"), time.Hour)
+	require.NoError(t, err)
+
+	err = leader.RenameBucket("text", "words")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return follower.Count("words") == 2
+	}, time.Second, time.Millisecond)
+
+	assert.False(t, follower.HasBucket("text"))
+
+	ttl, ok := follower.TTL("words", 3)
+	assert.True(t, ok)
+	assert.Positive(t, ttl)
+
+	// a Defrag of the leader replaces the file, which is read again
+	err = leader.Defrag()
+	require.NoError(t, err)
+
+	err = leader.Set("words", 4, []byte("four"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		value, ok = follower.Get("words", 4)
+
+		return ok && follower.Count("words") == 3
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, []byte("four"), value)
+}
+
+func Test_OpenFollower_noFile(t *testing.T) {
+	_, err := fastdb.OpenFollower(filepath.Join(t.TempDir(), "missing.db"), 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package fastdb_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Freeze_Thaw(t *testing.T) {
+	path := "data/fastdb_freeze.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("value"))
+	require.NoError(t, err)
+
+	err = store.Freeze()
+	require.NoError(t, err)
+	assert.True(t, store.Frozen())
+
+	err = store.Freeze()
+	require.Error(t, err)
+
+	// everything is on disk
+	data, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, fileHeader(string(data))+"set\ntext_1\nvalue\n", string(data))
+
+	// reads go on
+	memData, ok := store.Get("text", 1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value"), memData)
+
+	// writes wait until the thaw
+	written := make(chan error)
+
+	go func() {
+		written <- store.Set("text", 2, []byte("waited"))
+	}()
+
+	select {
+	case <-written:
+		require.Fail(t, "write during freeze")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	store.Thaw()
+	assert.False(t, store.Frozen())
+	require.NoError(t, <-written)
+
+	memData, ok = store.Get("text", 2)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("waited"), memData)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Freeze_timeout(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithFreezeTimeout(10*time.Millisecond))
+	require.NoError(t, err)
+
+	err = store.Freeze()
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("value"))
+	require.ErrorIs(t, err, fastdb.ErrFrozen)
+
+	_, err = store.Del("text", 1)
+	require.ErrorIs(t, err, fastdb.ErrFrozen)
+
+	_, err = store.Insert("text", []byte("value"))
+	require.ErrorIs(t, err, fastdb.ErrFrozen)
+
+	_, err = store.Sequence("ids").Next()
+	require.ErrorIs(t, err, fastdb.ErrFrozen)
+
+	// closing thaws the database
+	err = store.Close()
+	require.NoError(t, err)
+	assert.False(t, store.Frozen())
+}
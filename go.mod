@@ -3,6 +3,7 @@ module github.com/marcelloh/fastdb
 go 1.23.2
 
 require (
+	github.com/klauspost/compress v1.17.9
 	github.com/stretchr/testify v1.9.0
 	github.com/tidwall/gjson v1.18.0
 )
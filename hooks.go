@@ -0,0 +1,137 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+/*
+BeforeSetFunc is called before a value is stored. It returns the value that will be stored
+(so it can be changed, e.g. to add audit data), or an error to refuse the write.
+*/
+type BeforeSetFunc func(bucket string, key int, value []byte) ([]byte, error)
+
+// AfterSetFunc is called after a value was stored.
+type AfterSetFunc func(bucket string, key int, value []byte)
+
+// BeforeDelFunc is called before a record is deleted, it returns an error to refuse the delete.
+type BeforeDelFunc func(bucket string, key int) error
+
+// AfterDelFunc is called after a record was deleted.
+type AfterDelFunc func(bucket string, key int)
+
+// hooks holds the functions that are called around the operations, in the order they were added.
+type hooks struct {
+	beforeSet []BeforeSetFunc
+	afterSet  []AfterSetFunc
+	beforeDel []BeforeDelFunc
+	afterDel  []AfterDelFunc
+	authorize []AuthorizeFunc
+	watermark []WatermarkFunc
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+OnBeforeSet adds a function that is called before every value is stored
+(by Set, SetWithTTL, SetMulti, Insert and Restore).
+The hooks are called while the database is locked, so they can't use the database.
+*/
+func (fdb *DB) OnBeforeSet(hook BeforeSetFunc) {
+	defer fdb.lockUnlock()()
+
+	fdb.hooks.beforeSet = append(fdb.hooks.beforeSet, hook)
+}
+
+/*
+OnAfterSet adds a function that is called after every value was stored.
+The hooks are called while the database is locked, so they can't use the database.
+*/
+func (fdb *DB) OnAfterSet(hook AfterSetFunc) {
+	defer fdb.lockUnlock()()
+
+	fdb.hooks.afterSet = append(fdb.hooks.afterSet, hook)
+}
+
+/*
+OnBeforeDel adds a function that is called before every record is deleted (also when it expired).
+The hooks are called while the database is locked, so they can't use the database.
+*/
+func (fdb *DB) OnBeforeDel(hook BeforeDelFunc) {
+	defer fdb.lockUnlock()()
+
+	fdb.hooks.beforeDel = append(fdb.hooks.beforeDel, hook)
+}
+
+/*
+OnAfterDel adds a function that is called after every record was deleted.
+The hooks are called while the database is locked, so they can't use the database.
+*/
+func (fdb *DB) OnAfterDel(hook AfterDelFunc) {
+	defer fdb.lockUnlock()()
+
+	fdb.hooks.afterDel = append(fdb.hooks.afterDel, hook)
+}
+
+/*
+beforeSet runs the before set hooks and returns the value that has to be stored.
+The caller must hold the write lock.
+*/
+func (fdb *DB) beforeSet(bucket string, key int, value []byte) ([]byte, error) {
+	for _, hook := range fdb.hooks.beforeSet {
+		var err error
+
+		value, err = hook(bucket, key, value)
+		if err != nil {
+			return nil, fmt.Errorf("hook (%s_%d) error: %w", bucket, key, err)
+		}
+	}
+
+	return value, nil
+}
+
+/*
+afterSet runs the after set hooks (and tells the observer).
+The caller must hold the write lock.
+*/
+func (fdb *DB) afterSet(bucket string, key int, value []byte) {
+	if fdb.cfg.observer.Set != nil {
+		fdb.cfg.observer.Set(bucket, len(value))
+	}
+
+	for _, hook := range fdb.hooks.afterSet {
+		hook(bucket, key, value)
+	}
+}
+
+/*
+beforeDel runs the before del hooks.
+The caller must hold the write lock.
+*/
+func (fdb *DB) beforeDel(bucket string, key int) error {
+	for _, hook := range fdb.hooks.beforeDel {
+		err := hook(bucket, key)
+		if err != nil {
+			return fmt.Errorf("hook (%s_%d) error: %w", bucket, key, err)
+		}
+	}
+
+	return nil
+}
+
+/*
+afterDel runs the after del hooks (and tells the observer).
+The caller must hold the write lock.
+*/
+func (fdb *DB) afterDel(bucket string, key int) {
+	if fdb.cfg.observer.Del != nil {
+		fdb.cfg.observer.Del(bucket)
+	}
+
+	for _, hook := range fdb.hooks.afterDel {
+		hook(bucket, key)
+	}
+}
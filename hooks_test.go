@@ -0,0 +1,72 @@
+package fastdb_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Hooks(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	var audit []string
+
+	errVeto := errors.New("veto")
+
+	store.OnBeforeSet(func(_ string, key int, value []byte) ([]byte, error) {
+		if key == 13 {
+			return nil, errVeto
+		}
+
+		return append([]byte("audited:"), value...), nil
+	})
+	store.OnAfterSet(func(bucket string, _ int, value []byte) {
+		audit = append(audit, "set "+bucket+" "+string(value))
+	})
+	store.OnBeforeDel(func(_ string, key int) error {
+		if key == 2 {
+			return errVeto
+		}
+
+		return nil
+	})
+	store.OnAfterDel(func(bucket string, _ int) {
+		audit = append(audit, "del "+bucket)
+	})
+
+	err = store.Set("text", 1, []byte("one"))
+	require.NoError(t, err)
+
+	err = store.Set("text", 13, []byte("unlucky"))
+	require.ErrorIs(t, err, errVeto)
+
+	err = store.SetMulti("text", map[int][]byte{2: []byte("two"), 13: []byte("unlucky")})
+	require.ErrorIs(t, err, errVeto)
+
+	err = store.SetMulti("text", map[int][]byte{2: []byte("two")})
+	require.NoError(t, err)
+
+	memData, ok := store.Get("text", 1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("audited:one"), memData)
+
+	_, ok = store.Get("text", 13)
+	assert.False(t, ok)
+
+	deleted, err := store.Del("text", 2)
+	require.ErrorIs(t, err, errVeto)
+	assert.False(t, deleted)
+
+	deleted, err = store.Del("text", 1)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	assert.Equal(t, []string{"set text audited:one", "set text audited:two", "del text"}, audit)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
@@ -0,0 +1,226 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+/*
+IDGenerator generates a new key for a bucket.
+It is called while the database is locked, with the current records of the bucket,
+so the key can be checked against the existing ones.
+*/
+type IDGenerator interface {
+	NextID(bucket string, records map[int][]byte) (int, error)
+}
+
+// IDGeneratorFunc is a function that can be used as an IDGenerator.
+type IDGeneratorFunc func(bucket string, records map[int][]byte) (int, error)
+
+// snowflake generates time based, unique keys.
+type snowflake struct {
+	lastTime int64
+	node     int
+	sequence int
+	mu       sync.Mutex
+}
+
+const (
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+	snowflakeMaxNode      = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSequence  = 1<<snowflakeSequenceBits - 1
+	randomTries           = 100
+)
+
+// snowflakeEpoch is the start of the time part of a snowflake key (2024-01-01).
+var snowflakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+NextID calls the function.
+*/
+func (fn IDGeneratorFunc) NextID(bucket string, records map[int][]byte) (int, error) {
+	return fn(bucket, records)
+}
+
+/*
+AutoIncrement returns a generator that returns the highest key of the bucket + 1
+(like GetNewIndex). This is the default generator.
+*/
+func AutoIncrement() IDGenerator {
+	return IDGeneratorFunc(func(_ string, records map[int][]byte) (int, error) {
+		return highestKey(records) + 1, nil
+	})
+}
+
+/*
+Snowflake returns a generator for unique, time ordered keys:
+the milliseconds since 2024 (41 bits), the node (10 bits) and a sequence (12 bits).
+The node (0-1023) should differ per process that generates keys for the same data.
+The keys need 64 bits, so on a platform with 32 bit ints NextID fails.
+*/
+func Snowflake(node int) IDGenerator {
+	return &snowflake{node: node & snowflakeMaxNode}
+}
+
+/*
+NextID returns the next snowflake key.
+*/
+func (flake *snowflake) NextID(_ string, _ map[int][]byte) (int, error) {
+	if strconv.IntSize < 64 {
+		return 0, fmt.Errorf("snowflake->keys need 64 bits, an int has %d bits here", strconv.IntSize)
+	}
+
+	flake.mu.Lock()
+	defer flake.mu.Unlock()
+
+	now := time.Now().UnixMilli() - snowflakeEpoch
+
+	if now <= flake.lastTime {
+		// same millisecond (or the clock went back): continue with the sequence
+		now = flake.lastTime
+		flake.sequence = (flake.sequence + 1) & snowflakeMaxSequence
+
+		if flake.sequence == 0 {
+			// sequence exhausted, borrow from the next millisecond
+			now++
+		}
+	} else {
+		flake.sequence = 0
+	}
+
+	flake.lastTime = now
+
+	key := now<<snowflakeTimeShift | int64(flake.node)<<snowflakeSequenceBits | int64(flake.sequence)
+
+	return int(key), nil
+}
+
+/*
+RandomInRange returns a generator for random keys between minKey and maxKey (both included),
+that are not in use yet.
+*/
+func RandomInRange(minKey, maxKey int) IDGenerator {
+	return IDGeneratorFunc(func(bucket string, records map[int][]byte) (int, error) {
+		if minKey < 0 || maxKey < minKey {
+			return 0, fmt.Errorf("randomInRange->wrong range %d-%d", minKey, maxKey)
+		}
+
+		for range randomTries {
+			// the size of the range can be one more than the highest int
+			key := minKey + int(rand.Uint64N(uint64(maxKey-minKey)+1)) //nolint:gosec // no crypto needed
+
+			_, found := records[key]
+			if !found {
+				return key, nil
+			}
+		}
+
+		return 0, fmt.Errorf("randomInRange->no free key found in bucket (%s)", bucket)
+	})
+}
+
+/*
+SetIDGenerator sets the generator that Insert uses for the keys of a bucket.
+A nil generator resets it to the default (AutoIncrement).
+*/
+func (fdb *DB) SetIDGenerator(bucket string, generator IDGenerator) {
+	defer fdb.lockUnlock()()
+
+	if generator == nil {
+		delete(fdb.idGenerators, bucket)
+
+		return
+	}
+
+	fdb.idGenerators[bucket] = generator
+}
+
+/*
+Insert stores a value in a bucket under a newly generated key, and returns that key.
+Generating the key and storing the value happens under one lock,
+so (unlike GetNewIndex followed by Set) two callers can never get the same key.
+*/
+func (fdb *DB) Insert(bucket string, value []byte) (int, error) {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return 0, fmt.Errorf("insert error: %w", err)
+	}
+
+	defer unlock()
+
+	generator, found := fdb.idGenerators[bucket]
+	if !found {
+		generator = AutoIncrement()
+	}
+
+	return fdb.insert(bucket, value, generator)
+}
+
+/*
+SetAuto stores a value in a bucket under the next available index (the one GetNewIndex returns),
+and returns that key. Both happen under one lock, so two callers can never get the same key.
+Unlike Insert, it always uses the next index, whatever the IDGenerator of the bucket is.
+*/
+func (fdb *DB) SetAuto(bucket string, value []byte) (int, error) {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return 0, fmt.Errorf("setAuto error: %w", err)
+	}
+
+	defer unlock()
+
+	key, err := fdb.insert(bucket, value, AutoIncrement())
+	if err != nil {
+		return 0, fmt.Errorf("setAuto->%w", err)
+	}
+
+	return key, nil
+}
+
+/*
+insert stores a value under a new key of the generator.
+The caller must hold the write lock.
+*/
+func (fdb *DB) insert(bucket string, value []byte, generator IDGenerator) (int, error) {
+	key, err := generator.NextID(bucket, fdb.keys[bucket])
+	if err != nil {
+		return 0, fmt.Errorf("insert->generate key error: %w", err)
+	}
+
+	_, found := fdb.keys[bucket][key]
+	if found {
+		return 0, errors.New("insert->generated key already exists")
+	}
+
+	err = fdb.set(bucket, key, value, 0)
+	if err != nil {
+		return 0, fmt.Errorf("insert error: %w", err)
+	}
+
+	return key, nil
+}
+
+/*
+highestKey returns the highest key of the records (0 if there are none).
+*/
+func highestKey(records map[int][]byte) int {
+	lkey := 0
+	for key := range records {
+		if key > lkey {
+			lkey = key
+		}
+	}
+
+	return lkey
+}
@@ -0,0 +1,253 @@
+package fastdb_test
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Insert_AutoIncrement(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	const (
+		numGoroutines = 20
+		numInserts    = 50
+	)
+
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+
+			for range numInserts {
+				_, err := store.Insert("user", []byte("value"))
+				assert.NoError(t, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// no key was handed out twice
+	records, err := store.GetAll("user")
+	require.NoError(t, err)
+	assert.Len(t, records, numGoroutines*numInserts)
+	assert.Equal(t, numGoroutines*numInserts+1, store.GetNewIndex("user"))
+}
+
+func Test_Insert_Snowflake(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	store.SetIDGenerator("event", fastdb.Snowflake(3))
+
+	previous := 0
+
+	for range 10000 {
+		key, err := store.Insert("event", []byte("value"))
+		require.NoError(t, err)
+		assert.Greater(t, key, previous)
+		assert.Equal(t, 3, (key>>12)&1023)
+
+		previous = key
+	}
+
+	// back to the default
+	store.SetIDGenerator("event", nil)
+
+	key, err := store.Insert("event", []byte("value"))
+	require.NoError(t, err)
+	assert.Equal(t, previous+1, key)
+}
+
+func Test_Insert_RandomInRange(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	store.SetIDGenerator("ticket", fastdb.RandomInRange(10, 12))
+
+	for range 3 {
+		key, err := store.Insert("ticket", []byte("value"))
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, key, 10)
+		assert.LessOrEqual(t, key, 12)
+	}
+
+	// the range is full
+	_, err = store.Insert("ticket", []byte("value"))
+	require.Error(t, err)
+
+	store.SetIDGenerator("ticket", fastdb.RandomInRange(12, 10))
+
+	_, err = store.Insert("ticket", []byte("value"))
+	require.Error(t, err)
+
+	// the extreme bounds
+	store.SetIDGenerator("ticket", fastdb.RandomInRange(0, math.MaxInt))
+
+	key, err := store.Insert("ticket", []byte("value"))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, key, 0)
+
+	store.SetIDGenerator("ticket", fastdb.RandomInRange(math.MaxInt-1, math.MaxInt))
+
+	for range 2 {
+		key, err = store.Insert("ticket", []byte("value"))
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, key, math.MaxInt-1)
+	}
+}
+
+func Test_Insert_errors(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	store.SetIDGenerator("fixed", fastdb.IDGeneratorFunc(func(_ string, _ map[int][]byte) (int, error) {
+		return 1, nil
+	}))
+
+	key, err := store.Insert("fixed", []byte("value"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, key)
+
+	// the generated key exists already
+	_, err = store.Insert("fixed", []byte("value"))
+	require.Error(t, err)
+
+	store.SetIDGenerator("failing", fastdb.IDGeneratorFunc(func(_ string, _ map[int][]byte) (int, error) {
+		return 0, errors.New("no more keys")
+	}))
+
+	_, err = store.Insert("failing", []byte("value"))
+	require.Error(t, err)
+
+	store.SetIDGenerator("negative", fastdb.IDGeneratorFunc(func(_ string, _ map[int][]byte) (int, error) {
+		return -1, nil
+	}))
+
+	_, err = store.Insert("negative", []byte("value"))
+	require.Error(t, err)
+}
+
+func Test_Insert_File(t *testing.T) {
+	path := "data/fastdb_insert.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	for want := 1; want <= 3; want++ {
+		key, err := store.Insert("user", []byte("user "+strconv.Itoa(want)))
+		require.NoError(t, err)
+		assert.Equal(t, want, key)
+	}
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// a closed store can't insert
+	_, err = store.Insert("user", []byte("value"))
+	require.Error(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	value, ok := store.Get("user", 3)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("user 3"), value)
+
+	key, err := store.Insert("user", []byte("user 4"))
+	require.NoError(t, err)
+	assert.Equal(t, 4, key)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_SetAuto(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	// the generator of the bucket isn't used
+	store.SetIDGenerator("user", fastdb.Snowflake(1))
+
+	const (
+		numGoroutines = 20
+		numInserts    = 50
+	)
+
+	var wg sync.WaitGroup
+
+	wg.Add(numGoroutines)
+
+	for range numGoroutines {
+		go func() {
+			defer wg.Done()
+
+			for range numInserts {
+				_, err := store.SetAuto("user", []byte("value"))
+				assert.NoError(t, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// no key was handed out twice
+	records, err := store.GetAll("user")
+	require.NoError(t, err)
+	assert.Len(t, records, numGoroutines*numInserts)
+
+	key, err := store.SetAuto("user", []byte("value"))
+	require.NoError(t, err)
+	assert.Equal(t, numGoroutines*numInserts+1, key)
+
+	readOnly, err := fastdb.Open(memory, fastdb.WithReadOnly())
+	require.NoError(t, err)
+
+	_, err = readOnly.SetAuto("user", []byte("value"))
+	require.ErrorIs(t, err, fastdb.ErrReadOnly)
+}
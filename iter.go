@@ -0,0 +1,91 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+	"iter"
+	"time"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+All returns an iterator over the keys and values of a bucket, in no particular order,
+e.g. for key, value := range store.All("user").
+Nothing is collected: the read lock is held while iterating, so the loop body can't change the database.
+A bucket that doesn't exist results in no records.
+*/
+func (fdb *DB) All(bucket string) iter.Seq2[int, []byte] {
+	return func(yield func(int, []byte) bool) {
+		defer fdb.mu.RLock().RUnlock()
+
+		now := time.Now().UnixNano()
+
+		for key, value := range fdb.keys[bucket] {
+			if fdb.expiredAt(bucket, key, now) {
+				continue
+			}
+
+			if !yield(key, value) {
+				return
+			}
+		}
+	}
+}
+
+/*
+AllSorted returns an iterator over the keys and values of a bucket, in Key sorted order.
+Only the (cached) sorted keys are used, no records are collected.
+The read lock is held while iterating, so the loop body can't change the database.
+A bucket that doesn't exist results in no records.
+*/
+func (fdb *DB) AllSorted(bucket string) iter.Seq2[int, []byte] {
+	return func(yield func(int, []byte) bool) {
+		defer fdb.mu.RLock().RUnlock()
+
+		memRecords, found := fdb.keys[bucket]
+		if !found {
+			return
+		}
+
+		now := time.Now().UnixNano()
+
+		for _, key := range fdb.sortedKeys(bucket) {
+			if fdb.expiredAt(bucket, key, now) {
+				continue
+			}
+
+			if !yield(key, memRecords[key]) {
+				return
+			}
+		}
+	}
+}
+
+/*
+ForEach calls fn for the keys and values of a bucket, in no particular order, until fn returns false.
+Nothing is copied: the read lock is held while iterating, so fn can't change the database.
+*/
+func (fdb *DB) ForEach(bucket string, fn func(key int, value []byte) bool) error {
+	defer fdb.mu.RLock().RUnlock()
+
+	memRecords, found := fdb.keys[bucket]
+	if !found {
+		return fmt.Errorf("%w (%s)", ErrBucketNotFound, bucket)
+	}
+
+	now := time.Now().UnixNano()
+
+	for key, value := range memRecords {
+		if fdb.expiredAt(bucket, key, now) {
+			continue
+		}
+
+		if !fn(key, value) {
+			break
+		}
+	}
+
+	return nil
+}
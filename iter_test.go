@@ -0,0 +1,106 @@
+package fastdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_All(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	for key := 5; key >= 1; key-- {
+		err = store.Set("text", key, []byte("value"))
+		require.NoError(t, err)
+	}
+
+	err = store.SetWithTTL("text", 6, []byte("value"), time.Nanosecond)
+	require.NoError(t, err)
+
+	time.Sleep(time.Millisecond)
+
+	seen := map[int][]byte{}
+	for key, value := range store.All("text") {
+		seen[key] = value
+	}
+
+	assert.Len(t, seen, 5)
+	assert.NotContains(t, seen, 6)
+
+	keys := []int{}
+	for key := range store.AllSorted("text") {
+		keys = append(keys, key)
+	}
+
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, keys)
+
+	// stopping early
+	keys = []int{}
+	for key := range store.AllSorted("text") {
+		if key > 2 {
+			break
+		}
+
+		keys = append(keys, key)
+	}
+
+	assert.Equal(t, []int{1, 2}, keys)
+
+	for range store.All("unknown") {
+		t.Fatal("no records expected")
+	}
+
+	for range store.AllSorted("unknown") {
+		t.Fatal("no records expected")
+	}
+
+	// the lock is released after the loop
+	err = store.Set("text", 7, []byte("value"))
+	require.NoError(t, err)
+}
+
+func Test_ForEach(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	err = store.ForEach("text", func(int, []byte) bool { return true })
+	require.Error(t, err)
+
+	for key := 1; key <= 5; key++ {
+		err = store.Set("text", key, []byte("value"))
+		require.NoError(t, err)
+	}
+
+	total := 0
+	err = store.ForEach("text", func(key int, value []byte) bool {
+		total += key
+		assert.Equal(t, []byte("value"), value)
+
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 15, total)
+
+	count := 0
+	err = store.ForEach("text", func(int, []byte) bool {
+		count++
+
+		return count < 2
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
@@ -0,0 +1,62 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// JoinRecord represents a record paired with the record it refers to in another bucket.
+type JoinRecord struct {
+	Record *Record
+	Joined *Record // nil if the referred record doesn't exist
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Join returns all records from a bucket (in Key sorted order), each paired with
+the record from the joinBucket whose key is the value of the given gjson field.
+Everything is read in one locked pass, so the pairs are consistent.
+Expired records are left out, on both sides.
+*/
+func (fdb *DB) Join(bucket, field, joinBucket string) ([]*JoinRecord, error) {
+	defer fdb.mu.RLock().RUnlock()
+
+	memRecords, found := fdb.keys[bucket]
+	if !found {
+		return nil, fmt.Errorf("%w (%s)", ErrBucketNotFound, bucket)
+	}
+
+	joinRecords := fdb.keys[joinBucket]
+	joined := make([]*JoinRecord, 0, len(memRecords))
+	now := time.Now().UnixNano()
+
+	for _, key := range fdb.sortedKeys(bucket) {
+		if fdb.expiredAt(bucket, key, now) {
+			continue
+		}
+
+		data := memRecords[key]
+		joinRecord := &JoinRecord{Record: &Record{Key: key, Data: data}}
+
+		ref := gjson.GetBytes(data, field)
+		if ref.Exists() {
+			joinKey := int(ref.Int())
+
+			joinData, ok := joinRecords[joinKey]
+			if ok && !fdb.expiredAt(joinBucket, joinKey, now) {
+				joinRecord.Joined = &Record{Key: joinKey, Data: joinData}
+			}
+		}
+
+		joined = append(joined, joinRecord)
+	}
+
+	return joined, nil
+}
@@ -0,0 +1,81 @@
+package fastdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Join(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.NotNil(t, store)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	err = store.Set("user", 1, []byte(`{"ID":1,"Name":"one"}`))
+	require.NoError(t, err)
+
+	err = store.Set("user", 2, []byte(`{"ID":2,"Name":"two"}`))
+	require.NoError(t, err)
+
+	err = store.Set("orders", 10, []byte(`{"ID":10,"UserID":2}`))
+	require.NoError(t, err)
+
+	err = store.Set("orders", 11, []byte(`{"ID":11,"UserID":1}`))
+	require.NoError(t, err)
+
+	err = store.Set("orders", 12, []byte(`{"ID":12,"UserID":3}`))
+	require.NoError(t, err)
+
+	err = store.Set("orders", 13, []byte(`{"ID":13}`))
+	require.NoError(t, err)
+
+	joined, err := store.Join("orders", "UserID", "user")
+	require.NoError(t, err)
+	require.Len(t, joined, 4)
+
+	assert.Equal(t, 10, joined[0].Record.Key)
+	require.NotNil(t, joined[0].Joined)
+	assert.Equal(t, 2, joined[0].Joined.Key)
+	assert.Equal(t, "two", joined[0].Joined.String("Name"))
+
+	assert.Equal(t, 11, joined[1].Record.Key)
+	require.NotNil(t, joined[1].Joined)
+	assert.Equal(t, "one", joined[1].Joined.String("Name"))
+
+	// referred user doesn't exist
+	assert.Equal(t, 12, joined[2].Record.Key)
+	assert.Nil(t, joined[2].Joined)
+
+	// no reference at all
+	assert.Equal(t, 13, joined[3].Record.Key)
+	assert.Nil(t, joined[3].Joined)
+
+	// expired records (that aren't reaped yet) are left out, on both sides
+	err = store.SetWithTTL("user", 2, []byte(`{"ID":2,"Name":"two"}`), time.Nanosecond)
+	require.NoError(t, err)
+
+	err = store.SetWithTTL("orders", 11, []byte(`{"ID":11,"UserID":1}`), time.Nanosecond)
+	require.NoError(t, err)
+
+	time.Sleep(time.Millisecond)
+
+	joined, err = store.Join("orders", "UserID", "user")
+	require.NoError(t, err)
+	require.Len(t, joined, 3)
+
+	assert.Equal(t, 10, joined[0].Record.Key)
+	assert.Nil(t, joined[0].Joined)
+	assert.Equal(t, 12, joined[1].Record.Key)
+
+	joined, err = store.Join("wrong_bucket", "UserID", "user")
+	require.Error(t, err)
+	assert.Nil(t, joined)
+}
@@ -0,0 +1,115 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+/*
+KeyCodec translates between the keys of an application (like time ordered IDs) and the int keys
+of the records. The order of the keys must be kept, so a range of application keys
+is a range of int keys (see GetRange and DeleteRange).
+*/
+type KeyCodec[K any] interface {
+	Encode(key K) (int, error)
+	Decode(key int) K
+}
+
+// timeIDs is the codec of TimeIDs.
+type timeIDs struct{}
+
+const (
+	// crockford is the base32 alphabet of ULIDs (without I, L, O and U).
+	crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	// timeIDLength is the number of base32 characters of a 63 bit key.
+	timeIDLength = 13
+	// snowflakeTimeShift is the position of the time part in a snowflake key.
+	snowflakeTimeShift = snowflakeNodeBits + snowflakeSequenceBits
+)
+
+var errTimeID = errors.New("wrong time ID")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+TimeIDs returns a codec for keys as fixed length strings (13 characters, in the base32 alphabet of ULIDs),
+that sort the same as the int keys. Together with the Snowflake generator, the IDs are time ordered,
+like ULIDs or KSUIDs, but they fit in the int key of a record.
+*/
+func TimeIDs() KeyCodec[string] {
+	return timeIDs{}
+}
+
+/*
+Encode returns the int key of a time ID (lower case is accepted too).
+*/
+func (timeIDs) Encode(id string) (int, error) {
+	if len(id) != timeIDLength || id[0] > '7' {
+		return 0, fmt.Errorf("%w: %q", errTimeID, id)
+	}
+
+	key := 0
+
+	for _, char := range strings.ToUpper(id) {
+		digit := strings.IndexRune(crockford, char)
+		if digit < 0 {
+			return 0, fmt.Errorf("%w: %q", errTimeID, id)
+		}
+
+		key = key<<5 | digit
+	}
+
+	return key, nil
+}
+
+/*
+Decode returns the time ID of an int key (a negative key has no ID, it returns an empty string).
+*/
+func (timeIDs) Decode(key int) string {
+	if key < 0 {
+		return ""
+	}
+
+	id := make([]byte, timeIDLength)
+	for pos := timeIDLength - 1; pos >= 0; pos-- {
+		id[pos] = crockford[key&31]
+		key >>= 5
+	}
+
+	return string(id)
+}
+
+/*
+SnowflakeTime returns the time at which a key of the Snowflake generator was made (to the millisecond).
+*/
+func SnowflakeTime(key int) time.Time {
+	return time.UnixMilli(int64(key>>snowflakeTimeShift) + snowflakeEpoch)
+}
+
+/*
+SnowflakeRange returns the lowest and highest key that the Snowflake generator makes from the time from,
+until (but not including) the time to, to use with GetRange or DeleteRange.
+*/
+func SnowflakeRange(from, to time.Time) (int, int) {
+	minKey := max(from.UnixMilli()-snowflakeEpoch, 0) << snowflakeTimeShift
+	maxKey := max(to.UnixMilli()-snowflakeEpoch, 0)<<snowflakeTimeShift - 1
+
+	return int(minKey), int(maxKey)
+}
+
+/*
+GetTimeRange returns the records of a bucket with Snowflake keys (see SetIDGenerator),
+that were made from the time from, until (but not including) the time to, in Key (so time) sorted order.
+Only the records in the range are collected, so reading an event log per day is cheap.
+*/
+func (fdb *DB) GetTimeRange(bucket string, from, to time.Time) ([]*SortRecord, error) {
+	minKey, maxKey := SnowflakeRange(from, to)
+
+	return fdb.GetRange(bucket, minKey, maxKey)
+}
@@ -0,0 +1,82 @@
+package fastdb_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_TimeIDs(t *testing.T) {
+	codec := fastdb.TimeIDs()
+
+	previous := ""
+
+	for _, key := range []int{0, 1, 31, 32, 1 << 40, math.MaxInt} {
+		id := codec.Decode(key)
+		assert.Len(t, id, 13)
+		assert.Greater(t, id, previous)
+
+		decoded, err := codec.Encode(id)
+		require.NoError(t, err)
+		assert.Equal(t, key, decoded)
+
+		previous = id
+	}
+
+	assert.Equal(t, "7ZZZZZZZZZZZZ", codec.Decode(math.MaxInt))
+	assert.Empty(t, codec.Decode(-1))
+
+	key, err := codec.Encode(strings.ToLower("000000000001Z"))
+	require.NoError(t, err)
+	assert.Equal(t, 63, key)
+
+	for _, id := range []string{"", "0000000000001X", "8000000000000", "000000000000U"} {
+		_, err = codec.Encode(id)
+		require.Error(t, err, id)
+	}
+}
+
+func Test_GetTimeRange(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	store.SetIDGenerator("event", fastdb.Snowflake(1))
+
+	start := time.Now()
+
+	first, err := store.Insert("event", []byte("first"))
+	require.NoError(t, err)
+
+	assert.WithinDuration(t, start, fastdb.SnowflakeTime(first), time.Second)
+
+	time.Sleep(5 * time.Millisecond)
+
+	middle := time.Now()
+
+	_, err = store.Insert("event", []byte("second"))
+	require.NoError(t, err)
+
+	records, err := store.GetTimeRange("event", start.Add(-time.Hour), middle)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, []byte("first"), records[0].Data)
+
+	records, err = store.GetTimeRange("event", middle, time.Now().Add(time.Millisecond))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, []byte("second"), records[0].Data)
+
+	minKey, maxKey := fastdb.SnowflakeRange(time.Time{}, time.Time{})
+	assert.Equal(t, 0, minKey)
+	assert.Equal(t, -1, maxKey)
+}
@@ -0,0 +1,219 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const labelsPrefix = "labels:"
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+SetLabels attaches labels (small name-value pairs, like the source or an import batch) to a key,
+independent of its value. The labels replace the ones the key had, nil or empty labels remove them.
+They are persisted, stay when the value changes, and are removed together with the key.
+*/
+func (fdb *DB) SetLabels(bucket string, key int, labels map[string]string) error {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return fmt.Errorf("setLabels error: %w", err)
+	}
+
+	defer unlock()
+
+	if fdb.readOnly() {
+		return fmt.Errorf("setLabels error: %w", ErrReadOnly)
+	}
+
+	_, found := fdb.keys[bucket][key]
+	if !found {
+		return fmt.Errorf("setLabels error: key %d not found in bucket (%s)", key, bucket)
+	}
+
+	name := labelsName(bucket, key)
+
+	if len(labels) == 0 {
+		_, found = fdb.meta[name]
+		if !found {
+			return nil
+		}
+
+		err = fdb.write(persist.DelMetaInstruction(name))
+		if err != nil {
+			return fmt.Errorf("setLabels->write error: %w", err)
+		}
+
+		delete(fdb.meta, name)
+
+		return nil
+	}
+
+	value, err := json.Marshal(labels)
+	if err != nil {
+		return fmt.Errorf("setLabels error: %w", err)
+	}
+
+	return fdb.setMeta(name, string(value))
+}
+
+/*
+GetWithMeta returns one map value from a bucket, together with the labels of the key
+(nil if it has none).
+*/
+func (fdb *DB) GetWithMeta(bucket string, key int) ([]byte, map[string]string, bool) {
+	defer fdb.mu.RLock().RUnlock()
+
+	data, ok := fdb.keys[bucket][key]
+	if !ok || fdb.expired(bucket, key) {
+		return nil, nil, false
+	}
+
+	return data, fdb.labels(bucket, key), true
+}
+
+/*
+SelectByLabel returns the records of a bucket (in Key sorted order) that have the label with the value.
+*/
+func (fdb *DB) SelectByLabel(bucket, name, value string) ([]*Record, error) {
+	defer fdb.mu.RLock().RUnlock()
+
+	keys, err := fdb.keysByLabel(bucket, name, value)
+	if err != nil {
+		return nil, fmt.Errorf("selectByLabel error: %w", err)
+	}
+
+	records := make([]*Record, 0, len(keys))
+	for _, key := range keys {
+		records = append(records, &Record{Key: key, Data: fdb.keys[bucket][key]})
+	}
+
+	return records, nil
+}
+
+/*
+DelByLabel deletes all the records of a bucket that have the label with the value (e.g. a bad import batch),
+with one write to the file. It returns the number of deleted records.
+*/
+func (fdb *DB) DelByLabel(bucket, name, value string) (int, error) {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return 0, fmt.Errorf("delByLabel error: %w", err)
+	}
+
+	defer unlock()
+
+	if fdb.readOnly() {
+		return 0, fmt.Errorf("delByLabel error: %w", ErrReadOnly)
+	}
+
+	keys, err := fdb.keysByLabel(bucket, name, value)
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+
+	err = fdb.delKeys(bucket, keys)
+	if err != nil {
+		return 0, fmt.Errorf("delByLabel->%w", err)
+	}
+
+	return len(keys), nil
+}
+
+/*
+ExportJSONByLabel writes the records of a bucket that have the label with the value,
+as a JSON document in the format of ExportJSON.
+*/
+func (fdb *DB) ExportJSONByLabel(writer io.Writer, bucket, name, value string) error {
+	defer fdb.mu.RLock().RUnlock()
+
+	keys, err := fdb.keysByLabel(bucket, name, value)
+	if err != nil {
+		return fmt.Errorf("exportJSONByLabel error: %w", err)
+	}
+
+	selection := map[string][]int{}
+	if len(keys) > 0 {
+		selection[bucket] = keys
+	}
+
+	err = fdb.writeJSON(writer, selection)
+	if err != nil {
+		return fmt.Errorf("exportJSONByLabel error: %w", err)
+	}
+
+	return nil
+}
+
+/*
+labels returns the labels of a key (nil if it has none).
+The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) labels(bucket string, key int) map[string]string {
+	var labels map[string]string
+
+	value, found := fdb.meta[labelsName(bucket, key)]
+	if found {
+		_ = json.Unmarshal([]byte(value), &labels)
+	}
+
+	return labels
+}
+
+/*
+keysByLabel returns the (sorted) keys of a bucket that have the label with the value.
+Expired keys are left out.
+The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) keysByLabel(bucket, name, value string) ([]int, error) {
+	_, found := fdb.keys[bucket]
+	if !found {
+		return nil, fmt.Errorf("%w (%s)", ErrBucketNotFound, bucket)
+	}
+
+	now := time.Now().UnixNano()
+
+	var keys []int
+
+	for _, key := range fdb.sortedKeys(bucket) {
+		if fdb.expiredAt(bucket, key, now) {
+			continue
+		}
+
+		labelValue, found := fdb.labels(bucket, key)[name]
+		if found && labelValue == value {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+/*
+labelsName returns the meta data name under which the labels of a key are stored.
+*/
+func labelsName(bucket string, key int) string {
+	return labelsPrefix + bucket + "_" + strconv.Itoa(key)
+}
+
+/*
+labelInstructions returns the instructions to remove the labels of a key that is deleted.
+The caller must hold the write lock.
+*/
+func (fdb *DB) labelInstructions(bucket string, key int) []persist.Instruction {
+	_, found := fdb.meta[labelsName(bucket, key)]
+	if found {
+		return []persist.Instruction{persist.DelMetaInstruction(labelsName(bucket, key))}
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package fastdb_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SetLabels(t *testing.T) {
+	path := "data/fastdb_labels.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	err = store.SetLabels("user", 1, map[string]string{"source": "import"})
+	require.Error(t, err)
+
+	for key := 1; key <= 2; key++ {
+		err = store.Set("user", key, []byte(`{"name":"John"}`))
+		require.NoError(t, err)
+
+		err = store.SetLabels("user", key, map[string]string{"source": "import", "batch": "7\nb"})
+		require.NoError(t, err)
+	}
+
+	// a new value keeps the labels
+	err = store.Set("user", 1, []byte(`{"name":"Jane"}`))
+	require.NoError(t, err)
+
+	_, err = store.Del("user", 2)
+	require.NoError(t, err)
+
+	err = store.Set("user", 2, []byte(`{"name":"Jim"}`))
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	value, labels, ok := store.GetWithMeta("user", 1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte(`{"name":"Jane"}`), value)
+	assert.Equal(t, map[string]string{"source": "import", "batch": "7\nb"}, labels)
+
+	// the labels were deleted with the key
+	_, labels, ok = store.GetWithMeta("user", 2)
+	assert.True(t, ok)
+	assert.Nil(t, labels)
+
+	err = store.SetLabels("user", 1, nil)
+	require.NoError(t, err)
+
+	_, labels, _ = store.GetWithMeta("user", 1)
+	assert.Nil(t, labels)
+
+	_, _, ok = store.GetWithMeta("user", 3)
+	assert.False(t, ok)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_SelectByLabel(t *testing.T) {
+	path := "data/fastdb_label_select.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	_, err = store.SelectByLabel("user", "import_batch", "2024-06")
+	require.Error(t, err)
+
+	for key := 1; key <= 6; key++ {
+		err = store.Set("user", key, []byte(`{"name":"John"}`))
+		require.NoError(t, err)
+
+		batch := "2024-05"
+		if key%2 == 0 {
+			batch = "2024-06"
+		}
+
+		err = store.SetLabels("user", key, map[string]string{"import_batch": batch})
+		require.NoError(t, err)
+	}
+
+	records, err := store.SelectByLabel("user", "import_batch", "2024-06")
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, 2, records[0].Key)
+	assert.Equal(t, 6, records[2].Key)
+
+	buf := &bytes.Buffer{}
+	err = store.ExportJSONByLabel(buf, "user", "import_batch", "2024-06")
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"user\": {\n    \"2\": {\"name\":\"John\"},\n    \"4\": {\"name\":\"John\"},\n"+
+		"    \"6\": {\"name\":\"John\"}\n  }\n}\n", buf.String())
+
+	count, err := store.DelByLabel("user", "import_batch", "2024-06")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	count, err = store.DelByLabel("user", "import_batch", "2024-06")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	all, err := store.GetAll("user")
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	records, err = store.SelectByLabel("user", "import_batch", "2024-06")
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	buf.Reset()
+	err = store.ExportJSONByLabel(buf, "user", "import_batch", "2024-06")
+	require.NoError(t, err)
+	assert.Equal(t, "{\n}\n", buf.String())
+}
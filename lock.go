@@ -0,0 +1,82 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"math/bits"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const (
+	// cacheLineSize is the size of two cache lines, because CPUs prefetch the adjacent line too.
+	cacheLineSize = 128
+	// maxLockShards limits the memory (and the work of a writer) on machines with many CPUs.
+	maxLockShards = 64
+)
+
+/*
+rwLock is a read-write lock that spreads the readers over shards, each on its own cache lines,
+so readers on many CPUs don't fight over the reader count of a single sync.RWMutex.
+A writer locks all the shards, which makes locking for a write a bit more expensive.
+*/
+type rwLock struct {
+	shards []lockShard
+	mask   uint32
+}
+
+// lockShard is one shard of the lock, padded so two shards never share a cache line.
+type lockShard struct {
+	sync.RWMutex
+	_ [cacheLineSize - unsafe.Sizeof(sync.RWMutex{})]byte
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+newRWLock returns the lock, with a shard per CPU (GOMAXPROCS, rounded up to a power of 2).
+*/
+func newRWLock() rwLock {
+	count := min(1<<bits.Len(uint(runtime.GOMAXPROCS(0)-1)), maxLockShards)
+
+	return rwLock{shards: make([]lockShard, count), mask: uint32(count - 1)} //nolint:gosec // count is small
+}
+
+/*
+RLock locks a (random) shard for reading, and returns it, so the same shard can be unlocked:
+
+	defer fdb.mu.RLock().RUnlock()
+*/
+func (lock *rwLock) RLock() *sync.RWMutex {
+	index := uint32(0)
+	if lock.mask > 0 {
+		index = rand.Uint32() & lock.mask
+	}
+
+	shard := &lock.shards[index].RWMutex
+	shard.RLock()
+
+	return shard
+}
+
+/*
+Lock locks all the shards for writing, always in the same order.
+*/
+func (lock *rwLock) Lock() {
+	for i := range lock.shards {
+		lock.shards[i].Lock()
+	}
+}
+
+/*
+Unlock unlocks all the shards.
+*/
+func (lock *rwLock) Unlock() {
+	for i := range lock.shards {
+		lock.shards[i].Unlock()
+	}
+}
@@ -0,0 +1,93 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"time"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const (
+	// keySize is the size of a key (an int).
+	keySize = 8
+	// recordOverhead is the estimated size of a record in the map of its bucket, besides its key and value:
+	// the slice header and the share of the map (at an average load).
+	recordOverhead = 48
+	// bucketOverhead is the estimated size of an (empty) bucket: its entry in the map of the buckets and its map.
+	bucketOverhead = 96
+)
+
+// memoryCheckInterval is the pause between two checks of the memory limit, see WithMemoryLimit.
+var memoryCheckInterval = time.Second
+
+// MemoryUsage holds the estimated heap usage of the records, see MemoryUsage.
+type MemoryUsage struct {
+	KeyBytes   int64 // the keys and the bucket names
+	ValueBytes int64 // the capacity of the values
+	MapBytes   int64 // the slice headers and the maps (estimated)
+	TotalBytes int64
+	Limit      int64 // the soft limit of WithMemoryLimit (0 if none)
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithMemoryLimit sets a soft limit (in bytes) for the estimated memory of the records (see MemoryUsage).
+It is checked every second, and warn is called once each time the usage goes over the limit.
+Nothing is refused, it only warns. A nil warn logs a warning with the logger (WithLogger).
+A limit of 0 (the default) means no limit.
+*/
+func WithMemoryLimit(limit int64, warn func(MemoryUsage)) Option {
+	return func(cfg *config) {
+		cfg.memoryLimit = limit
+		cfg.memoryWarn = warn
+	}
+}
+
+/*
+MemoryUsage returns the approximate heap usage of the records: the keys, the values and the maps that hold them.
+It's an estimate for capacity planning, it walks all the records, so don't call it too often.
+*/
+func (fdb *DB) MemoryUsage() MemoryUsage {
+	defer fdb.mu.RLock().RUnlock()
+
+	usage := MemoryUsage{Limit: fdb.cfg.memoryLimit}
+
+	for bucket, records := range fdb.keys {
+		usage.KeyBytes += int64(len(bucket)) + int64(len(records))*keySize
+		usage.MapBytes += bucketOverhead + int64(len(records))*recordOverhead
+
+		for _, value := range records {
+			usage.ValueBytes += int64(cap(value))
+		}
+	}
+
+	usage.TotalBytes = usage.KeyBytes + usage.ValueBytes + usage.MapBytes
+
+	return usage
+}
+
+/*
+checkMemory is the background task that warns when the memory usage went over the limit of WithMemoryLimit.
+*/
+func (fdb *DB) checkMemory() {
+	usage := fdb.MemoryUsage()
+
+	over := usage.TotalBytes > usage.Limit
+	if !over || fdb.overMemory {
+		fdb.overMemory = over
+
+		return
+	}
+
+	fdb.overMemory = true
+
+	if fdb.cfg.memoryWarn != nil {
+		fdb.cfg.memoryWarn(usage)
+
+		return
+	}
+
+	fdb.logger().Warn("memory limit exceeded", "usage", usage.TotalBytes, "limit", usage.Limit)
+}
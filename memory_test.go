@@ -0,0 +1,56 @@
+package fastdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MemoryUsage(t *testing.T) {
+	warnings := make(chan fastdb.MemoryUsage, 10)
+
+	store, err := fastdb.Open(memory, fastdb.WithMemoryLimit(1000, func(usage fastdb.MemoryUsage) {
+		warnings <- usage
+	}))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	usage := store.MemoryUsage()
+	assert.Equal(t, fastdb.MemoryUsage{Limit: 1000}, usage)
+
+	err = store.Set("text", 1, make([]byte, 100))
+	require.NoError(t, err)
+
+	usage = store.MemoryUsage()
+	assert.Equal(t, int64(len("text")+8), usage.KeyBytes)
+	assert.GreaterOrEqual(t, usage.ValueBytes, int64(100))
+	assert.Positive(t, usage.MapBytes)
+	assert.Equal(t, usage.KeyBytes+usage.ValueBytes+usage.MapBytes, usage.TotalBytes)
+	assert.Less(t, usage.TotalBytes, usage.Limit)
+
+	for key := 2; key <= 10; key++ {
+		err = store.Set("text", key, make([]byte, 100))
+		require.NoError(t, err)
+	}
+
+	select {
+	case warning := <-warnings:
+		assert.Greater(t, warning.TotalBytes, warning.Limit)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no warning")
+	}
+
+	// it only warns once, until the usage is below the limit again
+	select {
+	case <-warnings:
+		t.Fatal("warned twice")
+	case <-time.After(1200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,30 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+setMeta stores one meta data value, which is persisted next to the records.
+The caller must hold the write lock.
+*/
+func (fdb *DB) setMeta(name, value string) error {
+	if fdb.readOnly() {
+		return fmt.Errorf("setMeta error: %w", ErrReadOnly)
+	}
+
+	err := fdb.write(persist.MetaInstruction(name, value))
+	if err != nil {
+		return fmt.Errorf("setMeta->write error: %w", err)
+	}
+
+	fdb.meta[name] = value
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+LastModified returns when a record was last stored or deleted (also with its bucket), as it is read from the file,
+and false if that isn't known: the database isn't opened WithTimestamps (at that time),
+the record wasn't changed since a Defrag or a Checkpoint, or it's a database in memory.
+It reads the whole file, so it's meant for tooling, not for every request.
+*/
+func (fdb *DB) LastModified(bucket string, key int) (time.Time, bool, error) {
+	defer fdb.mu.RLock().RUnlock()
+
+	if fdb.closed {
+		return time.Time{}, false, fmt.Errorf("lastModified error: %w", ErrClosed)
+	}
+
+	if fdb.aof == nil {
+		return time.Time{}, false, nil
+	}
+
+	record := bucket + "_" + strconv.Itoa(key)
+
+	var last persist.Instruction
+
+	err := fdb.aof.Replay(func(ins persist.Instruction) error {
+		if changesRecord(ins, bucket, record) {
+			last = ins
+		}
+
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("lastModified error: %w", err)
+	}
+
+	modified, ok := last.Modified()
+
+	return modified, ok, nil
+}
+
+/*
+changesRecord tells if the instruction changes the record (bucket_key) in the bucket.
+*/
+func changesRecord(ins persist.Instruction, bucket, record string) bool {
+	switch ins.Name {
+	case "set", "del":
+		return ins.Key == record
+	case "delbucket":
+		return ins.Key == bucket
+	case "renamebucket":
+		return ins.Key == bucket || string(ins.Value) == bucket
+	case "copybucket":
+		return string(ins.Value) == bucket
+	}
+
+	return false
+}
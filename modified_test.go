@@ -0,0 +1,91 @@
+package fastdb_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LastModified(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "modified.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithTimestamps(), fastdb.WithWriteSequence())
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	before := time.Now()
+
+	err = store.Set("text", 1, []byte("one"))
+	require.NoError(t, err)
+
+	err = store.Set("text", 2, []byte("two"))
+	require.NoError(t, err)
+
+	first, found, err := store.LastModified("text", 1)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.False(t, first.Before(before))
+
+	_, err = store.Del("text", 1)
+	require.NoError(t, err)
+
+	deleted, found, err := store.LastModified("text", 1)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.False(t, deleted.Before(first))
+
+	// a rename changes the records of both buckets
+	err = store.RenameBucket("text", "words")
+	require.NoError(t, err)
+
+	renamed, found, err := store.LastModified("words", 2)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.False(t, renamed.Before(deleted))
+
+	_, found, err = store.LastModified("other", 1)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// the changes carry the time too
+	_, err = store.Changes(0, func(change fastdb.Change) error {
+		assert.False(t, change.Time.IsZero())
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	// a defrag keeps the records without their times
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	_, found, err = store.LastModified("words", 2)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func Test_LastModified_withoutTimestamps(t *testing.T) {
+	store, err := fastdb.Open(filepath.Join(t.TempDir(), "modified.db"), fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("one"))
+	require.NoError(t, err)
+
+	_, found, err := store.LastModified("text", 1)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	_, _, err = store.LastModified("text", 1)
+	require.ErrorIs(t, err, fastdb.ErrClosed)
+}
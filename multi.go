@@ -0,0 +1,206 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+SetMulti stores many map values in a bucket at once.
+It locks only once, and writes all the records to the file in one go (with one sync),
+which is much faster than calling Set for every record.
+Either all the values are stored, or none of them.
+*/
+func (fdb *DB) SetMulti(bucket string, items map[int][]byte) error {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return fmt.Errorf("setMulti error: %w", err)
+	}
+
+	defer unlock()
+
+	if fdb.readOnly() {
+		return fmt.Errorf("setMulti error: %w", ErrReadOnly)
+	}
+
+	sortedKeys := slices.Sorted(maps.Keys(items))
+	instructions := make([]persist.Instruction, 0, len(items))
+	values := make(map[int][]byte, len(items))
+
+	for _, key := range sortedKeys {
+		if key < 0 {
+			return errors.New("setMulti->key should be positive")
+		}
+
+		value, err := fdb.beforeSet(bucket, key, items[key])
+		if err != nil {
+			return fmt.Errorf("setMulti->%w", err)
+		}
+
+		if fdb.cfg.maxValueSize > 0 && len(value) > fdb.cfg.maxValueSize {
+			return fmt.Errorf("setMulti->value size (%d) of key %d exceeds the maximum (%d)",
+				len(value), key, fdb.cfg.maxValueSize)
+		}
+
+		values[key] = value
+		instructions = append(instructions, persist.SetInstruction(bucket, key, value))
+		instructions = append(instructions, fdb.expiryInstructions(bucket, key, 0)...)
+	}
+
+	if len(instructions) == 0 {
+		return nil
+	}
+
+	added := 0
+
+	for key := range values {
+		_, exists := fdb.keys[bucket][key]
+		if !exists {
+			added++
+		}
+	}
+
+	err = fdb.checkLimit(bucket, added)
+	if err != nil {
+		return fmt.Errorf("setMulti->%w", err)
+	}
+
+	err = fdb.write(instructions...)
+	if err != nil {
+		return fmt.Errorf("setMulti->write error: %w", err)
+	}
+
+	_, found := fdb.keys[bucket]
+	if !found {
+		fdb.keys[bucket] = make(map[int][]byte, len(items))
+	}
+
+	for _, key := range sortedKeys {
+		fdb.liveBytes -= fdb.recordSize(bucket, key)
+		fdb.keys[bucket][key] = values[key]
+		fdb.liveBytes += fdb.recordSize(bucket, key)
+		fdb.setExpiry(bucket, key, 0)
+		fdb.notify(Event{Type: EventSet, Bucket: bucket, Key: key, Value: values[key]})
+		fdb.afterSet(bucket, key, values[key])
+	}
+
+	fdb.touch(bucket)
+
+	return nil
+}
+
+/*
+GetMulti returns the map values of many keys from a bucket at once, locking only once.
+Keys that aren't found (or expired) are left out.
+*/
+func (fdb *DB) GetMulti(bucket string, keys []int) (map[int][]byte, error) {
+	defer fdb.mu.RLock().RUnlock()
+
+	memRecords, found := fdb.keys[bucket]
+	if !found {
+		return nil, fmt.Errorf("%w (%s)", ErrBucketNotFound, bucket)
+	}
+
+	now := time.Now().UnixNano()
+	values := make(map[int][]byte, len(keys))
+
+	for _, key := range keys {
+		value, found := memRecords[key]
+		if found && !fdb.expiredAt(bucket, key, now) {
+			values[key] = value
+		}
+	}
+
+	return values, nil
+}
+
+/*
+DelMulti deletes many keys from a bucket at once, with one lock and one write to the file.
+Keys that don't exist are skipped. It returns the number of deleted records.
+Either all the records are deleted, or none of them.
+*/
+func (fdb *DB) DelMulti(bucket string, keys []int) (int, error) {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return 0, fmt.Errorf("delMulti error: %w", err)
+	}
+
+	defer unlock()
+
+	if fdb.readOnly() {
+		return 0, fmt.Errorf("delMulti error: %w", ErrReadOnly)
+	}
+
+	existing := make([]int, 0, len(keys))
+
+	for _, key := range keys {
+		_, found := fdb.keys[bucket][key]
+		if found {
+			existing = append(existing, key)
+		}
+	}
+
+	slices.Sort(existing)
+	existing = slices.Compact(existing)
+
+	if len(existing) == 0 {
+		return 0, nil
+	}
+
+	err = fdb.delKeys(bucket, existing)
+	if err != nil {
+		return 0, fmt.Errorf("delMulti->%w", err)
+	}
+
+	return len(existing), nil
+}
+
+/*
+DeleteRange deletes the keys of a bucket that lie between minKey and maxKey (both included),
+with one lock and one write to the file. It returns the number of deleted records.
+*/
+func (fdb *DB) DeleteRange(bucket string, minKey, maxKey int) (int, error) {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return 0, fmt.Errorf("deleteRange error: %w", err)
+	}
+
+	defer unlock()
+
+	if fdb.readOnly() {
+		return 0, fmt.Errorf("deleteRange error: %w", ErrReadOnly)
+	}
+
+	_, found := fdb.keys[bucket]
+	if !found || minKey > maxKey {
+		return 0, nil
+	}
+
+	sortedKeys := fdb.sortedKeys(bucket)
+	start, _ := slices.BinarySearch(sortedKeys, minKey)
+	end, found := slices.BinarySearch(sortedKeys, maxKey)
+
+	if found {
+		end++
+	}
+
+	if start >= end {
+		return 0, nil
+	}
+
+	err = fdb.delKeys(bucket, sortedKeys[start:end])
+	if err != nil {
+		return 0, fmt.Errorf("deleteRange->%w", err)
+	}
+
+	return end - start, nil
+}
@@ -0,0 +1,189 @@
+package fastdb_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SetMulti_File(t *testing.T) {
+	path := "data/fastdb_setmulti.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(0))
+	require.NoError(t, err)
+
+	total := 1000
+	items := make(map[int][]byte, total)
+
+	for key := 1; key <= total; key++ {
+		items[key] = []byte("value " + strconv.Itoa(key))
+	}
+
+	err = store.SetMulti("text", items)
+	require.NoError(t, err)
+
+	err = store.SetMulti("text", nil)
+	require.NoError(t, err)
+
+	checkFileLines(t, filePath, total*3)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	assert.Equal(t, "1000 record(s) in 1 bucket(s)", store.Info())
+
+	value, ok := store.Get("text", 500)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value 500"), value)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// writing to a closed file fails
+	err = store.SetMulti("text", items)
+	require.Error(t, err)
+}
+
+func Test_SetMulti_errors(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithMaxValueSize(5))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	err = store.SetMulti("text", map[int][]byte{1: []byte("one"), -1: []byte("minus one")})
+	require.Error(t, err)
+
+	err = store.SetMulti("text", map[int][]byte{1: []byte("one"), 2: []byte("too long")})
+	require.Error(t, err)
+
+	// nothing is stored when one of the items is wrong
+	assert.Equal(t, "0 record(s) in 0 bucket(s)", store.Info())
+
+	readOnly, err := fastdb.Open(memory, fastdb.WithReadOnly())
+	require.NoError(t, err)
+
+	err = readOnly.SetMulti("text", map[int][]byte{1: []byte("one")})
+	require.ErrorIs(t, err, fastdb.ErrReadOnly)
+
+	err = readOnly.Close()
+	require.NoError(t, err)
+}
+
+func Test_GetMulti(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	_, err = store.GetMulti("text", []int{1})
+	require.Error(t, err)
+
+	err = store.SetMulti("text", map[int][]byte{1: []byte("one"), 2: []byte("two"), 3: []byte("three")})
+	require.NoError(t, err)
+
+	values, err := store.GetMulti("text", []int{3, 1, 4})
+	require.NoError(t, err)
+	assert.Equal(t, map[int][]byte{1: []byte("one"), 3: []byte("three")}, values)
+
+	values, err = store.GetMulti("text", nil)
+	require.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func Test_DelMulti(t *testing.T) {
+	path := "data/fastdb_delmulti.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	records := map[int][]byte{}
+	for key := 1; key <= 20; key++ {
+		records[key] = []byte("value " + strconv.Itoa(key))
+	}
+
+	err = store.SetMulti("text", records)
+	require.NoError(t, err)
+
+	count, err := store.DelMulti("text", []int{3, 1, 3, 99})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = store.DelMulti("unknown", []int{1})
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = store.DeleteRange("text", 5, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 6, count)
+
+	count, err = store.DeleteRange("text", 0, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = store.DeleteRange("text", 30, 40)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	all, err := store.GetAll("text")
+	require.NoError(t, err)
+	assert.Len(t, all, 10)
+	assert.NotContains(t, all, 1)
+	assert.NotContains(t, all, 7)
+	assert.Contains(t, all, 11)
+
+	count, err = store.DeleteRange("text", 0, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 10, count)
+	assert.Equal(t, "0 record(s) in 0 bucket(s)", store.Info())
+
+	readOnly, err := fastdb.Open(memory, fastdb.WithReadOnly())
+	require.NoError(t, err)
+
+	_, err = readOnly.DelMulti("text", []int{1})
+	require.ErrorIs(t, err, fastdb.ErrReadOnly)
+
+	_, err = readOnly.DeleteRange("text", 1, 2)
+	require.ErrorIs(t, err, fastdb.ErrReadOnly)
+
+	err = readOnly.Close()
+	require.NoError(t, err)
+}
@@ -0,0 +1,53 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"time"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+/*
+Observer receives the measurements of the database, for metrics (see the fastdbmetrics module).
+Every function is optional. They are called while the database is locked (or from the routine
+that syncs the file), so they have to be quick, and they can't use the database.
+*/
+type Observer struct {
+	Get       func(bucket string, found bool) // after every Get
+	Set       func(bucket string, size int)   // after every value that was stored, with its size
+	Del       func(bucket string)             // after every record that was deleted
+	Written   func(bytes int)                 // after every write to the files
+	Synced    func(duration time.Duration)    // after every sync of the files
+	Defragged func(duration time.Duration)    // after every Defrag
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithObserver sets the functions that receive the measurements of the database, see Observer.
+*/
+func WithObserver(observer Observer) Option {
+	return func(cfg *config) {
+		cfg.observer = observer
+	}
+}
+
+/*
+persistObserver returns the observer (and the tracing) for the persister.
+*/
+func (cfg *config) persistObserver() persist.Observer {
+	return persist.Observer{Written: cfg.observer.Written, Synced: cfg.observer.Synced, Trace: cfg.persistTrace()}
+}
+
+/*
+observeGet tells the observer (if any) about a Get.
+The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) observeGet(bucket string, found bool) {
+	if fdb.cfg.observer.Get != nil {
+		fdb.cfg.observer.Get(bucket, found)
+	}
+}
@@ -0,0 +1,77 @@
+package fastdb_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithObserver(t *testing.T) {
+	var (
+		counts  = map[string]int{}
+		written int
+		mu      sync.Mutex
+	)
+
+	count := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		counts[name]++
+	}
+
+	observer := fastdb.Observer{
+		Get: func(_ string, found bool) {
+			if found {
+				count("hit")
+			} else {
+				count("miss")
+			}
+		},
+		Set: func(_ string, _ int) { count("set") },
+		Del: func(_ string) { count("del") },
+		Written: func(bytes int) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			written += bytes
+		},
+		Synced:    func(time.Duration) { count("sync") },
+		Defragged: func(time.Duration) { count("defrag") },
+	}
+
+	store, err := fastdb.Open(t.TempDir()+"/fastdb_observe.db", fastdb.WithSyncTime(0), fastdb.WithObserver(observer))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("value"))
+	require.NoError(t, err)
+	err = store.SetMulti("text", map[int][]byte{2: []byte("value"), 3: []byte("value")})
+	require.NoError(t, err)
+
+	_, _ = store.Get("text", 1)
+	_, _ = store.Get("text", 4)
+
+	_, err = store.Del("text", 1)
+	require.NoError(t, err)
+
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, 3, counts["set"])
+	assert.Equal(t, 1, counts["hit"])
+	assert.Equal(t, 1, counts["miss"])
+	assert.Equal(t, 1, counts["del"])
+	assert.Equal(t, 1, counts["defrag"])
+	assert.GreaterOrEqual(t, counts["sync"], 3)
+	assert.Equal(t, 3*len("set\ntext_1\nvalue\n")+len("del\ntext_1\n"), written)
+}
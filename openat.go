@@ -0,0 +1,63 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+OpenAt opens the database of the file as it was at the given time, to recover the records
+from before a mistake (like a mass delete). The file must be written WithTimestamps:
+the records are read until the first one that was written after upTo
+(records without a time, like those that a Defrag wrote, are always read).
+The database is in memory and read-only, use Snapshot or ExportJSON to save what is recovered.
+It can't go back to before the last Defrag or Checkpoint, and striped files aren't supported.
+*/
+func OpenAt(path string, upTo time.Time, opts ...Option) (*DB, error) {
+	limit := upTo.UnixNano()
+
+	fdb, err := openUntil(path, func(ins persist.Instruction, _ int64) bool {
+		return ins.Time > limit
+	}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("openAt error: %w", err)
+	}
+
+	return fdb, nil
+}
+
+/*
+OpenAtOffset opens the database of the file as it was when the file had the given size
+(like the Offset of a Position), the records from the offset on are left out.
+Like OpenAt, the database is in memory and read-only, and it can't go back to before the last Defrag or Checkpoint.
+*/
+func OpenAtOffset(path string, offset int64, opts ...Option) (*DB, error) {
+	fdb, err := openUntil(path, func(_ persist.Instruction, start int64) bool {
+		return start >= offset
+	}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("openAtOffset error: %w", err)
+	}
+
+	return fdb, nil
+}
+
+/*
+openUntil opens the file as a read-only database in memory, with the records before stop.
+*/
+func openUntil(path string, stop func(ins persist.Instruction, offset int64) bool, opts []Option) (*DB, error) {
+	cfg := newConfig(append(opts, WithReadOnly()))
+
+	keys, meta, err := persist.ReadFileUntil(path, stop, cfg.persistOptions()...)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	return newDB(nil, keys, meta, cfg), nil
+}
@@ -0,0 +1,73 @@
+package fastdb_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenAt(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "openat.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithTimestamps())
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	err = store.SetMulti("user", map[int][]byte{1: []byte("one"), 2: []byte("two"), 3: []byte("three")})
+	require.NoError(t, err)
+
+	pos, err := store.Position()
+	require.NoError(t, err)
+
+	time.Sleep(2 * time.Millisecond)
+
+	beforeBug := time.Now()
+
+	time.Sleep(2 * time.Millisecond)
+
+	_, err = store.DelMulti("user", []int{1, 2, 3})
+	require.NoError(t, err)
+
+	err = store.Set("user", 4, []byte("four"))
+	require.NoError(t, err)
+
+	recovered, err := fastdb.OpenAt(filePath, beforeBug)
+	require.NoError(t, err)
+	assert.Equal(t, 3, recovered.Count("user"))
+	assert.False(t, recovered.Exists("user", 4))
+
+	err = recovered.Set("user", 5, []byte("five"))
+	require.ErrorIs(t, err, fastdb.ErrReadOnly)
+
+	err = recovered.Close()
+	require.NoError(t, err)
+
+	recovered, err = fastdb.OpenAtOffset(filePath, pos.Offset)
+	require.NoError(t, err)
+
+	records, err := recovered.GetAll("user")
+	require.NoError(t, err)
+	assert.Equal(t, map[int][]byte{1: []byte("one"), 2: []byte("two"), 3: []byte("three")}, records)
+
+	err = recovered.Close()
+	require.NoError(t, err)
+
+	// a time after the last write is the current state
+	recovered, err = fastdb.OpenAt(filePath, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, recovered.Count("user"))
+
+	err = recovered.Close()
+	require.NoError(t, err)
+
+	_, err = fastdb.OpenAt(filepath.Join(t.TempDir(), "missing.db"), time.Now())
+	require.Error(t, err)
+}
@@ -0,0 +1,420 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"log/slog"
+	"time"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const (
+	defaultSyncTime = 100
+	smallScanBuffer = 4 * 1024
+)
+
+// Format is the way the records are written to the file.
+type Format = persist.Format
+
+const (
+	// FormatText writes records as newline separated lines (the default),
+	// a value that doesn't fit on one line (like one with a newline) is written as a binary frame.
+	FormatText = persist.FormatText
+	// FormatBinary writes records as length-prefixed frames, values can hold any (binary) data.
+	FormatBinary = persist.FormatBinary
+)
+
+// Option configures the database when it is opened.
+type Option func(*config)
+
+// config holds the settings of the database.
+type config struct {
+	logger             *slog.Logger
+	debugReport        func(Misuse)
+	memoryWarn         func(MemoryUsage)
+	observer           Observer
+	trace              TraceFunc
+	checkpointInterval time.Duration
+	scrubInterval      time.Duration
+	scrubBytes         int64
+	memoryLimit        int64
+	paranoidInterval   time.Duration
+	preallocation      int64
+	dirPerm            fs.FileMode
+	autoDefrag         float64
+	garbageRatio       float64
+	garbageBytes       int64
+	freezeTimeout      time.Duration
+	syncTime           int
+	maxValueSize       int
+	compression        int
+	snapshotLevel      int
+	scanBuffer         int
+	stripes            int
+	segmentSize        int64
+	format             Format
+	readOnly           bool
+	noSortCache        bool
+	writeSequence      bool
+	timestamps         bool
+	quarantine         bool
+	backupFallback     bool
+	recover            bool
+	debug              bool
+	paranoid           bool
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithSyncTime sets the time (in milliseconds) between two syncs of the file to disk.
+A value of 0 means that every write is synced immediately (safest, but slower).
+The default is 100 milliseconds.
+*/
+func WithSyncTime(syncTime int) Option {
+	return func(cfg *config) {
+		cfg.syncTime = syncTime
+	}
+}
+
+/*
+WithReadOnly opens the database in read-only mode, so all writes will fail with ErrReadOnly.
+The file must exist, it's opened under a shared lock (so other read-only opens can share it),
+and it isn't changed in any way: an open that would have to (like WithRecover for a torn write) fails with ErrReadOnly.
+*/
+func WithReadOnly() Option {
+	return func(cfg *config) {
+		cfg.readOnly = true
+	}
+}
+
+/*
+WithMaxValueSize sets the maximum size (in bytes) of a value, bigger values will be refused.
+A value of 0 (the default) means that there is no maximum.
+*/
+func WithMaxValueSize(size int) Option {
+	return func(cfg *config) {
+		cfg.maxValueSize = size
+	}
+}
+
+/*
+WithLogger sets the logger for the internal events of the database: a flush routine that stops on an error,
+the corrupt entries that are skipped (see WithQuarantine), and the start and end of a Defrag with its backup.
+By default nothing is logged.
+*/
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *config) {
+		if logger != nil {
+			cfg.logger = logger
+		}
+	}
+}
+
+/*
+WithQuarantine makes Open skip bad entries in the file, instead of refusing to open it.
+The bad entries are moved to a quarantine file (the path + ".quarantine"),
+and CorruptionReport tells what was skipped.
+*/
+func WithQuarantine() Option {
+	return func(cfg *config) {
+		cfg.quarantine = true
+	}
+}
+
+/*
+WithBackupFallback makes Open restore the backup of the last Defrag (the path + ".bak") when the file
+is corrupt, instead of refusing to open it, for a database that must come back up unattended.
+The corrupt file is moved aside (to the path + ".corrupt"), and RestoredFromBackup tells where it is.
+The records that were written after the last Defrag are lost. Striped files aren't restored.
+*/
+func WithBackupFallback() Option {
+	return func(cfg *config) {
+		cfg.backupFallback = true
+	}
+}
+
+/*
+WithRecover makes Open cut off an entry at the end of the file that can't be read (a write that was torn
+by a crash), instead of refusing to open it. All the complete records are read, the torn entry is saved
+to the path + ".broken", and DiscardedBytes tells how many bytes were cut off.
+*/
+func WithRecover() Option {
+	return func(cfg *config) {
+		cfg.recover = true
+	}
+}
+
+/*
+WithFormat sets the format in which records are written to the file.
+Both formats can always be read, so an existing text file can be opened with FormatBinary,
+after which a Defrag converts all the records.
+*/
+func WithFormat(format Format) Option {
+	return func(cfg *config) {
+		cfg.format = format
+	}
+}
+
+/*
+WithCompression makes the database compress the values (of at least 64 bytes) in the file with gzip,
+at the given level (1 is the fastest, 9 the smallest, -1 the default level).
+A level of 0 (the default) means no compression.
+Compressed values are always written as binary frames. Files are always read with transparent
+decompression, so compression can be switched on or off at any time (a Defrag rewrites all the records).
+*/
+func WithCompression(level int) Option {
+	return func(cfg *config) {
+		cfg.compression = level
+	}
+}
+
+/*
+WithSnapshotCompression makes Snapshot and Checkpoint compress the snapshot with zstd, at the given level
+(1 is the fastest, 22 the smallest, -1 the default level 3). A level of 0 (the default) means no compression.
+The snapshot is compressed (and read) in chunks by a worker per CPU (GOMAXPROCS), so a big snapshot
+doesn't slow down the start. Snapshots are always read with transparent decompression.
+*/
+func WithSnapshotCompression(level int) Option {
+	return func(cfg *config) {
+		cfg.snapshotLevel = level
+	}
+}
+
+/*
+WithCheckpointInterval makes the database write a checkpoint (see Checkpoint) after every interval,
+so the file only holds the changes since the last checkpoint and opening stays quick.
+*/
+func WithCheckpointInterval(interval time.Duration) Option {
+	return func(cfg *config) {
+		cfg.checkpointInterval = interval
+	}
+}
+
+/*
+WithScrub makes the database check (scrub) the next bytesPerInterval bytes of the file after every interval,
+so silent corruption (like a bad disk block) is found early, instead of at the next Open.
+A corruption is logged, sent as an EventCorruption to the watchers of all buckets, and shown by Health.
+It holds the read lock while it reads, so the amount of bytes limits the delay of the writes.
+A value of 0 (the default) means no scrubbing, and it doesn't work with WithStripes.
+*/
+func WithScrub(interval time.Duration, bytesPerInterval int64) Option {
+	return func(cfg *config) {
+		cfg.scrubInterval = interval
+		cfg.scrubBytes = bytesPerInterval
+	}
+}
+
+/*
+WithFreezeTimeout sets how long a write waits while the database is frozen (see Freeze),
+before it fails with ErrFrozen. A value of 0 (the default) means that it waits until Thaw.
+*/
+func WithFreezeTimeout(timeout time.Duration) Option {
+	return func(cfg *config) {
+		cfg.freezeTimeout = timeout
+	}
+}
+
+/*
+WithWriteSequence persists the write sequence (see Position) with every write,
+so it continues where it was after a restart. This makes every write a bit bigger.
+*/
+func WithWriteSequence() Option {
+	return func(cfg *config) {
+		cfg.writeSequence = true
+	}
+}
+
+/*
+WithTimestamps writes the time of the write with every record in the file, so LastModified and Changes
+can tell when a record was changed. It needs a newer format (every record is written as a binary frame),
+which older versions of fastdb can't read. A Defrag and a Checkpoint keep the records without their times.
+*/
+func WithTimestamps() Option {
+	return func(cfg *config) {
+		cfg.timestamps = true
+	}
+}
+
+/*
+WithScanBuffer sets the size (in bytes) of the buffer with which the file is read (default 1 MB).
+A smaller buffer saves memory, it grows (up to 10 MB) when a record needs more.
+*/
+func WithScanBuffer(size int) Option {
+	return func(cfg *config) {
+		cfg.scanBuffer = size
+	}
+}
+
+/*
+WithStripes spreads the records over count files (the file itself, plus the path + ".stripe1" etc.),
+by the hash of the bucket, so the syncs of a write run in parallel on fast disks.
+All the records of one bucket stay in the same file, so they keep their order.
+Opening the database with another count (or without this option) rewrites the files, like a Defrag.
+Snapshot, Position and OpenFromBackup need a single file, so they can't be used with stripes.
+*/
+func WithStripes(count int) Option {
+	return func(cfg *config) {
+		cfg.stripes = count
+	}
+}
+
+/*
+WithSegments writes the file in segments of (about) size bytes: a full file is sealed (renamed to the path + ".seg1" etc.)
+before the next write, and a new one is started. Sealed segments never change, so a backup only has to copy the new ones
+(see Segments), and CompactSegments merges the oldest ones, at a cost that doesn't grow with the database.
+A Defrag still rewrites everything into the file itself. Checkpoint, Snapshot, OpenFromBackup, OpenAt and OpenFollower
+need a single file, so they can't be used with segments, and neither can stripes.
+*/
+func WithSegments(size int64) Option {
+	return func(cfg *config) {
+		cfg.segmentSize = size
+	}
+}
+
+/*
+WithCreateDirs makes Open create the missing parent directories of the file, with the given permissions
+(like 0o700, before the umask). So every test can use its own directory (like one of t.TempDir),
+instead of sharing a data directory that has to exist.
+*/
+func WithCreateDirs(perm fs.FileMode) Option {
+	return func(cfg *config) {
+		cfg.dirPerm = perm
+	}
+}
+
+/*
+WithPreallocation reserves the disk space of the file ahead of the writes, in extents of the given size
+(in bytes), so appending needs less metadata updates and syncs are quicker. The size of the file doesn't change.
+It only has effect on Linux.
+*/
+func WithPreallocation(extent int64) Option {
+	return func(cfg *config) {
+		cfg.preallocation = extent
+	}
+}
+
+/*
+WithAutoDefrag makes the database run a Defrag by itself, as soon as the files are at least 1 MB,
+and the write amplification (see Stats) reached the given level (e.g. 4: the files are 4 times
+as big as the records). A level of 0 (the default) means no automatic Defrag.
+*/
+func WithAutoDefrag(amplification float64) Option {
+	return func(cfg *config) {
+		cfg.autoDefrag = amplification
+	}
+}
+
+/*
+WithAutoDefragGarbage makes the database run a Defrag by itself, as soon as the given part
+of the stores and deletes in the files is dead (superseded or deleted, see Stats.GarbageRatio,
+e.g. 0.5 for half of them) and the files are at least 1 MB, or as soon as a Defrag would free
+the given number of bytes (see Stats.GarbageBytes). A value of 0 turns that limit off (the default).
+*/
+func WithAutoDefragGarbage(ratio float64, bytes int64) Option {
+	return func(cfg *config) {
+		cfg.garbageRatio = ratio
+		cfg.garbageBytes = bytes
+	}
+}
+
+/*
+WithoutSortCache turns off the cache of the sorted keys (see GetAllSorted),
+which saves memory, but makes every sorted read sort the keys again.
+*/
+func WithoutSortCache() Option {
+	return func(cfg *config) {
+		cfg.noSortCache = true
+	}
+}
+
+/*
+WithSmallFootprint is the profile for devices with little memory (like a Raspberry Pi):
+a small read buffer, no sort cache and the best compression of the values.
+Options that come after it, can still change those settings.
+*/
+func WithSmallFootprint() Option {
+	return func(cfg *config) {
+		cfg.scanBuffer = smallScanBuffer
+		cfg.noSortCache = true
+		cfg.compression = gzip.BestCompression
+	}
+}
+
+/*
+persistOptions returns the options for the persister.
+*/
+func (cfg *config) persistOptions() []persist.Option {
+	opts := []persist.Option{
+		persist.WithFormat(cfg.format),
+		persist.WithPreallocation(cfg.preallocation),
+		persist.WithObserver(cfg.persistObserver()),
+		persist.WithLogger(cfg.logger),
+	}
+
+	if cfg.quarantine {
+		opts = append(opts, persist.WithQuarantine())
+	}
+
+	if cfg.backupFallback {
+		opts = append(opts, persist.WithBackupFallback())
+	}
+
+	if cfg.recover {
+		opts = append(opts, persist.WithRecover())
+	}
+
+	// a file of an older version is only migrated (rewritten) when it can be written
+	if cfg.readOnly {
+		opts = append(opts, persist.WithReadOnly(), persist.WithoutMigration())
+	}
+
+	if cfg.timestamps {
+		opts = append(opts, persist.WithTimestamps())
+	}
+
+	if cfg.scanBuffer > 0 {
+		opts = append(opts, persist.WithScanBuffer(cfg.scanBuffer))
+	}
+
+	if cfg.stripes > 0 {
+		opts = append(opts, persist.WithStripes(cfg.stripes))
+	}
+
+	if cfg.segmentSize > 0 {
+		opts = append(opts, persist.WithSegments(cfg.segmentSize))
+	}
+
+	opts = append(opts, persist.WithSnapshotCompression(cfg.snapshotLevel))
+
+	if cfg.compression != 0 {
+		opts = append(opts, persist.WithCompression(cfg.compression))
+	} else {
+		opts = append(opts, persist.WithoutCompression())
+	}
+
+	return opts
+}
+
+/*
+newConfig returns the settings, based on the defaults and the given options.
+*/
+func newConfig(opts []Option) config {
+	cfg := config{
+		syncTime: defaultSyncTime,
+		format:   FormatText,
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
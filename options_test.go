@@ -0,0 +1,412 @@
+package fastdb_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Open_WithReadOnly(t *testing.T) {
+	path := "data/fastdb_readonly.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	// a file that isn't there isn't created
+	_, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithReadOnly())
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("value"))
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithReadOnly())
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	value, ok := store.Get("text", 1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value"), value)
+
+	err = store.Set("text", 2, []byte("value"))
+	require.ErrorIs(t, err, fastdb.ErrReadOnly)
+
+	ok, err = store.Del("text", 1)
+	require.ErrorIs(t, err, fastdb.ErrReadOnly)
+	assert.False(t, ok)
+
+	_, err = store.Sequence("text").Next()
+	require.ErrorIs(t, err, fastdb.ErrReadOnly)
+
+	err = store.Defrag()
+	require.ErrorIs(t, err, fastdb.ErrReadOnly)
+
+	err = store.ReplicateFrom(context.Background(), "localhost:7070", nil)
+	require.ErrorIs(t, err, fastdb.ErrReadOnly)
+
+	// another read-only open shares the file, an open for writing doesn't
+	other, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithReadOnly())
+	require.NoError(t, err)
+
+	_, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.ErrorIs(t, err, fastdb.ErrLocked)
+
+	err = other.Close()
+	require.NoError(t, err)
+
+	assert.Equal(t, "1 record(s) in 1 bucket(s)", store.Info())
+}
+
+func Test_Open_olderFileVersion(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "old.db")
+	lines := "set\ntext_1\nvalue\n"
+
+	err := os.WriteFile(filePath, []byte(lines), 0o600)
+	require.NoError(t, err)
+
+	// read-only, the file isn't migrated
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithReadOnly())
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.Count("text"))
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, lines, string(content))
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.Count("text"))
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	content, err = os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, fileHeader(string(content))+lines, string(content))
+	assert.Contains(t, string(content), "filegeneration")
+
+	err = os.WriteFile(filePath, []byte("meta\nfileversion\n3\n"+lines), 0o600)
+	require.NoError(t, err)
+
+	_, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.ErrorIs(t, err, fastdb.ErrNewerFileVersion)
+}
+
+func Test_Open_WithMaxValueSize(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithMaxValueSize(5))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	err = store.Set("text", 1, []byte("12345"))
+	require.NoError(t, err)
+
+	err = store.Set("text", 2, []byte("123456"))
+	require.Error(t, err)
+
+	_, ok := store.Get("text", 2)
+	assert.False(t, ok)
+}
+
+func Test_Open_WithLogger(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buffer, nil))
+
+	store, err := fastdb.Open(memory, fastdb.WithLogger(logger), fastdb.WithLogger(nil))
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Open_WithFormat(t *testing.T) {
+	path := "data/fastdb_binary.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithFormat(fastdb.FormatBinary))
+	require.NoError(t, err)
+
+	value := []byte("a value\nset\nwith newlines\n\x00and zero bytes")
+
+	err = store.Set("text", 1, value)
+	require.NoError(t, err)
+
+	err = store.Set("text", 2, []byte("second value"))
+	require.NoError(t, err)
+
+	_, err = store.Del("text", 2)
+	require.NoError(t, err)
+
+	snapshot := &bytes.Buffer{}
+	err = store.Snapshot(snapshot)
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	memData, ok := store.Get("text", 1)
+	assert.True(t, ok)
+	assert.Equal(t, value, memData)
+	assert.Equal(t, "1 record(s) in 1 bucket(s)", store.Info())
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.OpenFromBackup(snapshot, filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	memData, ok = store.Get("text", 1)
+	assert.True(t, ok)
+	assert.Equal(t, value, memData)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Open_WithCompression(t *testing.T) {
+	path := "data/fastdb_compress.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithCompression(-1))
+	require.NoError(t, err)
+
+	value := bytes.Repeat([]byte(`{"UUID":"UUIDtext_","Email":"test@example.com"}`), 50)
+
+	for key := 1; key <= 10; key++ {
+		err = store.Set("user", key, value)
+		require.NoError(t, err)
+	}
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	info, err := os.Stat(filePath)
+	require.NoError(t, err)
+	assert.Less(t, info.Size(), int64(len(value)))
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	memData, ok := store.Get("user", 10)
+	assert.True(t, ok)
+	assert.Equal(t, value, memData)
+	assert.Equal(t, "10 record(s) in 1 bucket(s)", store.Info())
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Open_WithSmallFootprint(t *testing.T) {
+	path := "data/fastdb_small.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithSmallFootprint())
+	require.NoError(t, err)
+
+	// bigger than the read buffer, even compressed
+	bigValue := make([]byte, 64*1024)
+	_, err = rand.Read(bigValue)
+	require.NoError(t, err)
+
+	for i := range bigValue {
+		bigValue[i] = 'a' + bigValue[i]%26
+	}
+
+	for _, key := range []int{3, 1, 2} {
+		err = store.Set("bucket", key, bigValue)
+		require.NoError(t, err)
+	}
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithSmallFootprint())
+	require.NoError(t, err)
+
+	records, err := store.GetAllSorted("bucket")
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, 1, records[0].SortField)
+	assert.Equal(t, bigValue, records[2].Data)
+
+	// without the cache, changes are seen as well
+	_, err = store.Del("bucket", 1)
+	require.NoError(t, err)
+
+	records, err = store.GetAllSorted("bucket")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, 2, records[0].SortField)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Open_WithStripes(t *testing.T) {
+	path := "data/fastdb_stripes.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		matches, err := filepath.Glob(filePath + "*")
+		require.NoError(t, err)
+
+		for _, match := range matches {
+			require.NoError(t, os.Remove(match))
+		}
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(0), fastdb.WithStripes(4))
+	require.NoError(t, err)
+
+	for key := 1; key <= 10; key++ {
+		err = store.Set("bucket"+strconv.Itoa(key), key, []byte("value"))
+		require.NoError(t, err)
+	}
+
+	_, err = store.Del("bucket1", 1)
+	require.NoError(t, err)
+
+	err = store.Reconfigure(fastdb.WithStripes(2))
+	require.Error(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithStripes(4))
+	require.NoError(t, err)
+	assert.Equal(t, "9 record(s) in 9 bucket(s)", store.Info())
+
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	memData, ok := store.Get("bucket7", 7)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value"), memData)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Open_WithPreallocation(t *testing.T) {
+	path := "data/fastdb_prealloc.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(0), fastdb.WithPreallocation(1024*1024))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("value"))
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	info, err := os.Stat(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(headerText+"set\ntext_1\nvalue\n")), info.Size())
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.Equal(t, "1 record(s) in 1 bucket(s)", store.Info())
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_WithCreateDirs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "nested", "deeper", "fastdb.db")
+
+	_, err := fastdb.Open(filePath)
+	require.Error(t, err)
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(0), fastdb.WithCreateDirs(0o700))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("value"))
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	info, err := os.Stat(filepath.Dir(filePath))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+	}
+
+	// the same file, with forward slashes
+	store, err = fastdb.Open(filepath.ToSlash(filePath))
+	require.NoError(t, err)
+
+	value, found := store.Get("text", 1)
+	assert.True(t, found)
+	assert.Equal(t, []byte("value"), value)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// a file is in the way of the directory
+	_, err = fastdb.Open(filepath.Join(filePath, "fastdb.db"), fastdb.WithCreateDirs(0o700))
+	require.Error(t, err)
+}
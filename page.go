@@ -0,0 +1,98 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// Page holds one page of records of a bucket, see GetPage.
+type Page struct {
+	Next    string // the token for the next page (see GetPageAfter), empty on the last page
+	Records []*SortRecord
+	Total   int // the number of records in the bucket
+}
+
+var errInvalidToken = errors.New("invalid page token")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+GetPage returns at most limit records of a bucket in Key sorted order, skipping the first offset records.
+Only the records of the page are collected (the sorted keys are cached), so paging through
+a big bucket doesn't copy the whole bucket for every page.
+Use the Next token of the page with GetPageAfter, to get the next page without
+skipping or repeating records when others are added or deleted in the meantime.
+*/
+func (fdb *DB) GetPage(bucket string, offset, limit int) (*Page, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, fmt.Errorf("getPage error: invalid offset (%d) or limit (%d)", offset, limit)
+	}
+
+	return fdb.page(bucket, limit, func([]int) int {
+		return offset
+	})
+}
+
+/*
+GetPageAfter returns at most limit records of a bucket in Key sorted order,
+that come after the page of the token (the Next of that page).
+*/
+func (fdb *DB) GetPageAfter(bucket, token string, limit int) (*Page, error) {
+	after, err := strconv.Atoi(token)
+	if err != nil {
+		return nil, fmt.Errorf("getPageAfter error: %w '%s'", errInvalidToken, token)
+	}
+
+	if limit <= 0 {
+		return nil, fmt.Errorf("getPageAfter error: invalid limit (%d)", limit)
+	}
+
+	return fdb.page(bucket, limit, func(sortedKeys []int) int {
+		start, found := slices.BinarySearch(sortedKeys, after)
+		if found {
+			start++
+		}
+
+		return start
+	})
+}
+
+/*
+page returns at most limit records of a bucket, from the position that start returns in the sorted keys.
+Expired records (that weren't removed yet) are skipped.
+*/
+func (fdb *DB) page(bucket string, limit int, start func(sortedKeys []int) int) (*Page, error) {
+	defer fdb.mu.RLock().RUnlock()
+
+	memRecords, found := fdb.keys[bucket]
+	if !found {
+		return nil, fmt.Errorf("%w (%s)", ErrBucketNotFound, bucket)
+	}
+
+	sortedKeys := fdb.sortedKeys(bucket)
+	now := time.Now().UnixNano()
+	page := &Page{Total: len(sortedKeys), Records: make([]*SortRecord, 0, min(limit, len(sortedKeys)))}
+
+	pos := min(start(sortedKeys), len(sortedKeys))
+	for ; pos < len(sortedKeys) && len(page.Records) < limit; pos++ {
+		key := sortedKeys[pos]
+		if fdb.expiredAt(bucket, key, now) {
+			continue
+		}
+
+		page.Records = append(page.Records, &SortRecord{SortField: key, Data: memRecords[key]})
+	}
+
+	if pos < len(sortedKeys) {
+		page.Next = strconv.Itoa(sortedKeys[pos-1])
+	}
+
+	return page, nil
+}
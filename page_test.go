@@ -0,0 +1,69 @@
+package fastdb_test
+
+import (
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetPage(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	records := map[int][]byte{}
+	for key := 1; key <= 10; key++ {
+		records[key*10] = []byte("value")
+	}
+
+	err = store.SetMulti("text", records)
+	require.NoError(t, err)
+
+	page, err := store.GetPage("text", 2, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int{30, 40, 50}, pageKeys(page))
+	assert.Equal(t, 10, page.Total)
+	assert.Equal(t, "50", page.Next)
+
+	// a record that is added before the token, doesn't shift the next page
+	err = store.Set("text", 15, []byte("value"))
+	require.NoError(t, err)
+
+	page, err = store.GetPageAfter("text", page.Next, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int{60, 70, 80}, pageKeys(page))
+
+	_, err = store.Del("text", 80)
+	require.NoError(t, err)
+
+	page, err = store.GetPageAfter("text", page.Next, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int{90, 100}, pageKeys(page))
+	assert.Empty(t, page.Next)
+
+	page, err = store.GetPage("text", 20, 3)
+	require.NoError(t, err)
+	assert.Empty(t, page.Records)
+
+	_, err = store.GetPage("text", -1, 3)
+	require.Error(t, err)
+
+	_, err = store.GetPageAfter("text", "x", 3)
+	require.Error(t, err)
+
+	_, err = store.GetPage("none", 0, 3)
+	require.Error(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func pageKeys(page *fastdb.Page) []int {
+	keys := make([]int, 0, len(page.Records))
+	for _, record := range page.Records {
+		keys = append(keys, record.SortField.(int)) //nolint:forcetypeassert // always an int
+	}
+
+	return keys
+}
@@ -0,0 +1,157 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// ErrMismatch is returned (or reported) in paranoid mode, when the file doesn't hold what the memory holds.
+var ErrMismatch = errors.New("the file doesn't match the memory")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithParanoid turns on the paranoid mode, for hunting persistence bugs (it's slow):
+  - every write is read back from the file (not from memory) and compared with what was meant to be written,
+    a difference makes the write fail with ErrMismatch (it doesn't work with WithStripes)
+  - after every interval, the whole file is read again and compared with the memory,
+    a difference is logged and sent as an EventCorruption to the watchers of all buckets
+
+An interval of 0 only checks the writes. For a database in memory it does nothing.
+*/
+func WithParanoid(interval time.Duration) Option {
+	return func(cfg *config) {
+		cfg.paranoid = true
+		cfg.paranoidInterval = interval
+	}
+}
+
+/*
+writeBatch writes the instructions to the file, and in paranoid mode it reads them back to compare them.
+*/
+func (fdb *DB) writeBatch(instructions []persist.Instruction) error {
+	if !fdb.cfg.paranoid || fdb.aof.Striped() {
+		return fdb.aof.WriteBatch(instructions) //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	// a full segment is sealed before the offset is taken, so the write is read back from the right file
+	err := fdb.aof.Rotate()
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	offset, err := fdb.aof.Offset()
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	err = fdb.aof.WriteBatch(instructions)
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	written, err := fdb.aof.ReadBack(offset)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMismatch, err)
+	}
+
+	return compareWritten(instructions, written)
+}
+
+/*
+compareWritten compares the instructions with the ones that were read back,
+in which a present is a set without a value.
+*/
+func compareWritten(instructions, written []persist.Instruction) error {
+	if len(written) != len(instructions) {
+		return fmt.Errorf("%w: %d instructions were written, %d were read back", ErrMismatch, len(instructions), len(written))
+	}
+
+	for index, ins := range instructions {
+		if ins.Name == "present" {
+			ins = persist.Instruction{Name: "set", Key: ins.Key, Value: []byte{}}
+		}
+
+		got := written[index]
+		if got.Name != ins.Name || got.Key != ins.Key || !bytes.Equal(got.Value, ins.Value) {
+			return fmt.Errorf("%w: %s %q (%d bytes) was read back as %s %q (%d bytes)",
+				ErrMismatch, ins.Name, ins.Key, len(ins.Value), got.Name, got.Key, len(got.Value))
+		}
+	}
+
+	return nil
+}
+
+/*
+compareFile is the background task that reads the whole file again and compares it with the memory.
+It holds the read lock, so the file isn't written or defragmented meanwhile.
+*/
+func (fdb *DB) compareFile() {
+	readLock := fdb.mu.RLock()
+
+	keys, meta, err := persist.ReadFile(fdb.aof.Path(), fdb.cfg.persistOptions()...)
+	if err == nil {
+		err = compareState(fdb.keys, fdb.meta, keys, meta)
+	} else {
+		err = fmt.Errorf("%w: %w", ErrMismatch, err)
+	}
+
+	logger := fdb.cfg.logger
+	readLock.RUnlock()
+
+	if err != nil {
+		logger.Error("paranoid error", "error", err)
+		fdb.notify(Event{Type: EventCorruption, Err: err})
+	}
+}
+
+/*
+compareState returns the first difference between the records and meta data in memory and in the file.
+*/
+func compareState(keys map[string]map[int][]byte, meta map[string]string,
+	fileKeys map[string]map[int][]byte, fileMeta map[string]string,
+) error {
+	for bucket, records := range keys {
+		for key, value := range records {
+			fileValue, found := fileKeys[bucket][key]
+			if !found {
+				return fmt.Errorf("%w: %s_%d is in memory, but not in the file", ErrMismatch, bucket, key)
+			}
+
+			if !bytes.Equal(value, fileValue) {
+				return fmt.Errorf("%w: %s_%d has another value in the file", ErrMismatch, bucket, key)
+			}
+		}
+	}
+
+	for bucket, records := range fileKeys {
+		for key := range records {
+			if _, found := keys[bucket][key]; !found {
+				return fmt.Errorf("%w: %s_%d is in the file, but not in memory", ErrMismatch, bucket, key)
+			}
+		}
+	}
+
+	for name, value := range meta {
+		fileValue, found := fileMeta[name]
+		if !found || fileValue != value {
+			return fmt.Errorf("%w: meta data %s has another value in the file", ErrMismatch, name)
+		}
+	}
+
+	for name := range fileMeta {
+		if _, found := meta[name]; !found {
+			return fmt.Errorf("%w: meta data %s is in the file, but not in memory", ErrMismatch, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package fastdb_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithParanoid(t *testing.T) {
+	for _, format := range []fastdb.Format{fastdb.FormatText, fastdb.FormatBinary} {
+		path := t.TempDir() + "/fastdb_paranoid.db"
+
+		store, err := fastdb.Open(path, fastdb.WithSyncTime(syncIime), fastdb.WithFormat(format),
+			fastdb.WithParanoid(5*time.Millisecond), fastdb.WithWriteSequence())
+		require.NoError(t, err)
+
+		err = store.Set("text", 1, []byte("a value\nwith a newline\r"))
+		require.NoError(t, err)
+		err = store.Set("text", 2, nil)
+		require.NoError(t, err)
+		err = store.SetWithTTL("text", 3, []byte("expires"), time.Hour)
+		require.NoError(t, err)
+		_, err = store.Del("text", 2)
+		require.NoError(t, err)
+
+		events, stop := store.Watch("")
+
+		time.Sleep(20 * time.Millisecond) // a few comparisons
+
+		select {
+		case event := <-events:
+			t.Fatalf("unexpected event: %v", event)
+		default:
+		}
+
+		// add a record to the file, behind the back of the database (changing a value in memory
+		// would race with the comparison, which reads it in the background)
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+		require.NoError(t, err)
+
+		_, err = file.WriteString("set\ntext_9\nbehind the back\n")
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		select {
+		case event := <-events:
+			assert.Equal(t, fastdb.EventCorruption, event.Type)
+			require.ErrorIs(t, event.Err, fastdb.ErrMismatch)
+		case <-time.After(time.Second):
+			t.Fatal("no corruption event")
+		}
+
+		stop()
+
+		err = store.Close()
+		require.NoError(t, err)
+	}
+}
+
+func Test_WithParanoid_memory(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithParanoid(time.Millisecond))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("value"))
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
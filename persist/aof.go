@@ -4,25 +4,66 @@ package persist
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 /* ---------------------- Constants/Types/Variables ------------------ */
 
-const fileMode = 0o600
+const (
+	fileMode          = 0o600
+	defaultScanBuffer = 1024 * 1024
+	maxScanBuffer     = 10 * 1024 * 1024
+)
 
 // AOF is Append Only File.
 type AOF struct {
-	file     *os.File
-	syncTime int
-	mu       sync.RWMutex
+	file          *os.File
+	lockFile      *os.File   // holds the lock on the file, see acquireLock
+	stripes       []*os.File // the extra files when the instructions are striped
+	segments      []int      // the numbers of the sealed segments, see WithSegments
+	meta          map[string]string
+	allocations   map[*os.File]*allocation // the reserved disk space per file, WithPreallocation
+	report        *CorruptionReport
+	source        string         // name of what is being read, used in error messages
+	corruptFile   string         // where the corrupt file was moved, see WithBackupFallback
+	syncTime      atomic.Int64   // in milliseconds, it can be changed with Reconfigure
+	timestamps    atomic.Bool    // write the time with every instruction, see WithTimestamps
+	flushGen      atomic.Uint64  // the generation of the running flush routine
+	size          atomic.Int64   // the bytes of all the files, see Size
+	records       int            // the record instructions that were read, see RecordsRead
+	version       int            // the version of the format of the file, see FileVersion
+	generation    string         // the identity of the file, see generationMeta
+	bucketRecords map[string]int // the record instructions per bucket, only counted by Verify
+	tornAt        int64          // the offset of the torn write at the end of the file, see WithRecover
+	tornBytes     int64          // the size of the torn write, 0 when there is none
+	scanBuffer    int
+	extent        int64 // the size with which disk space is reserved, 0 means no preallocation
+	segmentSize   int64 // the size at which the file is sealed as a segment, 0 means no segments
+	stripeCount   int
+	snapshotLevel int // the compression level of the checkpoint snapshot, 0 means none
+	format        Format
+	compressor    *compressor
+	observer      Observer // see WithObserver
+	log           *slog.Logger
+	mu            sync.RWMutex
+	allocMu       sync.Mutex
+	quarantine    bool
+	fallback      bool // restore the backup when the file is corrupt, see WithBackupFallback
+	noMigration   bool // don't rewrite a file of an older version, see WithoutMigration
+	repair        bool // cut off a torn write at the end of the file, see WithRecover
+	readOnly      bool // never change the file, see WithReadOnly
 }
 
 var (
@@ -34,12 +75,16 @@ var (
 
 /*
 OpenPersister opens the append only file and reads in all the data.
+The options (like WithQuarantine) change the default behaviour.
 */
-func OpenPersister(path string, syncIime int) (*AOF, map[string]map[int][]byte, error) {
-	aof := &AOF{syncTime: syncIime}
+func OpenPersister(path string, syncIime int, opts ...Option) (*AOF, map[string]map[int][]byte, error) {
+	aof := newAOF(syncIime, opts)
+	if aof.Striped() && aof.segmentSize > 0 {
+		return nil, nil, fmt.Errorf("openPersister error: segments %w", errStriped)
+	}
 
-	filePath := filepath.Clean(path)
-	if filePath != path {
+	filePath, ok := cleanPath(path)
+	if !ok {
 		return nil, nil, fmt.Errorf("openPersister error: invalid path '%s'", path)
 	}
 
@@ -48,20 +93,122 @@ func OpenPersister(path string, syncIime int) (*AOF, map[string]map[int][]byte,
 		return nil, nil, fmt.Errorf("openPersister (%s) error: %w", path, err)
 	}
 
-	keys, err := aof.getData(filePath)
+	aof.lockFile, err = acquireLock(filePath, aof.readOnly)
+	if err != nil {
+		return nil, nil, fmt.Errorf("openPersister (%s) error: %w", path, err)
+	}
+
+	keys, err := aof.load(filePath)
+	if err != nil && aof.fallback && errors.Is(err, ErrCorrupted) {
+		aof, keys, err = aof.loadBackup(filePath, syncIime, opts, err)
+	}
+
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, errors.Join(err, aof.releaseLock())
 	}
 
-	go aof.flush()
+	aof.measure()
+	aof.startFlush()
 
 	return aof, keys, nil
 }
 
 /*
-getData opens a file and reads the data into the memory.
+load reads the file (and the checkpoint and the stripes) into the keys.
+*/
+func (aof *AOF) load(filePath string) (map[string]map[int][]byte, error) {
+	keys, err := aof.readCheckpointOrFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = aof.cutTornTail(filePath)
+	if err != nil {
+		return nil, errors.Join(err, aof.file.Close())
+	}
+
+	rewrite, err := aof.loadStripes(filePath, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	migrate, err := aof.checkVersion(keys)
+	if err != nil {
+		return nil, errors.Join(err, aof.closeFiles())
+	}
+
+	// a checkpoint can't be combined with segments, so it's rewritten into the file
+	_, err = os.Stat(filePath + snapshotExtension)
+	if err == nil && aof.segmentSize > 0 {
+		rewrite = true
+	}
+
+	if rewrite || migrate {
+		err = aof.checkWritable("openPersister->rewrite")
+		if err != nil {
+			return nil, errors.Join(err, aof.closeFiles())
+		}
+
+		err = aof.Defrag(keys)
+		if err != nil {
+			return nil, fmt.Errorf("openPersister->rewrite error: %w", err)
+		}
+	}
+
+	err = aof.writeQuarantine(filePath)
+	if err != nil {
+		return nil, errors.Join(err, aof.file.Close())
+	}
+
+	return keys, nil
+}
+
+/*
+newAOF creates the persister with the given options.
+*/
+func newAOF(syncIime int, opts []Option) *AOF {
+	aof := &AOF{
+		meta:        map[string]string{},
+		format:      FormatText,
+		scanBuffer:  defaultScanBuffer,
+		stripeCount: 1,
+		log:         discardLogger,
+	}
+	aof.syncTime.Store(int64(syncIime))
+
+	for _, opt := range opts {
+		opt(aof)
+	}
+
+	return aof
+}
+
+/*
+cleanPath returns the cleaned path, and false if cleaning changes more than the separators
+(like a path with ".."). So "C:/dir/file.db" is accepted on Windows, just like "C:\dir\file.db".
+*/
+func cleanPath(path string) (string, bool) {
+	filePath := filepath.Clean(path)
+
+	return filePath, filepath.ToSlash(filePath) == filepath.ToSlash(path)
+}
+
+/*
+WithScanBuffer sets the size (in bytes) of the buffer with which the file is read (default 1 MB).
+A smaller buffer saves memory, it grows (up to 10 MB) when a record needs more.
+*/
+func WithScanBuffer(size int) Option {
+	return func(aof *AOF) {
+		if size > 0 {
+			aof.scanBuffer = size
+		}
+	}
+}
+
+/*
+getData opens a file and reads the data into the keys (on top of what is in them already).
 */
-func (aof *AOF) getData(path string) (map[string]map[int][]byte, error) {
+func (aof *AOF) getData(path string, keys map[string]map[int][]byte) (map[string]map[int][]byte, error) {
 	aof.mu.Lock()
 	defer aof.mu.Unlock()
 
@@ -70,14 +217,15 @@ func (aof *AOF) getData(path string) (map[string]map[int][]byte, error) {
 		err  error
 	)
 
-	file, err = os.OpenFile(path, os.O_RDWR|osCreate, fileMode) //nolint:gosec // path is clean
+	file, err = os.OpenFile(path, aof.openFlags(), fileMode) //nolint:gosec // path is clean
 	if err != nil {
 		return nil, fmt.Errorf("openfile (%s) error: %w", path, err)
 	}
 
 	aof.file = file
+	aof.source = file.Name()
 
-	return aof.readDataFromFile(path)
+	return aof.readDataFromFile(path, keys)
 }
 
 /*
@@ -86,8 +234,8 @@ Returns the keys map and an error if something went wrong.
 It also closes the file if there was an error, and returns
 an error with the close error if there is one.
 */
-func (aof *AOF) readDataFromFile(path string) (map[string]map[int][]byte, error) {
-	keys, err := aof.fileReader()
+func (aof *AOF) readDataFromFile(path string, keys map[string]map[int][]byte) (map[string]map[int][]byte, error) {
+	keys, err := aof.fileReader(keys)
 	if err != nil {
 		closeErr := aof.file.Close()
 		if closeErr != nil {
@@ -103,27 +251,84 @@ func (aof *AOF) readDataFromFile(path string) (map[string]map[int][]byte, error)
 /*
 fileReader reads the file and fills the keys.
 */
-func (aof *AOF) fileReader() (map[string]map[int][]byte, error) {
+func (aof *AOF) fileReader(keys map[string]map[int][]byte) (map[string]map[int][]byte, error) {
+	scanner, recorder := aof.newScanner(aof.file)
+	recorder.repair = aof.repair
+
+	err := aof.readInstructions(scanner, recorder, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+/*
+newScanner returns a line scanner, with a buffer big enough for large values,
+and the recorder of the offsets (and the lines, WithQuarantine) it scans.
+The buffer starts at the scan buffer size, and grows when a record needs more.
+*/
+func (aof *AOF) newScanner(reader io.Reader) (*bufio.Scanner, *lineRecorder) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, aof.scanBuffer), max(aof.scanBuffer, maxScanBuffer))
+
+	return scanner, recordLines(scanner, aof.quarantine)
+}
+
+/*
+readInstructions reads all the instructions from the scanner and fills the keys.
+*/
+func (aof *AOF) readInstructions(scanner *bufio.Scanner, recorder *lineRecorder, keys map[string]map[int][]byte) error {
 	var (
-		count int
-		err   error
+		count   int
+		err     error
+		corrupt *CorruptionError
 	)
 
-	keys := make(map[string]map[int][]byte, 1)
-	scanner := bufio.NewScanner(aof.file)
-	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024) // Increase buffer size
+	// the lines that were scanned before (like the header of a snapshot) aren't part of an entry
+	recorder.lines = nil
 
 	for scanner.Scan() {
 		count++
+		start := count
+		offset := recorder.start
 		instruction := scanner.Text()
 
 		count, err = aof.processInstruction(instruction, scanner, count, keys)
 		if err != nil {
-			return nil, err
+			if errors.As(err, &corrupt) {
+				corrupt.Offset = offset
+			}
+
+			if recorder.repair && recorder.atEnd {
+				aof.tornAt = offset
+				aof.tornBytes = recorder.offset - offset
+
+				break
+			}
+
+			if !aof.quarantine {
+				return err
+			}
+
+			// skip the bad entry and continue with the next line
+			count = start + len(recorder.lines) - 1
+			aof.addCorruption(start, recorder.lines, err)
 		}
+
+		recorder.lines = nil
 	}
 
-	return keys, nil
+	// an entry that doesn't fit in the buffer (like a frame with a wrong length) ends the scan
+	err = scanner.Err()
+	if err != nil {
+		corrupt = aof.corrupted(count+1, "an entry that can't be read (too big, or a wrong frame length)", err)
+		corrupt.Offset = recorder.offset
+
+		return corrupt
+	}
+
+	return nil
 }
 
 /*
@@ -135,13 +340,31 @@ func (aof *AOF) processInstruction(
 	count int,
 	keys map[string]map[int][]byte,
 ) (int, error) {
+	if len(instruction) > 0 && instruction[0] == frameMarker {
+		return aof.handleFrame(instruction, count, keys)
+	}
+
+	if instruction == "set" || instruction == "present" || instruction == "del" {
+		aof.records++
+	}
+
 	switch instruction {
 	case "set":
 		return aof.handleSetInstruction(scanner, count, keys)
+	case "present":
+		return aof.handlePresentInstruction(scanner, count, keys)
 	case "del":
 		return aof.handleDelInstruction(scanner, count, keys)
+	case "delbucket":
+		return aof.handleDelBucketInstruction(scanner, count, keys)
+	case "renamebucket", "copybucket":
+		return aof.handleCopyBucketInstruction(instruction, scanner, count, keys)
+	case "meta":
+		return aof.handleMetaInstruction(scanner, count)
+	case "delmeta":
+		return aof.handleDelMetaInstruction(scanner, count)
 	default:
-		return count, fmt.Errorf("file (%s) has wrong instruction format '%s' on line: %d", aof.file.Name(), instruction, count)
+		return count, aof.corrupted(count, fmt.Sprintf("wrong instruction format '%s'", instruction), nil)
 	}
 }
 
@@ -152,18 +375,18 @@ func (aof *AOF) handleSetInstruction(scanner *bufio.Scanner, inpCount int, keys
 	count := inpCount
 
 	if !scanner.Scan() {
-		return count, fmt.Errorf("file (%s) has incomplete set instruction on line: %d", aof.file.Name(), count)
+		return count, aof.corrupted(count, "incomplete set instruction", nil)
 	}
 
 	key := scanner.Text()
 
 	if !scanner.Scan() {
-		return count, fmt.Errorf("file (%s) has incomplete set instruction on line: %d", aof.file.Name(), count)
+		return count, aof.corrupted(count, "incomplete set instruction", nil)
 	}
 
 	line := scanner.Text()
 
-	err := aof.setBucketAndKey(key, line, keys)
+	err := aof.setBucketAndKey(key, line, count, keys)
 	if err != nil {
 		return count, err
 	}
@@ -173,6 +396,26 @@ func (aof *AOF) handleSetInstruction(scanner *bufio.Scanner, inpCount int, keys
 	return count, nil
 }
 
+/*
+handlePresentInstruction handles the present instruction (a set without a value line).
+*/
+func (aof *AOF) handlePresentInstruction(scanner *bufio.Scanner, inpCount int, keys map[string]map[int][]byte) (int, error) {
+	count := inpCount
+
+	if !scanner.Scan() {
+		return count, aof.corrupted(count, "incomplete present instruction", nil)
+	}
+
+	err := aof.setBucketAndKey(scanner.Text(), "", count, keys)
+	if err != nil {
+		return count, err
+	}
+
+	count++
+
+	return count, nil
+}
+
 /*
 handleDelInstruction handles the del instruction.
 */
@@ -180,32 +423,162 @@ func (aof *AOF) handleDelInstruction(scanner *bufio.Scanner, inpCount int, keys
 	count := inpCount
 
 	if !scanner.Scan() {
-		return count, fmt.Errorf("file (%s) has incomplete del instruction on line: %d", aof.file.Name(), count)
+		return count, aof.corrupted(count, "incomplete del instruction", nil)
 	}
 
 	key := scanner.Text()
 
-	bucket, keyID, ok := aof.parseBucketAndKey(key)
+	bucket, keyID, ok := parseBucketAndKey(key)
 	if !ok {
-		return count, fmt.Errorf("file (%s) has wrong key format: '%s' on line: %d", aof.file.Name(), key, count)
+		return count, aof.corrupted(count, fmt.Sprintf("wrong key format: '%s'", key), nil)
 	}
 
+	aof.countRecord(bucket)
+	deleteKey(keys, bucket, keyID)
+
+	count++
+
+	return count, nil
+}
+
+/*
+deleteKey deletes a key from its bucket, and the bucket when it has no keys left,
+the same way as a Del does in memory.
+*/
+func deleteKey(keys map[string]map[int][]byte, bucket string, keyID int) {
 	delete(keys[bucket], keyID)
 
+	if records, found := keys[bucket]; found && len(records) == 0 {
+		delete(keys, bucket)
+	}
+}
+
+/*
+handleDelBucketInstruction handles the delbucket instruction.
+*/
+func (aof *AOF) handleDelBucketInstruction(scanner *bufio.Scanner, inpCount int, keys map[string]map[int][]byte) (int, error) {
+	count := inpCount
+
+	if !scanner.Scan() {
+		return count, aof.corrupted(count, "incomplete delbucket instruction", nil)
+	}
+
+	delete(keys, scanner.Text())
+
+	count++
+
+	return count, nil
+}
+
+/*
+handleCopyBucketInstruction handles the renamebucket and copybucket instructions.
+*/
+func (aof *AOF) handleCopyBucketInstruction(
+	instruction string,
+	scanner *bufio.Scanner,
+	inpCount int,
+	keys map[string]map[int][]byte,
+) (int, error) {
+	count := inpCount
+
+	if !scanner.Scan() {
+		return count, aof.corrupted(count, fmt.Sprintf("incomplete %s instruction", instruction), nil)
+	}
+
+	src := scanner.Text()
+
+	if !scanner.Scan() {
+		return count, aof.corrupted(count, fmt.Sprintf("incomplete %s instruction", instruction), nil)
+	}
+
+	copyBucket(keys, src, scanner.Text(), instruction == "renamebucket")
+
+	count += 2
+
+	return count, nil
+}
+
+/*
+copyBucket copies the records of the source bucket to the target bucket (replacing what was there),
+and removes the source bucket if it's a move. The values are shared, because they are never changed.
+*/
+func copyBucket(keys map[string]map[int][]byte, src, dst string, move bool) {
+	records, found := keys[src]
+	if !found || src == dst {
+		return
+	}
+
+	if move {
+		keys[dst] = records
+		delete(keys, src)
+
+		return
+	}
+
+	keys[dst] = maps.Clone(records)
+}
+
+/*
+handleMetaInstruction handles the meta instruction.
+*/
+func (aof *AOF) handleMetaInstruction(scanner *bufio.Scanner, inpCount int) (int, error) {
+	count := inpCount
+
+	if !scanner.Scan() {
+		return count, aof.corrupted(count, "incomplete meta instruction", nil)
+	}
+
+	name := scanner.Text()
+
+	if !scanner.Scan() {
+		return count, aof.corrupted(count, "incomplete meta instruction", nil)
+	}
+
+	if !isHeaderMeta(name) {
+		aof.meta[name] = scanner.Text()
+	}
+
+	count += 2
+
+	return count, nil
+}
+
+/*
+handleDelMetaInstruction handles the delmeta instruction.
+*/
+func (aof *AOF) handleDelMetaInstruction(scanner *bufio.Scanner, inpCount int) (int, error) {
+	count := inpCount
+
+	if !scanner.Scan() {
+		return count, aof.corrupted(count, "incomplete delmeta instruction", nil)
+	}
+
+	delete(aof.meta, scanner.Text())
+
 	count++
 
 	return count, nil
 }
 
 /*
-setBucketAndKey sets a key-value pair in a bucket.
+Meta returns the meta data (name-value pairs) that was read from the file.
+The map is owned by the caller from now on, and will be written by Defrag.
+*/
+func (aof *AOF) Meta() map[string]string {
+	return aof.meta
+}
+
+/*
+setBucketAndKey sets a key-value pair in a bucket (the count is the line of the instruction, for the error).
 */
-func (aof *AOF) setBucketAndKey(key, value string, keys map[string]map[int][]byte) error {
-	bucket, keyID, ok := aof.parseBucketAndKey(key)
+func (aof *AOF) setBucketAndKey(key, value string, count int, keys map[string]map[int][]byte) error {
+	bucket, keyID, ok := parseBucketAndKey(key)
 	if !ok {
-		return fmt.Errorf("file (%s) has wrong key format: %s", aof.file.Name(), key)
+		return aof.corrupted(count, fmt.Sprintf("wrong key format: '%s'", key), nil)
 	}
 
+	aof.countRecord(bucket)
+
 	if _, found := keys[bucket]; !found {
 		keys[bucket] = map[int][]byte{}
 	}
@@ -220,7 +593,7 @@ parseBucketAndKey parses a key in the format "bucket_keyid" and returns
 the bucket name, key id and true if the key is valid.
 Otherwise it returns empty string, 0 and false.
 */
-func (*AOF) parseBucketAndKey(key string) (string, int, bool) {
+func parseBucketAndKey(key string) (string, int, bool) {
 	uPos := strings.LastIndex(key, "_")
 	if uPos < 0 {
 		return "", 0, false
@@ -238,30 +611,98 @@ func (*AOF) parseBucketAndKey(key string) (string, int, bool) {
 
 /*
 Write writes to the file.
+The lines can't be spread over striped files, use WriteBatch for those.
 */
 func (aof *AOF) Write(lines string) error {
-	_, err := aof.file.WriteString(lines)
-	if err == nil && aof.syncTime == 0 {
-		err = aof.file.Sync()
+	err := aof.checkWritable("write")
+	if err != nil {
+		return err
+	}
+
+	if aof.Striped() {
+		return fmt.Errorf("write error: %w", errStriped)
+	}
+
+	err = aof.Rotate()
+	if err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+
+	end := aof.startTrace("write")
+
+	aof.reserve(aof.file, len(lines))
+
+	written, err := aof.file.WriteString(lines)
+	aof.size.Add(int64(written))
+	aof.written(written)
+
+	if err == nil && aof.syncTime.Load() == 0 {
+		start := time.Now()
+
+		err = syncFile(aof.file)
+		if err == nil {
+			aof.synced(start)
+		}
 	}
 
 	if err != nil {
 		err = fmt.Errorf("write error: %#v %w", aof.file.Name(), err)
 	}
 
+	end(err)
+
 	return err
 }
 
 /*
-Flush starts a goroutine to sync the database.
-The routine will stop if the file is closed
+Sync flushes the data that was written to disk.
+*/
+func (aof *AOF) Sync() (err error) {
+	// nothing is written to a read-only file
+	if aof.readOnly {
+		return nil
+	}
+
+	start := time.Now()
+	end := aof.startTrace("sync")
+
+	defer func() {
+		end(err)
+	}()
+
+	err = syncFile(aof.file)
+	if err != nil {
+		return fmt.Errorf("sync (%s) error: %w", aof.file.Name(), err)
+	}
+
+	err = aof.syncStripes()
+	if err != nil {
+		return fmt.Errorf("sync (%s) error: %w", aof.file.Name(), err)
+	}
+
+	aof.synced(start)
+
+	return nil
+}
+
+/*
+startFlush starts a new flush routine, which replaces the one that was running.
+*/
+func (aof *AOF) startFlush() {
+	go aof.flush(aof.flushGen.Add(1))
+}
+
+/*
+Flush is the goroutine that syncs the database.
+The routine will stop if the file is closed, the sync time becomes 0,
+or a newer routine was started.
 */
-func (aof *AOF) flush() {
-	if aof.syncTime == 0 {
+func (aof *AOF) flush(generation uint64) {
+	flushPause := time.Millisecond * time.Duration(aof.syncTime.Load())
+	if flushPause == 0 {
 		return
 	}
 
-	flushPause := time.Millisecond * time.Duration(aof.syncTime)
 	tick := time.NewTicker(flushPause)
 
 	defer func() {
@@ -269,10 +710,52 @@ func (aof *AOF) flush() {
 	}()
 
 	for range tick.C {
-		err := aof.file.Sync()
+		if aof.flushGen.Load() != generation {
+			break
+		}
+
+		err := aof.Sync()
 		if err != nil {
+			// a file that was closed meanwhile (by Close) is no failure
+			if !errors.Is(err, os.ErrClosed) {
+				aof.logger().Error("flush stopped", "file", aof.file.Name(), "error", err)
+			}
+
 			break
 		}
+
+		pause := time.Millisecond * time.Duration(aof.syncTime.Load())
+		if pause == 0 {
+			break
+		}
+
+		if pause != flushPause {
+			flushPause = pause
+			tick.Reset(flushPause)
+		}
+	}
+}
+
+/*
+Reconfigure changes the sync time (in milliseconds) and applies the options on the open file.
+Options that only matter while reading the file (like WithQuarantine) have no effect anymore,
+and the number of stripes can't be changed.
+*/
+func (aof *AOF) Reconfigure(syncIime int, opts ...Option) {
+	aof.mu.Lock()
+	defer aof.mu.Unlock()
+
+	stripeCount := aof.stripeCount
+
+	for _, opt := range opts {
+		opt(aof)
+	}
+
+	aof.stripeCount = stripeCount
+
+	if aof.syncTime.Swap(int64(syncIime)) == 0 && syncIime > 0 {
+		// there is no flush routine running
+		aof.startFlush()
 	}
 }
 
@@ -281,11 +764,20 @@ Defrag will only store the last key information, so all the history is lost
 This can mean a smaller filesize, which is quicker to read.
 */
 func (aof *AOF) Defrag(keys map[string]map[int][]byte) (err error) {
+	err = aof.checkWritable("defrag")
+	if err != nil {
+		return err
+	}
+
 	lock.Lock()
 	defer lock.Unlock()
 
-	// close current file (to flush the last parts)
-	err = aof.Close()
+	start := time.Now()
+	before := aof.Size()
+	aof.logger().Info("defrag started", "file", aof.file.Name(), "bytes", before)
+
+	// close current file (to flush the last parts), the lock is kept
+	err = aof.closeFiles()
 	if err != nil {
 		return fmt.Errorf("defrag->close error: %w", err)
 	}
@@ -300,25 +792,76 @@ func (aof *AOF) Defrag(keys map[string]map[int][]byte) (err error) {
 		return fmt.Errorf("defrag->writeFile error: %w", err)
 	}
 
+	// the file is in the current format now
+	aof.version = FileVersion
+
+	// the file holds all the data now, so the stripes start empty
+	err = aof.resetStripes(aof.file.Name())
+	if err != nil {
+		return fmt.Errorf("defrag error: %w", err)
+	}
+
+	// the file holds all the data now, so the sealed segments aren't needed anymore
+	err = aof.resetSegments(aof.file.Name())
+	if err != nil {
+		return fmt.Errorf("defrag error: %w", err)
+	}
+
+	// the file holds all the data now, so a snapshot of a checkpoint isn't needed anymore
+	err = removeCheckpoint(aof.file.Name())
+	if err != nil {
+		return fmt.Errorf("defrag error: %w", err)
+	}
+
+	aof.measure()
+	aof.logger().Info("defrag finished", "file", aof.file.Name(), "bytes", aof.Size(), "before", before,
+		"duration", time.Since(start))
+
 	return nil
 }
 
 /*
-Close stops the flush routine, flushes the last data to disk and closes the file.
+Close stops the flush routine, flushes the last data to disk, closes the file (and the stripes)
+and releases the lock on the file.
 */
 func (aof *AOF) Close() error {
-	err := aof.file.Sync()
+	err := aof.closeFiles()
 	if err != nil {
-		return fmt.Errorf("close->Sync error: %s %w", aof.file.Name(), err)
+		return err
 	}
 
-	err = aof.file.Close()
+	return aof.releaseLock()
+}
+
+/*
+closeFiles stops the flush routine, flushes the last data to disk and closes the file (and the stripes).
+*/
+func (aof *AOF) closeFiles() error {
+	// the flush routine stops at its next tick, without syncing
+	aof.flushGen.Add(1)
+
+	// a read-only file has nothing to flush
+	if !aof.readOnly {
+		err := aof.file.Sync()
+		if err != nil {
+			return fmt.Errorf("close->Sync error: %s %w", aof.file.Name(), err)
+		}
+	}
+
+	err := aof.file.Close()
 	if err != nil {
 		return fmt.Errorf("close error: %s %w", aof.file.Name(), err)
 	}
 
+	err = aof.closeStripes()
+	if err != nil {
+		return fmt.Errorf("close error: %s %w", aof.file.Name(), err)
+	}
+
+	aof.resetAllocations()
+
 	// to be sure that the flushing is stopped
-	flushPause := time.Millisecond * time.Duration(aof.syncTime)
+	flushPause := time.Millisecond * time.Duration(aof.syncTime.Load())
 	time.Sleep(flushPause)
 
 	return nil
@@ -357,6 +900,8 @@ func (aof *AOF) makeBackup() (err error) {
 		return fmt.Errorf("defrag->copy error: %w", err)
 	}
 
+	aof.logger().Info("backup created", "file", path+".bak")
+
 	return nil
 }
 
@@ -371,22 +916,63 @@ func (aof *AOF) writeFile(keys map[string]map[int][]byte) error {
 		return fmt.Errorf("writeFile->remove (%#v) error: %w", path, err)
 	}
 
-	_, err = aof.getData(path)
+	_, err = aof.getData(path, map[string]map[int][]byte{})
 	if err != nil {
 		return fmt.Errorf("writeFile->getData error: %w", err)
 	}
 
 	// write keys to file
-	go aof.flush()
+	aof.startFlush()
+
+	writer := bufio.NewWriter(aof.file)
+
+	// a rewritten file is another file for a snapshot
+	aof.generation, err = writeFileRecords(writer, keys, aof.meta, aof.format, aof.compressor)
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	if err == nil && aof.syncTime.Load() == 0 {
+		err = syncFile(aof.file)
+	}
+
+	if err != nil {
+		return fmt.Errorf("write error:%w", err)
+	}
+
+	return nil
+}
+
+/*
+writeRecords writes the meta data and the keys as instructions in the given format,
+with the values compressed if there is a compressor.
+Everything is written in sorted order, so the same data always results in the same file.
+*/
+func writeRecords(
+	writer io.Writer,
+	keys map[string]map[int][]byte,
+	meta map[string]string,
+	format Format,
+	comp *compressor,
+) error {
+	var buf []byte
+
+	for _, name := range slices.Sorted(maps.Keys(meta)) {
+		buf = MetaInstruction(name, meta[name]).appendTo(buf[:0], format)
+
+		_, err := writer.Write(buf)
+		if err != nil {
+			return err //nolint:wrapcheck // it is wrapped by the caller
+		}
+	}
 
-	for bucket := range keys {
-		startLine := "set\n" + bucket + "_"
-		for key := range keys[bucket] {
-			lines := startLine + strconv.Itoa(key) + "\n" + string(keys[bucket][key]) + "\n"
+	for _, bucket := range slices.Sorted(maps.Keys(keys)) {
+		for _, key := range slices.Sorted(maps.Keys(keys[bucket])) {
+			buf = comp.compress(SetInstruction(bucket, key, keys[bucket][key])).appendTo(buf[:0], format)
 
-			err = aof.Write(lines)
+			_, err := writer.Write(buf)
 			if err != nil {
-				return fmt.Errorf("write error:%w", err)
+				return err //nolint:wrapcheck // it is wrapped by the caller
 			}
 		}
 	}
@@ -47,3 +47,72 @@ func Test_OpenPersister_closeError(t *testing.T) {
 	err = aof.Close()
 	require.Error(t, err)
 }
+
+func Test_syncFile(t *testing.T) {
+	path := "../data/fast_persister_sync.db"
+
+	defer func() {
+		err := os.Remove(filepath.Clean(path))
+		require.NoError(t, err)
+	}()
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, fileMode)
+	require.NoError(t, err)
+
+	_, err = file.WriteString("set\ntext_1\nvalue\n")
+	require.NoError(t, err)
+
+	err = syncFile(file)
+	require.NoError(t, err)
+
+	err = file.Close()
+	require.NoError(t, err)
+
+	err = syncFile(file)
+	require.Error(t, err)
+}
+
+func Test_cleanPath(t *testing.T) {
+	path, ok := cleanPath(filepath.Join("..", "data", "fast.db"))
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join("..", "data", "fast.db"), path)
+
+	// forward slashes are fine on every OS
+	_, ok = cleanPath("../data/fast.db")
+	assert.True(t, ok)
+
+	_, ok = cleanPath("../data/../fast.db")
+	assert.False(t, ok)
+
+	_, ok = cleanPath("../data//fast.db")
+	assert.False(t, ok)
+}
+
+func Test_OpenPersister_frameTooBig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "too_big.db")
+
+	// a frame bigger than the maximum of the scanner, which WriteBatch refuses to write
+	var data []byte
+	data = SetInstruction("bucket", 1, []byte("one")).appendTo(data, FormatBinary)
+	data = SetInstruction("bucket", 2, make([]byte, maxScanBuffer+1)).appendTo(data, FormatBinary)
+	data = SetInstruction("bucket", 3, []byte("three")).appendTo(data, FormatBinary)
+
+	err := os.WriteFile(path, data, fileMode)
+	require.NoError(t, err)
+
+	_, _, err = OpenPersister(path, 0, WithFormat(FormatBinary))
+	require.ErrorIs(t, err, ErrCorrupted)
+
+	var corrupt *CorruptionError
+	require.ErrorAs(t, err, &corrupt)
+	assert.Positive(t, corrupt.Offset)
+
+	aof, _, err := OpenPersister(filepath.Join(t.TempDir(), "refused.db"), 0, WithFormat(FormatBinary))
+	require.NoError(t, err)
+
+	err = aof.WriteBatch([]Instruction{SetInstruction("bucket", 2, make([]byte, maxScanBuffer+1))})
+	require.ErrorIs(t, err, ErrTooBig)
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
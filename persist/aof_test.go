@@ -360,6 +360,62 @@ func Test_OpenPersister_withWrongInstruction(t *testing.T) {
 	}()
 }
 
+func Test_OpenPersister_withMeta(t *testing.T) {
+	path := "../data/fast_persister_meta.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		_ = os.Remove(filePath + ".bak")
+	}()
+
+	aof, keys, err := persist.OpenPersister(path, syncIime)
+	require.NoError(t, err)
+	assert.Empty(t, aof.Meta())
+
+	err = aof.Write("meta\nseq:invoice\n1\nset\ntext_1\nvalue for key 1\nmeta\nseq:invoice\n2\n")
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	aof, keys, err = persist.OpenPersister(path, syncIime)
+	require.NoError(t, err)
+	assert.Len(t, keys["text"], 1)
+	assert.Equal(t, map[string]string{"seq:invoice": "2"}, aof.Meta())
+
+	// defrag keeps only the last meta data
+	err = aof.Defrag(keys)
+	require.NoError(t, err)
+
+	checkFileLines(t, filePath, 6)
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
+
+func Test_OpenPersister_IncompleteMetaInstruction(t *testing.T) {
+	path := "../data/fast_persister_meta_incomplete.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	for _, lines := range []string{"meta\n", "meta\nseq:invoice\n"} {
+		err := os.WriteFile(filePath, []byte(lines), 0o600)
+		require.NoError(t, err)
+
+		aof, keys, err := persist.OpenPersister(path, syncIime)
+		require.Error(t, err)
+		assert.Nil(t, aof)
+		assert.Nil(t, keys)
+	}
+}
+
 func Test_OpenPersister_concurrentWrites(t *testing.T) {
 	path := "../data/concurrent_write.db"
 
@@ -383,22 +439,60 @@ func Test_OpenPersister_concurrentWrites(t *testing.T) {
 
 			lines := fmt.Sprintf("set\nkey_%d\nvalue for key %d\n", i, i)
 
-			err = aof.Write(lines)
+			err := aof.Write(lines)
 			assert.NoError(t, err)
 		}(i)
 	}
 
 	wg.Wait()
 
+	err = aof.Close()
+	require.NoError(t, err)
+
 	// Check if all keys were written correctly
 	aof, keys, err := persist.OpenPersister(path, 0)
 	require.NoError(t, err)
+
+	defer func() {
+		err = aof.Close()
+		require.NoError(t, err)
+	}()
+
 	assert.Len(t, keys, 1) // Expecting 10 keys
 	bucketKeys := keys["key"]
 	assert.NotNil(t, bucketKeys)
 	assert.Len(t, bucketKeys, 10)
 }
 
+func Test_OpenPersister_locked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fast_persister_locked.db")
+
+	aof, _, err := persist.OpenPersister(path, syncIime)
+	require.NoError(t, err)
+	assert.FileExists(t, path+".lock")
+
+	// a second open (in another process, or in this one) is refused
+	_, _, err = persist.OpenPersister(path, syncIime)
+	require.ErrorIs(t, err, persist.ErrLocked)
+
+	// the lock is kept during a Defrag, which replaces the file
+	err = aof.Defrag(map[string]map[int][]byte{})
+	require.NoError(t, err)
+
+	_, _, err = persist.OpenPersister(path, syncIime)
+	require.ErrorIs(t, err, persist.ErrLocked)
+
+	err = aof.Close()
+	require.NoError(t, err)
+	assert.NoFileExists(t, path+".lock")
+
+	aof, _, err = persist.OpenPersister(path, syncIime)
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
+
 func Test_OpenPersister_writeAfterClose(t *testing.T) {
 	path := "../data/write_after_close.db"
 	defer func() {
@@ -476,6 +570,52 @@ func Test_Defrag(t *testing.T) {
 	checkFileLines(t, filePath, 3)
 }
 
+func Test_Defrag_reproducible(t *testing.T) {
+	path := "../data/fastdb_defrag_reproducible.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		_ = os.Remove(filePath + ".bak")
+	}()
+
+	aof, keys, err := persist.OpenPersister(path, syncIime)
+	require.NoError(t, err)
+
+	defer func() {
+		err = aof.Close()
+		require.NoError(t, err)
+	}()
+
+	for _, bucket := range []string{"b", "c", "a"} {
+		keys[bucket] = map[int][]byte{}
+		for key := range 50 {
+			keys[bucket][key*7%50] = []byte(fmt.Sprintf("value %s %d", bucket, key))
+		}
+	}
+
+	err = aof.Defrag(keys)
+	require.NoError(t, err)
+
+	first, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+
+	generation := aof.Generation()
+
+	err = aof.Defrag(keys)
+	require.NoError(t, err)
+
+	second, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+
+	// only the generation in the header differs, every rewrite is a new file
+	assert.NotEqual(t, generation, aof.Generation())
+	assert.Equal(t, first[aof.HeaderSize():], second[aof.HeaderSize():])
+	assert.Regexp(t, "^meta\nfileversion\n2\nmeta\nfilegeneration\n[0-9a-f]{16}\nset\na_0\nvalue a 0\nset\na_1\n", string(first))
+}
+
 func Test_Defrag_AlreadyClosed(t *testing.T) {
 	path := "../data/fastdb_defrag100.db"
 	filePath := filepath.Clean(path)
@@ -506,14 +646,24 @@ func checkFileLines(t *testing.T, filePath string, checkCount int) {
 	require.NoError(t, err)
 	assert.NotNil(t, readFile)
 
-	count := 0
+	var lines []string
 
 	scanner := bufio.NewScanner(readFile)
 	for scanner.Scan() {
-		count++
+		lines = append(lines, scanner.Text())
 	}
 
 	err = readFile.Close()
 	require.NoError(t, err)
-	assert.Equal(t, checkCount, count)
+
+	// the header with the file version (and the generation) isn't counted
+	if len(lines) >= 3 && lines[0] == "meta" && lines[1] == "fileversion" {
+		lines = lines[3:]
+	}
+
+	if len(lines) >= 3 && lines[0] == "meta" && lines[1] == "filegeneration" {
+		lines = lines[3:]
+	}
+
+	assert.Len(t, lines, checkCount)
 }
@@ -0,0 +1,69 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WriteRecords writes the meta data and the keys as the instructions of a file (in sorted order, like a Defrag),
+so what is written can be opened as a database file. The options (like WithFormat and WithCompression)
+tell how the records are written. The same records result in the same bytes, except for the generation in the header.
+*/
+func WriteRecords(writer io.Writer, keys map[string]map[int][]byte, meta map[string]string, opts ...Option) error {
+	aof := newAOF(0, opts)
+	buffered := bufio.NewWriter(writer)
+
+	_, err := writeFileRecords(buffered, keys, meta, aof.format, aof.compressor)
+	if err == nil {
+		err = buffered.Flush()
+	}
+
+	if err != nil {
+		return fmt.Errorf("writeRecords error: %w", err)
+	}
+
+	return nil
+}
+
+/*
+WriteFile writes the meta data and the keys to a new file (like WriteRecords), via a temporary file
+that is synced and then moved in place, so the file is either complete or not there.
+An existing file is replaced.
+*/
+func WriteFile(path string, keys map[string]map[int][]byte, meta map[string]string, opts ...Option) error {
+	tmpPath := path + ".tmp"
+
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fileMode) //nolint:gosec // the path is given by the caller
+	if err != nil {
+		return fmt.Errorf("writeFile (%s) error: %w", path, err)
+	}
+
+	err = WriteRecords(file, keys, meta, opts...)
+	if err == nil {
+		err = syncFile(file)
+	}
+
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmpPath)
+
+		return fmt.Errorf("writeFile (%s) error: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// minCompressSize is the size from which a value is worth compressing.
+const minCompressSize = 64
+
+// compressor compresses the values of set instructions with gzip.
+type compressor struct {
+	writers sync.Pool
+	level   int
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithCompression makes the persister compress the values (of at least 64 bytes) with gzip,
+at the given level (1 is the fastest, 9 the smallest, anything else means the default level).
+Compressed values are always written as binary frames, whatever the format is.
+Files are always read with transparent decompression, so compression can be switched
+on or off at any time, and a Defrag rewrites all the records.
+*/
+func WithCompression(level int) Option {
+	return func(aof *AOF) {
+		if level < gzip.BestSpeed || level > gzip.BestCompression {
+			level = gzip.DefaultCompression
+		}
+
+		aof.compressor = &compressor{level: level}
+	}
+}
+
+/*
+WithoutCompression turns the compression off (see WithCompression) for new writes.
+*/
+func WithoutCompression() Option {
+	return func(aof *AOF) {
+		aof.compressor = nil
+	}
+}
+
+/*
+compress returns the instruction with a compressed value, if that's smaller.
+Without a compressor (or for other instructions) the instruction is returned as it is.
+*/
+func (comp *compressor) compress(ins Instruction) Instruction {
+	if comp == nil || ins.Name != "set" || len(ins.Value) < minCompressSize {
+		return ins
+	}
+
+	var buf bytes.Buffer
+
+	writer, ok := comp.writers.Get().(*gzip.Writer)
+	if ok {
+		writer.Reset(&buf)
+	} else {
+		writer, _ = gzip.NewWriterLevel(&buf, comp.level) // the level is always valid
+	}
+
+	defer comp.writers.Put(writer)
+
+	_, err := writer.Write(ins.Value)
+	if err == nil {
+		err = writer.Close()
+	}
+
+	if err != nil || buf.Len() >= len(ins.Value) {
+		return ins
+	}
+
+	return Instruction{Name: "zset", Key: ins.Key, Value: buf.Bytes(), Time: ins.Time}
+}
+
+/*
+decompress returns the original value of a compressed value.
+*/
+func decompress(value []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(value))
+	if err != nil {
+		return nil, fmt.Errorf("decompress error: %w", err)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("decompress error: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,80 @@
+package persist_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenPersister_withCompression(t *testing.T) {
+	path := "../data/fast_persister_compress.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		_ = os.Remove(filePath + ".bak")
+	}()
+
+	aof, _, err := persist.OpenPersister(path, syncIime, persist.WithCompression(9))
+	require.NoError(t, err)
+
+	bigValue := bytes.Repeat([]byte(`{"name":"a verbose json value","nested":{"more":"text"}}`), 100)
+
+	err = aof.WriteBatch([]persist.Instruction{
+		persist.SetInstruction("text", 1, bigValue),
+		persist.SetInstruction("text", 2, []byte("small value")),
+		persist.MetaInstruction("seq:text", "2"),
+	})
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Less(t, len(data), len(bigValue)/10)
+	assert.Contains(t, string(data), "set\ntext_2\nsmall value\n")
+
+	// reading doesn't need the option
+	aof, keys, err := persist.OpenPersister(path, syncIime)
+	require.NoError(t, err)
+	assert.Equal(t, bigValue, keys["text"][1])
+	assert.Equal(t, []byte("small value"), keys["text"][2])
+	assert.Equal(t, "2", aof.Meta()["seq:text"])
+
+	// defrag without compression writes the plain values
+	err = aof.Defrag(keys)
+	require.NoError(t, err)
+
+	data, err = os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Greater(t, len(data), len(bigValue))
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	// defrag with compression compresses them again
+	aof, keys, err = persist.OpenPersister(path, syncIime, persist.WithCompression(0))
+	require.NoError(t, err)
+
+	err = aof.Defrag(keys)
+	require.NoError(t, err)
+
+	data, err = os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Less(t, len(data), len(bigValue)/10)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	_, keys, err = persist.OpenPersister(path, syncIime)
+	require.NoError(t, err)
+	assert.Equal(t, bigValue, keys["text"][1])
+}
@@ -0,0 +1,135 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const (
+	backupExtension  = ".bak"
+	corruptExtension = ".corrupt"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithBackupFallback makes the persister restore the backup of the last Defrag (the path + ".bak")
+when the file is corrupt, instead of refusing to open it. The corrupt file (and its checkpoint)
+is moved aside to the path + ".corrupt", and CorruptFile tells where it is.
+The records that were written after the last Defrag are lost. Striped and segmented files aren't restored.
+*/
+func WithBackupFallback() Option {
+	return func(aof *AOF) {
+		aof.fallback = true
+	}
+}
+
+/*
+CorruptFile returns the path to which the corrupt file was moved when the backup was restored,
+or an empty string if the file was read.
+*/
+func (aof *AOF) CorruptFile() string {
+	return aof.corruptFile
+}
+
+/*
+loadBackup moves the corrupt file aside and reads a copy of the backup instead, with a new persister
+that keeps the lock. When there is no backup (or the file is striped), the persister and the cause are returned.
+*/
+func (aof *AOF) loadBackup(
+	filePath string, syncIime int, opts []Option, cause error,
+) (*AOF, map[string]map[int][]byte, error) {
+	if aof.stripeCount > 1 || checkSegments(filePath) != nil {
+		return aof, nil, cause
+	}
+
+	_, err := os.Stat(filePath + backupExtension)
+	if err != nil {
+		return aof, nil, cause
+	}
+
+	err = aof.checkWritable("backupFallback")
+	if err != nil {
+		return aof, nil, errors.Join(cause, err)
+	}
+
+	if aof.file != nil {
+		_ = aof.file.Close() // it's already closed when the file couldn't be read
+	}
+
+	corruptPath := filePath + corruptExtension
+
+	err = restoreBackup(filePath, corruptPath)
+	if err != nil {
+		return aof, nil, errors.Join(cause, fmt.Errorf("backupFallback error: %w", err))
+	}
+
+	aof.log.Warn("corrupt file replaced by its backup", "file", filePath, "corrupt", corruptPath, "error", cause)
+
+	restored := newAOF(syncIime, opts)
+	restored.lockFile = aof.lockFile
+	restored.corruptFile = corruptPath
+
+	keys, err := restored.load(filePath)
+	if err != nil {
+		return restored, nil, fmt.Errorf("backupFallback error: %w", err)
+	}
+
+	return restored, keys, nil
+}
+
+/*
+restoreBackup moves the file and its checkpoint to the corrupt path, and puts a copy of the backup in place,
+so the backup is still there when the copy turns out to be bad too.
+*/
+func restoreBackup(filePath, corruptPath string) error {
+	err := os.Rename(filePath, corruptPath)
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	// the checkpoint belongs to the corrupt file, not to the backup
+	err = os.Rename(filePath+snapshotExtension, corruptPath+snapshotExtension)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	return copyFile(filePath+backupExtension, filePath)
+}
+
+/*
+copyFile copies the source to a new (synced) file at the destination.
+*/
+func copyFile(sourcePath, destinationPath string) error {
+	source, err := os.Open(sourcePath) //nolint:gosec // path is clean
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	defer func() {
+		_ = source.Close()
+	}()
+
+	destination, err := os.OpenFile(destinationPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, fileMode) //nolint:gosec // path is clean
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	_, err = io.Copy(destination, source)
+	if err == nil {
+		err = syncFile(destination)
+	}
+
+	closeErr := destination.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	return err //nolint:wrapcheck // it is wrapped by the caller
+}
@@ -0,0 +1,125 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+/*
+Follower reads a file that another process (the leader) appends to, like "tail -f".
+Nothing is locked, created or changed, so it can follow the file of a database that is in use.
+*/
+type Follower struct {
+	aof    *AOF        // the options with which the file is read
+	path   string      // the file that is followed
+	info   os.FileInfo // the file that was read last, a Defrag of the leader replaces it
+	offset int64       // the end of the last complete instruction that was read
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Follow returns a follower for the file, the first Poll reads all its instructions.
+Striped and segmented files can't be followed.
+*/
+func Follow(path string, opts ...Option) (*Follower, error) {
+	filePath, ok := cleanPath(path)
+	if !ok {
+		return nil, fmt.Errorf("follow error: invalid path '%s'", path)
+	}
+
+	stripes, err := existingStripes(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stripes) > 0 {
+		return nil, fmt.Errorf("follow (%s) error: %w", path, errStriped)
+	}
+
+	err = checkSegments(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("follow (%s) error: %w", path, err)
+	}
+
+	return &Follower{aof: newAOF(0, opts), path: filePath}, nil
+}
+
+/*
+Poll reads the instructions that were appended since the last Poll, and calls apply for every one of them
+(a compressed set and a present become a set). An instruction that isn't completely written yet
+is read by the next Poll. When the leader replaced the file (with a Defrag), nothing is applied
+and true is returned: the follower starts at the beginning again, so the next Poll reads the whole new file.
+*/
+func (flw *Follower) Poll(apply func(ins Instruction)) (bool, error) {
+	file, err := os.Open(flw.path)
+	if err != nil {
+		return false, fmt.Errorf("poll (%s) error: %w", flw.path, err)
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("poll (%s) error: %w", flw.path, err)
+	}
+
+	if flw.info != nil && (!os.SameFile(flw.info, info) || info.Size() < flw.offset) {
+		// a sealed segment (instead of a Defrag) would be lost by starting again
+		err = checkSegments(flw.path)
+		if err != nil {
+			return false, fmt.Errorf("poll (%s) error: %w", flw.path, err)
+		}
+
+		flw.info = nil
+		flw.offset = 0
+
+		return true, nil
+	}
+
+	flw.info = info
+
+	if info.Size() == flw.offset {
+		return false, nil
+	}
+
+	_, err = file.Seek(flw.offset, io.SeekStart)
+	if err != nil {
+		return false, fmt.Errorf("poll (%s) error: %w", flw.path, err)
+	}
+
+	scanner, recorder := flw.aof.newScanner(io.LimitReader(file, info.Size()-flw.offset))
+	recorder.offset = flw.offset
+	recorder.complete = true
+
+	for scanner.Scan() {
+		start := recorder.start
+
+		ins, err := readInstruction(scanner)
+		if errors.Is(err, errIncompleteInstruction) {
+			break
+		}
+
+		if err != nil {
+			return false, fmt.Errorf("poll (%s) error at offset %d: %w", flw.path, start, err)
+		}
+
+		apply(ins)
+		flw.offset = recorder.offset
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return false, fmt.Errorf("poll (%s) error: %w", flw.path, err)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,71 @@
+package persist_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Follow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fast_persister_follow.db")
+
+	err := os.WriteFile(path, []byte("set\nmyBucket_1\nvalue 1\nset\nmyBucket_2\nval"), 0o600)
+	require.NoError(t, err)
+
+	follower, err := persist.Follow(path)
+	require.NoError(t, err)
+
+	var read []persist.Instruction
+
+	apply := func(ins persist.Instruction) {
+		read = append(read, ins)
+	}
+
+	// the second set isn't completely written yet
+	replaced, err := follower.Poll(apply)
+	require.NoError(t, err)
+	assert.False(t, replaced)
+	assert.Equal(t, []persist.Instruction{persist.SetInstruction("myBucket", 1, []byte("value 1"))}, read)
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+
+	_, err = file.WriteString("ue 2\ndel\nmyBucket_1\n")
+	require.NoError(t, err)
+
+	read = nil
+
+	replaced, err = follower.Poll(apply)
+	require.NoError(t, err)
+	assert.False(t, replaced)
+	assert.Equal(t, []persist.Instruction{
+		persist.SetInstruction("myBucket", 2, []byte("value 2")),
+		persist.DelInstruction("myBucket", 1),
+	}, read)
+
+	err = file.Close()
+	require.NoError(t, err)
+
+	// a new file (like after a Defrag) is read from the beginning
+	err = os.Remove(path)
+	require.NoError(t, err)
+
+	err = os.WriteFile(path, []byte("set\nmyBucket_2\nvalue 2\n"), 0o600)
+	require.NoError(t, err)
+
+	read = nil
+
+	replaced, err = follower.Poll(apply)
+	require.NoError(t, err)
+	assert.True(t, replaced)
+	assert.Empty(t, read)
+
+	replaced, err = follower.Poll(apply)
+	require.NoError(t, err)
+	assert.False(t, replaced)
+	assert.Equal(t, []persist.Instruction{persist.SetInstruction("myBucket", 2, []byte("value 2"))}, read)
+}
@@ -0,0 +1,282 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// Format is the way the instructions are written to the file.
+type Format int
+
+const (
+	// FormatText writes every instruction as newline separated lines (the original format).
+	// An instruction that can't be read back as lines (like a value with a newline) is written as a frame.
+	FormatText Format = iota + 1
+	// FormatBinary writes every instruction as a length-prefixed frame,
+	// so values can hold any (binary) data.
+	FormatBinary
+)
+
+// frameMarker is the first byte of a binary frame, it can never start a text instruction.
+const frameMarker = 0x00
+
+// operation codes of the binary frames.
+const (
+	opSet byte = iota + 1
+	opDel
+	opMeta
+	opDelMeta
+	opCompressedSet
+	opPresent
+	opDelBucket
+	opRenameBucket
+	opCopyBucket
+)
+
+var (
+	opCodes = map[string]byte{
+		"set": opSet, "del": opDel, "meta": opMeta, "delmeta": opDelMeta, "zset": opCompressedSet,
+		"present": opPresent, "delbucket": opDelBucket, "renamebucket": opRenameBucket, "copybucket": opCopyBucket,
+	}
+	opNames = map[byte]string{
+		opSet: "set", opDel: "del", opMeta: "meta", opDelMeta: "delmeta", opCompressedSet: "zset",
+		opPresent: "present", opDelBucket: "delbucket", opRenameBucket: "renamebucket", opCopyBucket: "copybucket",
+	}
+
+	errIncompleteFrame = errors.New("incomplete frame")
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithFormat sets the format in which new instructions are written.
+Files are always read in both formats, so an existing text file can be opened
+with FormatBinary: the old instructions stay readable, and a Defrag converts them all.
+*/
+func WithFormat(format Format) Option {
+	return func(aof *AOF) {
+		aof.format = format
+	}
+}
+
+/*
+Format returns the format in which new instructions are written.
+*/
+func (aof *AOF) Format() Format {
+	return aof.format
+}
+
+/*
+hasValue returns true if the operation carries a value.
+*/
+func hasValue(op byte) bool {
+	return op == opSet || op == opMeta || op == opCompressedSet || op == opRenameBucket || op == opCopyBucket
+}
+
+/*
+appendFrame appends the instruction as a binary frame:
+marker, operation, (if it has one) time, key length, key and (for set and meta) value length and value.
+*/
+func (ins Instruction) appendFrame(buf []byte) []byte {
+	op := opCodes[ins.Name]
+
+	if ins.Time != 0 {
+		buf = append(buf, frameMarker, op|opTimestamped)
+		buf = binary.AppendUvarint(buf, uint64(ins.Time)) //nolint:gosec // a time of a write is never negative
+	} else {
+		buf = append(buf, frameMarker, op)
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(ins.Key)))
+	buf = append(buf, ins.Key...)
+
+	if hasValue(op) {
+		buf = binary.AppendUvarint(buf, uint64(len(ins.Value)))
+		buf = append(buf, ins.Value...)
+	}
+
+	return buf
+}
+
+/*
+frameSize returns the size of the binary frame at the start of the data.
+The bool is false if the data doesn't hold the complete frame (yet).
+*/
+func frameSize(data []byte) (int, bool) {
+	if len(data) < 2 {
+		return 0, false
+	}
+
+	pos := 2
+	parts := 1
+
+	if data[1]&opTimestamped != 0 {
+		_, size := binary.Uvarint(data[pos:])
+		if size <= 0 {
+			return 0, false
+		}
+
+		pos += size
+	}
+
+	if hasValue(data[1] &^ opTimestamped) {
+		parts = 2
+	}
+
+	for range parts {
+		length, size := binary.Uvarint(data[pos:])
+		if size <= 0 {
+			return 0, false
+		}
+
+		pos += size
+
+		if uint64(len(data)-pos) < length {
+			return 0, false
+		}
+
+		pos += int(length) //nolint:gosec // length is smaller than the data
+	}
+
+	return pos, true
+}
+
+/*
+decodeFrame decodes a binary frame into an instruction.
+*/
+func decodeFrame(frame []byte) (Instruction, error) {
+	size, ok := frameSize(frame)
+	if !ok || size != len(frame) {
+		return Instruction{}, errIncompleteFrame
+	}
+
+	op := frame[1] &^ opTimestamped
+
+	name, found := opNames[op]
+	if !found {
+		return Instruction{}, fmt.Errorf("unknown frame operation %d", frame[1])
+	}
+
+	ins := Instruction{Name: name}
+	pos := 2
+
+	if frame[1]&opTimestamped != 0 {
+		stamp, size := binary.Uvarint(frame[pos:])
+		ins.Time = int64(stamp) //nolint:gosec // it was written from an int64
+		pos += size
+	}
+
+	keyLength, size := binary.Uvarint(frame[pos:])
+	pos += size
+	ins.Key = string(frame[pos : pos+int(keyLength)]) //nolint:gosec // checked by frameSize
+	pos += int(keyLength)                             //nolint:gosec // checked by frameSize
+
+	if hasValue(op) {
+		valueLength, size := binary.Uvarint(frame[pos:])
+		pos += size
+		ins.Value = make([]byte, valueLength)
+		copy(ins.Value, frame[pos:])
+	}
+
+	return ins, nil
+}
+
+/*
+scanRecords is the split function of the scanner: it returns a binary frame as
+one token, and otherwise a line (for the text format).
+*/
+func scanRecords(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) > 0 && data[0] == frameMarker {
+		size, ok := frameSize(data)
+		if ok {
+			return size, data[:size], nil
+		}
+
+		if !atEOF {
+			return 0, nil, nil
+		}
+
+		// the frame is incomplete, this will be reported while decoding
+		return len(data), data, nil
+	}
+
+	return bufio.ScanLines(data, atEOF)
+}
+
+/*
+handleFrame handles an instruction in the binary format.
+*/
+func (aof *AOF) handleFrame(frame string, count int, keys map[string]map[int][]byte) (int, error) {
+	ins, err := decodeFrame([]byte(frame))
+	if err != nil {
+		return count, aof.corrupted(count, "a wrong frame", err)
+	}
+
+	if ins.Name == "zset" {
+		ins.Name = "set"
+
+		ins.Value, err = decompress(ins.Value)
+		if err != nil {
+			return count, aof.corrupted(count, "a wrong frame", err)
+		}
+	}
+
+	if ins.Name == "present" {
+		ins.Name = "set"
+		ins.Value = []byte{}
+	}
+
+	if ins.Name == "set" || ins.Name == "del" {
+		aof.records++
+	}
+
+	return count, aof.apply(ins, count, keys)
+}
+
+/*
+apply changes the keys (or the meta data) like the instruction (in which a compressed set
+and a present are a set) says. The count is the line, for the error.
+*/
+func (aof *AOF) apply(ins Instruction, count int, keys map[string]map[int][]byte) error {
+	switch ins.Name {
+	case "set":
+		bucket, keyID, ok := parseBucketAndKey(ins.Key)
+		if !ok {
+			return aof.corrupted(count, fmt.Sprintf("wrong key format: '%s'", ins.Key), nil)
+		}
+
+		aof.countRecord(bucket)
+
+		if _, found := keys[bucket]; !found {
+			keys[bucket] = map[int][]byte{}
+		}
+
+		keys[bucket][keyID] = ins.Value
+	case "del":
+		bucket, keyID, ok := parseBucketAndKey(ins.Key)
+		if !ok {
+			return aof.corrupted(count, fmt.Sprintf("wrong key format: '%s'", ins.Key), nil)
+		}
+
+		aof.countRecord(bucket)
+		deleteKey(keys, bucket, keyID)
+	case "delbucket":
+		delete(keys, ins.Key)
+	case "renamebucket", "copybucket":
+		copyBucket(keys, ins.Key, string(ins.Value), ins.Name == "renamebucket")
+	case "meta":
+		if !isHeader(ins) {
+			aof.meta[ins.Key] = string(ins.Value)
+		}
+	case "delmeta":
+		delete(aof.meta, ins.Key)
+	}
+
+	return nil
+}
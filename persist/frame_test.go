@@ -0,0 +1,132 @@
+package persist_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenPersister_binaryFormat(t *testing.T) {
+	path := "../data/fast_persister_binary.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		_ = os.Remove(filePath + ".bak")
+	}()
+
+	// start with a file in the text format
+	aof, _, err := persist.OpenPersister(path, syncIime)
+	require.NoError(t, err)
+	assert.Equal(t, persist.FormatText, aof.Format())
+
+	err = aof.Write("set\ntext_1\nvalue for key 1\nset\ntext_2\nvalue for key 2\n")
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	// continue in the binary format
+	aof, keys, err := persist.OpenPersister(path, syncIime, persist.WithFormat(persist.FormatBinary))
+	require.NoError(t, err)
+	assert.Equal(t, persist.FormatBinary, aof.Format())
+	assert.Len(t, keys["text"], 2)
+
+	binaryValue := []byte("line 1\nset\ndel\n\x00\x01\x02\r\nend")
+
+	err = aof.WriteBatch([]persist.Instruction{
+		persist.SetInstruction("text", 3, binaryValue),
+		persist.SetInstruction("text", 4, []byte{}),
+		persist.DelInstruction("text", 1),
+		persist.MetaInstruction("seq:text", "4"),
+		persist.MetaInstruction("gone", "soon"),
+		persist.DelMetaInstruction("gone"),
+	})
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	aof, keys, err = persist.OpenPersister(path, syncIime, persist.WithFormat(persist.FormatBinary))
+	require.NoError(t, err)
+	assert.Len(t, keys["text"], 3)
+	assert.Equal(t, binaryValue, keys["text"][3])
+	assert.Equal(t, []byte{}, keys["text"][4])
+	assert.Equal(t, map[string]string{"seq:text": "4"}, aof.Meta())
+
+	// defrag converts everything to the binary format
+	err = aof.Defrag(keys)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, byte(0), data[0])
+	assert.False(t, bytes.HasPrefix(data, []byte("set\n")))
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	// a binary file can be read without the option
+	aof, keys, err = persist.OpenPersister(path, syncIime)
+	require.NoError(t, err)
+	assert.Equal(t, binaryValue, keys["text"][3])
+	assert.Equal(t, []byte("value for key 2"), keys["text"][2])
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
+
+func Test_OpenPersister_wrongFrames(t *testing.T) {
+	path := "../data/fast_persister_binary_wrong.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		_ = os.Remove(filePath + ".quarantine")
+	}()
+
+	frames := [][]byte{
+		{0x00, 0x7f, 0x01, 'a'},                           // unknown operation
+		{0x00, 0x01, 0x04, 'a', 'b', 'c', 'd', 0x01, 'v'}, // key without underscore
+		{0x00, 0x02, 0x04, 'a', 'b', 'c', 'd'},            // key without underscore
+		{0x00, 0x01, 0x0a, 't', 'e', 'x', 't'},            // incomplete frame
+	}
+
+	for _, frame := range frames {
+		err := os.WriteFile(filePath, frame, 0o600)
+		require.NoError(t, err)
+
+		aof, keys, err := persist.OpenPersister(path, syncIime)
+		require.Error(t, err)
+		assert.Nil(t, aof)
+		assert.Nil(t, keys)
+	}
+
+	// in quarantine mode, the good frames are kept
+	good := persist.SetInstruction("text", 1, []byte("value"))
+	goodFrame := []byte{0x00, 0x01, 0x06, 't', 'e', 'x', 't', '_', '1', 0x05, 'v', 'a', 'l', 'u', 'e'}
+
+	data := append(append([]byte{}, frames[0]...), goodFrame...)
+	data = append(data, frames[3]...)
+
+	err := os.WriteFile(filePath, data, 0o600)
+	require.NoError(t, err)
+
+	aof, keys, err := persist.OpenPersister(path, syncIime, persist.WithQuarantine())
+	require.NoError(t, err)
+	assert.Equal(t, good.Value, keys["text"][1])
+	require.NotNil(t, aof.CorruptionReport())
+	assert.Len(t, aof.CorruptionReport().Entries, 2)
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
@@ -0,0 +1,248 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// Instruction represents one change, as it is written to the file.
+type Instruction struct {
+	Name  string // set, present, del, delbucket, renamebucket, copybucket, meta or delmeta
+	Key   string // bucket_key for records, the (source) bucket for the bucket instructions, the name for meta data
+	Value []byte // only used by set, meta and the target bucket of renamebucket and copybucket
+	Time  int64  // when it was written (in unix nanoseconds), 0 if it isn't known, see WithTimestamps
+}
+
+// ErrTooBig is returned for an instruction that is too big to be read back from the file.
+var ErrTooBig = errors.New("instruction too big")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+SetInstruction returns the instruction to store a value in a bucket.
+An empty value becomes a present instruction, which is written without a value.
+*/
+func SetInstruction(bucket string, key int, value []byte) Instruction {
+	if len(value) == 0 {
+		return PresentInstruction(bucket, key)
+	}
+
+	return Instruction{Name: "set", Key: bucket + "_" + strconv.Itoa(key), Value: value}
+}
+
+/*
+PresentInstruction returns the instruction to store a key without a value (an empty value) in a bucket,
+for buckets that are used as a set of keys.
+*/
+func PresentInstruction(bucket string, key int) Instruction {
+	return Instruction{Name: "present", Key: bucket + "_" + strconv.Itoa(key)}
+}
+
+/*
+DelInstruction returns the instruction to delete a key from a bucket.
+*/
+func DelInstruction(bucket string, key int) Instruction {
+	return Instruction{Name: "del", Key: bucket + "_" + strconv.Itoa(key)}
+}
+
+/*
+DelBucketInstruction returns the instruction to delete a bucket with all its records.
+*/
+func DelBucketInstruction(bucket string) Instruction {
+	return Instruction{Name: "delbucket", Key: bucket}
+}
+
+/*
+RenameBucketInstruction returns the instruction to move all the records of a bucket to another bucket.
+*/
+func RenameBucketInstruction(oldBucket, newBucket string) Instruction {
+	return Instruction{Name: "renamebucket", Key: oldBucket, Value: []byte(newBucket)}
+}
+
+/*
+CopyBucketInstruction returns the instruction to copy all the records of a bucket to another bucket.
+*/
+func CopyBucketInstruction(srcBucket, dstBucket string) Instruction {
+	return Instruction{Name: "copybucket", Key: srcBucket, Value: []byte(dstBucket)}
+}
+
+/*
+MetaInstruction returns the instruction to store a meta data value.
+*/
+func MetaInstruction(name, value string) Instruction {
+	return Instruction{Name: "meta", Key: name, Value: []byte(value)}
+}
+
+/*
+DelMetaInstruction returns the instruction to delete a meta data value.
+*/
+func DelMetaInstruction(name string) Instruction {
+	return Instruction{Name: "delmeta", Key: name}
+}
+
+/*
+BucketKey returns the bucket and the key of a record instruction (like set and del),
+and false if its key isn't in the "bucket_key" format.
+*/
+func (ins Instruction) BucketKey() (string, int, bool) {
+	return parseBucketAndKey(ins.Key)
+}
+
+/*
+String returns the instruction as the lines that are written to the file (in the text format).
+*/
+func (ins Instruction) String() string {
+	return string(ins.appendTo(nil, FormatText))
+}
+
+/*
+appendTo appends the instruction in the given format to the buffer.
+*/
+func (ins Instruction) appendTo(buf []byte, format Format) []byte {
+	// a compressed value can hold any data, and the lines have no room for a time, so those are always a frame
+	if format == FormatBinary || ins.Name == "zset" || ins.Time != 0 || !ins.fitsLines() {
+		return ins.appendFrame(buf)
+	}
+
+	buf = append(buf, ins.Name...)
+	buf = append(buf, '\n')
+	buf = append(buf, ins.Key...)
+	buf = append(buf, '\n')
+
+	if hasValue(opCodes[ins.Name]) {
+		buf = append(buf, ins.Value...)
+		buf = append(buf, '\n')
+	}
+
+	return buf
+}
+
+/*
+Size returns the number of bytes the instruction takes in the file, in the given format
+(without compression).
+*/
+func (ins Instruction) Size(format Format) int {
+	if format == FormatBinary || ins.Name == "zset" || ins.Time != 0 || !ins.fitsLines() {
+		size := 2 + uvarintSize(len(ins.Key)) + len(ins.Key)
+		if ins.Time != 0 {
+			size += uvarintSize(int(ins.Time))
+		}
+
+		if hasValue(opCodes[ins.Name]) {
+			size += uvarintSize(len(ins.Value)) + len(ins.Value)
+		}
+
+		return size
+	}
+
+	size := len(ins.Name) + len(ins.Key) + 2
+	if hasValue(opCodes[ins.Name]) {
+		size += len(ins.Value) + 1
+	}
+
+	return size
+}
+
+/*
+fitsLines tells if the instruction can be written as lines (in the text format), so it is read back the same.
+A key or value with a newline, that ends with a carriage return (which the line scanner drops),
+or that starts like a frame, is written as a frame instead.
+*/
+func (ins Instruction) fitsLines() bool {
+	if strings.IndexByte(ins.Key, '\n') >= 0 || bytes.IndexByte(ins.Value, '\n') >= 0 {
+		return false
+	}
+
+	return !confusingEdges(ins.Key) && !confusingEdges(ins.Value)
+}
+
+/*
+confusingEdges tells if the line would be read back differently, because of its first or last byte.
+*/
+func confusingEdges[T string | []byte](line T) bool {
+	return len(line) > 0 && (line[0] == frameMarker || line[len(line)-1] == '\r')
+}
+
+/*
+uvarintSize returns the number of bytes of a length, written as an uvarint.
+*/
+func uvarintSize(length int) int {
+	var buf [binary.MaxVarintLen64]byte
+
+	return binary.PutUvarint(buf[:], uint64(length)) //nolint:gosec // a length is never negative
+}
+
+/*
+WriteBatch writes all the instructions to the file in one write, followed by
+one sync (if the sync time is 0). This is much cheaper than a Write per instruction.
+With stripes, every file gets one write, and the files are synced at the same time.
+WithTimestamps, the instructions that don't have a time get the time of the write.
+An instruction that is too big to be read back (over 10 MB) fails the batch with ErrTooBig,
+nothing of the batch is written then.
+*/
+func (aof *AOF) WriteBatch(instructions []Instruction) error {
+	if len(instructions) == 0 {
+		return nil
+	}
+
+	err := aof.checkWritable("writeBatch")
+	if err != nil {
+		return err
+	}
+
+	aof.stamp(instructions)
+
+	if aof.Striped() {
+		bufs := make([][]byte, aof.stripeCount)
+		for _, ins := range instructions {
+			index := aof.stripeOf(ins)
+			start := len(bufs[index])
+			bufs[index] = aof.compressor.compress(ins).appendTo(bufs[index], aof.format)
+
+			err = checkSize(len(bufs[index]) - start)
+			if err != nil {
+				return err
+			}
+		}
+
+		return aof.writeStriped(bufs)
+	}
+
+	size := 0
+	for _, ins := range instructions {
+		size += len(ins.Name) + len(ins.Key) + len(ins.Value) + 3*binary.MaxVarintLen64
+	}
+
+	buf := make([]byte, 0, size)
+
+	for _, ins := range instructions {
+		start := len(buf)
+		buf = aof.compressor.compress(ins).appendTo(buf, aof.format)
+
+		err = checkSize(len(buf) - start)
+		if err != nil {
+			return err
+		}
+	}
+
+	return aof.Write(string(buf))
+}
+
+/*
+checkSize refuses an encoded instruction that doesn't fit in the buffer with which the file is read.
+*/
+func checkSize(size int) error {
+	if size > maxScanBuffer {
+		return fmt.Errorf("writeBatch error: %w (%d bytes, the maximum is %d)", ErrTooBig, size, maxScanBuffer)
+	}
+
+	return nil
+}
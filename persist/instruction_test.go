@@ -0,0 +1,199 @@
+package persist_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Instruction_String(t *testing.T) {
+	assert.Equal(t, "set\ntext_1\nvalue\n", persist.SetInstruction("text", 1, []byte("value")).String())
+	assert.Equal(t, "del\ntext_1\n", persist.DelInstruction("text", 1).String())
+	assert.Equal(t, "meta\nseq:text\n1\n", persist.MetaInstruction("seq:text", "1").String())
+	assert.Equal(t, "delmeta\nseq:text\n", persist.DelMetaInstruction("seq:text").String())
+	assert.Equal(t, "delbucket\ntext\n", persist.DelBucketInstruction("text").String())
+}
+
+func Test_WriteBatch(t *testing.T) {
+	path := "../data/fast_persister_batch.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	aof, _, err := persist.OpenPersister(path, 0)
+	require.NoError(t, err)
+
+	err = aof.WriteBatch(nil)
+	require.NoError(t, err)
+
+	err = aof.WriteBatch([]persist.Instruction{
+		persist.SetInstruction("text", 1, []byte("value for key 1")),
+		persist.SetInstruction("other", 1, []byte("value for key 1")),
+		persist.SetInstruction("text", 2, []byte("value for key 2")),
+		persist.DelInstruction("text", 1),
+		persist.MetaInstruction("seq:text", "2"),
+	})
+	require.NoError(t, err)
+
+	checkFileLines(t, filePath, 14)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	err = aof.WriteBatch([]persist.Instruction{persist.DelInstruction("text", 2)})
+	require.Error(t, err)
+
+	aof, keys, err := persist.OpenPersister(path, 0)
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+	assert.Len(t, keys["text"], 1)
+	assert.Equal(t, map[string]string{"seq:text": "2"}, aof.Meta())
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
+
+func Test_Instruction_Size(t *testing.T) {
+	instructions := []persist.Instruction{
+		persist.SetInstruction("text", 1, []byte("value")),
+		persist.SetInstruction("text", 2, make([]byte, 300)),
+		persist.DelInstruction("text", 1),
+		persist.MetaInstruction("name", "value"),
+		persist.DelMetaInstruction("name"),
+	}
+
+	for _, ins := range instructions {
+		assert.Len(t, ins.String(), ins.Size(persist.FormatText))
+	}
+
+	path := "../data/fast_persister_size.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	aof, _, err := persist.OpenPersister(path, 0, persist.WithFormat(persist.FormatBinary))
+	require.NoError(t, err)
+
+	size := 0
+	for _, ins := range instructions {
+		size += ins.Size(persist.FormatBinary)
+	}
+
+	err = aof.WriteBatch(instructions)
+	require.NoError(t, err)
+	assert.Equal(t, int64(size)+aof.HeaderSize(), aof.Size())
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
+
+func Test_WriteBatch_delbucket(t *testing.T) {
+	for _, format := range []persist.Format{persist.FormatText, persist.FormatBinary} {
+		path := "../data/fast_persister_delbucket.db"
+		filePath := filepath.Clean(path)
+
+		aof, _, err := persist.OpenPersister(path, 0, persist.WithFormat(format))
+		require.NoError(t, err)
+
+		instructions := []persist.Instruction{
+			persist.SetInstruction("text", 1, []byte("value for key 1")),
+			persist.SetInstruction("text_user", 1, []byte("value for key 1")),
+			persist.SetInstruction("text", 2, []byte("value for key 2")),
+			persist.DelBucketInstruction("text"),
+			persist.SetInstruction("text", 3, []byte("value for key 3")),
+		}
+
+		size := int64(0)
+		for _, ins := range instructions {
+			size += int64(ins.Size(format))
+		}
+
+		err = aof.WriteBatch(instructions)
+		require.NoError(t, err)
+		assert.Equal(t, size+aof.HeaderSize(), aof.Size())
+
+		err = aof.Close()
+		require.NoError(t, err)
+
+		aof, keys, err := persist.OpenPersister(path, 0)
+		require.NoError(t, err)
+		assert.Equal(t, map[int][]byte{3: []byte("value for key 3")}, keys["text"])
+		assert.Len(t, keys["text_user"], 1)
+
+		err = aof.Close()
+		require.NoError(t, err)
+
+		err = os.Remove(filePath)
+		require.NoError(t, err)
+	}
+}
+
+func Test_WriteBatch_adversarial(t *testing.T) {
+	path := "../data/fast_persister_adversarial.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	values := []string{
+		"", "set", "del", "meta", "delmeta", "zset", "text_1", "{}", "{\"set\":\"del\"}",
+		"\n", "\r", "\r\n", "set\n", "\nset", "value\r", "value\n\r",
+		"a value\nset\ntext_2\ninjected", "a value\ndel\ntext_1", "a value\nmeta\nname\ninjected",
+		"\x00", "\x00set", "\x00\x01\x05text_1", "set\x00", "\x01", "\xff\xfe",
+	}
+	buckets := []string{"text", "set", "del", "bucket_with_underscores", "new\nline", "\x00zero", "cr\r"}
+
+	aof, _, err := persist.OpenPersister(path, 0)
+	require.NoError(t, err)
+
+	size := int64(0)
+	want := map[string]map[int][]byte{}
+	meta := map[string]string{}
+
+	for _, bucket := range buckets {
+		want[bucket] = map[int][]byte{}
+		instructions := make([]persist.Instruction, 0, len(values))
+
+		for key, value := range values {
+			want[bucket][key] = []byte(value)
+			instructions = append(instructions, persist.SetInstruction(bucket, key, []byte(value)))
+		}
+
+		instructions = append(instructions, persist.MetaInstruction(bucket, "meta\n"+bucket))
+		meta[bucket] = "meta\n" + bucket
+
+		for _, ins := range instructions {
+			assert.Len(t, ins.String(), ins.Size(persist.FormatText))
+			size += int64(ins.Size(persist.FormatText))
+		}
+
+		err = aof.WriteBatch(instructions)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, size+aof.HeaderSize(), aof.Size())
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	aof, keys, err := persist.OpenPersister(path, 0)
+	require.NoError(t, err)
+	assert.Equal(t, want, keys)
+	assert.Equal(t, meta, aof.Meta())
+	assert.Nil(t, aof.CorruptionReport())
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
@@ -0,0 +1,127 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const (
+	lockExtension = ".lock"
+	// lockAttempts is how often a lock file that was removed meanwhile (by its previous owner) is tried again.
+	lockAttempts = 3
+)
+
+// ErrLocked is returned when the file is already opened by another process (or another persister).
+var ErrLocked = errors.New("file is locked by another process")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+acquireLock takes an exclusive lock on the file, so two processes can't append to it at the same time.
+The lock is held on a separate file (the path + ".lock"), because a Defrag replaces the file itself.
+It is an advisory lock of the system (flock or LockFileEx), so it is released when the process dies.
+A shared lock (for a read-only persister) can be held by several at the same time, but not with an exclusive one,
+and it returns no file when there is no lock file to hold (see openLockFile).
+*/
+func acquireLock(path string, shared bool) (*os.File, error) {
+	lockPath := path + lockExtension
+
+	for range lockAttempts {
+		file, err := openLockFile(lockPath, shared)
+		if err != nil {
+			return nil, fmt.Errorf("lock (%s) error: %w", path, err)
+		}
+
+		if file == nil {
+			return nil, nil //nolint:nilnil // there is no lock to hold
+		}
+
+		err = tryLock(file, shared)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("lock (%s) error: %w", path, err), file.Close())
+		}
+
+		// the previous owner removes the lock file when it releases it, so it must still be the same file
+		if isLockPath(file, lockPath) {
+			return file, nil
+		}
+
+		err = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("lock (%s) error: %w", path, err)
+		}
+	}
+
+	return nil, fmt.Errorf("lock (%s) error: %w", path, ErrLocked)
+}
+
+/*
+openLockFile opens the lock file, and creates it if it isn't there.
+For a shared lock it is only opened for reading, so a read-only persister can use a lock file it may not write.
+A shared lock is skipped (a nil file) when the lock file isn't there and can't be created,
+like in a read-only directory: then no writer can hold the lock either.
+*/
+func openLockFile(lockPath string, shared bool) (*os.File, error) {
+	if !shared {
+		return os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, fileMode) //nolint:gosec,wrapcheck // path is clean, it is wrapped by the caller
+	}
+
+	file, err := os.Open(lockPath) //nolint:gosec // path is clean
+	if !errors.Is(err, os.ErrNotExist) {
+		return file, err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	file, err = os.OpenFile(lockPath, os.O_RDONLY|os.O_CREATE, fileMode) //nolint:gosec // path is clean
+	if err != nil {
+		return nil, nil //nolint:nilnil // the lock is skipped
+	}
+
+	return file, nil
+}
+
+/*
+isLockPath reports if the file is (still) the one at the path.
+*/
+func isLockPath(file *os.File, path string) bool {
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	pathInfo, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(fileInfo, pathInfo)
+}
+
+/*
+releaseLock removes the lock file and releases the lock on the file.
+The lock file is removed while it is still locked, so nobody can take the lock on a removed file.
+*/
+func (aof *AOF) releaseLock() error {
+	if aof.lockFile == nil {
+		return nil
+	}
+
+	// on Windows an open file can't be removed, then it is left for the next owner,
+	// and a shared lock leaves it for the others that may still hold it
+	if !aof.readOnly {
+		_ = os.Remove(aof.lockFile.Name())
+	}
+
+	err := aof.lockFile.Close()
+	aof.lockFile = nil
+
+	if err != nil {
+		return fmt.Errorf("unlock error: %w", err)
+	}
+
+	return nil
+}
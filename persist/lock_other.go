@@ -0,0 +1,18 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"os"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+tryLock does nothing, this system has no advisory lock that the syscall package supports.
+*/
+func tryLock(_ *os.File, _ bool) error {
+	return nil
+}
@@ -0,0 +1,48 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+tryLock takes an exclusive (or shared) flock on the file without waiting, it returns ErrLocked if another one holds it.
+*/
+func tryLock(file *os.File, shared bool) error {
+	conn, err := file.SyscallConn()
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	var lockErr error
+
+	how := syscall.LOCK_EX
+	if shared {
+		how = syscall.LOCK_SH
+	}
+
+	err = conn.Control(func(fd uintptr) {
+		for {
+			lockErr = syscall.Flock(int(fd), how|syscall.LOCK_NB)
+			if !errors.Is(lockErr, syscall.EINTR) {
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	if errors.Is(lockErr, syscall.EWOULDBLOCK) {
+		return ErrLocked
+	}
+
+	return lockErr //nolint:wrapcheck // it is wrapped by the caller
+}
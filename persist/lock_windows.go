@@ -0,0 +1,56 @@
+//go:build windows
+
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const (
+	lockfileFailImmediately = 0x01
+	lockfileExclusiveLock   = 0x02
+	errorLockViolation      = syscall.Errno(33)
+)
+
+// procLockFileEx is LockFileEx of kernel32, which the syscall package doesn't have.
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+tryLock takes an exclusive (or shared) lock on the first byte of the file with LockFileEx, without waiting,
+it returns ErrLocked if another one holds it.
+*/
+func tryLock(file *os.File, shared bool) error {
+	flags := uintptr(lockfileExclusiveLock | lockfileFailImmediately)
+	if shared {
+		flags = lockfileFailImmediately
+	}
+
+	overlapped := &syscall.Overlapped{}
+
+	result, _, err := procLockFileEx.Call(
+		file.Fd(),
+		flags,
+		0, // reserved
+		1, // the number of bytes (low)
+		0, // the number of bytes (high)
+		uintptr(unsafe.Pointer(overlapped)),
+	)
+	if result != 0 {
+		return nil
+	}
+
+	if errors.Is(err, errorLockViolation) {
+		return ErrLocked
+	}
+
+	return err //nolint:wrapcheck // it is wrapped by the caller
+}
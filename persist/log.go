@@ -0,0 +1,38 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"io"
+	"log/slog"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// discardLogger is the logger when none was given, it logs nothing.
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithLogger sets the logger for the internal events of the files: a flush routine that stops on a sync error,
+the corrupt entries that are skipped while reading (see WithQuarantine), the start and end of a Defrag,
+and the backup it makes. Without it, nothing is logged.
+*/
+func WithLogger(logger *slog.Logger) Option {
+	return func(aof *AOF) {
+		if logger != nil {
+			aof.log = logger
+		}
+	}
+}
+
+/*
+logger returns the logger, it can be changed with Reconfigure.
+*/
+func (aof *AOF) logger() *slog.Logger {
+	aof.mu.RLock()
+	defer aof.mu.RUnlock()
+
+	return aof.log
+}
@@ -0,0 +1,38 @@
+package persist_test
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithLogger(t *testing.T) {
+	var logs bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	path := t.TempDir() + "/fast_persister_log.db"
+
+	err := os.WriteFile(path, []byte("set\nmyBucket_1\nvalue\nset\nmyBucket_x\nvalue\n"), 0o600)
+	require.NoError(t, err)
+
+	aof, keys, err := persist.OpenPersister(path, syncIime, persist.WithQuarantine(), persist.WithLogger(logger))
+	require.NoError(t, err)
+	assert.Contains(t, logs.String(), "corrupt entry skipped")
+
+	err = aof.Defrag(keys)
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	output := logs.String()
+	assert.Contains(t, output, "defrag started")
+	assert.Contains(t, output, "backup created")
+	assert.Contains(t, output, "defrag finished")
+	assert.NotContains(t, output, "flush stopped")
+}
@@ -0,0 +1,69 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"time"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// Observer is called for the writes and the syncs of the files (for metrics and tracing), all functions are optional.
+type Observer struct {
+	Written func(bytes int)                        // after every write, with the number of bytes written
+	Synced  func(duration time.Duration)           // after every sync, with the time it took
+	Trace   func(operation string) func(err error) // at the start of every "write" and "sync", returns the end
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithObserver sets the functions that are called after every write and sync.
+They are called from the routine that writes or syncs, so they have to be quick.
+*/
+func WithObserver(observer Observer) Option {
+	return func(aof *AOF) {
+		aof.observer = observer
+	}
+}
+
+/*
+written tells the observer (if any) how many bytes were written.
+*/
+func (aof *AOF) written(bytes int) {
+	aof.mu.RLock()
+	written := aof.observer.Written
+	aof.mu.RUnlock()
+
+	if written != nil && bytes > 0 {
+		written(bytes)
+	}
+}
+
+/*
+synced tells the observer (if any) how long the sync took, that started at the given time.
+*/
+func (aof *AOF) synced(start time.Time) {
+	aof.mu.RLock()
+	synced := aof.observer.Synced
+	aof.mu.RUnlock()
+
+	if synced != nil {
+		synced(time.Since(start))
+	}
+}
+
+/*
+startTrace starts tracing a write or sync, and returns the function that ends it.
+*/
+func (aof *AOF) startTrace(operation string) func(err error) {
+	aof.mu.RLock()
+	trace := aof.observer.Trace
+	aof.mu.RUnlock()
+
+	if trace == nil {
+		return func(error) {}
+	}
+
+	return trace(operation)
+}
@@ -0,0 +1,37 @@
+package persist_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithObserver(t *testing.T) {
+	var (
+		written int
+		syncs   int
+	)
+
+	observer := persist.Observer{
+		Written: func(bytes int) { written += bytes },
+		Synced:  func(time.Duration) { syncs++ },
+	}
+
+	aof, _, err := persist.OpenPersister(t.TempDir()+"/fast_persister_observe.db", 0, persist.WithObserver(observer))
+	require.NoError(t, err)
+
+	err = aof.Write("set\ntext_1\nvalue\n")
+	require.NoError(t, err)
+	assert.Equal(t, len("set\ntext_1\nvalue\n"), written)
+	assert.Equal(t, 1, syncs)
+
+	err = aof.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, 2, syncs)
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
@@ -0,0 +1,81 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"os"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// allocation tracks how much of a file is written, and how much disk space is reserved for it.
+type allocation struct {
+	written   int64
+	allocated int64
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithPreallocation reserves the disk space of the files ahead of the writes, in extents of the given size
+(in bytes), so appending needs less metadata updates, and the files get less fragmented.
+The size of the files doesn't change, so they stay readable as they are.
+It only works on Linux (with fallocate), on other systems it has no effect.
+*/
+func WithPreallocation(extent int64) Option {
+	return func(aof *AOF) {
+		aof.allocMu.Lock()
+		defer aof.allocMu.Unlock()
+
+		aof.extent = max(extent, 0)
+		aof.allocations = nil
+	}
+}
+
+/*
+reserve makes sure that there is disk space for the next size bytes of the file.
+Preallocation is an optimisation, so when it fails, the write just continues without it.
+*/
+func (aof *AOF) reserve(file *os.File, size int) {
+	aof.allocMu.Lock()
+	defer aof.allocMu.Unlock()
+
+	if aof.extent == 0 {
+		return
+	}
+
+	alloc, found := aof.allocations[file]
+	if !found {
+		info, err := file.Stat()
+		if err != nil {
+			return
+		}
+
+		if aof.allocations == nil {
+			aof.allocations = map[*os.File]*allocation{}
+		}
+
+		alloc = &allocation{written: info.Size(), allocated: info.Size()}
+		aof.allocations[file] = alloc
+	}
+
+	alloc.written += int64(size)
+	if alloc.written <= alloc.allocated {
+		return
+	}
+
+	end := (alloc.written/aof.extent + 1) * aof.extent
+
+	// when it fails, it is tried again at the next extent
+	_ = preallocate(file, alloc.allocated, end-alloc.allocated)
+	alloc.allocated = end
+}
+
+/*
+resetAllocations forgets what was reserved, because the files were emptied or closed.
+*/
+func (aof *AOF) resetAllocations() {
+	aof.allocMu.Lock()
+	aof.allocations = nil
+	aof.allocMu.Unlock()
+}
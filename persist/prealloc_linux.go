@@ -0,0 +1,38 @@
+//go:build linux
+
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"os"
+	"syscall"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// fallocKeepSize makes fallocate reserve the space, without changing the size of the file.
+const fallocKeepSize = 0x01
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+preallocate reserves the disk space from the offset for length bytes, without changing the size of the file.
+*/
+func preallocate(file *os.File, offset, length int64) error {
+	conn, err := file.SyscallConn()
+	if err != nil {
+		return err //nolint:wrapcheck // it is ignored by the caller
+	}
+
+	var allocErr error
+
+	err = conn.Control(func(fd uintptr) {
+		allocErr = syscall.Fallocate(int(fd), fallocKeepSize, offset, length)
+	})
+	if err != nil {
+		return err //nolint:wrapcheck // it is ignored by the caller
+	}
+
+	return allocErr //nolint:wrapcheck // it is ignored by the caller
+}
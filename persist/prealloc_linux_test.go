@@ -0,0 +1,43 @@
+//go:build linux
+
+package persist
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_preallocate(t *testing.T) {
+	path := "../data/fast_persister_preallocate.db"
+
+	defer func() {
+		err := os.Remove(filepath.Clean(path))
+		require.NoError(t, err)
+	}()
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, fileMode)
+	require.NoError(t, err)
+
+	err = preallocate(file, 0, 1024*1024)
+	if err != nil {
+		_ = file.Close()
+
+		t.Skipf("fallocate is not supported here: %v", err)
+	}
+
+	info, err := file.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	require.True(t, ok)
+	assert.GreaterOrEqual(t, stat.Blocks*512, int64(1024*1024))
+
+	err = file.Close()
+	require.NoError(t, err)
+}
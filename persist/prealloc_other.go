@@ -0,0 +1,19 @@
+//go:build !linux
+
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"os"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+preallocate isn't supported on this system, because it would change the size of the file.
+*/
+func preallocate(_ *os.File, _, _ int64) error {
+	return errors.ErrUnsupported
+}
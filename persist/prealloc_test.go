@@ -0,0 +1,47 @@
+package persist_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenPersister_WithPreallocation(t *testing.T) {
+	path := "../data/fast_persister_prealloc.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	aof, _, err := persist.OpenPersister(path, 0, persist.WithPreallocation(64*1024))
+	require.NoError(t, err)
+
+	lines := "set\ntext_1\nvalue for key 1\n"
+
+	err = aof.Write(lines)
+	require.NoError(t, err)
+
+	err = aof.WriteBatch([]persist.Instruction{persist.SetInstruction("text", 2, []byte("value for key 2"))})
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	// the reserved space doesn't count as data
+	info, err := os.Stat(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2*len(lines))+aof.HeaderSize(), info.Size())
+
+	aof, keys, err := persist.OpenPersister(path, 0, persist.WithPreallocation(64*1024))
+	require.NoError(t, err)
+	assert.Len(t, keys["text"], 2)
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
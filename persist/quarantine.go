@@ -0,0 +1,186 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const quarantineExtension = ".quarantine"
+
+// Option configures the persister when it is opened.
+type Option func(*AOF)
+
+// CorruptEntry represents one bad entry that was found while reading the file.
+type CorruptEntry struct {
+	Err   error    // what was wrong with the entry
+	Lines []string // the lines of the entry, as they were read
+	Line  int      // the line number where the entry starts
+}
+
+// CorruptionReport holds all the bad entries that were skipped while reading the file.
+type CorruptionReport struct {
+	QuarantinePath string // the file with the lines of the bad entries
+	Entries        []CorruptEntry
+}
+
+// CorruptionError describes a bad entry in a file, it matches ErrCorrupted (with errors.Is).
+type CorruptionError struct {
+	Err    error  // the cause, if there is one (like a wrong checksum of a frame)
+	File   string // the file with the entry
+	Reason string // what is wrong with the entry
+	Line   int    // the line number where the entry starts
+	Offset int64  // the byte offset where the entry starts
+}
+
+// lineRecorder keeps the lines that were scanned for the current instruction (only WithQuarantine),
+// and the byte offset of the last line.
+type lineRecorder struct {
+	lines    []string
+	offset   int64 // the number of bytes that were scanned
+	start    int64 // the offset of the last line
+	keep     bool
+	complete bool // only scan complete lines and frames, the rest can still be written (see Follower)
+	repair   bool // a bad entry at the end is a torn write that can be cut off (WithRecover)
+	atEnd    bool // everything up to the end of the file was scanned
+}
+
+// ErrCorrupted is the error of a bad entry in a file, the error is a *CorruptionError.
+var ErrCorrupted = errors.New("corrupted entry")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithQuarantine makes the persister skip bad entries instead of refusing to open the file.
+The bad entries are written to a quarantine file (the path + ".quarantine"),
+and can be inspected with CorruptionReport.
+*/
+func WithQuarantine() Option {
+	return func(aof *AOF) {
+		aof.quarantine = true
+	}
+}
+
+/*
+CorruptionReport returns the bad entries that were skipped while reading the file,
+or nil if nothing was skipped.
+*/
+func (aof *AOF) CorruptionReport() *CorruptionReport {
+	return aof.report
+}
+
+/*
+Error returns the description of the bad entry.
+*/
+func (ce *CorruptionError) Error() string {
+	message := fmt.Sprintf("file (%s) has %s on line: %d", ce.File, ce.Reason, ce.Line)
+	if ce.Err != nil {
+		message += ": " + ce.Err.Error()
+	}
+
+	return message
+}
+
+/*
+Is reports if the target is ErrCorrupted.
+*/
+func (*CorruptionError) Is(target error) bool {
+	return target == ErrCorrupted
+}
+
+/*
+Unwrap returns the cause of the bad entry.
+*/
+func (ce *CorruptionError) Unwrap() error {
+	return ce.Err
+}
+
+/*
+corrupted returns the error of a bad entry on a line of the file (the offset is added by readInstructions).
+*/
+func (aof *AOF) corrupted(line int, reason string, err error) *CorruptionError {
+	return &CorruptionError{Err: err, File: aof.source, Reason: reason, Line: line}
+}
+
+/*
+recordLines makes the scanner keep track of the offset of every line it scans,
+and remember the lines when keep is true.
+*/
+func recordLines(scanner *bufio.Scanner, keep bool) *lineRecorder {
+	recorder := &lineRecorder{keep: keep}
+
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := scanRecords(data, atEOF && !recorder.complete)
+		if token != nil {
+			recorder.start = recorder.offset
+
+			if recorder.keep {
+				recorder.lines = append(recorder.lines, string(token))
+			}
+		}
+
+		recorder.offset += int64(advance)
+		recorder.atEnd = atEOF && advance == len(data)
+
+		return advance, token, err
+	})
+
+	return recorder
+}
+
+/*
+addCorruption adds a bad entry to the corruption report (and logs it), the caller holds the lock.
+*/
+func (aof *AOF) addCorruption(line int, lines []string, err error) {
+	aof.log.Warn("corrupt entry skipped", "file", aof.source, "line", line, "error", err)
+
+	if aof.report == nil {
+		aof.report = &CorruptionReport{}
+	}
+
+	aof.report.Entries = append(aof.report.Entries, CorruptEntry{
+		Line:  line,
+		Lines: lines,
+		Err:   err,
+	})
+}
+
+/*
+writeQuarantine writes the lines of all bad entries to the quarantine file.
+*/
+func (aof *AOF) writeQuarantine(path string) error {
+	if aof.report == nil {
+		return nil
+	}
+
+	err := aof.checkWritable("writeQuarantine (" + path + ")")
+	if err != nil {
+		return err
+	}
+
+	builder := &strings.Builder{}
+
+	for _, entry := range aof.report.Entries {
+		for _, line := range entry.Lines {
+			builder.WriteString(line)
+			builder.WriteByte('\n')
+		}
+	}
+
+	quarantinePath := path + quarantineExtension
+
+	err = os.WriteFile(quarantinePath, []byte(builder.String()), fileMode)
+	if err != nil {
+		return fmt.Errorf("writeQuarantine (%s) error: %w", quarantinePath, err)
+	}
+
+	aof.report.QuarantinePath = quarantinePath
+
+	return nil
+}
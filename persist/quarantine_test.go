@@ -0,0 +1,139 @@
+package persist_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenPersister_withQuarantine(t *testing.T) {
+	path := "../data/fast_persister_quarantine.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		err = os.Remove(filePath + ".quarantine")
+		require.NoError(t, err)
+	}()
+
+	lines := "set\nmyBucket_1\nvalue for key 1\nwith extra enter\n" +
+		"set\nmyBucket_2\nvalue for key 2\n" +
+		"del\nnounderscore\n" +
+		"set\nmyBucket_x\nvalue for key x\n" +
+		"set\nmyBucket_3\nvalue for key 3\n" +
+		"set\nmyBucket_4\n"
+	err := os.WriteFile(filePath, []byte(lines), 0o600)
+	require.NoError(t, err)
+
+	// without quarantine, the file can't be opened
+	aof, keys, err := persist.OpenPersister(path, syncIime)
+	require.Error(t, err)
+	assert.Nil(t, aof)
+	assert.Nil(t, keys)
+
+	aof, keys, err = persist.OpenPersister(path, syncIime, persist.WithQuarantine())
+	require.NoError(t, err)
+	require.NotNil(t, aof)
+
+	defer func() {
+		err = aof.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.Len(t, keys["myBucket"], 3)
+
+	report := aof.CorruptionReport()
+	require.NotNil(t, report)
+	assert.Equal(t, filePath+".quarantine", report.QuarantinePath)
+	require.Len(t, report.Entries, 4)
+
+	assert.Equal(t, 4, report.Entries[0].Line)
+	assert.Equal(t, []string{"with extra enter"}, report.Entries[0].Lines)
+	require.Error(t, report.Entries[0].Err)
+
+	assert.Equal(t, 8, report.Entries[1].Line)
+	assert.Equal(t, []string{"del", "nounderscore"}, report.Entries[1].Lines)
+
+	assert.Equal(t, 10, report.Entries[2].Line)
+	assert.Equal(t, []string{"set", "myBucket_x", "value for key x"}, report.Entries[2].Lines)
+
+	assert.Equal(t, 16, report.Entries[3].Line)
+	assert.Equal(t, []string{"set", "myBucket_4"}, report.Entries[3].Lines)
+
+	quarantined, err := os.ReadFile(report.QuarantinePath)
+	require.NoError(t, err)
+	assert.Equal(t, "with extra enter\ndel\nnounderscore\nset\nmyBucket_x\nvalue for key x\nset\nmyBucket_4\n",
+		string(quarantined))
+}
+
+func Test_OpenPersister_withQuarantine_noCorruption(t *testing.T) {
+	path := "../data/fast_persister_quarantine_ok.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	aof, _, err := persist.OpenPersister(path, syncIime, persist.WithQuarantine())
+	require.NoError(t, err)
+	assert.Nil(t, aof.CorruptionReport())
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	_, err = os.Stat(filePath + ".quarantine")
+	require.Error(t, err)
+}
+
+func Test_CorruptionError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fast_persister_corruption.db")
+
+	lines := "set\nmyBucket_1\nvalue for key 1\n" +
+		"set\nmyBucket_x\nvalue for key x\n"
+	err := os.WriteFile(path, []byte(lines), 0o600)
+	require.NoError(t, err)
+
+	aof, keys, err := persist.OpenPersister(path, syncIime)
+	require.ErrorIs(t, err, persist.ErrCorrupted)
+	assert.Nil(t, aof)
+	assert.Nil(t, keys)
+
+	var corrupt *persist.CorruptionError
+
+	require.ErrorAs(t, err, &corrupt)
+	assert.Equal(t, path, corrupt.File)
+	assert.Equal(t, 4, corrupt.Line)
+	assert.Equal(t, int64(len("set\nmyBucket_1\nvalue for key 1\n")), corrupt.Offset)
+	assert.Equal(t, "wrong key format: 'myBucket_x'", corrupt.Reason)
+	assert.Contains(t, err.Error(), "has wrong key format: 'myBucket_x' on line: 4")
+}
+
+func Test_OpenPersister_withBackupFallback(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "fallback.db")
+	lines := "set\ntext_1\none\nwrong line\n"
+
+	err := os.WriteFile(filePath, []byte(lines), 0o600)
+	require.NoError(t, err)
+
+	// without a backup, the corruption is returned
+	_, _, err = persist.OpenPersister(filePath, syncIime, persist.WithBackupFallback())
+	require.ErrorIs(t, err, persist.ErrCorrupted)
+
+	err = os.WriteFile(filePath+".bak", []byte("set\ntext_1\none\n"), 0o600)
+	require.NoError(t, err)
+
+	aof, keys, err := persist.OpenPersister(filePath, syncIime, persist.WithBackupFallback())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[int][]byte{"text": {1: []byte("one")}}, keys)
+	assert.Equal(t, filePath+".corrupt", aof.CorruptFile())
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
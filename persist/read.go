@@ -0,0 +1,358 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+var errIncompleteInstruction = errors.New("incomplete instruction")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+ReadFile reads all the data of a file (with the snapshot of a checkpoint, the segments and the stripes, if there are any)
+and returns the keys and the meta data. Nothing is locked, created or changed,
+so it can be used on the file of a database that is in use.
+*/
+func ReadFile(path string, opts ...Option) (map[string]map[int][]byte, map[string]string, error) {
+	aof := newAOF(0, opts)
+
+	keys, err := aof.readAll(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("readFile (%s) error: %w", path, err)
+	}
+
+	return keys, aof.meta, nil
+}
+
+/*
+readAll reads the snapshot of the checkpoint, the sealed segments, the file and the stripes into the keys (see ReadFile).
+*/
+func (aof *AOF) readAll(path string) (map[string]map[int][]byte, error) {
+	file, err := os.Open(path) //nolint:gosec // the path is given by the caller
+	if err != nil {
+		return nil, err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	keys, position, err := aof.readFileSnapshot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	segments, _, err := liveSegments(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, number := range segments {
+		err = aof.readExtraFile(segmentPath(path, number), keys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	aof.file = file
+	aof.source = path
+
+	err = aof.replayFrom(position, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	stripes, err := existingStripes(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, index := range stripes {
+		err = aof.readExtraFile(stripePath(path, index), keys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+/*
+ReadFileUntil reads the data of a file like ReadFile, but stops at the first instruction for which stop
+returns true, so it returns the state of an earlier moment. Stop gets the instruction and the offset
+in the file at which it starts. The changes from before the checkpoint (if any) are in its snapshot,
+so it can't stop before those. Striped files aren't supported, because the order of their writes is lost,
+and neither are sealed segments.
+*/
+func ReadFileUntil(
+	path string,
+	stop func(ins Instruction, offset int64) bool,
+	opts ...Option,
+) (map[string]map[int][]byte, map[string]string, error) {
+	aof := newAOF(0, opts)
+
+	stripes, err := existingStripes(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(stripes) > 0 {
+		return nil, nil, fmt.Errorf("readFileUntil (%s) error: %w", path, errStriped)
+	}
+
+	err = checkSegments(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("readFileUntil (%s) error: %w", path, err)
+	}
+
+	file, err := os.Open(path) //nolint:gosec // the path is given by the caller
+	if err != nil {
+		return nil, nil, fmt.Errorf("readFileUntil (%s) error: %w", path, err)
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	keys, position, err := aof.readFileSnapshot(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = file.Seek(position.Offset, io.SeekStart)
+	if err != nil {
+		return nil, nil, fmt.Errorf("readFileUntil (%s) error: %w", path, err)
+	}
+
+	aof.source = path
+
+	scanner, recorder := aof.newScanner(file)
+	recorder.offset = position.Offset
+	recorder.complete = true
+
+	for scanner.Scan() {
+		start := recorder.start
+
+		ins, err := readInstruction(scanner)
+		if errors.Is(err, errIncompleteInstruction) || (err == nil && stop(ins, start)) {
+			break
+		}
+
+		if err == nil {
+			err = aof.apply(ins, 0, keys)
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("readFileUntil (%s) error at offset %d: %w", path, start, err)
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, nil, fmt.Errorf("readFileUntil (%s) error: %w", path, err)
+	}
+
+	return keys, aof.meta, nil
+}
+
+/*
+readFileSnapshot reads the snapshot of the checkpoint of the file, if there is one.
+*/
+func (aof *AOF) readFileSnapshot(path string) (map[string]map[int][]byte, Position, error) {
+	snapshot, err := os.Open(path + snapshotExtension) //nolint:gosec // the path is given by the caller
+	if err != nil {
+		return map[string]map[int][]byte{}, Position{}, nil //nolint:nilerr // there is no checkpoint
+	}
+
+	defer func() {
+		_ = snapshot.Close()
+	}()
+
+	keys, position, err := aof.readSnapshot(snapshot)
+	if err != nil {
+		return nil, Position{}, fmt.Errorf("checkpoint (%s) error: %w", path+snapshotExtension, err)
+	}
+
+	return keys, position, nil
+}
+
+/*
+ReadBack reads the instructions that are in the file from the offset on (see Offset),
+so what was written can be compared with what was meant to be written.
+They are returned the way they are replayed: a compressed set and a present become a set.
+The file is opened separately, and it doesn't work with stripes.
+*/
+func (aof *AOF) ReadBack(offset int64) ([]Instruction, error) {
+	if aof.Striped() {
+		return nil, fmt.Errorf("readBack error: %w", errStriped)
+	}
+
+	path := aof.file.Name()
+
+	file, err := os.Open(path) //nolint:gosec // it's the file of the persister
+	if err != nil {
+		return nil, fmt.Errorf("readBack (%s) error: %w", path, err)
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("readBack (%s) error: %w", path, err)
+	}
+
+	var instructions []Instruction
+
+	scanner, _ := aof.newScanner(file)
+	for scanner.Scan() {
+		ins, err := readInstruction(scanner)
+		if err != nil {
+			return nil, fmt.Errorf("readBack (%s) error after offset %d: %w", path, offset, err)
+		}
+
+		instructions = append(instructions, ins)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("readBack (%s) error: %w", path, err)
+	}
+
+	return instructions, nil
+}
+
+/*
+readInstruction reads the instruction that starts at the current token of the scanner.
+*/
+func readInstruction(scanner *bufio.Scanner) (Instruction, error) {
+	token := scanner.Bytes()
+
+	var (
+		ins Instruction
+		err error
+	)
+
+	if len(token) > 0 && token[0] == frameMarker {
+		ins, err = decodeFrame(token)
+		if err != nil {
+			return Instruction{}, err
+		}
+	} else {
+		ins.Name = string(token)
+
+		op, found := opCodes[ins.Name]
+		if !found || ins.Name == "zset" {
+			return Instruction{}, fmt.Errorf("unknown instruction '%s'", ins.Name)
+		}
+
+		if !scanner.Scan() {
+			return Instruction{}, errIncompleteInstruction
+		}
+
+		ins.Key = scanner.Text()
+
+		if hasValue(op) {
+			if !scanner.Scan() {
+				return Instruction{}, errIncompleteInstruction
+			}
+
+			ins.Value = []byte(scanner.Text())
+		}
+	}
+
+	switch ins.Name {
+	case "zset":
+		ins.Name = "set"
+
+		ins.Value, err = decompress(ins.Value)
+		if err != nil {
+			return Instruction{}, err
+		}
+	case "present":
+		ins.Name = "set"
+		ins.Value = []byte{}
+	}
+
+	return ins, nil
+}
+
+/*
+Replay reads the complete instructions of the file from the start (after the sealed segments, if there are any),
+and calls apply for every one of them (a compressed set and a present become a set), until apply returns an error.
+An instruction that isn't completely written yet is left out. The files are opened separately,
+and it doesn't work with stripes.
+*/
+func (aof *AOF) Replay(apply func(ins Instruction) error) error {
+	if aof.Striped() {
+		return fmt.Errorf("replay error: %w", errStriped)
+	}
+
+	for _, path := range append(aof.Segments(), aof.file.Name()) {
+		err := aof.replayFile(path, apply)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+replayFile calls apply for every complete instruction of one file, after its header.
+*/
+func (aof *AOF) replayFile(path string, apply func(ins Instruction) error) error {
+	file, err := os.Open(path) //nolint:gosec // it's a file of the persister
+	if err != nil {
+		return fmt.Errorf("replay (%s) error: %w", path, err)
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner, recorder := aof.newScanner(file)
+	recorder.complete = true
+
+	header := true
+
+	for scanner.Scan() {
+		start := recorder.start
+
+		ins, err := readInstruction(scanner)
+		if errors.Is(err, errIncompleteInstruction) {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("replay (%s) error at offset %d: %w", path, start, err)
+		}
+
+		if header && isHeader(ins) {
+			continue
+		}
+
+		header = false
+
+		err = apply(ins)
+		if err != nil {
+			return err //nolint:wrapcheck // it is the error of the caller
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return fmt.Errorf("replay (%s) error: %w", path, err)
+	}
+
+	return nil
+}
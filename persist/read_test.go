@@ -0,0 +1,171 @@
+package persist_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ReadFile(t *testing.T) {
+	path := "../data/fast_persister_read.db"
+
+	defer func() {
+		for _, file := range []string{path, path + ".snapshot"} {
+			err := os.Remove(file)
+			require.NoError(t, err)
+		}
+	}()
+
+	aof, keys, err := persist.OpenPersister(path, 0)
+	require.NoError(t, err)
+
+	err = aof.Write("set\ntext_1\nvalue for key 1\n")
+	require.NoError(t, err)
+
+	keys["text"] = map[int][]byte{1: []byte("value for key 1")}
+
+	err = aof.Checkpoint(keys)
+	require.NoError(t, err)
+
+	err = aof.Write("set\ntext_2\nvalue for key 2\nmeta\nname\nvalue\n")
+	require.NoError(t, err)
+
+	// the file is still open
+	readKeys, meta, err := persist.ReadFile(path)
+	require.NoError(t, err)
+	assert.Len(t, readKeys["text"], 2)
+	assert.Equal(t, []byte("value for key 2"), readKeys["text"][2])
+	assert.Equal(t, "value", meta["name"])
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	_, _, err = persist.ReadFile("../data/non_existing.db")
+	require.Error(t, err)
+}
+
+func Test_ReadBack(t *testing.T) {
+	path := t.TempDir() + "/fast_persister_readback.db"
+
+	aof, _, err := persist.OpenPersister(path, 0, persist.WithCompression(1))
+	require.NoError(t, err)
+
+	defer func() {
+		err = aof.Close()
+		require.NoError(t, err)
+	}()
+
+	err = aof.Write("set\ntext_1\nfirst\n")
+	require.NoError(t, err)
+
+	offset, err := aof.Offset()
+	require.NoError(t, err)
+
+	big := []byte(strings.Repeat("compress me ", 20))
+	err = aof.WriteBatch([]persist.Instruction{
+		persist.SetInstruction("text", 2, big),
+		persist.SetInstruction("text", 3, nil),
+		persist.DelInstruction("text", 1),
+		persist.MetaInstruction("name", "value"),
+	})
+	require.NoError(t, err)
+
+	instructions, err := aof.ReadBack(offset)
+	require.NoError(t, err)
+	require.Len(t, instructions, 4)
+	assert.Equal(t, persist.Instruction{Name: "set", Key: "text_2", Value: big}, instructions[0])
+	assert.Equal(t, persist.Instruction{Name: "set", Key: "text_3", Value: []byte{}}, instructions[1])
+	assert.Equal(t, persist.DelInstruction("text", 1), instructions[2])
+	assert.Equal(t, persist.MetaInstruction("name", "value"), instructions[3])
+
+	err = aof.Write("set\ntext_4\n")
+	require.NoError(t, err)
+
+	_, err = aof.ReadBack(offset)
+	require.Error(t, err)
+}
+
+func Test_Replay(t *testing.T) {
+	path := t.TempDir() + "/fast_persister_replay.db"
+
+	aof, _, err := persist.OpenPersister(path, 0)
+	require.NoError(t, err)
+
+	defer func() {
+		err = aof.Close()
+		require.NoError(t, err)
+	}()
+
+	err = aof.WriteBatch([]persist.Instruction{
+		persist.SetInstruction("text", 1, []byte("first")),
+		persist.MetaInstruction("lsn", "1"),
+	})
+	require.NoError(t, err)
+
+	// an incomplete instruction is left out
+	err = aof.Write("set\ntext_2\n")
+	require.NoError(t, err)
+
+	var instructions []persist.Instruction
+
+	err = aof.Replay(func(ins persist.Instruction) error {
+		instructions = append(instructions, ins)
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []persist.Instruction{
+		persist.SetInstruction("text", 1, []byte("first")),
+		persist.MetaInstruction("lsn", "1"),
+	}, instructions)
+
+	errStop := errors.New("stop")
+	err = aof.Replay(func(persist.Instruction) error { return errStop })
+	require.ErrorIs(t, err, errStop)
+}
+
+func Test_ReadFileUntil(t *testing.T) {
+	path := t.TempDir() + "/fast_persister_until.db"
+
+	aof, _, err := persist.OpenPersister(path, 0)
+	require.NoError(t, err)
+
+	err = aof.WriteBatch([]persist.Instruction{persist.SetInstruction("text", 1, []byte("first"))})
+	require.NoError(t, err)
+
+	err = aof.Checkpoint(map[string]map[int][]byte{"text": {1: []byte("first")}})
+	require.NoError(t, err)
+
+	err = aof.WriteBatch([]persist.Instruction{persist.SetInstruction("text", 2, []byte("second"))})
+	require.NoError(t, err)
+
+	offset, err := aof.Offset()
+	require.NoError(t, err)
+
+	err = aof.WriteBatch([]persist.Instruction{persist.DelInstruction("text", 1)})
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	// the snapshot of the checkpoint is always read
+	keys, _, err := persist.ReadFileUntil(path, func(persist.Instruction, int64) bool { return true })
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[int][]byte{"text": {1: []byte("first")}}, keys)
+
+	keys, _, err = persist.ReadFileUntil(path, func(_ persist.Instruction, start int64) bool { return start >= offset })
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[int][]byte{"text": {1: []byte("first"), 2: []byte("second")}}, keys)
+
+	keys, _, err = persist.ReadFileUntil(path, func(persist.Instruction, int64) bool { return false })
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[int][]byte{"text": {2: []byte("second")}}, keys)
+
+	_, _, err = persist.ReadFileUntil(path+".missing", func(persist.Instruction, int64) bool { return false })
+	require.Error(t, err)
+}
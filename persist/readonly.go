@@ -0,0 +1,53 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// ErrReadOnly is returned for everything that would change the file of a persister that was opened WithReadOnly.
+var ErrReadOnly = errors.New("database is read-only")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithReadOnly opens the file (which must exist) without ever changing it, under a shared lock,
+so several read-only persisters can have it open, but no writing one.
+The lock file is only read, and in a directory where it can't be created (without it), the lock is skipped.
+Everything that would change the file fails with ErrReadOnly, also while opening:
+cutting off a torn write (WithRecover), writing the quarantine file (WithQuarantine), restoring the backup
+(WithBackupFallback) and a migration (unless WithoutMigration) or another rewrite.
+*/
+func WithReadOnly() Option {
+	return func(aof *AOF) {
+		aof.readOnly = true
+	}
+}
+
+/*
+openFlags returns the flags with which the file is opened: for appending (and created if it isn't there),
+or only for reading.
+*/
+func (aof *AOF) openFlags() int {
+	if aof.readOnly {
+		return os.O_RDONLY
+	}
+
+	return os.O_RDWR | os.O_APPEND | osCreate
+}
+
+/*
+checkWritable returns ErrReadOnly (with the operation) if the persister is read-only.
+*/
+func (aof *AOF) checkWritable(operation string) error {
+	if aof.readOnly {
+		return fmt.Errorf("%s error: %w", operation, ErrReadOnly)
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package persist_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenPersister_WithReadOnly(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "readonly.db")
+
+	// a file that isn't there isn't created
+	_, _, err := persist.OpenPersister(filePath, syncIime, persist.WithReadOnly())
+	require.ErrorIs(t, err, os.ErrNotExist)
+	assert.NoFileExists(t, filePath)
+
+	aof, _, err := persist.OpenPersister(filePath, syncIime)
+	require.NoError(t, err)
+
+	err = aof.Write("set\ntext_1\nvalue\n")
+	require.NoError(t, err)
+
+	// a read-only open has to wait for the writer
+	_, _, err = persist.OpenPersister(filePath, syncIime, persist.WithReadOnly())
+	require.ErrorIs(t, err, persist.ErrLocked)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	before, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+
+	reader, keys, err := persist.OpenPersister(filePath, syncIime, persist.WithReadOnly())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), keys["text"][1])
+
+	// the lock is shared with other readers, but not with a writer
+	other, _, err := persist.OpenPersister(filePath, syncIime, persist.WithReadOnly())
+	require.NoError(t, err)
+
+	_, _, err = persist.OpenPersister(filePath, syncIime)
+	require.ErrorIs(t, err, persist.ErrLocked)
+
+	err = reader.Write("set\ntext_2\nvalue\n")
+	require.ErrorIs(t, err, persist.ErrReadOnly)
+
+	err = reader.WriteBatch([]persist.Instruction{persist.SetInstruction("text", 2, []byte("value"))})
+	require.ErrorIs(t, err, persist.ErrReadOnly)
+
+	err = reader.Defrag(keys)
+	require.ErrorIs(t, err, persist.ErrReadOnly)
+
+	err = reader.Checkpoint(keys)
+	require.ErrorIs(t, err, persist.ErrReadOnly)
+
+	err = other.Close()
+	require.NoError(t, err)
+
+	err = reader.Close()
+	require.NoError(t, err)
+
+	after, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	aof, _, err = persist.OpenPersister(filePath, syncIime)
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
+
+func Test_OpenPersister_WithReadOnly_lockFile(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("the permissions of the file aren't enforced")
+	}
+
+	filePath := filepath.Join(t.TempDir(), "readonly_lock.db")
+
+	aof, _, err := persist.OpenPersister(filePath, syncIime)
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	// a lock file that may only be read (left by another user) is enough for a shared lock
+	err = os.WriteFile(filePath+".lock", nil, 0o400)
+	require.NoError(t, err)
+
+	reader, _, err := persist.OpenPersister(filePath, syncIime, persist.WithReadOnly())
+	require.NoError(t, err)
+
+	err = reader.Close()
+	require.NoError(t, err)
+
+	_, _, err = persist.OpenPersister(filePath, syncIime)
+	require.ErrorIs(t, err, os.ErrPermission)
+}
+
+func Test_OpenPersister_WithReadOnly_readOnlyDir(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("the permissions of the directory aren't enforced")
+	}
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "readonly_dir.db")
+
+	aof, _, err := persist.OpenPersister(filePath, syncIime)
+	require.NoError(t, err)
+
+	err = aof.Write("set\ntext_1\nvalue\n")
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	err = os.Chmod(dir, 0o500)
+	require.NoError(t, err)
+
+	defer func() {
+		err = os.Chmod(dir, 0o700)
+		require.NoError(t, err)
+	}()
+
+	// without a lock file (which can't be created), the file is read without a lock
+	reader, keys, err := persist.OpenPersister(filePath, syncIime, persist.WithReadOnly())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), keys["text"][1])
+	assert.NoFileExists(t, filePath+".lock")
+
+	err = reader.Close()
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func Test_OpenPersister_WithReadOnly_wouldChange(t *testing.T) {
+	dir := t.TempDir()
+
+	// a torn write at the end can't be cut off
+	tornPath := filepath.Join(dir, "torn.db")
+
+	err := os.WriteFile(tornPath, []byte("meta\nfileversion\n2\nset\ntext_1\nvalue\nset\ntext_2"), 0o600)
+	require.NoError(t, err)
+
+	_, _, err = persist.OpenPersister(tornPath, syncIime, persist.WithReadOnly(), persist.WithRecover())
+	require.ErrorIs(t, err, persist.ErrReadOnly)
+	assert.NoFileExists(t, tornPath+".broken")
+
+	// a bad entry can't be quarantined
+	badPath := filepath.Join(dir, "bad.db")
+
+	err = os.WriteFile(badPath, []byte("meta\nfileversion\n2\nwrong\ntext_1\nvalue\nset\ntext_2\nvalue\n"), 0o600)
+	require.NoError(t, err)
+
+	_, _, err = persist.OpenPersister(badPath, syncIime, persist.WithReadOnly(), persist.WithQuarantine())
+	require.ErrorIs(t, err, persist.ErrReadOnly)
+	assert.NoFileExists(t, badPath+".quarantine")
+
+	// an older version can't be migrated, unless it is read without a migration
+	oldPath := filepath.Join(dir, "old.db")
+	lines := []byte("set\ntext_1\nvalue\n")
+
+	err = os.WriteFile(oldPath, lines, 0o600)
+	require.NoError(t, err)
+
+	_, _, err = persist.OpenPersister(oldPath, syncIime, persist.WithReadOnly())
+	require.ErrorIs(t, err, persist.ErrReadOnly)
+
+	aof, keys, err := persist.OpenPersister(oldPath, syncIime, persist.WithReadOnly(), persist.WithoutMigration())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), keys["text"][1])
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(oldPath)
+	require.NoError(t, err)
+	assert.Equal(t, lines, content)
+}
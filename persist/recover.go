@@ -0,0 +1,77 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const brokenExtension = ".broken"
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithRecover makes the persister cut off an entry at the end of the file that can't be read
+(a write that was torn by a crash), instead of refusing to open the file. The entry is saved
+to a file (the path + ".broken") before the file is truncated, and DiscardedBytes tells its size.
+Bad entries before the end still fail (see WithQuarantine), and the stripes aren't repaired.
+*/
+func WithRecover() Option {
+	return func(aof *AOF) {
+		aof.repair = true
+	}
+}
+
+/*
+DiscardedBytes returns the number of bytes that were cut off the end of the file while opening it
+(WithRecover), 0 when the file was complete.
+*/
+func (aof *AOF) DiscardedBytes() int64 {
+	return aof.tornBytes
+}
+
+/*
+cutTornTail saves the torn write at the end of the file that was read to the broken file,
+and truncates the file to the last complete entry.
+*/
+func (aof *AOF) cutTornTail(filePath string) error {
+	if aof.tornBytes == 0 {
+		return nil
+	}
+
+	err := aof.checkWritable("recover (" + filePath + ")")
+	if err != nil {
+		return err
+	}
+
+	tail := make([]byte, aof.tornBytes)
+
+	_, err = aof.file.ReadAt(tail, aof.tornAt)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("recover (%s) error: %w", filePath, err)
+	}
+
+	err = os.WriteFile(filePath+brokenExtension, tail, fileMode)
+	if err != nil {
+		return fmt.Errorf("recover (%s) error: %w", filePath, err)
+	}
+
+	err = aof.file.Truncate(aof.tornAt)
+	if err == nil {
+		err = syncFile(aof.file)
+	}
+
+	if err != nil {
+		return fmt.Errorf("recover (%s) error: %w", filePath, err)
+	}
+
+	aof.log.Warn("torn write cut off", "file", filePath, "offset", aof.tornAt, "bytes", aof.tornBytes,
+		"broken", filePath+brokenExtension)
+
+	return nil
+}
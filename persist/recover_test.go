@@ -0,0 +1,86 @@
+package persist_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenPersister_withRecover(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "recover.db")
+
+	aof, _, err := persist.OpenPersister(filePath, syncIime, persist.WithFormat(persist.FormatBinary))
+	require.NoError(t, err)
+
+	err = aof.WriteBatch([]persist.Instruction{persist.SetInstruction("text", 1, []byte("one"))})
+	require.NoError(t, err)
+
+	err = aof.WriteBatch([]persist.Instruction{persist.SetInstruction("text", 2, []byte("two"))})
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	info, err := os.Stat(filePath)
+	require.NoError(t, err)
+
+	// a crash in the middle of the last write
+	err = os.Truncate(filePath, info.Size()-3)
+	require.NoError(t, err)
+
+	_, _, err = persist.OpenPersister(filePath, syncIime)
+	require.ErrorIs(t, err, persist.ErrCorrupted)
+
+	aof, keys, err := persist.OpenPersister(filePath, syncIime, persist.WithRecover())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[int][]byte{"text": {1: []byte("one")}}, keys)
+
+	discarded := aof.DiscardedBytes()
+	assert.Positive(t, discarded)
+
+	err = aof.WriteBatch([]persist.Instruction{persist.SetInstruction("text", 3, []byte("three"))})
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	broken, err := os.ReadFile(filePath + ".broken")
+	require.NoError(t, err)
+	assert.Len(t, broken, int(discarded))
+
+	aof, keys, err = persist.OpenPersister(filePath, syncIime)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[int][]byte{"text": {1: []byte("one"), 3: []byte("three")}}, keys)
+	assert.Zero(t, aof.DiscardedBytes())
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
+
+func Test_OpenPersister_withRecover_middle(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "recover.db")
+
+	lines := "set\ntext_1\none\nwrong line\nset\ntext_2\ntwo\nset\ntext_3\n"
+	err := os.WriteFile(filePath, []byte(lines), 0o600)
+	require.NoError(t, err)
+
+	// only the end can be cut off
+	_, _, err = persist.OpenPersister(filePath, syncIime, persist.WithRecover())
+	require.ErrorIs(t, err, persist.ErrCorrupted)
+
+	lines = "set\ntext_1\none\nset\ntext_2\ntwo\nset\ntext_3\n"
+	err = os.WriteFile(filePath, []byte(lines), 0o600)
+	require.NoError(t, err)
+
+	aof, keys, err := persist.OpenPersister(filePath, syncIime, persist.WithRecover())
+	require.NoError(t, err)
+	assert.Len(t, keys["text"], 2)
+	assert.Equal(t, int64(len("set\ntext_3\n")), aof.DiscardedBytes())
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
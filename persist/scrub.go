@@ -0,0 +1,102 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Scrub re-reads a part of the file (about limit bytes, from offset) and checks that every entry
+in it can still be read, so corruption (like a bad disk block) is found before the file is opened again.
+It returns the offset of the next entry to scrub, which is 0 when the end of the file was reached
+(or when offset is beyond the end, because the file was defragmented in the meantime).
+The file is opened separately, but the caller has to make sure it isn't written meanwhile.
+*/
+func (aof *AOF) Scrub(offset, limit int64) (int64, error) {
+	if aof.Striped() {
+		return 0, fmt.Errorf("scrub error: %w", errStriped)
+	}
+
+	path := aof.file.Name()
+
+	file, err := os.Open(path) //nolint:gosec // it's the file of the persister
+	if err != nil {
+		return 0, fmt.Errorf("scrub (%s) error: %w", path, err)
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("scrub (%s) error: %w", path, err)
+	}
+
+	end := info.Size()
+	if offset >= end {
+		offset = 0
+	}
+
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return 0, fmt.Errorf("scrub (%s) error: %w", path, err)
+	}
+
+	next, err := aof.scrubEntries(io.LimitReader(file, end-offset), offset, limit)
+	if err != nil {
+		return 0, fmt.Errorf("scrub (%s) error: %w", path, err)
+	}
+
+	if next >= end {
+		return 0, nil
+	}
+
+	return next, nil
+}
+
+/*
+scrubEntries reads the entries until at least limit bytes were read, and returns the offset after the last one.
+*/
+func (aof *AOF) scrubEntries(reader io.Reader, offset, limit int64) (int64, error) {
+	scratch := newAOF(0, []Option{WithScanBuffer(aof.scanBuffer)})
+	scratch.source = aof.file.Name()
+	keys := map[string]map[int][]byte{}
+
+	var corrupt *CorruptionError
+
+	scanner, recorder := scratch.newScanner(reader)
+	recorder.offset = offset
+
+	for recorder.offset-offset < limit {
+		if !scanner.Scan() {
+			break
+		}
+
+		start := recorder.start
+
+		_, err := scratch.processInstruction(scanner.Text(), scanner, 1, keys)
+		if err != nil {
+			if errors.As(err, &corrupt) {
+				corrupt.Offset = start
+			}
+
+			return 0, fmt.Errorf("entry at offset %d: %w", start, err)
+		}
+
+		clear(keys)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return 0, fmt.Errorf("read error after offset %d: %w", recorder.offset, err)
+	}
+
+	return recorder.offset, nil
+}
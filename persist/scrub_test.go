@@ -0,0 +1,65 @@
+package persist_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Scrub(t *testing.T) {
+	path := "../data/fast_persister_scrub.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	// every entry is 31 bytes
+	lines := "set\nmyBucket_1\nvalue for key 1\n" +
+		"set\nmyBucket_2\nvalue for key 2\n" +
+		"set\nmyBucket_3\nvalue for key 3\n"
+	err := os.WriteFile(filePath, []byte(lines), 0o600)
+	require.NoError(t, err)
+
+	// without a migration, the file keeps its offsets
+	aof, _, err := persist.OpenPersister(path, syncIime, persist.WithoutMigration())
+	require.NoError(t, err)
+
+	defer func() {
+		err = aof.Close()
+		require.NoError(t, err)
+	}()
+
+	next, err := aof.Scrub(0, 40)
+	require.NoError(t, err)
+	assert.Equal(t, int64(62), next)
+
+	next, err = aof.Scrub(next, 40)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), next)
+
+	// an offset beyond the end starts over
+	next, err = aof.Scrub(1000, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(31), next)
+
+	// a corruption of the second entry is found when its part of the file is scrubbed
+	file, err := os.OpenFile(filePath, os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = file.WriteAt([]byte("sXt"), 31)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	next, err = aof.Scrub(0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(31), next)
+
+	next, err = aof.Scrub(next, 1)
+	require.ErrorContains(t, err, "offset 31")
+	assert.Equal(t, int64(0), next)
+}
@@ -0,0 +1,419 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const (
+	segmentExtension = ".seg"
+	// baseMeta is the meta data name in the header of a compacted segment, which holds everything before it.
+	baseMeta = "segmentbase"
+	// segmentHeaderLimit is the number of bytes in which the header of a segment must be.
+	segmentHeaderLimit = 512
+)
+
+var errSegmented = errors.New("not possible with segmented files")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithSegments makes the persister write the file in segments of (about) the given number of bytes.
+When the file reaches the size, it is sealed (renamed to the path + ".seg1" etc.) before the next write,
+and a new empty file is started. Sealed segments never change, so only the new ones have to be backed up,
+and the oldest ones can be merged with CompactSegments (which is much cheaper than a Defrag of everything).
+Segments can't be combined with stripes, a checkpoint or a follower, and a checkpoint
+that exists when the file is opened is rewritten into the file (like a Defrag).
+*/
+func WithSegments(size int64) Option {
+	return func(aof *AOF) {
+		aof.segmentSize = max(size, 0)
+	}
+}
+
+/*
+Segmented returns true if the file is written in segments, or if there are sealed segments.
+*/
+func (aof *AOF) Segmented() bool {
+	aof.mu.RLock()
+	defer aof.mu.RUnlock()
+
+	return aof.segmentSize > 0 || len(aof.segments) > 0
+}
+
+/*
+Segments returns the paths of the sealed segments, oldest first. The file itself is the active segment.
+*/
+func (aof *AOF) Segments() []string {
+	aof.mu.RLock()
+	defer aof.mu.RUnlock()
+
+	paths := make([]string, 0, len(aof.segments))
+	for _, number := range aof.segments {
+		paths = append(paths, segmentPath(aof.file.Name(), number))
+	}
+
+	return paths
+}
+
+/*
+Rotate seals the file as the next segment, and starts a new empty file, when the file reached the segment size.
+It is done before every write, so the instructions of one write are never spread over two segments.
+*/
+func (aof *AOF) Rotate() error {
+	if aof.segmentSize == 0 || aof.Striped() {
+		return nil
+	}
+
+	info, err := aof.file.Stat()
+	if err != nil {
+		return fmt.Errorf("rotate (%s) error: %w", aof.file.Name(), err)
+	}
+
+	if info.Size() < aof.segmentSize || info.Size() <= aof.HeaderSize() {
+		return nil
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	path := aof.file.Name()
+
+	number := 1
+	if count := len(aof.segments); count > 0 {
+		number = aof.segments[count-1] + 1
+	}
+
+	err = aof.closeFiles()
+	if err != nil {
+		return fmt.Errorf("rotate->close error: %w", err)
+	}
+
+	err = os.Rename(path, segmentPath(path, number))
+	if err != nil {
+		return fmt.Errorf("rotate (%s) error: %w", path, err)
+	}
+
+	_, err = aof.getData(path, map[string]map[int][]byte{})
+	if err == nil {
+		err = aof.writeHeader()
+	}
+
+	if err != nil {
+		return fmt.Errorf("rotate (%s) error: %w", path, err)
+	}
+
+	aof.mu.Lock()
+	aof.segments = append(aof.segments, number)
+	aof.mu.Unlock()
+
+	aof.measure()
+	aof.startFlush()
+
+	aof.logger().Info("segment sealed", "file", path, "segment", segmentPath(path, number), "bytes", info.Size())
+
+	return nil
+}
+
+/*
+CompactSegments merges the oldest count sealed segments into one (the newest of them),
+that only holds the records and the meta data that are left, so the cost is bounded by their size.
+The trailer gets the meta data of the merged segments, and returns the instructions to write after the records
+(like a marker that the history before it is gone). It returns the number of record instructions that were dropped.
+*/
+func (aof *AOF) CompactSegments(count int, trailer func(meta map[string]string) []Instruction) (int, error) {
+	err := aof.checkWritable("compactSegments")
+	if err != nil {
+		return 0, err
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	aof.mu.RLock()
+	merged := slices.Clone(aof.segments[:min(max(count, 0), len(aof.segments))])
+	aof.mu.RUnlock()
+
+	if len(merged) == 0 {
+		return 0, nil
+	}
+
+	start := time.Now()
+	path := aof.file.Name()
+
+	reader := newAOF(0, []Option{WithScanBuffer(aof.scanBuffer)})
+	reader.log = aof.log
+	keys := map[string]map[int][]byte{}
+
+	for _, number := range merged {
+		err = reader.readExtraFile(segmentPath(path, number), keys)
+		if err != nil {
+			return 0, fmt.Errorf("compactSegments error: %w", err)
+		}
+	}
+
+	var instructions []Instruction
+	if trailer != nil {
+		instructions = trailer(reader.meta)
+	}
+
+	last := merged[len(merged)-1]
+
+	err = aof.writeSegment(segmentPath(path, last), merged[0], keys, reader.meta, instructions)
+	if err != nil {
+		return 0, fmt.Errorf("compactSegments error: %w", err)
+	}
+
+	// the merged segment holds everything before it, so a crash before these are removed does no harm
+	err = removeSegments(path, merged[:len(merged)-1])
+
+	aof.mu.Lock()
+	aof.segments = slices.DeleteFunc(aof.segments, func(number int) bool {
+		return number < last
+	})
+	aof.mu.Unlock()
+
+	aof.measure()
+
+	if err != nil {
+		return 0, fmt.Errorf("compactSegments error: %w", err)
+	}
+
+	written := 0
+	for _, records := range keys {
+		written += len(records)
+	}
+
+	aof.logger().Info("segments compacted", "file", path, "segments", len(merged), "records", written,
+		"dropped", reader.records-written, "duration", time.Since(start))
+
+	return reader.records - written, nil
+}
+
+/*
+writeSegment writes a compacted segment to a temporary file and then moves it in place:
+the header (with the mark that it holds everything before it), the records and the trailer.
+*/
+func (aof *AOF) writeSegment(
+	segment string,
+	first int,
+	keys map[string]map[int][]byte,
+	meta map[string]string,
+	trailer []Instruction,
+) error {
+	tmpPath := segment + ".tmp"
+
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fileMode) //nolint:gosec // path is clean
+	if err != nil {
+		return fmt.Errorf("create (%s) error: %w", tmpPath, err)
+	}
+
+	writer := bufio.NewWriter(file)
+
+	buf := versionHeader().appendTo(nil, aof.format)
+	buf = MetaInstruction(baseMeta, strconv.Itoa(first)).appendTo(buf, aof.format)
+
+	_, err = writer.Write(buf)
+	if err == nil {
+		err = writeRecords(writer, keys, meta, aof.format, aof.compressor)
+	}
+
+	for _, ins := range trailer {
+		if err == nil {
+			_, err = writer.Write(ins.appendTo(nil, aof.format))
+		}
+	}
+
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	if err == nil {
+		err = syncFile(file)
+	}
+
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmpPath, segment)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmpPath)
+
+		return fmt.Errorf("write (%s) error: %w", segment, err)
+	}
+
+	return nil
+}
+
+/*
+loadSegments reads the sealed segments into new keys, starting at the last compacted one.
+The segments before it were merged into it already, so they are removed (unless WithoutMigration).
+*/
+func (aof *AOF) loadSegments(path string) (map[string]map[int][]byte, error) {
+	keys := make(map[string]map[int][]byte, 1)
+
+	live, stale, err := liveSegments(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !aof.noMigration && !aof.readOnly {
+		err = removeSegments(path, stale)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, number := range live {
+		err = aof.readExtraFile(segmentPath(path, number), keys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	aof.mu.Lock()
+	aof.segments = live
+	aof.mu.Unlock()
+
+	return keys, nil
+}
+
+/*
+liveSegments returns the numbers of the segments that hold the data (from the last compacted one on),
+and the stale ones before it, which a compaction didn't remove (because it was interrupted).
+*/
+func liveSegments(path string) ([]int, []int, error) {
+	numbers, err := existingSegments(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for index := len(numbers) - 1; index > 0; index-- {
+		base, err := isBaseSegment(segmentPath(path, numbers[index]))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if base {
+			return numbers[index:], numbers[:index], nil
+		}
+	}
+
+	return numbers, nil, nil
+}
+
+/*
+isBaseSegment tells if the header of the segment marks it as compacted.
+*/
+func isBaseSegment(segment string) (bool, error) {
+	file, err := os.Open(segment) //nolint:gosec // path is clean
+	if err != nil {
+		return false, fmt.Errorf("isBaseSegment error: %w", err)
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner := bufio.NewScanner(io.LimitReader(file, segmentHeaderLimit))
+	recordLines(scanner, false)
+
+	// the mark comes right after the version
+	for range 2 {
+		if !scanner.Scan() {
+			return false, nil
+		}
+
+		ins, err := readInstruction(scanner)
+		if err != nil {
+			return false, nil //nolint:nilerr // a bad entry is reported while reading the segment
+		}
+
+		if ins.Name == "meta" && ins.Key == baseMeta {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+/*
+segmentPath returns the path of a sealed segment.
+*/
+func segmentPath(path string, number int) string {
+	return path + segmentExtension + strconv.Itoa(number)
+}
+
+/*
+existingSegments returns the (sorted) numbers of the sealed segments that exist for the path.
+*/
+func existingSegments(path string) ([]int, error) {
+	return numberedFiles(path, segmentExtension)
+}
+
+/*
+removeSegments removes the sealed segments with the numbers.
+*/
+func removeSegments(path string, numbers []int) error {
+	for _, number := range numbers {
+		err := os.Remove(segmentPath(path, number))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removeSegments error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+/*
+resetSegments removes all the sealed segments (after everything was written to the file itself).
+*/
+func (aof *AOF) resetSegments(path string) error {
+	existing, err := existingSegments(path)
+	if err != nil {
+		return err
+	}
+
+	err = removeSegments(path, existing)
+	if err != nil {
+		return err
+	}
+
+	aof.mu.Lock()
+	aof.segments = nil
+	aof.mu.Unlock()
+
+	return nil
+}
+
+/*
+checkSegments refuses to continue when there are sealed segments for the path,
+for the features that only read the file itself.
+*/
+func checkSegments(path string) error {
+	segments, err := existingSegments(path)
+	if err != nil {
+		return err
+	}
+
+	if len(segments) > 0 {
+		return errSegmented
+	}
+
+	return nil
+}
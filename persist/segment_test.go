@@ -0,0 +1,217 @@
+package persist_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenPersister_WithSegments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "segments.db")
+
+	aof, _, err := persist.OpenPersister(path, 0, persist.WithSegments(100))
+	require.NoError(t, err)
+	assert.True(t, aof.Segmented())
+	assert.Empty(t, aof.Segments())
+
+	writeSegmentRecords(t, aof)
+
+	err = aof.WriteBatch([]persist.Instruction{persist.DelInstruction("bucket", 1), persist.MetaInstruction("name", "value")})
+	require.NoError(t, err)
+
+	segments := aof.Segments()
+	require.NotEmpty(t, segments)
+	assert.Equal(t, path+".seg1", segments[0])
+
+	var size int64
+
+	for _, segment := range append(segments, path) {
+		info, err := os.Stat(segment)
+		require.NoError(t, err)
+
+		size += info.Size()
+	}
+
+	assert.Equal(t, size, aof.Size())
+
+	var replayed int
+
+	err = aof.Replay(func(ins persist.Instruction) error {
+		assert.NotEqual(t, "fileversion", ins.Key)
+
+		replayed++
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 22, replayed)
+
+	err = aof.Checkpoint(map[string]map[int][]byte{})
+	require.Error(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	keys, meta, err := persist.ReadFile(path)
+	require.NoError(t, err)
+	assert.Len(t, keys["bucket"], 19)
+	assert.Equal(t, "value", meta["name"])
+
+	// the sealed segments are read without the option too
+	aof, keys, err = persist.OpenPersister(path, 0)
+	require.NoError(t, err)
+	assert.Len(t, keys["bucket"], 19)
+	assert.Equal(t, []byte("value 20"), keys["bucket"][20])
+	assert.Equal(t, "value", aof.Meta()["name"])
+	assert.Equal(t, segments, aof.Segments())
+
+	err = aof.Defrag(keys)
+	require.NoError(t, err)
+	assert.Empty(t, aof.Segments())
+	assert.False(t, aof.Segmented())
+
+	_, err = os.Stat(path + ".seg1")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	aof, keys, err = persist.OpenPersister(path, 0)
+	require.NoError(t, err)
+	assert.Len(t, keys["bucket"], 19)
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
+
+func Test_CompactSegments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "compact.db")
+
+	aof, _, err := persist.OpenPersister(path, 0, persist.WithSegments(100))
+	require.NoError(t, err)
+
+	writeSegmentRecords(t, aof)
+	writeSegmentRecords(t, aof)
+
+	err = aof.WriteBatch([]persist.Instruction{persist.DelBucketInstruction("bucket"), persist.SetInstruction("other", 1, []byte("one"))})
+	require.NoError(t, err)
+
+	writeSegmentRecords(t, aof)
+
+	segments := aof.Segments()
+	require.Greater(t, len(segments), 2)
+
+	// a copy of the oldest segment is what an interrupted compaction leaves behind
+	stale, err := os.ReadFile(segments[0])
+	require.NoError(t, err)
+
+	dropped, err := aof.CompactSegments(len(segments)-1, func(meta map[string]string) []persist.Instruction {
+		return []persist.Instruction{persist.MetaInstruction("compacted", "yes")}
+	})
+	require.NoError(t, err)
+	assert.Positive(t, dropped)
+	assert.Equal(t, segments[len(segments)-2:], aof.Segments())
+
+	_, err = os.Stat(segments[0])
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	dropped, err = aof.CompactSegments(0, nil)
+	require.NoError(t, err)
+	assert.Zero(t, dropped)
+
+	var trailer bool
+
+	err = aof.Replay(func(ins persist.Instruction) error {
+		if ins.Key == "compacted" {
+			trailer = true
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, trailer)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	err = os.WriteFile(segments[0], stale, 0o600)
+	require.NoError(t, err)
+
+	keys, _, err := persist.ReadFile(path)
+	require.NoError(t, err)
+	assert.Len(t, keys["bucket"], 20)
+	assert.Equal(t, []byte("one"), keys["other"][1])
+
+	aof, keys, err = persist.OpenPersister(path, 0, persist.WithSegments(100))
+	require.NoError(t, err)
+	assert.Len(t, keys["bucket"], 20)
+	assert.Equal(t, []byte("one"), keys["other"][1])
+	assert.Equal(t, "yes", aof.Meta()["compacted"])
+
+	// the stale segment is removed when the file is opened
+	_, err = os.Stat(segments[0])
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
+
+func Test_Segments_notPossible(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "guards.db")
+
+	_, _, err := persist.OpenPersister(path, 0, persist.WithSegments(100), persist.WithStripes(2))
+	require.Error(t, err)
+
+	aof, _, err := persist.OpenPersister(path, 0, persist.WithSegments(100))
+	require.NoError(t, err)
+
+	writeSegmentRecords(t, aof)
+
+	_, err = persist.Follow(path)
+	require.Error(t, err)
+
+	_, _, err = persist.ReadFileUntil(path, func(persist.Instruction, int64) bool { return false })
+	require.Error(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	// a checkpoint is rewritten into the file when it's opened with segments
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.db")
+
+	aof, _, err = persist.OpenPersister(checkpointPath, 0)
+	require.NoError(t, err)
+
+	writeSegmentRecords(t, aof)
+
+	err = aof.Checkpoint(map[string]map[int][]byte{"bucket": {1: []byte("value 1")}})
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	aof, keys, err := persist.OpenPersister(checkpointPath, 0, persist.WithSegments(100))
+	require.NoError(t, err)
+	assert.Len(t, keys["bucket"], 1)
+
+	_, err = os.Stat(checkpointPath + ".snapshot")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
+
+// writeSegmentRecords writes 20 records in separate writes, so the file is sealed a few times.
+func writeSegmentRecords(t *testing.T, aof *persist.AOF) {
+	t.Helper()
+
+	for key := 1; key <= 20; key++ {
+		err := aof.WriteBatch([]persist.Instruction{persist.SetInstruction("bucket", key, []byte("value "+strconv.Itoa(key)))})
+		require.NoError(t, err)
+	}
+}
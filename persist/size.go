@@ -0,0 +1,83 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"os"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Size returns the number of bytes of all the files on disk: the file itself, the stripes,
+the sealed segments and the snapshot of a checkpoint. It is kept up to date by the writes, so it is cheap to call.
+*/
+func (aof *AOF) Size() int64 {
+	return aof.size.Load()
+}
+
+/*
+RecordsRead returns the number of record instructions (stores and deletes) that were read while opening,
+from all the files. Compared with the number of records, it tells how many are superseded or deleted.
+*/
+func (aof *AOF) RecordsRead() int {
+	return aof.records
+}
+
+/*
+Path returns the path of the file.
+*/
+func (aof *AOF) Path() string {
+	return aof.file.Name()
+}
+
+/*
+measure sets the size to the sizes of the files on disk.
+*/
+func (aof *AOF) measure() {
+	path := aof.file.Name()
+	paths := []string{path, path + snapshotExtension}
+
+	extra, err := extraPaths(path)
+	if err == nil {
+		paths = append(paths, extra...)
+	}
+
+	var total int64
+
+	for _, filePath := range paths {
+		info, err := os.Stat(filePath)
+		if err == nil {
+			total += info.Size()
+		}
+	}
+
+	aof.size.Store(total)
+}
+
+/*
+extraPaths returns the paths of the stripes and the sealed segments that exist for the path.
+*/
+func extraPaths(path string) ([]string, error) {
+	stripes, err := existingStripes(path)
+	if err != nil {
+		return nil, err
+	}
+
+	segments, err := existingSegments(path)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(stripes)+len(segments))
+
+	for _, index := range stripes {
+		paths = append(paths, stripePath(path, index))
+	}
+
+	for _, number := range segments {
+		paths = append(paths, segmentPath(path, number))
+	}
+
+	return paths, nil
+}
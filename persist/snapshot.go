@@ -0,0 +1,435 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const (
+	snapshotHeader    = "snapshot"
+	snapshotExtension = ".snapshot"
+)
+
+/*
+Position is where a snapshot was taken: the offset in the file up to which it is complete,
+and the generation of the file (see Generation), so a snapshot isn't replayed on top of a file
+that was rewritten after it (by a Defrag or a Checkpoint). A snapshot without a generation isn't checked.
+*/
+type Position struct {
+	Offset     int64
+	Generation string
+}
+
+// ErrSnapshotMismatch is returned when the file was rewritten after the snapshot was taken.
+var ErrSnapshotMismatch = errors.New("the snapshot doesn't belong to the file")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Offset returns the current size of the file, which is where the next write will go.
+With stripes there is no single offset, so it returns an error.
+*/
+func (aof *AOF) Offset() (int64, error) {
+	if aof.Striped() {
+		return 0, fmt.Errorf("offset error: %w", errStriped)
+	}
+
+	info, err := aof.file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("offset (%s) error: %w", aof.file.Name(), err)
+	}
+
+	return info.Size(), nil
+}
+
+/*
+Position returns the offset (see Offset) and the generation of the file, for a snapshot.
+*/
+func (aof *AOF) Position() (Position, error) {
+	offset, err := aof.Offset()
+	if err != nil {
+		return Position{}, err
+	}
+
+	return Position{Offset: offset, Generation: aof.generation}, nil
+}
+
+/*
+WriteSnapshot writes the keys and the meta data to a snapshot stream.
+The position tells up to where in the file the snapshot is complete,
+so everything after it can be replayed on top of the snapshot.
+*/
+func WriteSnapshot(
+	writer io.Writer,
+	position Position,
+	keys map[string]map[int][]byte,
+	meta map[string]string,
+	format Format,
+) error {
+	return writeSnapshot(writer, position, keys, meta, format, nil)
+}
+
+/*
+writeSnapshot writes the snapshot stream, with the values compressed if there is a compressor.
+*/
+func writeSnapshot(
+	writer io.Writer,
+	position Position,
+	keys map[string]map[int][]byte,
+	meta map[string]string,
+	format Format,
+	comp *compressor,
+) error {
+	bufWriter := bufio.NewWriter(writer)
+
+	line := strconv.FormatInt(position.Offset, 10)
+	if position.Generation != "" {
+		line += " " + position.Generation
+	}
+
+	_, err := bufWriter.WriteString(snapshotHeader + "\n" + line + "\n")
+	if err == nil {
+		err = writeRecords(bufWriter, keys, meta, format, comp)
+	}
+
+	if err == nil {
+		err = bufWriter.Flush()
+	}
+
+	if err != nil {
+		return fmt.Errorf("writeSnapshot error: %w", err)
+	}
+
+	return nil
+}
+
+/*
+OpenPersisterFromSnapshot reads the data from a snapshot stream and then replays
+the part of the file that was written after the snapshot was taken.
+This is much quicker than reading the whole history of the file.
+*/
+func OpenPersisterFromSnapshot(
+	reader io.Reader,
+	path string,
+	syncIime int,
+	opts ...Option,
+) (*AOF, map[string]map[int][]byte, error) {
+	aof := newAOF(syncIime, opts)
+	if aof.Striped() {
+		return nil, nil, fmt.Errorf("openPersisterFromSnapshot error: %w", errStriped)
+	}
+
+	filePath, ok := cleanPath(path)
+	if !ok {
+		return nil, nil, fmt.Errorf("openPersisterFromSnapshot error: invalid path '%s'", path)
+	}
+
+	_, err := os.Stat(filepath.Dir(filePath))
+	if err != nil {
+		return nil, nil, fmt.Errorf("openPersisterFromSnapshot (%s) error: %w", path, err)
+	}
+
+	err = checkSegments(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("openPersisterFromSnapshot (%s) error: %w", path, err)
+	}
+
+	aof.lockFile, err = acquireLock(filePath, aof.readOnly)
+	if err != nil {
+		return nil, nil, fmt.Errorf("openPersisterFromSnapshot (%s) error: %w", path, err)
+	}
+
+	keys, err := aof.loadSnapshot(reader, filePath)
+	if err != nil {
+		return nil, nil, errors.Join(err, aof.releaseLock())
+	}
+
+	aof.measure()
+	aof.startFlush()
+
+	return aof, keys, nil
+}
+
+/*
+loadSnapshot reads the snapshot, and the file after the offset of the snapshot, into the keys.
+*/
+func (aof *AOF) loadSnapshot(reader io.Reader, filePath string) (map[string]map[int][]byte, error) {
+	keys, position, err := aof.readSnapshot(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = aof.tailFile(filePath, position, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	err = aof.writeQuarantine(filePath)
+	if err != nil {
+		return nil, errors.Join(err, aof.file.Close())
+	}
+
+	return keys, nil
+}
+
+/*
+ReadSnapshot reads the keys from a snapshot stream (as written by WriteSnapshot).
+*/
+func ReadSnapshot(reader io.Reader) (map[string]map[int][]byte, error) {
+	keys, _, err := newAOF(0, nil).readSnapshot(reader)
+
+	return keys, err
+}
+
+/*
+readSnapshot reads the snapshot stream and returns the keys and the position in the file.
+*/
+func (aof *AOF) readSnapshot(reader io.Reader) (map[string]map[int][]byte, Position, error) {
+	aof.source = snapshotHeader
+
+	reader, stop := snapshotReader(reader)
+	defer stop()
+
+	scanner, recorder := aof.newScanner(reader)
+
+	if !scanner.Scan() || scanner.Text() != snapshotHeader || !scanner.Scan() {
+		return nil, Position{}, fmt.Errorf("readSnapshot error: missing %s header", snapshotHeader)
+	}
+
+	// an older snapshot only has the offset
+	offsetText, generation, _ := strings.Cut(scanner.Text(), " ")
+
+	offset, err := strconv.ParseInt(offsetText, 10, 64)
+	if err != nil || offset < 0 {
+		return nil, Position{}, fmt.Errorf("readSnapshot error: wrong offset '%s'", scanner.Text())
+	}
+
+	keys := make(map[string]map[int][]byte, 1)
+
+	err = aof.readInstructions(scanner, recorder, keys)
+	if err == nil {
+		err = scanner.Err()
+	}
+
+	if err != nil {
+		return nil, Position{}, fmt.Errorf("readSnapshot error: %w", err)
+	}
+
+	return keys, Position{Offset: offset, Generation: generation}, nil
+}
+
+/*
+tailFile opens the file and replays everything after the position into the keys.
+*/
+func (aof *AOF) tailFile(path string, position Position, keys map[string]map[int][]byte) error {
+	aof.mu.Lock()
+	defer aof.mu.Unlock()
+
+	file, err := os.OpenFile(path, aof.openFlags(), fileMode) //nolint:gosec // path is clean
+	if err != nil {
+		return fmt.Errorf("openfile (%s) error: %w", path, err)
+	}
+
+	aof.file = file
+	aof.source = file.Name()
+
+	err = aof.replayFrom(position, keys)
+	// a new file gets its header, like with OpenPersister
+	if err == nil && !aof.readOnly {
+		err = aof.headerIfEmpty()
+	}
+
+	if err != nil {
+		closeErr := file.Close()
+		if closeErr != nil {
+			return fmt.Errorf("tailFile (%s) error: %w; close error: %w", path, err, closeErr)
+		}
+
+		return fmt.Errorf("tailFile (%s) error: %w", path, err)
+	}
+
+	return nil
+}
+
+/*
+replayFrom replays the instructions of the file from the position into the keys,
+if the file is still the one of the snapshot (see Position).
+*/
+func (aof *AOF) replayFrom(position Position, keys map[string]map[int][]byte) error {
+	version, generation, size, err := aof.readVersion()
+	if err != nil {
+		return err
+	}
+
+	if position.Generation != "" && position.Generation != generation {
+		return fmt.Errorf("%w (the snapshot is of generation '%s', the file of '%s')",
+			ErrSnapshotMismatch, position.Generation, generation)
+	}
+
+	if size < position.Offset {
+		return fmt.Errorf("file is smaller (%d) than the snapshot offset (%d)", size, position.Offset)
+	}
+
+	aof.version = version
+	aof.generation = generation
+
+	_, err = aof.file.Seek(position.Offset, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("seek error: %w", err)
+	}
+
+	scanner, recorder := aof.newScanner(aof.file)
+	recorder.offset = position.Offset
+	recorder.repair = aof.repair
+
+	return aof.readInstructions(scanner, recorder, keys)
+}
+
+/*
+Checkpoint writes all the keys and the meta data to the snapshot file (the path + ".snapshot"),
+and then empties the file, so it only has to hold the changes since the checkpoint.
+Opening the file reads the snapshot first, and then replays only those changes.
+It isn't possible with segments (see WithSegments).
+*/
+func (aof *AOF) Checkpoint(keys map[string]map[int][]byte) error {
+	err := aof.checkWritable("checkpoint")
+	if err != nil {
+		return err
+	}
+
+	if aof.Segmented() {
+		return fmt.Errorf("checkpoint error: %w", errSegmented)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	aof.mu.Lock()
+	defer aof.mu.Unlock()
+
+	path := aof.file.Name()
+
+	err = aof.writeCheckpoint(path+snapshotExtension, keys)
+	if err != nil {
+		return fmt.Errorf("checkpoint error: %w", err)
+	}
+
+	// if this fails, the snapshot plus the whole file still results in the same data
+	err = aof.file.Truncate(0)
+	if err == nil {
+		_, err = aof.file.Seek(0, io.SeekStart)
+	}
+
+	// the emptied file is another file for a snapshot
+	if err == nil {
+		err = aof.writeHeader()
+	}
+
+	if err == nil {
+		err = aof.file.Sync()
+	}
+
+	if err == nil {
+		err = aof.truncateStripes()
+	}
+
+	// truncating releases the reserved disk space too
+	aof.resetAllocations()
+
+	if err != nil {
+		return fmt.Errorf("checkpoint->truncate (%s) error: %w", path, err)
+	}
+
+	aof.measure()
+
+	return nil
+}
+
+/*
+writeCheckpoint writes the snapshot to a temporary file and then moves it in place,
+so there's always a complete snapshot file.
+*/
+func (aof *AOF) writeCheckpoint(snapshotPath string, keys map[string]map[int][]byte) error {
+	tmpPath := snapshotPath + ".tmp"
+
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fileMode) //nolint:gosec // path is clean
+	if err != nil {
+		return fmt.Errorf("create (%s) error: %w", tmpPath, err)
+	}
+
+	err = writeCompressedSnapshot(file, Position{}, keys, aof.meta, FormatBinary, aof.compressor, aof.snapshotLevel)
+	if err == nil {
+		err = file.Sync()
+	}
+
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmpPath, snapshotPath)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmpPath)
+
+		return fmt.Errorf("write (%s) error: %w", snapshotPath, err)
+	}
+
+	return nil
+}
+
+/*
+readCheckpointOrFile reads the snapshot file and the changes after it if there is a snapshot,
+and otherwise the sealed segments (if there are any) and the whole file.
+*/
+func (aof *AOF) readCheckpointOrFile(path string) (map[string]map[int][]byte, error) {
+	snapshot, err := os.Open(path + snapshotExtension) //nolint:gosec // path is clean
+	if err != nil {
+		keys, err := aof.loadSegments(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return aof.getData(path, keys)
+	}
+
+	defer func() {
+		_ = snapshot.Close()
+	}()
+
+	keys, position, err := aof.readSnapshot(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint (%s) error: %w", path+snapshotExtension, err)
+	}
+
+	err = aof.tailFile(path, position, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+/*
+removeCheckpoint removes the snapshot file, because the file holds all the data again.
+*/
+func removeCheckpoint(path string) error {
+	err := os.Remove(path + snapshotExtension)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removeCheckpoint error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,433 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const (
+	// compressedSnapshotHeader starts a compressed snapshot stream, it can never start a plain one.
+	compressedSnapshotHeader = "snapshot+zstd\n"
+	// gzipSnapshotHeader starts a stream of an older version, of which the chunks are compressed with gzip.
+	gzipSnapshotHeader = "snapshot+gzip\n"
+	// snapshotChunkSize is the size of the parts of the stream that are compressed independently.
+	snapshotChunkSize = 1024 * 1024
+	// defaultSnapshotLevel is the default zstd level of a compressed snapshot.
+	defaultSnapshotLevel = 3
+	// maxSnapshotLevel is the highest zstd level (the smallest snapshot).
+	maxSnapshotLevel = 22
+)
+
+// chunk is one part of a compressed snapshot stream, it is (de)compressed by one worker.
+type chunk struct {
+	err  error
+	data []byte
+}
+
+/*
+chunkWriter compresses the stream in chunks, with a worker per CPU (GOMAXPROCS),
+and writes the chunks in their order, each one prefixed with its (uvarint) size.
+*/
+type chunkWriter struct {
+	writer  io.Writer
+	err     error
+	encoder *zstd.Encoder   // shared by the workers, every chunk is a zstd frame of its own
+	order   chan chan chunk // the results, in the order of the chunks
+	workers chan struct{}   // limits the number of workers
+	done    chan struct{}   // closed when all chunks are written
+	buf     []byte
+}
+
+/*
+chunkReader reads the chunks of a compressed stream, and decompresses them with a worker per CPU,
+while the decompressed data is read in the original order.
+*/
+type chunkReader struct {
+	decompress func(data []byte) ([]byte, error) // zstd, or gzip for a stream of an older version
+	order      chan chan chunk
+	workers    chan struct{}
+	stop       chan struct{} // closed by close, to stop reading ahead
+	current    []byte
+	err        error
+	once       sync.Once
+}
+
+var errChunkSize = errors.New("wrong chunk size")
+
+/*
+snapshotDecoder returns the zstd decoder of the chunks, which is shared by all the readers,
+because it is safe for concurrent use (with a decoder per CPU).
+A chunk that would decompress to more than twice the chunk size is refused.
+*/
+var snapshotDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+	return zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(2*snapshotChunkSize))
+})
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithSnapshotCompression makes Checkpoint compress the snapshot file with zstd, at the given level
+(1 is the fastest, 22 the smallest, anything else means the default level 3, 0 turns it off).
+The stream is compressed (and read) in chunks with a worker per CPU, so big snapshots stay quick.
+Snapshots are always read with transparent decompression, also those of an older version (compressed with gzip).
+*/
+func WithSnapshotCompression(level int) Option {
+	return func(aof *AOF) {
+		aof.snapshotLevel = snapshotLevel(level)
+	}
+}
+
+/*
+WriteCompressedSnapshot writes a snapshot stream like WriteSnapshot, compressed with zstd
+at the given level (see WithSnapshotCompression).
+*/
+func WriteCompressedSnapshot(
+	writer io.Writer,
+	position Position,
+	keys map[string]map[int][]byte,
+	meta map[string]string,
+	format Format,
+	level int,
+) error {
+	return writeCompressedSnapshot(writer, position, keys, meta, format, nil, snapshotLevel(level))
+}
+
+/*
+writeCompressedSnapshot writes the snapshot stream, compressed if there is a level.
+*/
+func writeCompressedSnapshot(
+	writer io.Writer,
+	position Position,
+	keys map[string]map[int][]byte,
+	meta map[string]string,
+	format Format,
+	comp *compressor,
+	level int,
+) error {
+	if level == 0 {
+		return writeSnapshot(writer, position, keys, meta, format, comp)
+	}
+
+	chunks, err := newChunkWriter(writer, level)
+	if err != nil {
+		return fmt.Errorf("writeSnapshot error: %w", err)
+	}
+
+	_, err = io.WriteString(writer, compressedSnapshotHeader)
+	if err != nil {
+		return errors.Join(fmt.Errorf("writeSnapshot error: %w", err), chunks.Close())
+	}
+
+	err = writeSnapshot(chunks, position, keys, meta, format, comp)
+
+	return errors.Join(err, chunks.Close())
+}
+
+/*
+snapshotLevel returns the valid compression level for the snapshot (0 for none).
+*/
+func snapshotLevel(level int) int {
+	if level != 0 && (level < 1 || level > maxSnapshotLevel) {
+		return defaultSnapshotLevel
+	}
+
+	return level
+}
+
+/*
+snapshotReader returns a reader of the plain snapshot stream, that decompresses a compressed one,
+and a function to stop the decompression (when the stream isn't read to the end).
+*/
+func snapshotReader(reader io.Reader) (io.Reader, func()) {
+	bufReader := bufio.NewReader(reader)
+
+	header, err := bufReader.Peek(len(compressedSnapshotHeader))
+	if err != nil {
+		return bufReader, func() {}
+	}
+
+	var decompressor func(data []byte) ([]byte, error)
+
+	switch string(header) {
+	case compressedSnapshotHeader:
+		decompressor = decompressChunk
+	case gzipSnapshotHeader:
+		decompressor = decompress
+	default:
+		return bufReader, func() {}
+	}
+
+	_, _ = bufReader.Discard(len(header))
+	chunks := newChunkReader(bufReader, decompressor)
+
+	return chunks, chunks.close
+}
+
+/*
+decompressChunk decompresses a zstd chunk of a snapshot stream.
+*/
+func decompressChunk(data []byte) ([]byte, error) {
+	decoder, err := snapshotDecoder()
+	if err != nil {
+		return nil, fmt.Errorf("decompress error: %w", err)
+	}
+
+	data, err = decoder.DecodeAll(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decompress error: %w", err)
+	}
+
+	return data, nil
+}
+
+/*
+newChunkWriter returns the writer that compresses the chunks.
+*/
+func newChunkWriter(writer io.Writer, level int) (*chunkWriter, error) {
+	workers := runtime.GOMAXPROCS(0)
+
+	encoder, err := zstd.NewWriter(nil,
+		zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)), zstd.WithEncoderConcurrency(workers))
+	if err != nil {
+		return nil, err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	chunks := &chunkWriter{
+		writer:  writer,
+		encoder: encoder,
+		order:   make(chan chan chunk, workers),
+		workers: make(chan struct{}, workers),
+		done:    make(chan struct{}),
+	}
+
+	go chunks.writeChunks()
+
+	return chunks, nil
+}
+
+/*
+Write collects the data, and hands every full chunk to a worker.
+*/
+func (chunks *chunkWriter) Write(data []byte) (int, error) {
+	chunks.buf = append(chunks.buf, data...)
+
+	for len(chunks.buf) >= snapshotChunkSize {
+		chunks.compress(chunks.buf[:snapshotChunkSize:snapshotChunkSize])
+		chunks.buf = chunks.buf[snapshotChunkSize:]
+	}
+
+	return len(data), nil
+}
+
+/*
+Close compresses the last chunk, waits until all chunks are written, and ends the stream.
+*/
+func (chunks *chunkWriter) Close() error {
+	if len(chunks.buf) > 0 {
+		chunks.compress(chunks.buf)
+		chunks.buf = nil
+	}
+
+	close(chunks.order)
+	<-chunks.done
+
+	// all the workers are done
+	err := chunks.encoder.Close()
+	if err != nil {
+		return fmt.Errorf("writeSnapshot error: %w", err)
+	}
+
+	if chunks.err != nil {
+		return chunks.err
+	}
+
+	// a chunk of size 0 ends the stream
+	_, err = chunks.writer.Write([]byte{0})
+	if err != nil {
+		return fmt.Errorf("writeSnapshot error: %w", err)
+	}
+
+	return nil
+}
+
+/*
+compress starts a worker that compresses the chunk (as soon as there is a free worker).
+*/
+func (chunks *chunkWriter) compress(data []byte) {
+	result := make(chan chunk, 1)
+	chunks.order <- result
+	chunks.workers <- struct{}{}
+
+	go func() {
+		defer func() {
+			<-chunks.workers
+		}()
+
+		result <- chunk{data: chunks.encoder.EncodeAll(data, nil)}
+	}()
+}
+
+/*
+writeChunks writes the compressed chunks in their order, until the writer is closed.
+After an error, the remaining chunks are skipped.
+*/
+func (chunks *chunkWriter) writeChunks() {
+	defer close(chunks.done)
+
+	size := make([]byte, binary.MaxVarintLen64)
+
+	for result := range chunks.order {
+		compressed := <-result
+		if chunks.err != nil {
+			continue
+		}
+
+		err := compressed.err
+		if err == nil {
+			_, err = chunks.writer.Write(size[:binary.PutUvarint(size, uint64(len(compressed.data)))])
+		}
+
+		if err == nil {
+			_, err = chunks.writer.Write(compressed.data)
+		}
+
+		if err != nil {
+			chunks.err = fmt.Errorf("writeSnapshot->chunk error: %w", err)
+		}
+	}
+}
+
+/*
+newChunkReader returns the reader that decompresses the chunks of the stream.
+*/
+func newChunkReader(reader *bufio.Reader, decompress func(data []byte) ([]byte, error)) *chunkReader {
+	workers := runtime.GOMAXPROCS(0)
+	chunks := &chunkReader{
+		decompress: decompress,
+		order:      make(chan chan chunk, workers),
+		workers:    make(chan struct{}, workers),
+		stop:       make(chan struct{}),
+	}
+
+	go chunks.readChunks(reader)
+
+	return chunks
+}
+
+/*
+Read returns the decompressed data, in the order of the chunks.
+*/
+func (chunks *chunkReader) Read(data []byte) (int, error) {
+	for len(chunks.current) == 0 {
+		if chunks.err != nil {
+			return 0, chunks.err
+		}
+
+		result, ok := <-chunks.order
+		if !ok {
+			chunks.err = io.EOF
+
+			continue
+		}
+
+		decompressed := <-result
+		chunks.current, chunks.err = decompressed.data, decompressed.err
+	}
+
+	count := copy(data, chunks.current)
+	chunks.current = chunks.current[count:]
+
+	return count, nil
+}
+
+/*
+close stops reading ahead, it can be called more than once.
+*/
+func (chunks *chunkReader) close() {
+	chunks.once.Do(func() {
+		close(chunks.stop)
+	})
+}
+
+/*
+readChunks reads the chunks of the stream, and hands every chunk to a worker.
+*/
+func (chunks *chunkReader) readChunks(reader *bufio.Reader) {
+	defer close(chunks.order)
+
+	for {
+		data, err := readChunk(reader)
+		if err == nil && data == nil {
+			return
+		}
+
+		result := make(chan chunk, 1)
+
+		select {
+		case chunks.order <- result:
+		case <-chunks.stop:
+			return
+		}
+
+		if err != nil {
+			result <- chunk{err: err}
+
+			return
+		}
+
+		select {
+		case chunks.workers <- struct{}{}:
+		case <-chunks.stop:
+			result <- chunk{err: io.ErrClosedPipe}
+
+			return
+		}
+
+		go func() {
+			defer func() {
+				<-chunks.workers
+			}()
+
+			decompressed, err := chunks.decompress(data)
+			result <- chunk{data: decompressed, err: err}
+		}()
+	}
+}
+
+/*
+readChunk reads the next compressed chunk, it returns nil at the end of the stream.
+*/
+func readChunk(reader *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, fmt.Errorf("readSnapshot->chunk size error: %w", err)
+	}
+
+	if size == 0 {
+		return nil, nil
+	}
+
+	if size > 2*snapshotChunkSize {
+		return nil, fmt.Errorf("readSnapshot->chunk error: %w %d", errChunkSize, size)
+	}
+
+	data := make([]byte, size)
+
+	_, err = io.ReadFull(reader, data)
+	if err != nil {
+		return nil, fmt.Errorf("readSnapshot->chunk error: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,80 @@
+package persist_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WriteCompressedSnapshot(t *testing.T) {
+	// enough data for several chunks
+	keys := map[string]map[int][]byte{"text": {}, "other": {1: []byte("one")}}
+	for key := range 50_000 {
+		keys["text"][key] = []byte(fmt.Sprintf("value %d for a key that compresses well", key))
+	}
+
+	meta := map[string]string{"seq:text": "50000"}
+
+	plain := &bytes.Buffer{}
+	err := persist.WriteSnapshot(plain, persist.Position{Offset: 12}, keys, meta, persist.FormatBinary)
+	require.NoError(t, err)
+
+	compressed := &bytes.Buffer{}
+	err = persist.WriteCompressedSnapshot(compressed, persist.Position{Offset: 12}, keys, meta, persist.FormatBinary, -1)
+	require.NoError(t, err)
+	assert.Less(t, compressed.Len(), plain.Len()/3)
+	assert.True(t, bytes.HasPrefix(compressed.Bytes(), []byte("snapshot+zstd\n")))
+
+	read, err := persist.ReadSnapshot(bytes.NewReader(compressed.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, keys, read)
+
+	// level 0 writes a plain snapshot
+	uncompressed := &bytes.Buffer{}
+	err = persist.WriteCompressedSnapshot(uncompressed, persist.Position{Offset: 12}, keys, meta, persist.FormatBinary, 0)
+	require.NoError(t, err)
+	assert.Equal(t, plain.Len(), uncompressed.Len())
+
+	// a truncated or damaged stream is refused
+	_, err = persist.ReadSnapshot(bytes.NewReader(compressed.Bytes()[:compressed.Len()/2]))
+	require.Error(t, err)
+
+	damaged := bytes.Clone(compressed.Bytes())
+	damaged[len(damaged)/2] ^= 0xff
+
+	_, err = persist.ReadSnapshot(bytes.NewReader(damaged))
+	require.Error(t, err)
+}
+
+func Test_ReadSnapshot_gzip(t *testing.T) {
+	keys := map[string]map[int][]byte{"text": {1: []byte("one"), 2: []byte("two")}}
+
+	plain := &bytes.Buffer{}
+	err := persist.WriteSnapshot(plain, persist.Position{Offset: 12}, keys, nil, persist.FormatText)
+	require.NoError(t, err)
+
+	// a snapshot of an older version has one chunk per MB, compressed with gzip
+	chunk := &bytes.Buffer{}
+	writer := gzip.NewWriter(chunk)
+
+	_, err = writer.Write(plain.Bytes())
+	require.NoError(t, err)
+
+	err = writer.Close()
+	require.NoError(t, err)
+
+	stream := []byte("snapshot+gzip\n")
+	stream = binary.AppendUvarint(stream, uint64(chunk.Len()))
+	stream = append(stream, chunk.Bytes()...)
+	stream = append(stream, 0)
+
+	read, err := persist.ReadSnapshot(bytes.NewReader(stream))
+	require.NoError(t, err)
+	assert.Equal(t, keys, read)
+}
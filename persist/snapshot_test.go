@@ -0,0 +1,70 @@
+package persist_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenPersisterFromSnapshot(t *testing.T) {
+	path := "../data/fast_persister_snapshot.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	aof, keys, err := persist.OpenPersister(path, syncIime)
+	require.NoError(t, err)
+
+	err = aof.Write("set\ntext_1\nvalue for key 1\nset\ntext_2\nvalue for key 2\n")
+	require.NoError(t, err)
+
+	keys["text"] = map[int][]byte{1: []byte("value for key 1"), 2: []byte("value for key 2")}
+
+	position, err := aof.Position()
+	require.NoError(t, err)
+	assert.Equal(t, int64(54)+aof.HeaderSize(), position.Offset)
+	assert.Equal(t, aof.Generation(), position.Generation)
+
+	snapshot := &bytes.Buffer{}
+	err = persist.WriteSnapshot(snapshot, position, keys, aof.Meta(), persist.FormatText)
+	require.NoError(t, err)
+
+	stale := bytes.Clone(snapshot.Bytes())
+
+	err = aof.Write("del\ntext_1\n")
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	aof, keys, err = persist.OpenPersisterFromSnapshot(snapshot, path, syncIime)
+	require.NoError(t, err)
+	assert.Len(t, keys["text"], 1)
+	assert.Equal(t, []byte("value for key 2"), keys["text"][2])
+
+	// after a Defrag, the offset of the snapshot doesn't mean anything in the file anymore
+	err = aof.Defrag(keys)
+	require.NoError(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	_, _, err = persist.OpenPersisterFromSnapshot(bytes.NewReader(stale), path, syncIime)
+	require.ErrorIs(t, err, persist.ErrSnapshotMismatch)
+}
+
+func Test_OpenPersisterFromSnapshot_nonExistingPath(t *testing.T) {
+	path := "../data/non_existent_dir/fast.db"
+	aof, keys, err := persist.OpenPersisterFromSnapshot(&bytes.Buffer{}, path, syncIime)
+	require.Error(t, err)
+	assert.Nil(t, aof)
+	assert.Nil(t, keys)
+}
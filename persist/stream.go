@@ -0,0 +1,51 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+AppendFrames appends the instructions as binary frames (like FormatBinary writes them to the file),
+to send them over a stream, see ReadFrames.
+*/
+func AppendFrames(buf []byte, instructions []Instruction) []byte {
+	for _, ins := range instructions {
+		buf = ins.appendFrame(buf)
+	}
+
+	return buf
+}
+
+/*
+ReadFrames returns the instructions of the binary frames (made by AppendFrames),
+the way they are replayed: a compressed set and a present become a set.
+*/
+func ReadFrames(data []byte) ([]Instruction, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	scanner.Split(scanRecords)
+
+	var instructions []Instruction
+
+	for scanner.Scan() {
+		ins, err := readInstruction(scanner)
+		if err != nil {
+			return nil, fmt.Errorf("readFrames error: %w", err)
+		}
+
+		instructions = append(instructions, ins)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("readFrames error: %w", err)
+	}
+
+	return instructions, nil
+}
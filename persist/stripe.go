@@ -0,0 +1,341 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const stripeExtension = ".stripe"
+
+var errStriped = errors.New("not possible with striped files")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithStripes spreads the instructions over count files (the file itself, plus the path + ".stripe1" etc.),
+by the hash of the bucket (or the meta data name), so the syncs of a batch run in parallel.
+All the changes of one bucket go to the same file, so they keep their order.
+A batch over several buckets is no longer written in one go.
+Opening the files with another count rewrites them into the new stripes (like a Defrag).
+*/
+func WithStripes(count int) Option {
+	return func(aof *AOF) {
+		aof.stripeCount = max(count, 1)
+	}
+}
+
+/*
+Striped returns true if the instructions are spread over several files.
+*/
+func (aof *AOF) Striped() bool {
+	return aof.stripeCount > 1
+}
+
+/*
+stripeOf returns the index of the file for an instruction, 0 is the file itself.
+A renamebucket or copybucket instruction goes to the file of the source bucket,
+so it only replays correctly if the target bucket is in the same file.
+*/
+func (aof *AOF) stripeOf(ins Instruction) int {
+	name := ins.Key
+
+	// the key of a record holds the bucket and the key, the others only have a name (or a bucket)
+	if ins.Name == "set" || ins.Name == "present" || ins.Name == "del" {
+		uPos := strings.LastIndex(name, "_")
+		if uPos >= 0 {
+			name = name[:uPos]
+		}
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(name))
+
+	return int(hash.Sum32() % uint32(aof.stripeCount)) //nolint:gosec // stripeCount is small and positive
+}
+
+/*
+stripePath returns the path of a stripe file.
+*/
+func stripePath(path string, index int) string {
+	return path + stripeExtension + strconv.Itoa(index)
+}
+
+/*
+existingStripes returns the (sorted) indexes of the stripe files that exist for the path.
+*/
+func existingStripes(path string) ([]int, error) {
+	return numberedFiles(path, stripeExtension)
+}
+
+/*
+numberedFiles returns the (sorted) numbers of the files that exist for the path with the extension
+(followed by the number), like the stripes and the segments.
+*/
+func numberedFiles(path, extension string) ([]int, error) {
+	matches, err := filepath.Glob(path + extension + "*")
+	if err != nil {
+		return nil, fmt.Errorf("numberedFiles error: %w", err)
+	}
+
+	var numbers []int
+
+	for _, match := range matches {
+		number, err := strconv.Atoi(strings.TrimPrefix(match, path+extension))
+		if err == nil && number > 0 {
+			numbers = append(numbers, number)
+		}
+	}
+
+	slices.Sort(numbers)
+
+	return numbers, nil
+}
+
+/*
+loadStripes reads the existing stripe files into the keys, and opens the stripes for writing.
+Every bucket was always written to one file, so the order in which the files are read doesn't matter.
+It returns true if the files were written with another number of stripes, so they need to be rewritten.
+*/
+func (aof *AOF) loadStripes(path string, keys map[string]map[int][]byte) (bool, error) {
+	existing, err := existingStripes(path)
+	if err != nil {
+		return false, err
+	}
+
+	for _, index := range existing {
+		err = aof.readExtraFile(stripePath(path, index), keys)
+		if err != nil {
+			return false, errors.Join(err, aof.file.Close())
+		}
+	}
+
+	expected := make([]int, 0, aof.stripeCount)
+	for index := 1; index < aof.stripeCount; index++ {
+		expected = append(expected, index)
+	}
+
+	if len(existing) > 0 && !slices.Equal(existing, expected) {
+		return true, nil
+	}
+
+	// the stripes are only opened to write to them
+	if aof.readOnly {
+		return false, nil
+	}
+
+	err = aof.openStripes(path)
+	if err != nil {
+		return false, errors.Join(err, aof.file.Close())
+	}
+
+	return false, nil
+}
+
+/*
+readExtraFile reads the instructions of one extra file (a stripe or a segment) into the keys.
+*/
+func (aof *AOF) readExtraFile(path string, keys map[string]map[int][]byte) error {
+	file, err := os.Open(path) //nolint:gosec // path is clean
+	if err != nil {
+		return fmt.Errorf("readExtraFile (%s) error: %w", path, err)
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	aof.source = path
+
+	scanner, recorder := aof.newScanner(file)
+
+	err = aof.readInstructions(scanner, recorder, keys)
+	if err != nil {
+		return fmt.Errorf("readExtraFile (%s) error: %w", path, err)
+	}
+
+	return nil
+}
+
+/*
+openStripes opens (or creates) the stripe files for writing.
+*/
+func (aof *AOF) openStripes(path string) error {
+	stripes := make([]*os.File, 0, aof.stripeCount-1)
+
+	for index := 1; index < aof.stripeCount; index++ {
+		file, err := os.OpenFile(stripePath(path, index), os.O_WRONLY|os.O_APPEND|osCreate, fileMode)
+		if err != nil {
+			return errors.Join(fmt.Errorf("openStripes error: %w", err), closeFiles(stripes))
+		}
+
+		stripes = append(stripes, file)
+	}
+
+	aof.mu.Lock()
+	aof.stripes = stripes
+	aof.mu.Unlock()
+
+	return nil
+}
+
+/*
+resetStripes removes all the stripe files (after everything was written to the file itself),
+and opens new empty ones.
+*/
+func (aof *AOF) resetStripes(path string) error {
+	existing, err := existingStripes(path)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range existing {
+		err = os.Remove(stripePath(path, index))
+		if err != nil {
+			return fmt.Errorf("resetStripes error: %w", err)
+		}
+	}
+
+	return aof.openStripes(path)
+}
+
+/*
+closeStripes syncs and closes the stripe files.
+*/
+func (aof *AOF) closeStripes() error {
+	aof.mu.Lock()
+	stripes := aof.stripes
+	aof.stripes = nil
+	aof.mu.Unlock()
+
+	err := syncFiles(stripes)
+
+	return errors.Join(err, closeFiles(stripes))
+}
+
+/*
+syncStripes syncs all the stripe files at the same time.
+*/
+func (aof *AOF) syncStripes() error {
+	aof.mu.RLock()
+	stripes := aof.stripes
+	aof.mu.RUnlock()
+
+	return syncFiles(stripes)
+}
+
+/*
+truncateStripes empties all the stripe files.
+The caller must hold the lock.
+*/
+func (aof *AOF) truncateStripes() error {
+	for _, file := range aof.stripes {
+		err := file.Truncate(0)
+		if err != nil {
+			return fmt.Errorf("truncateStripes error: %w", err)
+		}
+	}
+
+	return syncFiles(aof.stripes)
+}
+
+/*
+writeStriped writes the buffers to their files, and then syncs them at the same time
+(if the sync time is 0).
+*/
+func (aof *AOF) writeStriped(bufs [][]byte) (err error) {
+	var written []*os.File
+
+	end := aof.startTrace("write")
+
+	defer func() {
+		end(err)
+	}()
+
+	for index, buf := range bufs {
+		if len(buf) == 0 {
+			continue
+		}
+
+		file := aof.file
+		if index > 0 {
+			file = aof.stripes[index-1]
+		}
+
+		aof.reserve(file, len(buf))
+
+		count, writeErr := file.Write(buf)
+		aof.size.Add(int64(count))
+		aof.written(count)
+
+		if writeErr != nil {
+			return fmt.Errorf("write error: %#v %w", file.Name(), writeErr)
+		}
+
+		written = append(written, file)
+	}
+
+	if aof.syncTime.Load() != 0 {
+		return nil
+	}
+
+	start := time.Now()
+
+	err = syncFiles(written)
+	if err == nil {
+		aof.synced(start)
+	}
+
+	return err
+}
+
+/*
+syncFiles syncs the files at the same time.
+*/
+func syncFiles(files []*os.File) error {
+	if len(files) == 1 {
+		return syncFile(files[0])
+	}
+
+	errs := make([]error, len(files))
+
+	var wg sync.WaitGroup
+
+	for i, file := range files {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			errs[i] = syncFile(file)
+		}()
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+/*
+closeFiles closes the files.
+*/
+func closeFiles(files []*os.File) error {
+	var errs []error
+
+	for _, file := range files {
+		errs = append(errs, file.Close())
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,87 @@
+package persist_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenPersister_WithStripes(t *testing.T) {
+	path := "../data/fast_persister_stripes.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		matches, err := filepath.Glob(filePath + "*")
+		require.NoError(t, err)
+
+		for _, match := range matches {
+			require.NoError(t, os.Remove(match))
+		}
+	}()
+
+	aof, _, err := persist.OpenPersister(path, 0, persist.WithStripes(3))
+	require.NoError(t, err)
+	assert.True(t, aof.Striped())
+
+	instructions := make([]persist.Instruction, 0, 20)
+	for key := 1; key <= 10; key++ {
+		instructions = append(instructions,
+			persist.SetInstruction("bucket"+strconv.Itoa(key), key, []byte("first")),
+			persist.SetInstruction("bucket"+strconv.Itoa(key), key, []byte("second")),
+		)
+	}
+
+	instructions = append(instructions, persist.DelInstruction("bucket1", 1), persist.MetaInstruction("name", "value"))
+
+	err = aof.WriteBatch(instructions)
+	require.NoError(t, err)
+
+	err = aof.Write("set\ntext_1\nvalue\n")
+	require.Error(t, err)
+
+	_, err = aof.Offset()
+	require.Error(t, err)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	for index := 1; index < 3; index++ {
+		_, err = os.Stat(filePath + ".stripe" + strconv.Itoa(index))
+		require.NoError(t, err)
+	}
+
+	aof, keys, err := persist.OpenPersister(path, 0, persist.WithStripes(3))
+	require.NoError(t, err)
+	assert.Len(t, keys, 9)
+	assert.NotContains(t, keys, "bucket1")
+	assert.Equal(t, []byte("second"), keys["bucket10"][10])
+	assert.Equal(t, "value", aof.Meta()["name"])
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	// another count rewrites everything into the file itself
+	aof, keys, err = persist.OpenPersister(path, 0)
+	require.NoError(t, err)
+	assert.False(t, aof.Striped())
+	assert.Len(t, keys, 9)
+	assert.Equal(t, []byte("second"), keys["bucket5"][5])
+
+	_, err = os.Stat(filePath + ".stripe1")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	aof, keys, err = persist.OpenPersister(path, 0)
+	require.NoError(t, err)
+	assert.Len(t, keys, 9)
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
@@ -0,0 +1,45 @@
+//go:build linux
+
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+syncFile flushes the data of the file to disk with fdatasync, which skips the metadata
+(like the modification time) that isn't needed to read the data back.
+The files are opened with O_APPEND, so every write is one append without a seek.
+*/
+func syncFile(file *os.File) error {
+	conn, err := file.SyscallConn()
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	var syncErr error
+
+	err = conn.Control(func(fd uintptr) {
+		for {
+			syncErr = syscall.Fdatasync(int(fd))
+			if !errors.Is(syncErr, syscall.EINTR) {
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	if syncErr != nil {
+		return &os.PathError{Op: "fdatasync", Path: file.Name(), Err: syncErr}
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"os"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+syncFile flushes the data of the file to disk.
+*/
+func syncFile(file *os.File) error {
+	return file.Sync() //nolint:wrapcheck // it is wrapped by the caller
+}
@@ -0,0 +1,56 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"time"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// opTimestamped is the flag in the operation of a frame that carries the time of the write
+// (as an uvarint of unix nanoseconds, right after the operation).
+const opTimestamped byte = 0x80
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithTimestamps writes the time of the write with every instruction of WriteBatch, so it can be read back
+(see Instruction.Time). The time needs a newer format of the frames: every instruction is written as a frame,
+and such a file can't be read by a version without timestamps. Files are always read with and without them.
+A Defrag, a Checkpoint and a snapshot write the records without their times.
+*/
+func WithTimestamps() Option {
+	return func(aof *AOF) {
+		aof.timestamps.Store(true)
+	}
+}
+
+/*
+stamp gives the instructions that don't have a time yet the time of now, WithTimestamps.
+The instructions of the caller are changed, so it knows with which time they were written.
+*/
+func (aof *AOF) stamp(instructions []Instruction) {
+	if !aof.timestamps.Load() {
+		return
+	}
+
+	now := time.Now().UnixNano()
+
+	for i := range instructions {
+		if instructions[i].Time == 0 {
+			instructions[i].Time = now
+		}
+	}
+}
+
+/*
+Modified returns the time of the write of the instruction, and false if the file didn't hold it.
+*/
+func (ins Instruction) Modified() (time.Time, bool) {
+	if ins.Time <= 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, ins.Time), true
+}
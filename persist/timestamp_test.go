@@ -0,0 +1,72 @@
+package persist_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithTimestamps(t *testing.T) {
+	path := t.TempDir() + "/fast_persister_timestamps.db"
+
+	aof, _, err := persist.OpenPersister(path, syncIime, persist.WithTimestamps(), persist.WithCompression(1))
+	require.NoError(t, err)
+
+	before := time.Now()
+	big := []byte(strings.Repeat("compress me ", 20))
+
+	instructions := []persist.Instruction{
+		persist.SetInstruction("text", 1, []byte("first")),
+		persist.SetInstruction("text", 2, big),
+		persist.DelInstruction("text", 3),
+		persist.MetaInstruction("name", "value"),
+	}
+
+	err = aof.WriteBatch(instructions)
+	require.NoError(t, err)
+
+	// the caller knows the time of the write
+	modified, ok := instructions[0].Modified()
+	require.True(t, ok)
+	assert.False(t, modified.Before(before))
+
+	var read []persist.Instruction
+
+	err = aof.Replay(func(ins persist.Instruction) error {
+		read = append(read, ins)
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, read, 4)
+	assert.Equal(t, instructions[0], read[0])
+	assert.Equal(t, persist.Instruction{Name: "set", Key: "text_2", Value: big, Time: instructions[1].Time}, read[1])
+	assert.Equal(t, instructions[2], read[2])
+	assert.Equal(t, instructions[3], read[3])
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	// the file is read like any other, and the frames of a stream carry the time too
+	aof, keys, err := persist.OpenPersister(path, syncIime)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("first"), keys["text"][1])
+	assert.Equal(t, big, keys["text"][2])
+	assert.Equal(t, "value", aof.Meta()["name"])
+
+	frames := persist.AppendFrames(nil, instructions[:1])
+
+	streamed, err := persist.ReadFrames(frames)
+	require.NoError(t, err)
+	assert.Equal(t, instructions[:1], streamed)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	_, ok = persist.DelInstruction("text", 1).Modified()
+	assert.False(t, ok)
+}
@@ -0,0 +1,169 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// Report holds what Verify found in the files of a database.
+type Report struct {
+	Buckets        map[string]BucketReport `json:"buckets"`
+	Path           string                  `json:"path"`
+	Problems       []Problem               `json:"problems"` // the entries that can't be read, in the order of the files
+	Size           int64                   `json:"size"`     // the bytes of the file, with the stripes, the segments and the snapshot
+	TornOffset     int64                   `json:"tornOffset"`
+	TornBytes      int64                   `json:"tornBytes"` // the torn write at the end of the file, see WithRecover
+	Records        int                     `json:"records"`   // the record instructions (set, present and del)
+	Live           int                     `json:"live"`      // the records that are left
+	DuplicateRatio float64                 `json:"duplicateRatio"`
+}
+
+// BucketReport holds the counts of one bucket in a Report.
+type BucketReport struct {
+	Records int `json:"records"`
+	Live    int `json:"live"`
+	// DuplicateRatio is the share of the record instructions that were overwritten or deleted later (what a Defrag removes).
+	DuplicateRatio float64 `json:"duplicateRatio"`
+}
+
+// Problem is an entry that can't be read, found by Verify.
+type Problem struct {
+	File   string `json:"file"`
+	Reason string `json:"reason"`
+	Line   int    `json:"line"`
+	Offset int64  `json:"offset"`
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Verify reads all the files of a database (like ReadFile) and reports what it found: the entries that can't be read
+(with their line and offset), a torn write at the end of the file, and the counts of every bucket.
+Unlike Open, it doesn't stop at the first bad entry, and nothing is locked, created or changed,
+so it can check the file of a database that is in use (in a CI job or an admin command).
+The error is only returned when the files can't be read at all, the problems are in the report.
+*/
+func Verify(path string, opts ...Option) (Report, error) {
+	aof := newAOF(0, append(opts, WithQuarantine(), WithRecover()))
+	aof.bucketRecords = map[string]int{}
+
+	report := Report{Path: path, Buckets: map[string]BucketReport{}, Problems: []Problem{}}
+
+	size, err := filesSize(path)
+	if err != nil {
+		return report, fmt.Errorf("verify (%s) error: %w", path, err)
+	}
+
+	report.Size = size
+
+	keys, err := aof.readAll(path)
+	if err != nil {
+		return report, fmt.Errorf("verify (%s) error: %w", path, err)
+	}
+
+	if aof.report != nil {
+		for _, entry := range aof.report.Entries {
+			report.Problems = append(report.Problems, newProblem(entry))
+		}
+	}
+
+	report.TornOffset = aof.tornAt
+	report.TornBytes = aof.tornBytes
+
+	for bucket, records := range aof.bucketRecords {
+		bucketReport := BucketReport{Records: records, Live: len(keys[bucket])}
+		bucketReport.DuplicateRatio = duplicateRatio(bucketReport.Records, bucketReport.Live)
+		report.Buckets[bucket] = bucketReport
+		report.Records += records
+	}
+
+	// a copied bucket has records without instructions of its own
+	for bucket, records := range keys {
+		if _, found := aof.bucketRecords[bucket]; !found {
+			report.Buckets[bucket] = BucketReport{Live: len(records)}
+		}
+
+		report.Live += len(records)
+	}
+
+	report.DuplicateRatio = duplicateRatio(report.Records, report.Live)
+
+	return report, nil
+}
+
+/*
+OK tells if Verify found no problems and no torn write.
+*/
+func (report *Report) OK() bool {
+	return len(report.Problems) == 0 && report.TornBytes == 0
+}
+
+/*
+filesSize returns the bytes of the file, with the stripes, the segments and the snapshot (if there are any).
+*/
+func filesSize(path string) (int64, error) {
+	extra, err := extraPaths(path)
+	if err != nil {
+		return 0, err
+	}
+
+	paths := append([]string{path, path + snapshotExtension}, extra...)
+
+	var size int64
+
+	for index, filePath := range paths {
+		info, err := os.Stat(filePath)
+		if errors.Is(err, os.ErrNotExist) && index == 1 {
+			continue // there is no checkpoint
+		}
+
+		if err != nil {
+			return 0, err //nolint:wrapcheck // it is wrapped by the caller
+		}
+
+		size += info.Size()
+	}
+
+	return size, nil
+}
+
+/*
+newProblem returns the problem of a bad entry.
+*/
+func newProblem(entry CorruptEntry) Problem {
+	problem := Problem{Line: entry.Line, Reason: entry.Err.Error()}
+
+	var corrupt *CorruptionError
+	if errors.As(entry.Err, &corrupt) {
+		problem.File = corrupt.File
+		problem.Reason = corrupt.Reason
+		problem.Offset = corrupt.Offset
+	}
+
+	return problem
+}
+
+/*
+duplicateRatio returns the share of the record instructions that aren't a live record anymore.
+*/
+func duplicateRatio(records, live int) float64 {
+	if records == 0 || live >= records {
+		return 0
+	}
+
+	return float64(records-live) / float64(records)
+}
+
+/*
+countRecord counts a record instruction of the bucket (only for Verify).
+*/
+func (aof *AOF) countRecord(bucket string) {
+	if aof.bucketRecords != nil {
+		aof.bucketRecords[bucket]++
+	}
+}
@@ -0,0 +1,80 @@
+package persist_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Verify(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "verify.db")
+
+	lines := "set\ntext_1\none\nset\ntext_1\nuno\nwrong line\nset\ntext_2\ntwo\ndel\nuser_1\nset\nuser_2\n"
+	err := os.WriteFile(filePath, []byte(lines), 0o600)
+	require.NoError(t, err)
+
+	report, err := persist.Verify(filePath)
+	require.NoError(t, err)
+	assert.False(t, report.OK())
+
+	assert.Equal(t, filePath, report.Path)
+	assert.Equal(t, int64(len(lines)), report.Size)
+
+	require.Len(t, report.Problems, 1)
+	assert.Equal(t, persist.Problem{
+		File: filePath, Reason: "wrong instruction format 'wrong line'", Line: 7, Offset: int64(len("set\ntext_1\none\nset\ntext_1\nuno\n")),
+	}, report.Problems[0])
+
+	// the set of user_2 is torn
+	assert.Equal(t, int64(len(lines)-len("set\nuser_2\n")), report.TornOffset)
+	assert.Equal(t, int64(len("set\nuser_2\n")), report.TornBytes)
+
+	assert.Equal(t, map[string]persist.BucketReport{
+		"text": {Records: 3, Live: 2, DuplicateRatio: 1.0 / 3},
+		"user": {Records: 1, Live: 0, DuplicateRatio: 1},
+	}, report.Buckets)
+	assert.Equal(t, 4, report.Records)
+	assert.Equal(t, 2, report.Live)
+	assert.InDelta(t, 0.5, report.DuplicateRatio, 0.001)
+
+	// nothing was changed
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, lines, string(content))
+
+	_, err = os.Stat(filePath + ".quarantine")
+	require.Error(t, err)
+
+	_, err = persist.Verify(filepath.Join(t.TempDir(), "missing.db"))
+	require.Error(t, err)
+}
+
+func Test_Verify_clean(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "verify.db")
+
+	aof, _, err := persist.OpenPersister(filePath, syncIime, persist.WithFormat(persist.FormatBinary))
+	require.NoError(t, err)
+
+	err = aof.WriteBatch([]persist.Instruction{
+		persist.SetInstruction("text", 1, []byte("one")),
+		persist.SetInstruction("text", 2, []byte("two")),
+		persist.CopyBucketInstruction("text", "copy"),
+	})
+	require.NoError(t, err)
+
+	report, err := persist.Verify(filePath)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Empty(t, report.Problems)
+	assert.Equal(t, persist.BucketReport{Records: 2, Live: 2}, report.Buckets["text"])
+	assert.Equal(t, persist.BucketReport{Live: 2}, report.Buckets["copy"])
+	assert.Equal(t, 4, report.Live)
+	assert.Zero(t, report.DuplicateRatio)
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
@@ -0,0 +1,262 @@
+package persist
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+/*
+FileVersion is the version of the file format that is written. A new file starts with a header
+(a meta instruction, which every version can read) with the version, and so does every rewrite of a file
+(a Defrag and a backup). A file without a header is version 1.
+*/
+const FileVersion = 2
+
+// versionMeta is the meta data name of the header with the version of the file.
+const versionMeta = "fileversion"
+
+/*
+generationMeta is the meta data name of the header (after the version) with the generation of the file:
+a new identity that a new file gets, and every rewrite of it (a Defrag, a Checkpoint),
+so a snapshot can tell if its offset still belongs to the file.
+*/
+const generationMeta = "filegeneration"
+
+// ErrNewerFileVersion is returned when a file is written in a newer format than this version can read.
+var ErrNewerFileVersion = errors.New("the file is written in a newer format")
+
+/*
+migrations convert the data of a file of a version (the index) to the next version,
+after which the file is rewritten in the current format.
+*/
+var migrations = map[int]func(keys map[string]map[int][]byte, meta map[string]string) error{
+	// version 2 only adds the header, the entries of version 1 are read as they are
+	1: func(map[string]map[int][]byte, map[string]string) error { return nil },
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithoutMigration makes the persister read a file of an older version without rewriting it
+in the current format (for a read-only use), the file is migrated by the next Defrag.
+*/
+func WithoutMigration() Option {
+	return func(aof *AOF) {
+		aof.noMigration = true
+	}
+}
+
+/*
+FileVersion returns the version of the format of the file, as it was opened (or migrated to).
+*/
+func (aof *AOF) FileVersion() int {
+	return aof.version
+}
+
+/*
+HeaderSize returns the size of the header with the version at the start of the file (0 for an older file).
+*/
+func (aof *AOF) HeaderSize() int64 {
+	if aof.version < FileVersion {
+		return 0
+	}
+
+	return int64(len(fileHeader(aof.format, aof.generation)))
+}
+
+/*
+Generation returns the generation of the file (see generationMeta), empty for a file that was opened without one.
+*/
+func (aof *AOF) Generation() string {
+	return aof.generation
+}
+
+/*
+checkVersion reads the version of the file from its header: a new file gets the header,
+a newer version is refused, and an older version (or a file without a generation) is migrated.
+It tells if the file must be rewritten.
+*/
+func (aof *AOF) checkVersion(keys map[string]map[int][]byte) (bool, error) {
+	version, generation, size, err := aof.readVersion()
+	if err != nil {
+		return false, err
+	}
+
+	aof.version = version
+	aof.generation = generation
+
+	switch {
+	case size == 0 && aof.readOnly:
+		return false, nil
+	case size == 0:
+		return false, aof.writeHeader()
+	case version > FileVersion:
+		return false, fmt.Errorf("checkVersion error: %w (version %d, supported up to %d)",
+			ErrNewerFileVersion, version, FileVersion)
+	case version == FileVersion && generation != "" || aof.noMigration:
+		return false, nil
+	}
+
+	for from := version; from < FileVersion; from++ {
+		err = migrations[from](keys, aof.meta)
+		if err != nil {
+			return false, fmt.Errorf("checkVersion->migrate (%d to %d) error: %w", from, from+1, err)
+		}
+	}
+
+	aof.log.Info("file migrated", "file", aof.source, "from", version, "to", FileVersion)
+
+	return true, nil
+}
+
+/*
+readVersion returns the version in the header of the file (1 without a header), the generation
+(empty without one), and the size of the file.
+*/
+func (aof *AOF) readVersion() (int, string, int64, error) {
+	info, err := aof.file.Stat()
+	if err != nil {
+		return 0, "", 0, fmt.Errorf("readVersion error: %w", err)
+	}
+
+	scanner, _ := aof.newScanner(io.NewSectionReader(aof.file, 0, info.Size()))
+	if !scanner.Scan() {
+		return 1, "", info.Size(), nil
+	}
+
+	ins, err := readInstruction(scanner)
+	if err != nil || ins.Name != "meta" || ins.Key != versionMeta {
+		return 1, "", info.Size(), nil //nolint:nilerr // a bad first entry is reported while reading the file
+	}
+
+	version, err := strconv.Atoi(string(ins.Value))
+	if err != nil {
+		return 0, "", 0, fmt.Errorf("readVersion error: wrong file version '%s'", ins.Value)
+	}
+
+	// the generation comes right after the version
+	var generation string
+
+	if scanner.Scan() {
+		ins, err = readInstruction(scanner)
+		if err == nil && ins.Name == "meta" && ins.Key == generationMeta {
+			generation = string(ins.Value)
+		}
+	}
+
+	return version, generation, info.Size(), nil
+}
+
+/*
+writeHeader writes the header with the version and a new generation to a new (empty) file.
+*/
+func (aof *AOF) writeHeader() error {
+	generation := newGeneration()
+
+	_, err := aof.file.Write(fileHeader(aof.format, generation))
+	if err != nil {
+		return fmt.Errorf("writeHeader error: %w", err)
+	}
+
+	aof.version = FileVersion
+	aof.generation = generation
+
+	return nil
+}
+
+/*
+headerIfEmpty writes the header to the file if it's empty (a new file).
+*/
+func (aof *AOF) headerIfEmpty() error {
+	info, err := aof.file.Stat()
+	if err != nil {
+		return fmt.Errorf("writeHeader error: %w", err)
+	}
+
+	if info.Size() > 0 {
+		return nil
+	}
+
+	return aof.writeHeader()
+}
+
+/*
+fileHeader returns the header of a file: the version, and the generation if there is one.
+*/
+func fileHeader(format Format, generation string) []byte {
+	buf := versionHeader().appendTo(nil, format)
+	if generation != "" {
+		buf = MetaInstruction(generationMeta, generation).appendTo(buf, format)
+	}
+
+	return buf
+}
+
+/*
+newGeneration returns a new random generation for a file.
+*/
+func newGeneration() string {
+	buf := make([]byte, 8)
+
+	_, err := rand.Read(buf)
+	if err != nil {
+		binary.BigEndian.PutUint64(buf, uint64(time.Now().UnixNano())) //nolint:gosec // only the bits are used
+	}
+
+	return hex.EncodeToString(buf)
+}
+
+/*
+versionHeader returns the instruction of the header of a file.
+*/
+func versionHeader() Instruction {
+	return MetaInstruction(versionMeta, strconv.Itoa(FileVersion))
+}
+
+/*
+isHeader tells if the instruction is part of the header of a file (the version, the generation,
+or the mark of a compacted segment), which isn't meta data.
+*/
+func isHeader(ins Instruction) bool {
+	return ins.Name == "meta" && isHeaderMeta(ins.Key)
+}
+
+/*
+isHeaderMeta tells if the meta data name is one of the header.
+*/
+func isHeaderMeta(name string) bool {
+	return name == versionMeta || name == generationMeta || name == baseMeta
+}
+
+/*
+writeFileRecords writes the records like writeRecords, after the header with the version and a new generation,
+so what is written is a database file (a snapshot has a header of its own). It returns the generation.
+The same records result in the same file, except for the generation: every rewrite is a new file,
+also when it holds the same records as an earlier one, so an older snapshot can't be combined with it.
+*/
+func writeFileRecords(
+	writer io.Writer,
+	keys map[string]map[int][]byte,
+	meta map[string]string,
+	format Format,
+	comp *compressor,
+) (string, error) {
+	generation := newGeneration()
+
+	_, err := writer.Write(fileHeader(format, generation))
+	if err != nil {
+		return "", err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	return generation, writeRecords(writer, keys, meta, format, comp)
+}
@@ -0,0 +1,120 @@
+package persist_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FileVersion_newFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "version.db")
+
+	aof, _, err := persist.OpenPersister(filePath, syncIime)
+	require.NoError(t, err)
+	assert.Equal(t, persist.FileVersion, aof.FileVersion())
+	assert.Empty(t, aof.Meta())
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "meta\nfileversion\n2\nmeta\nfilegeneration\n"+aof.Generation()+"\n", string(content))
+	assert.Len(t, aof.Generation(), 16)
+
+	// the header isn't meta data, and isn't written twice
+	aof, keys, err := persist.OpenPersister(filePath, syncIime)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	assert.Empty(t, aof.Meta())
+	assert.Equal(t, int64(len(content)), aof.Size())
+	assert.Equal(t, int64(len(content)), aof.HeaderSize())
+
+	err = aof.Close()
+	require.NoError(t, err)
+}
+
+func Test_FileVersion_migration(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "version.db")
+	lines := "set\ntext_1\none\nmeta\nname\nvalue\nset\ntext_1\nuno\n"
+
+	err := os.WriteFile(filePath, []byte(lines), 0o600)
+	require.NoError(t, err)
+
+	// read-only, the file stays as it is
+	aof, keys, err := persist.OpenPersister(filePath, syncIime, persist.WithoutMigration())
+	require.NoError(t, err)
+	assert.Equal(t, 1, aof.FileVersion())
+	assert.Zero(t, aof.HeaderSize())
+	assert.Equal(t, []byte("uno"), keys["text"][1])
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, lines, string(content))
+
+	aof, keys, err = persist.OpenPersister(filePath, syncIime)
+	require.NoError(t, err)
+	assert.Equal(t, persist.FileVersion, aof.FileVersion())
+	assert.Equal(t, []byte("uno"), keys["text"][1])
+	assert.Equal(t, map[string]string{"name": "value"}, aof.Meta())
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	content, err = os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "meta\nfileversion\n2\nmeta\nfilegeneration\n"+aof.Generation()+"\nmeta\nname\nvalue\nset\ntext_1\nuno\n",
+		string(content))
+
+	// the old file is kept
+	backup, err := os.ReadFile(filePath + ".bak")
+	require.NoError(t, err)
+	assert.Equal(t, lines, string(backup))
+}
+
+func Test_FileVersion_newer(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "version.db")
+	lines := "meta\nfileversion\n99\nset\ntext_1\none\n"
+
+	err := os.WriteFile(filePath, []byte(lines), 0o600)
+	require.NoError(t, err)
+
+	_, _, err = persist.OpenPersister(filePath, syncIime)
+	require.ErrorIs(t, err, persist.ErrNewerFileVersion)
+
+	// the file can be opened again (the lock was released), and wasn't changed
+	_, _, err = persist.OpenPersister(filePath, syncIime)
+	require.ErrorIs(t, err, persist.ErrNewerFileVersion)
+
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, lines, string(content))
+}
+
+func Test_FileVersion_withoutGeneration(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "version.db")
+	lines := "meta\nfileversion\n2\nset\ntext_1\none\n"
+
+	err := os.WriteFile(filePath, []byte(lines), 0o600)
+	require.NoError(t, err)
+
+	// a file of the version without a generation gets one
+	aof, keys, err := persist.OpenPersister(filePath, syncIime)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("one"), keys["text"][1])
+	assert.Len(t, aof.Generation(), 16)
+
+	err = aof.Close()
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "meta\nfileversion\n2\nmeta\nfilegeneration\n"+aof.Generation()+"\nset\ntext_1\none\n", string(content))
+}
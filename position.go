@@ -0,0 +1,102 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+	"strconv"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// lsnMeta is the meta data name under which the write sequence is persisted.
+const lsnMeta = "lsn"
+
+// Position tells where a write ended up.
+type Position struct {
+	Sequence uint64 // the number of the write, it increases by one for every write
+	Offset   int64  // the size of the file after the write (always 0 for a database in memory)
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+SetWithPosition stores one map value in a bucket, and returns the position of that write.
+*/
+func (fdb *DB) SetWithPosition(bucket string, key int, value []byte) (Position, error) {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return Position{}, fmt.Errorf("setWithPosition error: %w", err)
+	}
+
+	defer unlock()
+
+	err = fdb.set(bucket, key, value, 0)
+	if err != nil {
+		return Position{}, err
+	}
+
+	return fdb.position()
+}
+
+/*
+DelWithPosition deletes one map value in a bucket, and returns the position of that write.
+If nothing was deleted, the position is that of the last write.
+*/
+func (fdb *DB) DelWithPosition(bucket string, key int) (bool, Position, error) {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return false, Position{}, fmt.Errorf("delWithPosition error: %w", err)
+	}
+
+	defer unlock()
+
+	deleted, err := fdb.del(bucket, key)
+	if err != nil {
+		return false, Position{}, err
+	}
+
+	pos, err := fdb.position()
+
+	return deleted, pos, err
+}
+
+/*
+Position returns the position of the last write.
+The sequence starts at 0 when the database is opened, unless it's opened WithWriteSequence.
+The offset starts again at 0 after a Defrag or a Checkpoint.
+*/
+func (fdb *DB) Position() (Position, error) {
+	defer fdb.mu.RLock().RUnlock()
+
+	return fdb.position()
+}
+
+/*
+position returns the position of the last write.
+The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) position() (Position, error) {
+	pos := Position{Sequence: fdb.sequence}
+
+	if fdb.aof != nil {
+		offset, err := fdb.aof.Offset()
+		if err != nil {
+			return pos, fmt.Errorf("position error: %w", err)
+		}
+
+		pos.Offset = offset
+	}
+
+	return pos, nil
+}
+
+/*
+loadSequence sets the write sequence to the one that was persisted (if any).
+*/
+func (fdb *DB) loadSequence() {
+	sequence, err := strconv.ParseUint(fdb.meta[lsnMeta], 10, 64)
+	if err == nil {
+		fdb.sequence = sequence
+	}
+}
@@ -0,0 +1,90 @@
+package fastdb_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Position(t *testing.T) {
+	path := "data/fastdb_position.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		_ = os.Remove(filePath + ".bak")
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithWriteSequence())
+	require.NoError(t, err)
+
+	pos, err := store.SetWithPosition("text", 1, []byte("value"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), pos.Sequence)
+	assert.Positive(t, pos.Offset)
+
+	err = store.SetMulti("text", map[int][]byte{2: []byte("two"), 3: []byte("three")})
+	require.NoError(t, err)
+
+	deleted, delPos, err := store.DelWithPosition("text", 2)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	assert.Equal(t, uint64(3), delPos.Sequence)
+	assert.Greater(t, delPos.Offset, pos.Offset)
+
+	// nothing deleted, so nothing written
+	deleted, pos, err = store.DelWithPosition("text", 2)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	assert.Equal(t, delPos, pos)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// the sequence survives a restart and a defrag
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithWriteSequence())
+	require.NoError(t, err)
+
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	pos, err = store.Position()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), pos.Sequence)
+
+	pos, err = store.SetWithPosition("text", 4, []byte("four"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), pos.Sequence)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Position_memory(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	for key := 1; key <= 3; key++ {
+		err = store.Set("text", key, []byte("value"))
+		require.NoError(t, err)
+	}
+
+	pos, err := store.Position()
+	require.NoError(t, err)
+	assert.Equal(t, fastdb.Position{Sequence: 3}, pos)
+
+	_, err = store.SetWithPosition("text", -1, []byte("value"))
+	require.Error(t, err)
+}
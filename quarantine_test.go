@@ -0,0 +1,129 @@
+package fastdb_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Open_WithQuarantine(t *testing.T) {
+	path := "data/fastdb_quarantine.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		err = os.Remove(filePath + ".quarantine")
+		require.NoError(t, err)
+	}()
+
+	lines := "set\ntext_1\nvalue for key 1\nwrong line\nset\ntext_2\nvalue for key 2\n"
+	err := os.WriteFile(filePath, []byte(lines), 0o600)
+	require.NoError(t, err)
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.Error(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithQuarantine())
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.Equal(t, "2 record(s) in 1 bucket(s)", store.Info())
+
+	report := store.CorruptionReport()
+	require.NotNil(t, report)
+	require.Len(t, report.Entries, 1)
+	assert.Equal(t, 4, report.Entries[0].Line)
+
+	memStore, err := fastdb.Open(memory)
+	require.NoError(t, err)
+	assert.Nil(t, memStore.CorruptionReport())
+}
+
+func Test_Open_WithBackupFallback(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "fallback.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("one"))
+	require.NoError(t, err)
+
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	err = store.Set("text", 2, []byte("two"))
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	lines := "set\ntext_1\none\nwrong line\nset\ntext_2\ntwo\n"
+	err = os.WriteFile(filePath, []byte(lines), 0o600)
+	require.NoError(t, err)
+
+	_, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.ErrorIs(t, err, fastdb.ErrCorrupted)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithBackupFallback())
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.Equal(t, filePath+".corrupt", store.RestoredFromBackup())
+	assert.Equal(t, 1, store.Count("text"))
+
+	corrupt, err := os.ReadFile(filePath + ".corrupt")
+	require.NoError(t, err)
+	assert.Equal(t, lines, string(corrupt))
+
+	// the backup stays, for when the restored file gets corrupt too
+	_, err = os.Stat(filePath + ".bak")
+	require.NoError(t, err)
+
+	memStore, err := fastdb.Open(memory)
+	require.NoError(t, err)
+	assert.Empty(t, memStore.RestoredFromBackup())
+}
+
+func Test_Open_WithRecover(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "recover.db")
+
+	lines := "set\ntext_1\none\nset\ntext_2\ntwo\nset\ntext_3\n"
+	err := os.WriteFile(filePath, []byte(lines), 0o600)
+	require.NoError(t, err)
+
+	_, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.ErrorIs(t, err, fastdb.ErrCorrupted)
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithRecover())
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.Equal(t, 2, store.Count("text"))
+	assert.Equal(t, int64(len("set\ntext_3\n")), store.DiscardedBytes())
+
+	broken, err := os.ReadFile(filePath + ".broken")
+	require.NoError(t, err)
+	assert.Equal(t, "set\ntext_3\n", string(broken))
+
+	memStore, err := fastdb.Open(memory)
+	require.NoError(t, err)
+	assert.Zero(t, memStore.DiscardedBytes())
+}
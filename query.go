@@ -0,0 +1,178 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+/*
+Query selects records from a bucket by the fields of their JSON values.
+It is created with DB.Query, narrowed down with Where and Limit, and executed with Run.
+*/
+type Query struct {
+	fdb        *DB
+	err        error
+	bucket     string
+	conditions []condition
+	limit      int
+}
+
+// condition is one comparison of a field (gjson path) with a value.
+type condition struct {
+	path     string
+	operator string
+	value    gjson.Result
+}
+
+var errUnknownOperator = errors.New("unknown operator")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Find returns the records of a bucket (in Key sorted order) for which filter returns true.
+Everything is read in one locked pass, so filter can't use the database.
+*/
+func (fdb *DB) Find(bucket string, filter func(key int, value []byte) bool) ([]*Record, error) {
+	return fdb.find(bucket, filter, 0)
+}
+
+/*
+Query starts a query on a bucket, e.g. store.Query("user").Where("Age", ">", 30).Limit(50).Run().
+*/
+func (fdb *DB) Query(bucket string) *Query {
+	return &Query{fdb: fdb, bucket: bucket}
+}
+
+/*
+Where adds a condition: the field at the gjson path is compared with the value.
+The operators are =, !=, >, >=, < and <=. A field of another type than the value
+(or a missing field) never matches, except for !=.
+*/
+func (qry *Query) Where(path, operator string, value any) *Query {
+	switch operator {
+	case "=", "!=", ">", ">=", "<", "<=":
+	default:
+		qry.err = errors.Join(qry.err, fmt.Errorf("%w '%s'", errUnknownOperator, operator))
+
+		return qry
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		qry.err = errors.Join(qry.err, fmt.Errorf("where (%s) error: %w", path, err))
+
+		return qry
+	}
+
+	qry.conditions = append(qry.conditions, condition{path: path, operator: operator, value: gjson.ParseBytes(raw)})
+
+	return qry
+}
+
+/*
+Limit sets the maximum number of records, 0 (the default) means no maximum.
+*/
+func (qry *Query) Limit(limit int) *Query {
+	qry.limit = max(limit, 0)
+
+	return qry
+}
+
+/*
+Run returns the records that match all the conditions, in Key sorted order.
+*/
+func (qry *Query) Run() ([]*Record, error) {
+	if qry.err != nil {
+		return nil, fmt.Errorf("query error: %w", qry.err)
+	}
+
+	return qry.fdb.find(qry.bucket, qry.matches, qry.limit)
+}
+
+/*
+matches returns true if the value meets all the conditions.
+*/
+func (qry *Query) matches(_ int, value []byte) bool {
+	for _, cond := range qry.conditions {
+		if !cond.matches(gjson.GetBytes(value, cond.path)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+matches returns true if the field meets the condition.
+*/
+func (cond condition) matches(field gjson.Result) bool {
+	if kind(field) != kind(cond.value) {
+		return cond.operator == "!="
+	}
+
+	less := field.Less(cond.value, true)
+	greater := cond.value.Less(field, true)
+
+	switch cond.operator {
+	case "=":
+		return !less && !greater
+	case "!=":
+		return less || greater
+	case ">":
+		return greater
+	case ">=":
+		return !less
+	case "<":
+		return less
+	default: // "<="
+		return !greater
+	}
+}
+
+/*
+kind returns the type of a result, with true and false as the same type.
+*/
+func kind(result gjson.Result) gjson.Type {
+	if result.Type == gjson.True {
+		return gjson.False
+	}
+
+	return result.Type
+}
+
+/*
+find returns the records of a bucket (in Key sorted order) that match, up to the limit (0 is no limit).
+*/
+func (fdb *DB) find(bucket string, match func(key int, value []byte) bool, limit int) ([]*Record, error) {
+	defer fdb.mu.RLock().RUnlock()
+
+	memRecords, found := fdb.keys[bucket]
+	if !found {
+		return nil, fmt.Errorf("%w (%s)", ErrBucketNotFound, bucket)
+	}
+
+	now := time.Now().UnixNano()
+	records := []*Record{}
+
+	for _, key := range fdb.sortedKeys(bucket) {
+		if fdb.expiredAt(bucket, key, now) || !match(key, memRecords[key]) {
+			continue
+		}
+
+		records = append(records, &Record{Key: key, Data: memRecords[key]})
+
+		if len(records) == limit {
+			break
+		}
+	}
+
+	return records, nil
+}
@@ -0,0 +1,86 @@
+package fastdb_test
+
+import (
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Find(t *testing.T) {
+	store := queryStore(t)
+
+	records, err := store.Find("user", func(_ int, value []byte) bool {
+		return len(value) > 40
+	})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, 3, records[0].Key)
+
+	_, err = store.Find("none", func(int, []byte) bool { return true })
+	require.Error(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Query(t *testing.T) {
+	store := queryStore(t)
+
+	tests := []struct {
+		query *fastdb.Query
+		name  string
+		keys  []int
+	}{
+		{name: "greater", query: store.Query("user").Where("Age", ">", 30), keys: []int{2, 3}},
+		{name: "greater or equal", query: store.Query("user").Where("Age", ">=", 30), keys: []int{1, 2, 3}},
+		{name: "less", query: store.Query("user").Where("Age", "<", 40), keys: []int{1}},
+		{name: "less or equal", query: store.Query("user").Where("Age", "<=", 30), keys: []int{1}},
+		{name: "equal string", query: store.Query("user").Where("Name", "=", "Bob"), keys: []int{2}},
+		{name: "not equal", query: store.Query("user").Where("Name", "!=", "Bob"), keys: []int{1, 3, 4}},
+		{name: "bool", query: store.Query("user").Where("Admin", "=", true), keys: []int{3}},
+		{name: "and", query: store.Query("user").Where("Age", ">", 30).Where("Admin", "=", false), keys: []int{2}},
+		{name: "limit", query: store.Query("user").Where("Age", ">=", 30).Limit(2), keys: []int{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := tt.query.Run()
+			require.NoError(t, err)
+
+			keys := make([]int, 0, len(records))
+			for _, record := range records {
+				keys = append(keys, record.Key)
+			}
+
+			assert.Equal(t, tt.keys, keys)
+		})
+	}
+
+	_, err := store.Query("user").Where("Age", "~", 30).Run()
+	require.Error(t, err)
+
+	_, err = store.Query("none").Run()
+	require.Error(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func queryStore(t *testing.T) *fastdb.DB {
+	t.Helper()
+
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	err = store.SetMulti("user", map[int][]byte{
+		1: []byte(`{"Name":"Alice","Age":30,"Admin":false}`),
+		2: []byte(`{"Name":"Bob","Age":42,"Admin":false}`),
+		3: []byte(`{"Name":"Carol","Age":51,"Admin":true,"Extra":1}`),
+		4: []byte(`{"Name":"Dave","Age":"unknown"}`),
+	})
+	require.NoError(t, err)
+
+	return store
+}
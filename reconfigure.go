@@ -0,0 +1,97 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Reconfigure changes options of the live database, without closing and reopening it.
+The options that can be changed are WithSyncTime, WithMaxValueSize, WithLogger, WithFormat,
+WithCompression, WithSnapshotCompression, WithPreallocation, WithAutoDefrag, WithAutoDefragGarbage,
+WithFreezeTimeout, WithCheckpointInterval, WithScrub, WithoutSortCache and WithWriteSequence.
+WithReadOnly and WithStripes can't be changed, and options that only matter while opening
+(like WithQuarantine and WithScanBuffer) have no effect anymore.
+*/
+func (fdb *DB) Reconfigure(opts ...Option) error {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return fmt.Errorf("reconfigure error: %w", err)
+	}
+
+	defer unlock()
+
+	cfg := fdb.cfg
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	cfg.trace = fdb.cfg.trace // only set at Open
+
+	if cfg.readOnly != fdb.cfg.readOnly {
+		return errors.New("reconfigure error: read-only can't be changed on an open database")
+	}
+
+	if cfg.stripes != fdb.cfg.stripes {
+		return errors.New("reconfigure error: the stripes can't be changed on an open database")
+	}
+
+	restartCheckpoints := cfg.checkpointInterval != fdb.cfg.checkpointInterval
+	restartScrub := cfg.scrubInterval != fdb.cfg.scrubInterval || cfg.scrubBytes != fdb.cfg.scrubBytes
+
+	if cfg.noSortCache != fdb.cfg.noSortCache {
+		fdb.resetCaches()
+	}
+
+	formatChanged := cfg.format != fdb.cfg.format
+	fdb.cfg = cfg
+
+	if formatChanged {
+		fdb.loadLiveBytes()
+	}
+
+	if fdb.aof != nil {
+		fdb.aof.Reconfigure(cfg.syncTime, cfg.persistOptions()...)
+
+		if restartCheckpoints && !cfg.readOnly {
+			fdb.restartCheckpoints()
+		}
+
+		if restartScrub {
+			fdb.restartScrub()
+		}
+	}
+
+	return nil
+}
+
+/*
+restartCheckpoints stops the checkpoint task (if any), and starts it with the current interval.
+The caller must hold the write lock.
+*/
+func (fdb *DB) restartCheckpoints() {
+	if fdb.stopCheckpoints != nil {
+		close(fdb.stopCheckpoints)
+		fdb.stopCheckpoints = nil
+	}
+
+	// after a Close, no tasks are started anymore
+	if fdb.cfg.checkpointInterval > 0 && fdb.stopTasks != nil {
+		fdb.stopCheckpoints = make(chan struct{})
+		fdb.runEvery(fdb.cfg.checkpointInterval, fdb.stopCheckpoints, fdb.checkpoint)
+	}
+}
+
+/*
+logger returns the logger, which can be changed by Reconfigure.
+*/
+func (fdb *DB) logger() *slog.Logger {
+	defer fdb.mu.RLock().RUnlock()
+
+	return fdb.cfg.logger
+}
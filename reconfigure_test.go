@@ -0,0 +1,90 @@
+package fastdb_test
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Reconfigure(t *testing.T) {
+	path := "data/fastdb_reconfigure.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		_ = os.Remove(filePath + ".snapshot")
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(0))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("twelve bytes"))
+	require.NoError(t, err)
+
+	logs := &bytes.Buffer{}
+
+	err = store.Reconfigure(
+		fastdb.WithSyncTime(10),
+		fastdb.WithMaxValueSize(5),
+		fastdb.WithLogger(slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		fastdb.WithCheckpointInterval(10*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	err = store.Set("text", 2, []byte("twelve bytes"))
+	require.Error(t, err)
+
+	err = store.Set("text", 2, []byte("small"))
+	require.NoError(t, err)
+
+	// the checkpoint task is running now
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filePath + ".snapshot")
+
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+
+	err = store.Reconfigure(fastdb.WithSyncTime(0), fastdb.WithCheckpointInterval(0), fastdb.WithoutSortCache())
+	require.NoError(t, err)
+
+	records, err := store.GetAllSorted("text")
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	err = store.Reconfigure(fastdb.WithReadOnly())
+	require.Error(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.Equal(t, "2 record(s) in 1 bucket(s)", store.Info())
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Reconfigure_memory(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	err = store.Reconfigure(fastdb.WithMaxValueSize(1), fastdb.WithCheckpointInterval(time.Millisecond))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("too big"))
+	require.Error(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
@@ -0,0 +1,84 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+/*
+Record represents one stored value together with its key.
+It offers read-only access to the fields of a JSON value (by gjson path),
+without the need to unmarshal the whole value into a struct.
+*/
+type Record struct {
+	Data []byte
+	Key  int
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+GetRecord returns one value from a bucket, wrapped as a Record.
+*/
+func (fdb *DB) GetRecord(bucket string, key int) (*Record, bool) {
+	data, ok := fdb.Get(bucket, key)
+	if !ok {
+		return nil, false
+	}
+
+	return &Record{Key: key, Data: data}, true
+}
+
+/*
+Get returns the result for the given gjson path.
+*/
+func (rec *Record) Get(path string) gjson.Result {
+	return gjson.GetBytes(rec.Data, path)
+}
+
+/*
+Exists returns true if the given gjson path exists in the value.
+*/
+func (rec *Record) Exists(path string) bool {
+	return rec.Get(path).Exists()
+}
+
+/*
+String returns the field at the given gjson path as a string.
+*/
+func (rec *Record) String(path string) string {
+	return rec.Get(path).String()
+}
+
+/*
+Int returns the field at the given gjson path as an integer.
+*/
+func (rec *Record) Int(path string) int64 {
+	return rec.Get(path).Int()
+}
+
+/*
+Float returns the field at the given gjson path as a float.
+*/
+func (rec *Record) Float(path string) float64 {
+	return rec.Get(path).Float()
+}
+
+/*
+Bool returns the field at the given gjson path as a boolean.
+*/
+func (rec *Record) Bool(path string) bool {
+	return rec.Get(path).Bool()
+}
+
+/*
+Time returns the field at the given gjson path as a time (RFC3339 formatted).
+*/
+func (rec *Record) Time(path string) time.Time {
+	return rec.Get(path).Time()
+}
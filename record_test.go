@@ -0,0 +1,56 @@
+package fastdb_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetRecord(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.NotNil(t, store)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	created := time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+
+	data, err := json.Marshal(map[string]any{
+		"ID":      1,
+		"Name":    "Marcello",
+		"Score":   9.5,
+		"Active":  true,
+		"Created": created,
+		"Address": map[string]any{"City": "Amsterdam"},
+	})
+	require.NoError(t, err)
+
+	err = store.Set("user", 1, data)
+	require.NoError(t, err)
+
+	record, ok := store.GetRecord("user", 1)
+	assert.True(t, ok)
+	require.NotNil(t, record)
+
+	assert.Equal(t, 1, record.Key)
+	assert.Equal(t, data, record.Data)
+	assert.Equal(t, int64(1), record.Int("ID"))
+	assert.Equal(t, "Marcello", record.String("Name"))
+	assert.InDelta(t, 9.5, record.Float("Score"), 0.0001)
+	assert.True(t, record.Bool("Active"))
+	assert.True(t, created.Equal(record.Time("Created")))
+	assert.Equal(t, "Amsterdam", record.Get("Address.City").Str)
+	assert.True(t, record.Exists("Address.City"))
+	assert.False(t, record.Exists("Address.Street"))
+
+	record, ok = store.GetRecord("user", 2)
+	assert.False(t, ok)
+	assert.Nil(t, record)
+}
@@ -0,0 +1,504 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// replica is a follower that is connected to the leader, see ServeReplication.
+type replica struct {
+	batches chan []persist.Instruction // closed when the replica is dropped
+}
+
+const (
+	// replicationHeader starts the stream of the leader, so a follower knows it speaks the protocol.
+	replicationHeader = "fastdb-replication 1\n"
+	// the message types, every message is a type, the size of the payload (uvarint) and the payload.
+	msgReset  = 'R' // a full sync starts, the follower collects the state
+	msgBatch  = 'B' // instructions (binary frames), part of the full sync or a write of the leader
+	msgSynced = 'S' // the full sync is complete, the follower replaces its state
+	// syncBatchBytes is the size from which the full sync is sent in the next message.
+	syncBatchBytes = 1024 * 1024
+	// replicaQueue is the number of writes a replica can be behind, before it is dropped (and syncs again).
+	replicaQueue = 4096
+	// replicaRetry is the time a follower waits before it connects to the leader again.
+	replicaRetry = time.Second
+	// maxMessageSize protects a follower against a broken stream.
+	maxMessageSize = 1 << 30
+)
+
+var errReplication = errors.New("wrong replication stream")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+ServeReplication makes the database a leader: every follower (see ReplicateFrom) that connects
+to the listener gets a full sync, and after that every write, in the order they were done.
+It returns when the listener is closed. A follower that falls too far behind is disconnected,
+and gets a full sync when it connects again. Use tls.NewListener for an encrypted connection.
+The writes wait during the full sync of a follower, because it is a consistent copy.
+*/
+func (fdb *DB) ServeReplication(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+
+			return fmt.Errorf("serveReplication error: %w", err)
+		}
+
+		go fdb.serveReplica(conn)
+	}
+}
+
+/*
+serveReplica sends the full sync to a follower, and then the writes, until it is dropped or disconnects.
+*/
+func (fdb *DB) serveReplica(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	writer := bufio.NewWriter(conn)
+
+	rep, err := fdb.fullSync(writer)
+	if err != nil {
+		fdb.logger().Warn("replication error", "follower", conn.RemoteAddr().String(), "error", err)
+
+		return
+	}
+
+	defer fdb.dropReplica(rep)
+
+	for batch := range rep.batches {
+		err = writeMessage(writer, msgBatch, persist.AppendFrames(nil, batch))
+
+		// the writes that are queued go out together
+		if err == nil && len(rep.batches) == 0 {
+			err = writer.Flush()
+		}
+
+		if err != nil {
+			fdb.logger().Warn("replication error", "follower", conn.RemoteAddr().String(), "error", err)
+
+			return
+		}
+	}
+}
+
+/*
+fullSync sends the whole state to a follower, and registers it for the writes that follow.
+The read lock is held until it's registered, so no write is missed.
+*/
+func (fdb *DB) fullSync(writer *bufio.Writer) (*replica, error) {
+	defer fdb.mu.RLock().RUnlock()
+
+	if fdb.closed {
+		return nil, fmt.Errorf("fullSync error: %w", ErrClosed)
+	}
+
+	_, err := writer.WriteString(replicationHeader)
+	if err == nil {
+		err = writeMessage(writer, msgReset, nil)
+	}
+
+	var buf []byte
+
+	for _, name := range slices.Sorted(maps.Keys(fdb.meta)) {
+		if err != nil {
+			break
+		}
+
+		buf = persist.AppendFrames(buf, []persist.Instruction{persist.MetaInstruction(name, fdb.meta[name])})
+		buf, err = flushSync(writer, buf, syncBatchBytes)
+	}
+
+	for _, bucket := range slices.Sorted(maps.Keys(fdb.keys)) {
+		for _, key := range fdb.sortedKeys(bucket) {
+			if err != nil {
+				break
+			}
+
+			buf = persist.AppendFrames(buf, []persist.Instruction{persist.SetInstruction(bucket, key, fdb.keys[bucket][key])})
+			buf, err = flushSync(writer, buf, syncBatchBytes)
+		}
+	}
+
+	if err == nil {
+		_, err = flushSync(writer, buf, 1)
+	}
+
+	if err == nil {
+		err = writeMessage(writer, msgSynced, nil)
+	}
+
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("fullSync error: %w", err)
+	}
+
+	rep := &replica{batches: make(chan []persist.Instruction, replicaQueue)}
+
+	fdb.replicaMu.Lock()
+	defer fdb.replicaMu.Unlock()
+
+	if fdb.replicas == nil {
+		fdb.replicas = map[*replica]struct{}{}
+	}
+
+	fdb.replicas[rep] = struct{}{}
+
+	return rep, nil
+}
+
+/*
+flushSync sends the frames of the full sync when there are at least size bytes of them.
+*/
+func flushSync(writer *bufio.Writer, buf []byte, size int) ([]byte, error) {
+	if len(buf) < size {
+		return buf, nil
+	}
+
+	return buf[:0], writeMessage(writer, msgBatch, buf)
+}
+
+/*
+replicate queues the instructions of a write for all the followers.
+A follower that is too far behind is dropped, so it connects again and gets a full sync.
+The caller must hold the write lock.
+*/
+func (fdb *DB) replicate(instructions []persist.Instruction) {
+	fdb.replicaMu.Lock()
+	defer fdb.replicaMu.Unlock()
+
+	for rep := range fdb.replicas {
+		select {
+		case rep.batches <- instructions:
+		default:
+			delete(fdb.replicas, rep)
+			close(rep.batches)
+		}
+	}
+}
+
+/*
+dropReplica stops sending the writes to a follower.
+*/
+func (fdb *DB) dropReplica(rep *replica) {
+	fdb.replicaMu.Lock()
+	defer fdb.replicaMu.Unlock()
+
+	_, found := fdb.replicas[rep]
+	if found {
+		delete(fdb.replicas, rep)
+		close(rep.batches)
+	}
+}
+
+/*
+stopReplicas disconnects all the followers (when the database is closed, or its state is replaced).
+*/
+func (fdb *DB) stopReplicas() {
+	fdb.replicaMu.Lock()
+	defer fdb.replicaMu.Unlock()
+
+	for rep := range fdb.replicas {
+		delete(fdb.replicas, rep)
+		close(rep.batches)
+	}
+}
+
+/*
+ReplicateFrom makes the database a follower of the leader at the address (see ServeReplication),
+for a warm standby: it gets a full sync that replaces everything it holds, and after that every write
+of the leader is written to its own file too. When the connection breaks, it connects again (with a new
+full sync). It returns when the context is done. A nil config connects without TLS.
+While it runs, the own writes of the follower fail with ErrReadOnly, because the leader would overwrite them.
+A database that was opened WithReadOnly can't be a follower, because its file can't be written.
+*/
+func (fdb *DB) ReplicateFrom(ctx context.Context, address string, config *tls.Config) error {
+	if fdb.cfg.readOnly {
+		return fmt.Errorf("replicateFrom error: %w", ErrReadOnly)
+	}
+
+	fdb.replicating.Store(true)
+	defer fdb.replicating.Store(false)
+
+	for {
+		err := fdb.replicateOnce(ctx, address, config)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		fdb.logger().Warn("replication error", "leader", address, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(replicaRetry):
+		}
+	}
+}
+
+/*
+readOnly tells if the writes (other than those of the leader) are refused:
+the database was opened WithReadOnly, or it is a follower (see ReplicateFrom).
+*/
+func (fdb *DB) readOnly() bool {
+	return fdb.cfg.readOnly || fdb.replicating.Load()
+}
+
+/*
+replicateOnce connects to the leader and applies what it sends, until the connection breaks.
+*/
+func (fdb *DB) replicateOnce(ctx context.Context, address string, config *tls.Config) error {
+	var dialer interface {
+		DialContext(ctx context.Context, network, address string) (net.Conn, error)
+	} = &net.Dialer{}
+
+	if config != nil {
+		dialer = &tls.Dialer{Config: config}
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("replicate error: %w", err)
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+
+	defer func() {
+		stop()
+
+		_ = conn.Close()
+	}()
+
+	err = fdb.applyStream(bufio.NewReader(conn))
+	if err != nil {
+		return fmt.Errorf("replicate error: %w", err)
+	}
+
+	return nil
+}
+
+/*
+applyStream reads the messages of the leader, and applies them.
+*/
+func (fdb *DB) applyStream(reader *bufio.Reader) error {
+	header := make([]byte, len(replicationHeader))
+
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	if string(header) != replicationHeader {
+		return errReplication
+	}
+
+	var (
+		keys map[string]map[int][]byte
+		meta map[string]string
+	)
+
+	for {
+		msgType, payload, err := readMessage(reader)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case msgType == msgReset:
+			keys = map[string]map[int][]byte{}
+			meta = map[string]string{}
+		case msgType == msgBatch && keys != nil:
+			err = collectSync(keys, meta, payload)
+		case msgType == msgBatch:
+			err = fdb.applyBatch(payload)
+		case msgType == msgSynced && keys != nil:
+			err = fdb.replaceState(keys, meta)
+			keys, meta = nil, nil
+		default:
+			err = fmt.Errorf("%w: unexpected message %q", errReplication, msgType)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+/*
+collectSync adds the records and the meta data of a part of the full sync.
+*/
+func collectSync(keys map[string]map[int][]byte, meta map[string]string, payload []byte) error {
+	instructions, err := persist.ReadFrames(payload)
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	for _, ins := range instructions {
+		switch ins.Name {
+		case "meta":
+			meta[ins.Key] = string(ins.Value)
+		case "set":
+			bucket, key, ok := ins.BucketKey()
+			if !ok {
+				return fmt.Errorf("%w: wrong key '%s'", errReplication, ins.Key)
+			}
+
+			if _, found := keys[bucket]; !found {
+				keys[bucket] = map[int][]byte{}
+			}
+
+			keys[bucket][key] = ins.Value
+		default:
+			return fmt.Errorf("%w: unexpected %s in the full sync", errReplication, ins.Name)
+		}
+	}
+
+	return nil
+}
+
+/*
+applyBatch writes the instructions of a write of the leader, and applies them to the memory.
+*/
+func (fdb *DB) applyBatch(payload []byte) error {
+	instructions, err := persist.ReadFrames(payload)
+	if err != nil {
+		return err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	err = fdb.write(instructions...)
+	if err != nil {
+		return err
+	}
+
+	metaChanged := false
+
+	for _, ins := range instructions {
+		metaChanged = fdb.applyInstruction(ins, true) || metaChanged
+	}
+
+	if metaChanged {
+		fdb.loadExpiries()
+		fdb.loadSequence()
+	}
+
+	return nil
+}
+
+/*
+replaceState replaces all the records and meta data with those of the full sync,
+and rewrites the file with them (like a Defrag). The followers of this database sync again.
+*/
+func (fdb *DB) replaceState(keys map[string]map[int][]byte, meta map[string]string) error {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	if fdb.closed {
+		return ErrClosed
+	}
+
+	// the meta data is shared with the file, which writes it
+	clear(fdb.meta)
+	maps.Copy(fdb.meta, meta)
+
+	if fdb.aof != nil {
+		err = fdb.aof.Defrag(keys)
+		if err != nil {
+			return err //nolint:wrapcheck // it is wrapped by the caller
+		}
+
+		fdb.lastDefrag = time.Now()
+	}
+
+	fdb.keys = keys
+	fdb.resetCaches()
+	fdb.resetGrowth()
+	fdb.loadExpiries()
+	fdb.loadSequence()
+	fdb.loadLiveBytes()
+	fdb.stopReplicas()
+
+	fdb.fileRecords = fdb.totalCount()
+
+	return fdb.markBase()
+}
+
+/*
+writeMessage writes a message of the replication stream: the type, the size of the payload and the payload.
+*/
+func writeMessage(writer *bufio.Writer, msgType byte, payload []byte) error {
+	err := writer.WriteByte(msgType)
+	if err == nil {
+		_, err = writer.Write(binary.AppendUvarint(nil, uint64(len(payload))))
+	}
+
+	if err == nil {
+		_, err = writer.Write(payload)
+	}
+
+	return err //nolint:wrapcheck // it is wrapped by the caller
+}
+
+/*
+readMessage reads a message of the replication stream.
+*/
+func readMessage(reader *bufio.Reader) (byte, []byte, error) {
+	msgType, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	size, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return 0, nil, err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	if size > maxMessageSize {
+		return 0, nil, fmt.Errorf("%w: message of %d bytes", errReplication, size)
+	}
+
+	payload := make([]byte, size)
+
+	_, err = io.ReadFull(reader, payload)
+	if err != nil {
+		return 0, nil, err //nolint:wrapcheck // it is wrapped by the caller
+	}
+
+	return msgType, payload, nil
+}
@@ -0,0 +1,118 @@
+package fastdb_test
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Replication(t *testing.T) {
+	dir := t.TempDir()
+
+	leader, err := fastdb.Open(filepath.Join(dir, "leader.db"), fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	err = leader.Set("text", 1, []byte("one"))
+	require.NoError(t, err)
+
+	err = leader.SetWithTTL("text", 2, []byte("two"), time.Hour)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		assert.NoError(t, leader.ServeReplication(listener))
+	}()
+
+	// the follower has a record that isn't on the leader, which the full sync removes
+	followerPath := filepath.Join(dir, "follower.db")
+
+	follower, err := fastdb.Open(followerPath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	err = follower.Set("old", 1, []byte("old"))
+	require.NoError(t, err)
+
+	err = follower.Close()
+	require.NoError(t, err)
+
+	follower, err = fastdb.Open(followerPath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		assert.NoError(t, follower.ReplicateFrom(ctx, listener.Addr().String(), nil))
+	}()
+
+	require.Eventually(t, func() bool {
+		return follower.Count("text") == 2 && !follower.HasBucket("old")
+	}, 5*time.Second, time.Millisecond)
+
+	ttl, ok := follower.TTL("text", 2)
+	assert.True(t, ok)
+	assert.Positive(t, ttl)
+
+	// the follower only takes the writes of the leader
+	err = follower.Set("text", 9, []byte("own"))
+	require.ErrorIs(t, err, fastdb.ErrReadOnly)
+
+	// the writes of the leader follow
+	err = leader.Set("text", 3, []byte("three"))
+	require.NoError(t, err)
+
+	_, err = leader.Del("text", 1)
+	require.NoError(t, err)
+
+	err = leader.RenameBucket("text", "words")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return follower.Count("words") == 2 && !follower.HasBucket("text")
+	}, 5*time.Second, time.Millisecond)
+
+	cancel()
+
+	err = listener.Close()
+	require.NoError(t, err)
+
+	wg.Wait()
+
+	err = leader.Close()
+	require.NoError(t, err)
+
+	err = follower.Close()
+	require.NoError(t, err)
+
+	// the follower wrote everything to its own file
+	follower, err = fastdb.Open(followerPath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	records, err := follower.GetAll("words")
+	require.NoError(t, err)
+	assert.Equal(t, map[int][]byte{2: []byte("two"), 3: []byte("three")}, records)
+
+	_, ok = follower.TTL("words", 2)
+	assert.True(t, ok)
+
+	err = follower.Close()
+	require.NoError(t, err)
+}
@@ -0,0 +1,178 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+/*
+ConflictFunc decides what is stored when a restored key already exists in the database.
+It returns the value to store, so returning existing keeps the current value.
+An error stops the restore, without changing anything.
+*/
+type ConflictFunc func(bucket string, key int, existing, incoming []byte) ([]byte, error)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+KeepExisting is the ConflictFunc that keeps the current value (skip).
+*/
+func KeepExisting(_ string, _ int, existing, _ []byte) ([]byte, error) {
+	return existing, nil
+}
+
+/*
+KeepIncoming is the ConflictFunc that stores the restored value (overwrite).
+*/
+func KeepIncoming(_ string, _ int, _, incoming []byte) ([]byte, error) {
+	return incoming, nil
+}
+
+/*
+Restore merges the records of a snapshot stream (made by Snapshot) into the database.
+For keys that already exist, resolve decides what is stored (nil means KeepIncoming).
+The meta data of the snapshot (like sequences and expiry times) isn't restored.
+Everything is written in one go, so either all the records are restored, or none of them.
+*/
+func (fdb *DB) Restore(reader io.Reader, resolve ConflictFunc) error {
+	incoming, err := persist.ReadSnapshot(reader)
+	if err != nil {
+		return fmt.Errorf("restore error: %w", err)
+	}
+
+	return fdb.restore(incoming, resolve)
+}
+
+/*
+restore merges the records into the database, in one go.
+*/
+func (fdb *DB) restore(incoming map[string]map[int][]byte, resolve ConflictFunc) error {
+	if resolve == nil {
+		resolve = KeepIncoming
+	}
+
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return fmt.Errorf("restore error: %w", err)
+	}
+
+	defer unlock()
+
+	if fdb.readOnly() {
+		return fmt.Errorf("restore error: %w", ErrReadOnly)
+	}
+
+	merged, err := fdb.merge(incoming, resolve)
+	if err != nil {
+		return err
+	}
+
+	var instructions []persist.Instruction
+
+	for _, bucket := range slices.Sorted(maps.Keys(merged)) {
+		added := 0
+
+		for key := range merged[bucket] {
+			_, exists := fdb.keys[bucket][key]
+			if !exists {
+				added++
+			}
+		}
+
+		err = fdb.checkLimit(bucket, added)
+		if err != nil {
+			return fmt.Errorf("restore->%w", err)
+		}
+
+		for _, key := range slices.Sorted(maps.Keys(merged[bucket])) {
+			instructions = append(instructions, persist.SetInstruction(bucket, key, merged[bucket][key]))
+			instructions = append(instructions, fdb.expiryInstructions(bucket, key, 0)...)
+		}
+	}
+
+	if len(instructions) == 0 {
+		return nil
+	}
+
+	err = fdb.write(instructions...)
+	if err != nil {
+		return fmt.Errorf("restore->write error: %w", err)
+	}
+
+	for _, bucket := range slices.Sorted(maps.Keys(merged)) {
+		records := merged[bucket]
+
+		_, found := fdb.keys[bucket]
+		if !found {
+			fdb.keys[bucket] = make(map[int][]byte, len(records))
+		}
+
+		for _, key := range slices.Sorted(maps.Keys(records)) {
+			fdb.liveBytes -= fdb.recordSize(bucket, key)
+			fdb.keys[bucket][key] = records[key]
+			fdb.liveBytes += fdb.recordSize(bucket, key)
+			fdb.setExpiry(bucket, key, 0)
+			fdb.notify(Event{Type: EventSet, Bucket: bucket, Key: key, Value: records[key]})
+			fdb.afterSet(bucket, key, records[key])
+		}
+
+		fdb.touch(bucket)
+	}
+
+	return nil
+}
+
+/*
+merge returns the records that have to be stored, with the conflicts resolved.
+Records that wouldn't change are left out.
+The caller must hold the write lock.
+*/
+func (fdb *DB) merge(incoming map[string]map[int][]byte, resolve ConflictFunc) (map[string]map[int][]byte, error) {
+	merged := map[string]map[int][]byte{}
+
+	for bucket, records := range incoming {
+		for key, value := range records {
+			existing, found := fdb.keys[bucket][key]
+			if found {
+				var err error
+
+				value, err = resolve(bucket, key, existing, value)
+				if err != nil {
+					return nil, fmt.Errorf("restore->resolve (%s_%d) error: %w", bucket, key, err)
+				}
+
+				if bytes.Equal(value, existing) {
+					continue
+				}
+			}
+
+			stored, err := fdb.beforeSet(bucket, key, value)
+			if err != nil {
+				return nil, fmt.Errorf("restore->%w", err)
+			}
+
+			if fdb.cfg.maxValueSize > 0 && len(stored) > fdb.cfg.maxValueSize {
+				return nil, fmt.Errorf("restore->value size (%d) of %s_%d exceeds the maximum (%d)",
+					len(stored), bucket, key, fdb.cfg.maxValueSize)
+			}
+
+			_, found = merged[bucket]
+			if !found {
+				merged[bucket] = map[int][]byte{}
+			}
+
+			merged[bucket][key] = stored
+		}
+	}
+
+	return merged, nil
+}
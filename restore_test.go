@@ -0,0 +1,99 @@
+package fastdb_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Restore(t *testing.T) {
+	source, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	err = source.SetMulti("text", map[int][]byte{1: []byte("incoming 1"), 2: []byte("incoming 2"), 3: []byte("new 3")})
+	require.NoError(t, err)
+
+	snapshot := &bytes.Buffer{}
+	err = source.Snapshot(snapshot)
+	require.NoError(t, err)
+
+	err = source.Close()
+	require.NoError(t, err)
+
+	path := "data/fastdb_restore.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	err = store.SetMulti("text", map[int][]byte{1: []byte("existing 1"), 2: []byte("existing 2")})
+	require.NoError(t, err)
+
+	// an error changes nothing
+	err = store.Restore(bytes.NewReader(snapshot.Bytes()), func(_ string, _ int, _, _ []byte) ([]byte, error) {
+		return nil, errors.New("no way")
+	})
+	require.Error(t, err)
+	assert.Equal(t, "2 record(s) in 1 bucket(s)", store.Info())
+
+	// the callback decides per key
+	var conflicts []int
+
+	err = store.Restore(bytes.NewReader(snapshot.Bytes()), func(bucket string, key int, existing, incoming []byte) ([]byte, error) {
+		assert.Equal(t, "text", bucket)
+
+		conflicts = append(conflicts, key)
+
+		if key == 1 {
+			return existing, nil
+		}
+
+		return append(append(existing, '+'), incoming...), nil
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{1, 2}, conflicts)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	expected := map[int]string{1: "existing 1", 2: "existing 2+incoming 2", 3: "new 3"}
+	for key, value := range expected {
+		memData, ok := store.Get("text", key)
+		assert.True(t, ok)
+		assert.Equal(t, value, string(memData))
+	}
+
+	// the standard policies
+	err = store.Restore(bytes.NewReader(snapshot.Bytes()), fastdb.KeepExisting)
+	require.NoError(t, err)
+
+	memData, _ := store.Get("text", 2)
+	assert.Equal(t, "existing 2+incoming 2", string(memData))
+
+	err = store.Restore(bytes.NewReader(snapshot.Bytes()), nil)
+	require.NoError(t, err)
+
+	memData, _ = store.Get("text", 2)
+	assert.Equal(t, "incoming 2", string(memData))
+
+	err = store.Restore(bytes.NewBufferString("no snapshot"), nil)
+	require.Error(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
@@ -0,0 +1,135 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const defaultChunkSize = 1000
+
+// scanItem is one record that is handed to the scan function.
+type scanItem struct {
+	value []byte
+	key   int
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Scan calls fn for every record of a bucket, in Key sorted order, until fn returns false.
+The records are read in chunks of chunkSize (default 1000) and the lock is released
+between the chunks, so writers aren't blocked during a long scan.
+fn is called without holding the lock, so it may even write to the database.
+Changes made during the scan are seen if they come after the current chunk.
+*/
+func (fdb *DB) Scan(bucket string, chunkSize int, fn func(key int, value []byte) bool) error {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	cursor := 0
+	first := true
+
+	for {
+		items, next, done, found := fdb.scanChunk(bucket, cursor, chunkSize)
+		if !found && first {
+			return fmt.Errorf("%w (%s)", ErrBucketNotFound, bucket)
+		}
+
+		first = false
+
+		for _, item := range items {
+			if !fn(item.key, item.value) {
+				return nil
+			}
+		}
+
+		if done {
+			return nil
+		}
+
+		cursor = next
+	}
+}
+
+/*
+ScanConsistent calls fn for every record of a bucket, in Key sorted order, until fn returns false.
+The records are those of the moment the scan started: the lock is only held to take
+a (shallow) copy of the bucket, so writers aren't blocked during the scan.
+*/
+func (fdb *DB) ScanConsistent(bucket string, fn func(key int, value []byte) bool) error {
+	readLock := fdb.mu.RLock()
+
+	memRecords, found := fdb.keys[bucket]
+	if !found {
+		readLock.RUnlock()
+
+		return fmt.Errorf("%w (%s)", ErrBucketNotFound, bucket)
+	}
+
+	records := maps.Clone(memRecords)
+	sortedKeys := fdb.sortedKeys(bucket)
+	now := time.Now().UnixNano()
+
+	for key := range fdb.expiries[bucket] {
+		if fdb.expiredAt(bucket, key, now) {
+			delete(records, key)
+		}
+	}
+
+	readLock.RUnlock()
+
+	for _, key := range sortedKeys {
+		value, found := records[key]
+		if !found {
+			continue // expired
+		}
+
+		if !fn(key, value) {
+			break
+		}
+	}
+
+	return nil
+}
+
+/*
+scanChunk returns the records of at most chunkSize keys, starting at the cursor key, without the expired ones.
+It also returns the cursor of the next chunk, and true if this was the last one.
+The last bool is false if the bucket doesn't exist.
+*/
+func (fdb *DB) scanChunk(bucket string, cursor, chunkSize int) ([]scanItem, int, bool, bool) {
+	defer fdb.mu.RLock().RUnlock()
+
+	memRecords, found := fdb.keys[bucket]
+	if !found {
+		return nil, 0, true, false
+	}
+
+	sortedKeys := fdb.sortedKeys(bucket)
+	start, _ := slices.BinarySearch(sortedKeys, cursor)
+	end := min(start+chunkSize, len(sortedKeys))
+	now := time.Now().UnixNano()
+
+	items := make([]scanItem, 0, end-start)
+	for _, key := range sortedKeys[start:end] {
+		if fdb.expiredAt(bucket, key, now) {
+			continue
+		}
+
+		items = append(items, scanItem{key: key, value: memRecords[key]})
+	}
+
+	if end == len(sortedKeys) {
+		return items, 0, true, true
+	}
+
+	// there is a bigger key, so the last key of the chunk + 1 can't overflow
+	return items, sortedKeys[end-1] + 1, false, true
+}
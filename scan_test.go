@@ -0,0 +1,151 @@
+package fastdb_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Scan(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	total := 2500
+
+	for key := total; key > 0; key-- {
+		err = store.Set("text", key, []byte("value "+strconv.Itoa(key)))
+		require.NoError(t, err)
+	}
+
+	var keys []int
+
+	err = store.Scan("text", 100, func(key int, value []byte) bool {
+		assert.Equal(t, "value "+strconv.Itoa(key), string(value))
+
+		keys = append(keys, key)
+
+		// writing during the scan is allowed
+		if key == 50 {
+			_, err := store.Del("text", 150)
+			assert.NoError(t, err)
+
+			_, err = store.Del("text", total)
+			assert.NoError(t, err)
+		}
+
+		return true
+	})
+	require.NoError(t, err)
+
+	// the deleted keys were in a next chunk, so they are not seen
+	assert.Len(t, keys, total-2)
+	assert.NotContains(t, keys, 150)
+	assert.True(t, sortedInts(keys))
+
+	// stop early, with the default chunk size
+	count := 0
+	err = store.Scan("text", 0, func(_ int, _ []byte) bool {
+		count++
+
+		return count < 10
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 10, count)
+
+	err = store.Scan("wrong_bucket", 10, func(_ int, _ []byte) bool { return true })
+	require.Error(t, err)
+}
+
+func Test_Scan_maxKey(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	for _, key := range []int{1, math.MaxInt - 1, math.MaxInt} {
+		err = store.Set("text", key, []byte("value"))
+		require.NoError(t, err)
+	}
+
+	// a full chunk that ends with the highest possible key ends the scan
+	for _, chunkSize := range []int{1, 3} {
+		var keys []int
+
+		err = store.Scan("text", chunkSize, func(key int, _ []byte) bool {
+			keys = append(keys, key)
+
+			return true
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, math.MaxInt - 1, math.MaxInt}, keys)
+	}
+}
+
+func Test_ScanConsistent(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	for key := 1; key <= 100; key++ {
+		err = store.Set("text", key, []byte("value"))
+		require.NoError(t, err)
+	}
+
+	var keys []int
+
+	err = store.ScanConsistent("text", func(key int, value []byte) bool {
+		assert.Equal(t, []byte("value"), value)
+
+		keys = append(keys, key)
+
+		// changes during the scan are not seen
+		_, err := store.Del("text", key+1)
+		assert.NoError(t, err)
+
+		err = store.Set("text", key+1000, []byte("new"))
+		assert.NoError(t, err)
+
+		return true
+	})
+	require.NoError(t, err)
+	assert.Len(t, keys, 100)
+	assert.True(t, sortedInts(keys))
+
+	count := 0
+	err = store.ScanConsistent("text", func(_ int, _ []byte) bool {
+		count++
+
+		return false
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	err = store.ScanConsistent("wrong_bucket", func(_ int, _ []byte) bool { return true })
+	require.Error(t, err)
+}
+
+func sortedInts(values []int) bool {
+	for i := 1; i < len(values); i++ {
+		if values[i-1] >= values[i] {
+			return false
+		}
+	}
+
+	return true
+}
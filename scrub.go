@@ -0,0 +1,106 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// Health tells what the background scrubbing (see WithScrub) found.
+type Health struct {
+	LastScrub   time.Time // when the last part of the file was scrubbed, zero if never
+	Corruption  error     // the last corruption that was found, nil if none
+	ScrubOffset int64     // where the next part of the file will be scrubbed
+	ScrubPasses int       // the number of times the whole file was scrubbed
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Health returns what the background scrubbing found so far.
+*/
+func (fdb *DB) Health() Health {
+	fdb.scrubMu.Lock()
+	defer fdb.scrubMu.Unlock()
+
+	return fdb.health
+}
+
+/*
+Verify reads the whole file again and checks that every entry in it can still be read,
+like the background scrubbing (see WithScrub) does in parts. It holds the read lock while it reads.
+For a database in memory it does nothing, and it doesn't work with WithStripes.
+*/
+func (fdb *DB) Verify() error {
+	defer fdb.mu.RLock().RUnlock()
+
+	if fdb.aof == nil {
+		return nil
+	}
+
+	_, err := fdb.aof.Scrub(0, math.MaxInt64)
+	if err != nil {
+		return fmt.Errorf("verify error: %w", err)
+	}
+
+	return nil
+}
+
+/*
+restartScrub stops the scrub task (if any), and starts it with the current settings.
+The caller must hold the write lock.
+*/
+func (fdb *DB) restartScrub() {
+	if fdb.stopScrub != nil {
+		close(fdb.stopScrub)
+		fdb.stopScrub = nil
+	}
+
+	// after a Close, no tasks are started anymore
+	if fdb.cfg.scrubInterval > 0 && fdb.cfg.scrubBytes > 0 && fdb.stopTasks != nil && !fdb.aof.Striped() {
+		fdb.stopScrub = make(chan struct{})
+		fdb.runEvery(fdb.cfg.scrubInterval, fdb.stopScrub, fdb.scrub)
+	}
+}
+
+/*
+scrub is the background task that checks the next part of the file.
+It holds the read lock, so the file isn't written or defragmented meanwhile.
+*/
+func (fdb *DB) scrub() {
+	readLock := fdb.mu.RLock()
+
+	fdb.scrubMu.Lock()
+	offset := fdb.health.ScrubOffset
+	fdb.scrubMu.Unlock()
+
+	next, err := fdb.aof.Scrub(offset, fdb.cfg.scrubBytes)
+	logger := fdb.cfg.logger
+	readLock.RUnlock()
+
+	fdb.scrubMu.Lock()
+	defer fdb.scrubMu.Unlock()
+
+	fdb.health.LastScrub = time.Now()
+	fdb.health.ScrubOffset = next
+
+	if err == nil {
+		if next == 0 {
+			fdb.health.ScrubPasses++
+		}
+
+		return
+	}
+
+	// a corruption is reported once, and stays in Health (scrubbing starts over)
+	if fdb.health.Corruption == nil || fdb.health.Corruption.Error() != err.Error() {
+		logger.Error("scrub error", "error", err)
+		fdb.notify(Event{Type: EventCorruption, Err: err})
+	}
+
+	fdb.health.Corruption = err
+}
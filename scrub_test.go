@@ -0,0 +1,132 @@
+package fastdb_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithScrub(t *testing.T) {
+	path := "data/fastdb_scrub.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(0), fastdb.WithScrub(5*time.Millisecond, 20))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.True(t, store.Health().LastScrub.IsZero())
+
+	for key := 1; key <= 5; key++ {
+		err = store.Set("text", key, []byte("a value to scrub"))
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		return store.Health().ScrubPasses >= 2
+	}, time.Second, time.Millisecond)
+
+	health := store.Health()
+	require.NoError(t, health.Corruption)
+	assert.False(t, health.LastScrub.IsZero())
+
+	events, stop := store.Watch("")
+	defer stop()
+
+	// break the third entry on disk, behind the back of the database
+	file, err := os.OpenFile(filePath, os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = file.WriteAt([]byte("sXt"), 2*int64(len("set\ntext_1\na value to scrub\n")))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	select {
+	case event := <-events:
+		assert.Equal(t, fastdb.EventCorruption, event.Type)
+		require.Error(t, event.Err)
+	case <-time.After(time.Second):
+		t.Fatal("no corruption event")
+	}
+
+	require.Error(t, store.Health().Corruption)
+
+	// scrubbing can be stopped
+	err = store.Reconfigure(fastdb.WithScrub(0, 0))
+	require.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond) // a scrub that was already due
+
+	lastScrub := store.Health().LastScrub
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, lastScrub, store.Health().LastScrub)
+}
+
+func Test_WithScrub_memory(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithScrub(time.Millisecond, 100))
+	require.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+	assert.True(t, store.Health().LastScrub.IsZero())
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Verify(t *testing.T) {
+	path := "data/fastdb_verify.db"
+	filePath := filepath.Clean(path)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(0))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	for key := 1; key <= 5; key++ {
+		err = store.Set("text", key, []byte("a value to verify"))
+		require.NoError(t, err)
+	}
+
+	err = store.Verify()
+	require.NoError(t, err)
+
+	// break the last entry on disk, behind the back of the database
+	file, err := os.OpenFile(filePath, os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = file.WriteAt([]byte("sXt"), 4*int64(len("set\ntext_1\na value to verify\n")))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	err = store.Verify()
+	require.Error(t, err)
+
+	memStore, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	err = memStore.Verify()
+	require.NoError(t, err)
+
+	err = memStore.Close()
+	require.NoError(t, err)
+}
@@ -0,0 +1,72 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+CompactSegments merges the oldest count sealed segments (see WithSegments) into one, that only holds
+the records that are left. Unlike a Defrag, only those segments are read and written,
+so the write lock is held for a time that doesn't grow with the database.
+The changes in the merged segments are gone, so a consumer of Changes that is behind gets ErrChangesGone.
+For a database in memory (or without sealed segments), it does nothing.
+*/
+func (fdb *DB) CompactSegments(count int) error {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return fmt.Errorf("compactSegments error: %w", err)
+	}
+
+	defer unlock()
+
+	if fdb.aof == nil {
+		return nil
+	}
+
+	if fdb.cfg.readOnly {
+		return fmt.Errorf("compactSegments error: %w", ErrReadOnly)
+	}
+
+	dropped, err := fdb.aof.CompactSegments(count, baseTrailer)
+	if err != nil {
+		return fmt.Errorf("compactSegments error: %w", err)
+	}
+
+	fdb.fileRecords = max(fdb.fileRecords-dropped, fdb.totalCount())
+
+	return nil
+}
+
+/*
+Segments returns the paths of the sealed segments (see WithSegments), oldest first.
+They don't change anymore (until CompactSegments or Defrag removes them), so an incremental backup
+only has to copy the ones it doesn't have yet, plus the file itself.
+*/
+func (fdb *DB) Segments() []string {
+	defer fdb.mu.RLock().RUnlock()
+
+	if fdb.aof == nil {
+		return nil
+	}
+
+	return fdb.aof.Segments()
+}
+
+/*
+baseTrailer returns the mark that ends a compacted segment: it holds the state up to the write sequence
+of its last write, so Changes knows that the changes before it are gone (see markBase).
+*/
+func baseTrailer(meta map[string]string) []persist.Instruction {
+	lsn, found := meta[lsnMeta]
+	if !found {
+		return nil
+	}
+
+	return []persist.Instruction{persist.MetaInstruction(lsnBaseMeta, lsn)}
+}
@@ -0,0 +1,74 @@
+package fastdb_test
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Open_WithSegments(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "segments.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithSegments(200), fastdb.WithWriteSequence())
+	require.NoError(t, err)
+
+	for round := 1; round <= 3; round++ {
+		for key := 1; key <= 10; key++ {
+			err = store.Set("text", key, []byte("value "+strconv.Itoa(round)))
+			require.NoError(t, err)
+		}
+	}
+
+	segments := store.Segments()
+	require.Greater(t, len(segments), 2)
+	assert.Equal(t, filePath+".seg1", segments[0])
+
+	err = store.Checkpoint()
+	require.Error(t, err)
+
+	var changes int
+
+	last, err := store.Changes(0, func(fastdb.Change) error {
+		changes++
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(30), last)
+	assert.Equal(t, 30, changes)
+
+	err = store.CompactSegments(len(segments) - 1)
+	require.NoError(t, err)
+	assert.Equal(t, segments[len(segments)-2:], store.Segments())
+
+	// the changes in the compacted segments are gone, the later ones are still there
+	_, err = store.Changes(0, func(fastdb.Change) error { return nil })
+	require.ErrorIs(t, err, fastdb.ErrChangesGone)
+
+	_, err = store.Changes(last, func(fastdb.Change) error { return nil })
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithSegments(200), fastdb.WithWriteSequence())
+	require.NoError(t, err)
+	assert.Equal(t, "10 record(s) in 1 bucket(s)", store.Info())
+
+	value, ok := store.Get("text", 10)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value 3"), value)
+
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	// the rewritten file is full already, so the next write (the mark of the Defrag) seals it
+	assert.Equal(t, []string{filePath + ".seg1"}, store.Segments())
+
+	err = store.Close()
+	require.NoError(t, err)
+}
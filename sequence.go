@@ -0,0 +1,74 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+	"strconv"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const sequencePrefix = "seq:"
+
+/*
+Sequence represents a named auto-increment counter.
+Its value is persisted, and it never goes backwards (not even after deletions
+or a restart), which makes it different from GetNewIndex.
+*/
+type Sequence struct {
+	fdb  *DB
+	name string
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Sequence returns the named sequence.
+*/
+func (fdb *DB) Sequence(name string) *Sequence {
+	return &Sequence{fdb: fdb, name: name}
+}
+
+/*
+Current returns the last value that was handed out by the sequence (0 if none).
+*/
+func (seq *Sequence) Current() int {
+	defer seq.fdb.mu.RLock().RUnlock()
+
+	return seq.current()
+}
+
+/*
+Next increments the sequence, persists it and returns the new value.
+*/
+func (seq *Sequence) Next() (int, error) {
+	unlock, err := seq.fdb.writeLock()
+	if err != nil {
+		return 0, fmt.Errorf("sequence (%s) next error: %w", seq.name, err)
+	}
+
+	defer unlock()
+
+	next := seq.current() + 1
+
+	err = seq.fdb.setMeta(sequencePrefix+seq.name, strconv.Itoa(next))
+	if err != nil {
+		return 0, fmt.Errorf("sequence (%s) next error: %w", seq.name, err)
+	}
+
+	return next, nil
+}
+
+/*
+current returns the current value of the sequence.
+The caller must hold (at least) the read lock.
+*/
+func (seq *Sequence) current() int {
+	value, err := strconv.Atoi(seq.fdb.meta[sequencePrefix+seq.name])
+	if err != nil {
+		return 0
+	}
+
+	return value
+}
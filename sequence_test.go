@@ -0,0 +1,117 @@
+package fastdb_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Sequence_Memory(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.NotNil(t, store)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	seq := store.Sequence("invoice")
+	assert.Equal(t, 0, seq.Current())
+
+	for i := 1; i <= 10; i++ {
+		next, err := seq.Next()
+		require.NoError(t, err)
+		assert.Equal(t, i, next)
+	}
+
+	assert.Equal(t, 10, seq.Current())
+
+	// sequences are independent
+	next, err := store.Sequence("order").Next()
+	require.NoError(t, err)
+	assert.Equal(t, 1, next)
+
+	// sequences don't show up as records
+	assert.Equal(t, "0 record(s) in 0 bucket(s)", store.Info())
+}
+
+func Test_Sequence_File(t *testing.T) {
+	path := "data/fastdb_sequence.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		_ = os.Remove(filePath + ".bak")
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	for range 5 {
+		key, err := store.Sequence("invoice").Next()
+		require.NoError(t, err)
+
+		err = store.Set("invoice", key, []byte("an invoice"))
+		require.NoError(t, err)
+	}
+
+	// delete the last records, the sequence must not go backwards
+	for key := 3; key <= 5; key++ {
+		_, err = store.Del("invoice", key)
+		require.NoError(t, err)
+	}
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.Equal(t, 5, store.Sequence("invoice").Current())
+
+	next, err := store.Sequence("invoice").Next()
+	require.NoError(t, err)
+	assert.Equal(t, 6, next)
+
+	// the sequence must survive a defrag
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.Equal(t, 6, store.Sequence("invoice").Current())
+	assert.Equal(t, 3, store.GetNewIndex("invoice"))
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Sequence_writeError(t *testing.T) {
+	path := "data/fastdb_sequence_error.db"
+	filePath := filepath.Clean(path)
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	defer func() {
+		err = os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	next, err := store.Sequence("invoice").Next()
+	require.Error(t, err)
+	assert.Equal(t, 0, next)
+}
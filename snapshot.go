@@ -0,0 +1,127 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"fmt"
+	"io"
+	"maps"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Snapshot writes the current state of the database to a stream.
+The stream also records up to where the file was written, so it can be used
+with OpenFromBackup to bring a database online without replaying the whole file.
+A snapshot can't be combined with the file anymore after a Defrag or a Checkpoint (ErrSnapshotMismatch).
+The state is copied under the lock, so a slow writer doesn't block the database.
+*/
+func (fdb *DB) Snapshot(writer io.Writer) error {
+	keys, meta, position, err := fdb.snapshotState()
+	if err != nil {
+		return fmt.Errorf("snapshot error: %w", err)
+	}
+
+	err = persist.WriteCompressedSnapshot(writer, position, keys, meta, fdb.cfg.format, fdb.cfg.snapshotLevel)
+	if err != nil {
+		return fmt.Errorf("snapshot error: %w", err)
+	}
+
+	return nil
+}
+
+/*
+snapshotState returns a (shallow) copy of the records and the meta data, with the position in the file.
+*/
+func (fdb *DB) snapshotState() (map[string]map[int][]byte, map[string]string, persist.Position, error) {
+	defer fdb.mu.RLock().RUnlock()
+
+	var position persist.Position
+
+	if fdb.aof != nil {
+		var err error
+
+		position, err = fdb.aof.Position()
+		if err != nil {
+			return nil, nil, position, err //nolint:wrapcheck // it is wrapped by the caller
+		}
+	}
+
+	keys := make(map[string]map[int][]byte, len(fdb.keys))
+	for bucket, records := range fdb.keys {
+		keys[bucket] = maps.Clone(records)
+	}
+
+	return keys, maps.Clone(fdb.meta), position, nil
+}
+
+/*
+OpenFromBackup opens a database from a snapshot stream (made by Snapshot),
+and then replays only the part of the live file that was written after the
+snapshot was taken. From then on, the live file is used like with Open.
+*/
+func OpenFromBackup(reader io.Reader, livePath string, opts ...Option) (*DB, error) {
+	cfg := newConfig(opts)
+
+	aof, keys, err := persist.OpenPersisterFromSnapshot(reader, livePath, cfg.syncTime, cfg.persistOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("openFromBackup error: %w", err)
+	}
+
+	return newDB(aof, keys, aof.Meta(), cfg), nil
+}
+
+/*
+Checkpoint writes the current state to a snapshot file next to the database file,
+and empties the database file, so opening the database only has to load the snapshot
+and replay the changes since the checkpoint (instead of the whole history).
+For a database in memory, it does nothing.
+*/
+func (fdb *DB) Checkpoint() error {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return fmt.Errorf("checkpoint error: %w", err)
+	}
+
+	defer unlock()
+
+	if fdb.aof == nil {
+		return nil
+	}
+
+	if fdb.cfg.readOnly {
+		return fmt.Errorf("checkpoint error: %w", ErrReadOnly)
+	}
+
+	err = fdb.aof.Checkpoint(fdb.keys)
+	if err == nil {
+		err = fdb.markBase()
+	}
+
+	if err != nil {
+		return fmt.Errorf("checkpoint error: %w", err)
+	}
+
+	fdb.fileRecords = fdb.totalCount()
+
+	fdb.resetGrowth()
+
+	return nil
+}
+
+/*
+checkpoint is the background task that makes a checkpoint.
+*/
+func (fdb *DB) checkpoint() {
+	if fdb.Frozen() {
+		return
+	}
+
+	err := fdb.Checkpoint()
+	if err != nil {
+		fdb.logger().Error("checkpoint error", "error", err)
+	}
+}
@@ -0,0 +1,360 @@
+package fastdb_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenFromBackup(t *testing.T) {
+	path := "data/fastdb_snapshot.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	for key := 1; key <= 10; key++ {
+		err = store.Set("text", key, []byte("value before snapshot"))
+		require.NoError(t, err)
+	}
+
+	_, err = store.Sequence("text").Next()
+	require.NoError(t, err)
+
+	snapshot := &bytes.Buffer{}
+	err = store.Snapshot(snapshot)
+	require.NoError(t, err)
+
+	// changes after the snapshot, must be replayed from the live file
+	err = store.Set("text", 1, []byte("value after snapshot"))
+	require.NoError(t, err)
+
+	err = store.Set("other", 1, []byte("other value"))
+	require.NoError(t, err)
+
+	_, err = store.Del("text", 10)
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.OpenFromBackup(snapshot, filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	assert.Equal(t, "10 record(s) in 2 bucket(s)", store.Info())
+	assert.Equal(t, 1, store.Sequence("text").Current())
+
+	value, ok := store.Get("text", 1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value after snapshot"), value)
+
+	_, ok = store.Get("text", 10)
+	assert.False(t, ok)
+
+	// the live file is used from now on
+	err = store.Set("text", 11, []byte("new value"))
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.Equal(t, "11 record(s) in 2 bucket(s)", store.Info())
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Snapshot_Memory(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("value"))
+	require.NoError(t, err)
+
+	snapshot := &bytes.Buffer{}
+	err = store.Snapshot(snapshot)
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot\n0\nset\ntext_1\nvalue\n", snapshot.String())
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_OpenFromBackup_errors(t *testing.T) {
+	path := "data/fastdb_snapshot_error.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		_ = os.Remove(filePath)
+	}()
+
+	store, err := fastdb.OpenFromBackup(strings.NewReader("no snapshot\n"), filePath, fastdb.WithSyncTime(syncIime))
+	require.Error(t, err)
+	assert.Nil(t, store)
+
+	store, err = fastdb.OpenFromBackup(strings.NewReader("snapshot\nwrong\n"), filePath, fastdb.WithSyncTime(syncIime))
+	require.Error(t, err)
+	assert.Nil(t, store)
+
+	store, err = fastdb.OpenFromBackup(strings.NewReader("snapshot\n0\nwrong\n"), filePath, fastdb.WithSyncTime(syncIime))
+	require.Error(t, err)
+	assert.Nil(t, store)
+
+	// the live file is older than the snapshot
+	store, err = fastdb.OpenFromBackup(strings.NewReader("snapshot\n100\n"), filePath, fastdb.WithSyncTime(syncIime))
+	require.Error(t, err)
+	assert.Nil(t, store)
+
+	store, err = fastdb.OpenFromBackup(strings.NewReader("snapshot\n0\n"), "data/../data/wrong.db", fastdb.WithSyncTime(syncIime))
+	require.Error(t, err)
+	assert.Nil(t, store)
+}
+
+func Test_OpenFromBackup_afterCheckpoint(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "mismatch.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	for key := 1; key <= 10; key++ {
+		err = store.Set("text", key, []byte("value "+strconv.Itoa(key)))
+		require.NoError(t, err)
+	}
+
+	snapshot := &bytes.Buffer{}
+	err = store.Snapshot(snapshot)
+	require.NoError(t, err)
+
+	// the file is emptied, and grows past the offset of the snapshot again
+	err = store.Checkpoint()
+	require.NoError(t, err)
+
+	for key := 1; key <= 10; key++ {
+		_, err = store.Del("text", key)
+		require.NoError(t, err)
+	}
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.OpenFromBackup(snapshot, filePath, fastdb.WithSyncTime(syncIime))
+	require.ErrorIs(t, err, fastdb.ErrSnapshotMismatch)
+	assert.Nil(t, store)
+}
+
+func Test_OpenFromBackup_afterDefragToSameRecords(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "same_records.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("one"))
+	require.NoError(t, err)
+
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	err = store.Set("text", 2, []byte("two"))
+	require.NoError(t, err)
+
+	snapshot := &bytes.Buffer{}
+	err = store.Snapshot(snapshot)
+	require.NoError(t, err)
+
+	// a Defrag back to the same records is still a new file
+	_, err = store.Del("text", 2)
+	require.NoError(t, err)
+
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	err = store.Set("text", 7, []byte("seven-seven-seven"))
+	require.NoError(t, err)
+
+	err = store.Set("text", 8, []byte("eight"))
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.OpenFromBackup(snapshot, filePath, fastdb.WithSyncTime(syncIime))
+	require.ErrorIs(t, err, fastdb.ErrSnapshotMismatch)
+	assert.Nil(t, store)
+}
+
+func Test_Checkpoint(t *testing.T) {
+	path := "data/fastdb_checkpoint.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		_ = os.Remove(filePath + ".snapshot")
+		_ = os.Remove(filePath + ".bak")
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	for key := 1; key <= 100; key++ {
+		err = store.Set("text", key, []byte("value "+strconv.Itoa(key)))
+		require.NoError(t, err)
+	}
+
+	_, err = store.Sequence("text").Next()
+	require.NoError(t, err)
+
+	err = store.Checkpoint()
+	require.NoError(t, err)
+
+	// the file only holds the changes after the checkpoint
+	checkFileLines(t, filePath, 0)
+
+	err = store.Set("text", 1, []byte("changed"))
+	require.NoError(t, err)
+
+	_, err = store.Del("text", 100)
+	require.NoError(t, err)
+
+	checkFileLines(t, filePath, 5)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	assert.Equal(t, "99 record(s) in 1 bucket(s)", store.Info())
+	assert.Equal(t, 1, store.Sequence("text").Current())
+
+	value, ok := store.Get("text", 1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("changed"), value)
+
+	// after a defrag, the file holds everything again
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	_, err = os.Stat(filePath + ".snapshot")
+	require.Error(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.Equal(t, "99 record(s) in 1 bucket(s)", store.Info())
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Checkpoint_interval(t *testing.T) {
+	path := "data/fastdb_checkpoint_interval.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		_ = os.Remove(filePath + ".snapshot")
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime),
+		fastdb.WithCheckpointInterval(20*time.Millisecond))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("value"))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filePath + ".snapshot")
+
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.Equal(t, "1 record(s) in 1 bucket(s)", store.Info())
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// a database in memory has nothing to checkpoint
+	memStore, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	err = memStore.Checkpoint()
+	require.NoError(t, err)
+}
+
+func Test_WithSnapshotCompression(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "fastdb_compressed.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithSnapshotCompression(1))
+	require.NoError(t, err)
+
+	for key := 1; key <= 100; key++ {
+		err = store.Set("text", key, []byte("value "+strconv.Itoa(key)))
+		require.NoError(t, err)
+	}
+
+	snapshot := &bytes.Buffer{}
+	err = store.Snapshot(snapshot)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(snapshot.Bytes(), []byte("snapshot+zstd\n")))
+
+	err = store.Checkpoint()
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("changed"))
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// the checkpoint is read without the option
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.Equal(t, "100 record(s) in 1 bucket(s)", store.Info())
+
+	value, _ := store.Get("text", 1)
+	assert.Equal(t, []byte("changed"), value)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// the compressed snapshot can be restored
+	memStore, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	err = memStore.Restore(snapshot, fastdb.KeepIncoming)
+	require.NoError(t, err)
+	assert.Equal(t, "100 record(s) in 1 bucket(s)", memStore.Info())
+
+	err = memStore.Close()
+	require.NoError(t, err)
+}
@@ -0,0 +1,274 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"math"
+	"math/bits"
+	"time"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// autoDefragMinSize is the size of the files from which an automatic Defrag is worth it.
+const autoDefragMinSize = 1024 * 1024
+
+// autoDefragInterval is the pause between two checks whether the files need a Defrag.
+var autoDefragInterval = time.Second
+
+// Stats holds the numbers about the storage, see Stats.
+type Stats struct {
+	LastDefrag         time.Time         // when the files were last rewritten (by Defrag or Truncate), zero if not since the open
+	Descriptions       map[string]string // the description per bucket, see SetDescription
+	BucketRecords      map[string]int    // the number of records per bucket
+	Records            int
+	Buckets            int
+	MemoryBytes        int64   // the size of the values in memory
+	LiveBytes          int64   // the size of the records, as a Defrag would write them (without compression)
+	FileBytes          int64   // the size of the files on disk (0 in memory)
+	GarbageBytes       int64   // an estimate of what a Defrag would free: FileBytes minus LiveBytes (0 in memory)
+	DeadRecords        int     // the stores and deletes in the files that a Defrag would remove (0 in memory)
+	GarbageRatio       float64 // DeadRecords divided by all the stores and deletes in the files
+	WriteAmplification float64 // FileBytes divided by LiveBytes (0 without records)
+	GrowthPerDay       float64 // in bytes, measured since the open, the last Defrag or the last Checkpoint
+	DaysUntilFull      float64 // when the disk is full at the current growth, -1 if unknown or not growing
+}
+
+// BucketStats holds the numbers about the values of one bucket, see BucketStats.
+type BucketStats struct {
+	Bucket     string
+	Sizes      []int // the number of values per size class: 0 bytes, 1, 2-3, 4-7, 8-15 and so on (doubling)
+	Records    int
+	TotalBytes int64 // the size of the values
+	MinSize    int
+	MaxSize    int
+	AvgSize    float64
+	LargestKey int // the key with the largest value (the lowest key, if there are several)
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Stats returns the numbers about the storage: how many records there are (per bucket),
+how big they are compared to the files, and how quickly the files grow.
+Unlike Info, the numbers don't have to be parsed.
+*/
+func (fdb *DB) Stats() Stats {
+	defer fdb.mu.RLock().RUnlock()
+
+	stats := Stats{
+		Buckets:       len(fdb.keys),
+		BucketRecords: make(map[string]int, len(fdb.keys)),
+		LiveBytes:     fdb.liveBytes,
+		LastDefrag:    fdb.lastDefrag,
+		DaysUntilFull: -1,
+		Descriptions:  fdb.descriptions(),
+	}
+
+	for bucket, records := range fdb.keys {
+		stats.Records += len(records)
+		stats.BucketRecords[bucket] = len(records)
+
+		for _, value := range records {
+			stats.MemoryBytes += int64(len(value))
+		}
+	}
+
+	if fdb.aof == nil {
+		return stats
+	}
+
+	stats.FileBytes = fdb.aof.Size()
+	stats.GarbageBytes = max(fdb.dataBytes()-stats.LiveBytes, 0)
+	stats.WriteAmplification = fdb.amplification()
+	stats.DeadRecords = fdb.deadRecords()
+	stats.GarbageRatio = fdb.garbageRatio()
+
+	elapsed := time.Since(fdb.growthSince)
+	if elapsed >= time.Second {
+		stats.GrowthPerDay = float64(stats.FileBytes-fdb.growthBase) / elapsed.Hours() * 24
+	}
+
+	if stats.GrowthPerDay > 0 {
+		free, err := freeDiskSpace(fdb.aof.Path())
+		if err == nil {
+			stats.DaysUntilFull = float64(free) / stats.GrowthPerDay
+		}
+	}
+
+	return stats
+}
+
+/*
+BucketStats returns the numbers about the values of a bucket: how many and how big they are,
+with a histogram of the sizes, so a bucket that takes too much memory can be found without exporting it.
+A bucket that doesn't exist has no records.
+*/
+func (fdb *DB) BucketStats(bucket string) BucketStats {
+	defer fdb.mu.RLock().RUnlock()
+
+	stats := BucketStats{Bucket: bucket, Records: len(fdb.keys[bucket])}
+
+	if stats.Records == 0 {
+		return stats
+	}
+
+	stats.MinSize = math.MaxInt
+	stats.LargestKey = math.MaxInt
+
+	for key, value := range fdb.keys[bucket] {
+		size := len(value)
+		class := bits.Len(uint(size))
+
+		if class >= len(stats.Sizes) {
+			stats.Sizes = append(stats.Sizes, make([]int, class+1-len(stats.Sizes))...)
+		}
+
+		stats.Sizes[class]++
+		stats.TotalBytes += int64(size)
+		stats.MinSize = min(stats.MinSize, size)
+
+		if size > stats.MaxSize || (size == stats.MaxSize && key < stats.LargestKey) {
+			stats.MaxSize = size
+			stats.LargestKey = key
+		}
+	}
+
+	stats.AvgSize = float64(stats.TotalBytes) / float64(stats.Records)
+
+	return stats
+}
+
+/*
+amplification returns how many bytes the files take for every byte of the records.
+The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) amplification() float64 {
+	if fdb.aof == nil || fdb.liveBytes == 0 {
+		return 0
+	}
+
+	return float64(fdb.dataBytes()) / float64(fdb.liveBytes)
+}
+
+/*
+dataBytes returns the size of the files without the header, which a Defrag can't remove.
+The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) dataBytes() int64 {
+	return fdb.aof.Size() - fdb.aof.HeaderSize()
+}
+
+/*
+deadRecords returns how many of the stores and deletes in the files are superseded or deleted
+(the deletes themselves included), which is what a Defrag removes.
+The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) deadRecords() int {
+	if fdb.aof == nil {
+		return 0
+	}
+
+	return max(fdb.fileRecords-fdb.totalCount(), 0)
+}
+
+/*
+garbageRatio returns which part of the stores and deletes in the files is dead (0 without any).
+The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) garbageRatio() float64 {
+	if fdb.aof == nil || fdb.fileRecords == 0 {
+		return 0
+	}
+
+	return float64(fdb.deadRecords()) / float64(fdb.fileRecords)
+}
+
+/*
+recordInstructions returns the number of stores and deletes of records among the instructions.
+*/
+func recordInstructions(instructions []persist.Instruction) int {
+	count := 0
+
+	for _, ins := range instructions {
+		if ins.Name == "set" || ins.Name == "present" || ins.Name == "del" {
+			count++
+		}
+	}
+
+	return count
+}
+
+/*
+recordSize returns the number of bytes a record takes in the file (0 if it doesn't exist).
+The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) recordSize(bucket string, key int) int64 {
+	value, found := fdb.keys[bucket][key]
+	if !found {
+		return 0
+	}
+
+	return int64(persist.SetInstruction(bucket, key, value).Size(fdb.cfg.format))
+}
+
+/*
+loadLiveBytes counts the size of all the records.
+The caller must hold the write lock.
+*/
+func (fdb *DB) loadLiveBytes() {
+	fdb.liveBytes = 0
+
+	for bucket, records := range fdb.keys {
+		for key := range records {
+			fdb.liveBytes += fdb.recordSize(bucket, key)
+		}
+	}
+}
+
+/*
+resetGrowth starts measuring the growth of the files again, because they were rewritten.
+The caller must hold the write lock.
+*/
+func (fdb *DB) resetGrowth() {
+	fdb.growthSince = time.Now()
+
+	if fdb.aof != nil {
+		fdb.growthBase = fdb.aof.Size()
+	}
+}
+
+/*
+autoDefrag is the background task that runs a Defrag when the write amplification
+reached the level of WithAutoDefrag, or the garbage the limits of WithAutoDefragGarbage.
+*/
+func (fdb *DB) autoDefrag() {
+	readLock := fdb.mu.RLock()
+	cfg := fdb.cfg
+	size := fdb.aof.Size()
+	amplification := fdb.amplification()
+	ratio := fdb.garbageRatio()
+	garbage := max(fdb.dataBytes()-fdb.liveBytes, 0)
+	frozen := fdb.frozen != nil
+	readLock.RUnlock()
+
+	bigEnough := size >= autoDefragMinSize
+	needed := (cfg.autoDefrag > 0 && amplification >= cfg.autoDefrag && bigEnough) ||
+		(cfg.garbageRatio > 0 && ratio >= cfg.garbageRatio && bigEnough) ||
+		(cfg.garbageBytes > 0 && garbage >= cfg.garbageBytes)
+
+	if !needed || frozen {
+		return
+	}
+
+	err := fdb.Defrag()
+	if err != nil {
+		fdb.logger().Error("auto defrag error", "error", err)
+
+		return
+	}
+
+	fdb.logger().Debug("auto defrag done", "amplification", amplification, "garbageRatio", ratio, "garbageBytes", garbage)
+}
@@ -0,0 +1,26 @@
+//go:build linux
+
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"path/filepath"
+	"syscall"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+freeDiskSpace returns the number of bytes that are available on the disk of the file.
+*/
+func freeDiskSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+
+	err := syscall.Statfs(filepath.Dir(path), &stat)
+	if err != nil {
+		return 0, err //nolint:wrapcheck // it is ignored by the caller
+	}
+
+	return stat.Bavail * uint64(stat.Bsize), nil //nolint:gosec // the block size is never negative
+}
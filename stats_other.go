@@ -0,0 +1,18 @@
+//go:build !linux
+
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+freeDiskSpace isn't supported on this system.
+*/
+func freeDiskSpace(_ string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
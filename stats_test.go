@@ -0,0 +1,193 @@
+package fastdb_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Stats(t *testing.T) {
+	path := "data/fastdb_stats.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		_ = os.Remove(filePath + ".bak")
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	for range 4 {
+		err = store.Set("text", 1, []byte("value"))
+		require.NoError(t, err)
+	}
+
+	err = store.Set("text", 2, []byte("value"))
+	require.NoError(t, err)
+
+	record := int64(len("set\ntext_1\nvalue\n"))
+	header := int64(len(headerText))
+
+	stats := store.Stats()
+	assert.Equal(t, 2, stats.Records)
+	assert.Equal(t, 1, stats.Buckets)
+	assert.Equal(t, 2*record, stats.LiveBytes)
+	assert.Equal(t, header+5*record, stats.FileBytes)
+	assert.InDelta(t, 2.5, stats.WriteAmplification, 0.001)
+	assert.Equal(t, 3*record, stats.GarbageBytes)
+	assert.Equal(t, 3, stats.DeadRecords)
+	assert.InDelta(t, 0.6, stats.GarbageRatio, 0.001)
+	assert.Equal(t, map[string]int{"text": 2}, stats.BucketRecords)
+	assert.Equal(t, int64(2*len("value")), stats.MemoryBytes)
+	assert.True(t, stats.LastDefrag.IsZero())
+
+	_, err = store.Del("text", 2)
+	require.NoError(t, err)
+
+	// the dead records are counted again when the file is opened
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	stats = store.Stats()
+	assert.Equal(t, 5, stats.DeadRecords)
+	assert.InDelta(t, 5.0/6, stats.GarbageRatio, 0.001)
+
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	stats = store.Stats()
+	assert.Equal(t, record, stats.LiveBytes)
+	assert.Equal(t, header+record, stats.FileBytes)
+	assert.InDelta(t, 1, stats.WriteAmplification, 0.001)
+	assert.Zero(t, stats.GarbageBytes)
+	assert.Zero(t, stats.DeadRecords)
+	assert.Zero(t, stats.GarbageRatio)
+	assert.False(t, stats.LastDefrag.IsZero())
+	assert.Zero(t, stats.GrowthPerDay)
+	assert.InDelta(t, -1, stats.DaysUntilFull, 0.001)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Stats_memory(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("value"))
+	require.NoError(t, err)
+
+	stats := store.Stats()
+	assert.Equal(t, 1, stats.Records)
+	assert.Positive(t, stats.LiveBytes)
+	assert.Zero(t, stats.FileBytes)
+	assert.Zero(t, stats.WriteAmplification)
+	assert.Zero(t, stats.GarbageBytes)
+	assert.Equal(t, map[string]int{"text": 1}, stats.BucketRecords)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Open_WithAutoDefrag(t *testing.T) {
+	path := "data/fastdb_autodefrag.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		_ = os.Remove(filePath + ".bak")
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithAutoDefrag(4))
+	require.NoError(t, err)
+
+	value := bytes.Repeat([]byte("x"), 64*1024)
+
+	for range 20 {
+		err = store.Set("text", 1, value)
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		return store.Stats().WriteAmplification < 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	memData, ok := store.Get("text", 1)
+	assert.True(t, ok)
+	assert.Equal(t, value, memData)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Open_WithAutoDefragGarbage(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "autodefrag_garbage.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime), fastdb.WithAutoDefragGarbage(0, 1024))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	value := bytes.Repeat([]byte("x"), 100)
+
+	for key := range 20 {
+		err = store.Set("text", key, value)
+		require.NoError(t, err)
+	}
+
+	_, err = store.DelMulti("text", []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return store.Stats().DeadRecords == 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	assert.Equal(t, 7, store.Count("text"))
+	assert.Less(t, store.Stats().GarbageBytes, int64(1024))
+}
+
+func Test_BucketStats(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	stats := store.BucketStats("text")
+	assert.Equal(t, fastdb.BucketStats{Bucket: "text"}, stats)
+
+	for key, value := range map[int]string{1: "", 2: "a", 3: "abc", 4: "abcdefghij", 5: "klmnopqrst"} {
+		err = store.Set("text", key, []byte(value))
+		require.NoError(t, err)
+	}
+
+	stats = store.BucketStats("text")
+	assert.Equal(t, 5, stats.Records)
+	assert.Equal(t, int64(24), stats.TotalBytes)
+	assert.Equal(t, 0, stats.MinSize)
+	assert.Equal(t, 10, stats.MaxSize)
+	assert.InDelta(t, 4.8, stats.AvgSize, 0.001)
+	assert.Equal(t, 4, stats.LargestKey)
+	assert.Equal(t, []int{1, 1, 1, 0, 2}, stats.Sizes)
+}
@@ -0,0 +1,49 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+ServerTLSConfig returns the TLS configuration for the servers (HTTP, gRPC and Redis),
+with the certificate and key of the server (PEM files).
+If clientCAFile isn't empty, clients have to present a certificate that is signed
+by one of its CA's (mutual TLS), so only known clients can connect.
+*/
+func ServerTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("serverTLSConfig error: %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile == "" {
+		return config, nil
+	}
+
+	pem, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("serverTLSConfig error: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("serverTLSConfig error: no certificates in %s", clientCAFile)
+	}
+
+	config.ClientCAs = pool
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+
+	return config, nil
+}
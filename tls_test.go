@@ -0,0 +1,103 @@
+package fastdb_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ServerTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := writeCert(t, dir)
+
+	_, err := fastdb.ServerTLSConfig(filepath.Join(dir, "unknown.pem"), keyFile, "")
+	require.Error(t, err)
+
+	config, err := fastdb.ServerTLSConfig(certFile, keyFile, "")
+	require.NoError(t, err)
+	assert.Equal(t, tls.NoClientCert, config.ClientAuth)
+
+	_, err = fastdb.ServerTLSConfig(certFile, keyFile, keyFile)
+	require.Error(t, err)
+
+	config, err = fastdb.ServerTLSConfig(certFile, keyFile, caFile)
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, config.ClientAuth)
+
+	// a client without a certificate is refused
+	err = handshake(config, &tls.Config{InsecureSkipVerify: true}) //nolint:gosec // it's a test
+	require.Error(t, err)
+
+	// a client with a certificate of the CA is accepted
+	err = handshake(config, &tls.Config{InsecureSkipVerify: true, Certificates: config.Certificates}) //nolint:gosec // it's a test
+	require.NoError(t, err)
+}
+
+/*
+handshake runs the TLS handshake between a server and a client, and returns the error of the server.
+*/
+func handshake(serverConfig, clientConfig *tls.Config) error {
+	serverConn, clientConn := net.Pipe()
+
+	go func() {
+		_ = tls.Client(clientConn, clientConfig).Handshake()
+		_ = clientConn.Close()
+	}()
+
+	defer serverConn.Close()
+
+	return tls.Server(serverConn, serverConfig).Handshake()
+}
+
+/*
+writeCert writes a self-signed certificate (which is also its own CA) and its key, and returns the files.
+*/
+func writeCert(t *testing.T, dir string) (string, string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	require.NoError(t, err)
+
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600)
+	require.NoError(t, err)
+
+	return certFile, keyFile, certFile
+}
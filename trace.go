@@ -0,0 +1,55 @@
+package fastdb
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+/*
+TraceFunc is called at the start of an operation, and returns the function that is called at its end,
+with the error of the operation (if any), see WithTracing.
+The operations are "set", "get" and "del" (with the bucket and key), "defrag",
+and "write" and "sync" of the files (without a bucket).
+*/
+type TraceFunc func(operation, bucket string, key int) func(err error)
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+WithTracing calls the trace function around every Set, Get, Del and Defrag, and every write and sync
+of the files, to make tracing spans (see the fastdbotel module), so slow syncs can be found.
+It can't be changed with Reconfigure.
+*/
+func WithTracing(trace TraceFunc) Option {
+	return func(cfg *config) {
+		cfg.trace = trace
+	}
+}
+
+/*
+startTrace starts tracing an operation, and returns the function that ends it (which does nothing without tracing).
+*/
+func (fdb *DB) startTrace(operation, bucket string, key int) func(err error) {
+	if fdb.trace == nil {
+		return endNothing
+	}
+
+	return fdb.trace(operation, bucket, key)
+}
+
+/*
+endNothing is the end of an operation that isn't traced.
+*/
+func endNothing(error) {}
+
+/*
+persistTrace returns the trace function for the persister (nil without tracing).
+*/
+func (cfg *config) persistTrace() func(operation string) func(err error) {
+	if cfg.trace == nil {
+		return nil
+	}
+
+	trace := cfg.trace
+
+	return func(operation string) func(err error) {
+		return trace(operation, "", 0)
+	}
+}
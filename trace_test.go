@@ -0,0 +1,59 @@
+package fastdb_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithTracing(t *testing.T) {
+	var (
+		spans []string
+		mu    sync.Mutex
+	)
+
+	trace := func(operation, bucket string, key int) func(error) {
+		return func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			spans = append(spans, fmt.Sprintf("%s %s_%d %v", operation, bucket, key, err != nil))
+		}
+	}
+
+	store, err := fastdb.Open(t.TempDir()+"/fastdb_trace.db", fastdb.WithSyncTime(0), fastdb.WithTracing(trace),
+		fastdb.WithMaxValueSize(10))
+	require.NoError(t, err)
+
+	err = store.Set("text", 1, []byte("value"))
+	require.NoError(t, err)
+
+	err = store.Set("text", 2, []byte("a value that is too big"))
+	require.Error(t, err)
+
+	_, _ = store.Get("text", 1)
+
+	_, err = store.Del("text", 1)
+	require.NoError(t, err)
+
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, []string{
+		"write _0 false", "set text_1 false",
+		"set text_2 true",
+		"get text_1 false",
+		"write _0 false", "del text_1 false",
+		"defrag _0 false",
+	}, spans)
+}
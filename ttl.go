@@ -0,0 +1,299 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+const ttlPrefix = "ttl:"
+
+// reapInterval is the pause between two runs of the expiration reaper.
+var reapInterval = time.Second
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+SetWithTTL stores one map value in a bucket, which expires after the given duration.
+Expired values are no longer returned by Get and are removed by a background reaper.
+The expiry time is persisted, so it survives a restart.
+*/
+func (fdb *DB) SetWithTTL(bucket string, key int, value []byte, ttl time.Duration) error {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return fmt.Errorf("setWithTTL error: %w", err)
+	}
+
+	defer unlock()
+
+	if ttl <= 0 {
+		return errors.New("setWithTTL->ttl should be positive")
+	}
+
+	return fdb.set(bucket, key, value, time.Now().Add(ttl).UnixNano())
+}
+
+/*
+TTL returns the time to live of a key.
+The bool is false if the key doesn't exist or has no expiry time.
+*/
+func (fdb *DB) TTL(bucket string, key int) (time.Duration, bool) {
+	defer fdb.mu.RLock().RUnlock()
+
+	expiry, found := fdb.expiries[bucket][key]
+	if !found {
+		return 0, false
+	}
+
+	return max(time.Until(time.Unix(0, expiry)), 0), true
+}
+
+/*
+ttlName returns the meta data name under which the expiry time of a key is stored.
+*/
+func ttlName(bucket string, key int) string {
+	return ttlPrefix + bucket + "_" + strconv.Itoa(key)
+}
+
+/*
+expiryInstructions returns the instructions to persist a (changed) expiry time of a key.
+An expiry of 0 means that the key doesn't expire (anymore).
+The caller must hold the write lock.
+*/
+func (fdb *DB) expiryInstructions(bucket string, key int, expiry int64) []persist.Instruction {
+	if expiry != 0 {
+		return []persist.Instruction{persist.MetaInstruction(ttlName(bucket, key), strconv.FormatInt(expiry, 10))}
+	}
+
+	_, found := fdb.expiries[bucket][key]
+	if found {
+		return []persist.Instruction{persist.DelMetaInstruction(ttlName(bucket, key))}
+	}
+
+	return nil
+}
+
+/*
+setExpiry stores (or removes, if expiry is 0) the expiry time of a key.
+The caller must hold the write lock.
+*/
+func (fdb *DB) setExpiry(bucket string, key int, expiry int64) {
+	if expiry == 0 {
+		_, found := fdb.expiries[bucket][key]
+		if !found {
+			return
+		}
+
+		delete(fdb.meta, ttlName(bucket, key))
+		delete(fdb.expiries[bucket], key)
+
+		if len(fdb.expiries[bucket]) == 0 {
+			delete(fdb.expiries, bucket)
+		}
+
+		return
+	}
+
+	_, found := fdb.expiries[bucket]
+	if !found {
+		fdb.expiries[bucket] = map[int]int64{}
+	}
+
+	fdb.meta[ttlName(bucket, key)] = strconv.FormatInt(expiry, 10)
+	fdb.expiries[bucket][key] = expiry
+}
+
+/*
+expired returns true if the key has an expiry time that lies in the past.
+The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) expired(bucket string, key int) bool {
+	return fdb.expiredAt(bucket, key, time.Now().UnixNano())
+}
+
+/*
+expiredAt returns true if the key has an expiry time that lies before now (in unix nanoseconds).
+The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) expiredAt(bucket string, key int, now int64) bool {
+	if len(fdb.expiries) == 0 {
+		return false
+	}
+
+	expiry, found := fdb.expiries[bucket][key]
+
+	return found && expiry <= now
+}
+
+/*
+expiredCount returns the number of records of a bucket that expired before now (in unix nanoseconds),
+but weren't removed yet. The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) expiredCount(bucket string, now int64) int {
+	count := 0
+
+	for _, expiry := range fdb.expiries[bucket] {
+		if expiry <= now {
+			count++
+		}
+	}
+
+	return count
+}
+
+/*
+unexpired returns the records of a bucket without the expired ones (that the reaper didn't remove yet),
+which is a copy only if there are expired ones.
+The caller must hold (at least) the read lock.
+*/
+func (fdb *DB) unexpired(bucket string, records map[int][]byte) map[int][]byte {
+	now := time.Now().UnixNano()
+
+	var live map[int][]byte
+
+	for key, expiry := range fdb.expiries[bucket] {
+		if expiry > now {
+			continue
+		}
+
+		if live == nil {
+			live = maps.Clone(records)
+		}
+
+		delete(live, key)
+	}
+
+	if live == nil {
+		return records
+	}
+
+	return live
+}
+
+/*
+loadExpiries fills the expiry times from the meta data that was read from the file.
+*/
+func (fdb *DB) loadExpiries() {
+	fdb.expiries = map[string]map[int]int64{}
+
+	for name, value := range fdb.meta {
+		bucketKey, found := strings.CutPrefix(name, ttlPrefix)
+		if !found {
+			continue
+		}
+
+		uPos := strings.LastIndex(bucketKey, "_")
+		if uPos < 0 {
+			continue
+		}
+
+		key, err := strconv.Atoi(bucketKey[uPos+1:])
+		if err != nil {
+			continue
+		}
+
+		expiry, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		bucket := bucketKey[:uPos]
+
+		_, found = fdb.expiries[bucket]
+		if !found {
+			fdb.expiries[bucket] = map[int]int64{}
+		}
+
+		fdb.expiries[bucket][key] = expiry
+	}
+}
+
+/*
+reapExpired is the background task that removes the expired keys.
+*/
+func (fdb *DB) reapExpired() {
+	count, err := fdb.reap()
+	if err != nil {
+		fdb.logger().Error("reaper error", "error", err)
+	}
+
+	if count > 0 {
+		fdb.logger().Debug("reaper removed expired keys", "count", count)
+	}
+}
+
+/*
+reap removes all the expired keys and returns how many were removed.
+A key that can't be removed (like one that a before del hook vetoes) is logged and skipped.
+*/
+func (fdb *DB) reap() (int, error) {
+	readLock := fdb.mu.RLock()
+	// a follower gets the removals of the leader
+	hasExpiries := len(fdb.expiries) > 0 && fdb.frozen == nil && !fdb.replicating.Load()
+	readLock.RUnlock()
+
+	if !hasExpiries {
+		return 0, nil
+	}
+
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return 0, fmt.Errorf("reap error: %w", err)
+	}
+
+	defer unlock()
+
+	count := 0
+	now := time.Now().UnixNano()
+
+	for bucket, keys := range fdb.expiries {
+		for key, expiry := range keys {
+			if expiry > now {
+				continue
+			}
+
+			err = fdb.reapKey(bucket, key)
+			if err != nil {
+				// a key that is vetoed (or can't be written) is tried again in the next pass
+				fdb.cfg.logger.Warn("reaper skipped an expired key", "bucket", bucket, "key", key, "error", err)
+
+				continue
+			}
+
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+/*
+reapKey removes one expired key. The caller must hold the write lock.
+*/
+func (fdb *DB) reapKey(bucket string, key int) error {
+	ok, err := fdb.del(bucket, key)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		// the key doesn't exist anymore, so only the expiry time has to go (from the file too)
+		err = fdb.write(fdb.expiryInstructions(bucket, key, 0)...)
+		if err != nil {
+			return fmt.Errorf("reap->write error: %w", err)
+		}
+
+		fdb.setExpiry(bucket, key, 0)
+	}
+
+	return nil
+}
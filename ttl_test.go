@@ -0,0 +1,226 @@
+package fastdb_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SetWithTTL_Memory(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+	assert.NotNil(t, store)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	err = store.SetWithTTL("session", 1, []byte("short"), 50*time.Millisecond)
+	require.NoError(t, err)
+
+	err = store.SetWithTTL("session", 2, []byte("long"), time.Hour)
+	require.NoError(t, err)
+
+	err = store.Set("session", 3, []byte("forever"))
+	require.NoError(t, err)
+
+	err = store.SetWithTTL("session", 4, []byte("wrong"), 0)
+	require.Error(t, err)
+
+	ttl, ok := store.TTL("session", 2)
+	assert.True(t, ok)
+	assert.Greater(t, ttl, 59*time.Minute)
+
+	_, ok = store.TTL("session", 3)
+	assert.False(t, ok)
+
+	// expired keys are not returned anymore, even before they are reaped
+	time.Sleep(60 * time.Millisecond)
+
+	_, ok = store.Get("session", 1)
+	assert.False(t, ok)
+
+	ttl, ok = store.TTL("session", 1)
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	records, err := store.GetAll("session")
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	var scanned []int
+
+	err = store.Scan("session", 1, func(key int, _ []byte) bool {
+		scanned = append(scanned, key)
+
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3}, scanned)
+
+	scanned = nil
+
+	err = store.ScanConsistent("session", func(key int, _ []byte) bool {
+		scanned = append(scanned, key)
+
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3}, scanned)
+
+	// the reaper removes the expired key
+	assert.Eventually(t, func() bool {
+		return store.Info() == "2 record(s) in 1 bucket(s)"
+	}, 3*time.Second, 50*time.Millisecond)
+
+	_, ok = store.TTL("session", 1)
+	assert.False(t, ok)
+
+	// a normal set removes the expiry time
+	err = store.Set("session", 2, []byte("no longer expiring"))
+	require.NoError(t, err)
+
+	_, ok = store.TTL("session", 2)
+	assert.False(t, ok)
+}
+
+func Test_SetWithTTL_File(t *testing.T) {
+	path := "data/fastdb_ttl.db"
+	filePath := filepath.Clean(path)
+	_ = os.Remove(filePath)
+
+	defer func() {
+		err := os.Remove(filePath)
+		require.NoError(t, err)
+
+		_ = os.Remove(filePath + ".bak")
+	}()
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	err = store.SetWithTTL("session", 1, []byte("short"), 200*time.Millisecond)
+	require.NoError(t, err)
+
+	err = store.SetWithTTL("session", 2, []byte("long"), time.Hour)
+	require.NoError(t, err)
+
+	err = store.SetWithTTL("session", 3, []byte("deleted"), time.Hour)
+	require.NoError(t, err)
+
+	ok, err := store.Del("session", 3)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// the expiry times survive a restart
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	_, ok = store.TTL("session", 1)
+	assert.True(t, ok)
+
+	ttl, ok := store.TTL("session", 2)
+	assert.True(t, ok)
+	assert.Greater(t, ttl, 59*time.Minute)
+
+	_, ok = store.TTL("session", 3)
+	assert.False(t, ok)
+
+	assert.Eventually(t, func() bool {
+		return store.Info() == "1 record(s) in 1 bucket(s)"
+	}, 3*time.Second, 50*time.Millisecond)
+
+	// and a defrag
+	err = store.Defrag()
+	require.NoError(t, err)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	_, ok = store.Get("session", 1)
+	assert.False(t, ok)
+
+	_, ok = store.TTL("session", 2)
+	assert.True(t, ok)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_SetWithTTL_expiryWithoutRecord(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "ttl_orphan.db")
+
+	// an expiry time of a record that isn't there (anymore)
+	err := os.WriteFile(filePath, []byte("meta\nttl:session_5\n1\nset\nsession_1\nkept\n"), 0o600)
+	require.NoError(t, err)
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		_, ok := store.TTL("session", 5)
+
+		return !ok
+	}, 3*time.Second, 50*time.Millisecond)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// the reaper removed it from the file too
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(syncIime))
+	require.NoError(t, err)
+
+	_, ok := store.TTL("session", 5)
+	assert.False(t, ok)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_SetWithTTL_vetoedReap(t *testing.T) {
+	logs := &bytes.Buffer{}
+
+	store, err := fastdb.Open(memory, fastdb.WithLogger(slog.New(slog.NewTextHandler(logs, nil))))
+	require.NoError(t, err)
+
+	store.OnBeforeDel(func(_ string, key int) error {
+		if key == 1 {
+			return errors.New("keep it")
+		}
+
+		return nil
+	})
+
+	for key := 1; key <= 10; key++ {
+		err = store.SetWithTTL("session", key, []byte("short"), time.Nanosecond)
+		require.NoError(t, err)
+	}
+
+	// a vetoed key doesn't stop the reaper from removing the others
+	assert.Eventually(t, func() bool {
+		return store.Info() == "1 record(s) in 1 bucket(s)"
+	}, 3*time.Second, 50*time.Millisecond)
+
+	_, ok := store.TTL("session", 1)
+	assert.True(t, ok)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	assert.Contains(t, logs.String(), "reaper skipped an expired key")
+}
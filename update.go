@@ -0,0 +1,221 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/marcelloh/fastdb/persist"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+var errOverflow = errors.New("counter overflow")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Update stores the value that fn makes of the current value of the key (found is false if it doesn't exist),
+while it holds the write lock, so a read-modify-write (like an increment, or a change of a JSON document)
+can't be mixed up with other writes. If fn returns an error, nothing is stored.
+The current value must not be modified, and fn must not use the database (that would deadlock).
+*/
+func (fdb *DB) Update(bucket string, key int, fn func(current []byte, found bool) ([]byte, error)) error {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return fmt.Errorf("update error: %w", err)
+	}
+
+	defer unlock()
+
+	if fdb.readOnly() {
+		return fmt.Errorf("update error: %w", ErrReadOnly)
+	}
+
+	current, found := fdb.keys[bucket][key]
+	if found && fdb.expired(bucket, key) {
+		current, found = nil, false
+	}
+
+	value, err := fn(current, found)
+	if err != nil {
+		return fmt.Errorf("update error: %w", err)
+	}
+
+	err = fdb.set(bucket, key, value, 0)
+	if err != nil {
+		return fmt.Errorf("update->%w", err)
+	}
+
+	return nil
+}
+
+/*
+GetOrSet returns the current value of the key (and true), or stores the value (and returns it with false)
+if the key doesn't exist yet, without another write in between, to create a record only if it's missing.
+*/
+func (fdb *DB) GetOrSet(bucket string, key int, value []byte) ([]byte, bool, error) {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return nil, false, fmt.Errorf("getOrSet error: %w", err)
+	}
+
+	defer unlock()
+
+	current, found := fdb.keys[bucket][key]
+	if found && !fdb.expired(bucket, key) {
+		return current, true, nil
+	}
+
+	err = fdb.set(bucket, key, value, 0)
+	if err != nil {
+		return nil, false, fmt.Errorf("getOrSet->%w", err)
+	}
+
+	return fdb.keys[bucket][key], false, nil
+}
+
+/*
+GetAndDelete returns the value of the key and deletes it, without another write in between,
+so a bucket can be used as a work queue without handing out an item twice.
+It returns false (without an error) if the key doesn't exist.
+*/
+func (fdb *DB) GetAndDelete(bucket string, key int) ([]byte, bool, error) {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return nil, false, fmt.Errorf("getAndDelete error: %w", err)
+	}
+
+	defer unlock()
+
+	if fdb.readOnly() {
+		return nil, false, fmt.Errorf("getAndDelete error: %w", ErrReadOnly)
+	}
+
+	value, found := fdb.keys[bucket][key]
+	if !found || fdb.expired(bucket, key) {
+		return nil, false, nil
+	}
+
+	err = fdb.delKeys(bucket, []int{key})
+	if err != nil {
+		return nil, false, fmt.Errorf("getAndDelete->%w", err)
+	}
+
+	return value, true, nil
+}
+
+/*
+Incr adds delta (which may be negative) to the counter in the key, and returns the new value,
+without another write in between. The counter is stored as a decimal number (so it's readable with Get),
+and a missing key counts from 0. A value that isn't a number, or a result that doesn't fit an int64, is refused.
+*/
+func (fdb *DB) Incr(bucket string, key int, delta int64) (int64, error) {
+	var counter int64
+
+	err := fdb.Update(bucket, key, func(current []byte, found bool) ([]byte, error) {
+		if found {
+			value, err := strconv.ParseInt(string(current), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("value of %s_%d isn't a number: %w", bucket, key, err)
+			}
+
+			counter = value
+		}
+
+		if (delta > 0 && counter > math.MaxInt64-delta) || (delta < 0 && counter < math.MinInt64-delta) {
+			return nil, fmt.Errorf("%w: %d + %d", errOverflow, counter, delta)
+		}
+
+		counter += delta
+
+		return strconv.AppendInt(nil, counter, 10), nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("incr->%w", err)
+	}
+
+	return counter, nil
+}
+
+/*
+Promote moves a record to another bucket (like from "staging" to "published"), with the value that
+transform makes of it (nil keeps the value), in one write, so readers never see it in both buckets or in none.
+The expiry time and labels of the record stay behind (they are deleted). If transform returns an error,
+nothing changes. Like with Update, transform must not use the database.
+*/
+func (fdb *DB) Promote(src, dst string, key int, transform func([]byte) ([]byte, error)) error {
+	unlock, err := fdb.writeLock()
+	if err != nil {
+		return fmt.Errorf("promote error: %w", err)
+	}
+
+	defer unlock()
+
+	if fdb.readOnly() {
+		return fmt.Errorf("promote error: %w", ErrReadOnly)
+	}
+
+	if src == dst {
+		return fmt.Errorf("promote error: source and target are the same bucket (%s)", src)
+	}
+
+	value, found := fdb.keys[src][key]
+	if !found || fdb.expired(src, key) {
+		return fmt.Errorf("promote error: key %d not found in bucket (%s)", key, src)
+	}
+
+	if transform != nil {
+		value, err = transform(value)
+		if err != nil {
+			return fmt.Errorf("promote->transform error: %w", err)
+		}
+	}
+
+	err = fdb.beforeDel(src, key)
+	if err != nil {
+		return fmt.Errorf("promote->%w", err)
+	}
+
+	value, err = fdb.checkSet(dst, key, value)
+	if err != nil {
+		return fmt.Errorf("promote->%w", err)
+	}
+
+	instructions := append([]persist.Instruction{persist.SetInstruction(dst, key, value)},
+		fdb.expiryInstructions(dst, key, 0)...)
+	instructions = append(instructions, persist.DelInstruction(src, key))
+	instructions = append(instructions, fdb.expiryInstructions(src, key, 0)...)
+	instructions = append(instructions, fdb.labelInstructions(src, key)...)
+
+	err = fdb.write(instructions...)
+	if err != nil {
+		return fmt.Errorf("promote->write error: %w", err)
+	}
+
+	fdb.storeRecord(dst, key, value, 0)
+	fdb.removeRecord(src, key)
+	fdb.touch(src)
+
+	if len(fdb.keys[src]) == 0 {
+		delete(fdb.keys, src)
+	}
+
+	return nil
+}
+
+/*
+Move moves a record to another bucket (like from "pending" to "done") in one write,
+so a crash can never lose it halfway. It's Promote without a change of the value.
+*/
+func (fdb *DB) Move(srcBucket string, key int, dstBucket string) error {
+	err := fdb.Promote(srcBucket, dstBucket, key, nil)
+	if err != nil {
+		return fmt.Errorf("move->%w", err)
+	}
+
+	return nil
+}
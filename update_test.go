@@ -0,0 +1,349 @@
+package fastdb_test
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Update(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	increment := func(current []byte, found bool) ([]byte, error) {
+		counter := 0
+		if found {
+			counter, _ = strconv.Atoi(string(current))
+		}
+
+		return []byte(strconv.Itoa(counter + 1)), nil
+	}
+
+	// concurrent increments don't lose updates
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range 10 {
+				assert.NoError(t, store.Update("count", 1, increment))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	value, _ := store.Get("count", 1)
+	assert.Equal(t, []byte("100"), value)
+
+	// an error stores nothing
+	errStop := errors.New("stop")
+	err = store.Update("count", 1, func([]byte, bool) ([]byte, error) {
+		return nil, errStop
+	})
+	require.ErrorIs(t, err, errStop)
+
+	value, _ = store.Get("count", 1)
+	assert.Equal(t, []byte("100"), value)
+
+	err = store.Update("count", -1, increment)
+	require.Error(t, err)
+}
+
+func Test_Update_readOnly(t *testing.T) {
+	store, err := fastdb.Open(memory, fastdb.WithReadOnly())
+	require.NoError(t, err)
+
+	called := false
+	err = store.Update("count", 1, func([]byte, bool) ([]byte, error) {
+		called = true
+
+		return nil, nil
+	})
+	require.ErrorIs(t, err, fastdb.ErrReadOnly)
+	assert.False(t, called)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_GetOrSet(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	// only one of the concurrent callers stores its value
+	var (
+		wg     sync.WaitGroup
+		stored atomic.Int32
+	)
+
+	for worker := range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			value, found, err := store.GetOrSet("text", 1, []byte(strconv.Itoa(worker)))
+			assert.NoError(t, err)
+
+			if !found {
+				stored.Add(1)
+				assert.Equal(t, []byte(strconv.Itoa(worker)), value)
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, int32(1), stored.Load())
+
+	current, _ := store.Get("text", 1)
+	value, found, err := store.GetOrSet("text", 1, []byte("other"))
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, current, value)
+
+	_, _, err = store.GetOrSet("text", -1, []byte("other"))
+	require.Error(t, err)
+}
+
+func Test_GetAndDelete(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	for key := range 100 {
+		err = store.Set("queue", key, []byte(strconv.Itoa(key)))
+		require.NoError(t, err)
+	}
+
+	// concurrent consumers never get the same item
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		taken = map[int]int{}
+	)
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for key := range 100 {
+				value, found, err := store.GetAndDelete("queue", key)
+				assert.NoError(t, err)
+
+				if found {
+					assert.Equal(t, []byte(strconv.Itoa(key)), value)
+					mu.Lock()
+					taken[key]++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Len(t, taken, 100)
+
+	for _, count := range taken {
+		assert.Equal(t, 1, count)
+	}
+
+	_, found := store.Get("queue", 1)
+	assert.False(t, found)
+
+	value, found, err := store.GetAndDelete("queue", 1)
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Nil(t, value)
+}
+
+func Test_GetAndDelete_file(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "fastdb_pop.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(0))
+	require.NoError(t, err)
+
+	err = store.Set("queue", 1, []byte("item"))
+	require.NoError(t, err)
+
+	value, found, err := store.GetAndDelete("queue", 1)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("item"), value)
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// one del record is written
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, fileHeader(string(content))+"set\nqueue_1\nitem\ndel\nqueue_1\n", string(content))
+}
+
+func Test_Incr(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	// concurrent increments don't lose updates
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range 10 {
+				_, err := store.Incr("count", 1, 2)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	counter, err := store.Incr("count", 1, -50)
+	require.NoError(t, err)
+	assert.Equal(t, int64(150), counter)
+
+	value, _ := store.Get("count", 1)
+	assert.Equal(t, []byte("150"), value)
+
+	counter, err = store.Incr("count", 2, -1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), counter)
+
+	err = store.Set("count", 3, []byte("not a number"))
+	require.NoError(t, err)
+
+	_, err = store.Incr("count", 3, 1)
+	require.Error(t, err)
+
+	err = store.Set("count", 4, []byte(strconv.FormatInt(math.MaxInt64, 10)))
+	require.NoError(t, err)
+
+	_, err = store.Incr("count", 4, 1)
+	require.Error(t, err)
+
+	counter, err = store.Incr("count", 4, math.MinInt64)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), counter)
+}
+
+func Test_Promote(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "fastdb_promote.db")
+
+	store, err := fastdb.Open(filePath, fastdb.WithSyncTime(0))
+	require.NoError(t, err)
+
+	err = store.Set("staging", 1, []byte("draft"))
+	require.NoError(t, err)
+
+	err = store.Set("staging", 2, []byte("other draft"))
+	require.NoError(t, err)
+
+	// a failing transform changes nothing
+	err = store.Promote("staging", "published", 1, func([]byte) ([]byte, error) {
+		return nil, errors.New("not ready")
+	})
+	require.Error(t, err)
+	assert.True(t, store.Exists("staging", 1))
+	assert.False(t, store.HasBucket("published"))
+
+	err = store.Promote("staging", "published", 3, nil)
+	require.Error(t, err)
+
+	err = store.Promote("staging", "staging", 1, nil)
+	require.Error(t, err)
+
+	err = store.Promote("staging", "published", 1, func(value []byte) ([]byte, error) {
+		return append([]byte("final "), value...), nil
+	})
+	require.NoError(t, err)
+	assert.False(t, store.Exists("staging", 1))
+
+	value, found := store.Get("published", 1)
+	assert.True(t, found)
+	assert.Equal(t, []byte("final draft"), value)
+
+	err = store.Promote("staging", "published", 2, nil)
+	require.NoError(t, err)
+	assert.False(t, store.HasBucket("staging"))
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	// the set and the del are written in one batch
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "set\npublished_1\nfinal draft\ndel\nstaging_1\n")
+
+	store, err = fastdb.Open(filePath, fastdb.WithSyncTime(0))
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	assert.Equal(t, 2, store.Count("published"))
+	assert.Equal(t, 0, store.Count("staging"))
+}
+
+func Test_Move(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	err = store.Set("pending", 1, []byte("job"))
+	require.NoError(t, err)
+
+	err = store.Move("pending", 1, "done")
+	require.NoError(t, err)
+	assert.False(t, store.Exists("pending", 1))
+
+	value, found := store.Get("done", 1)
+	assert.True(t, found)
+	assert.Equal(t, []byte("job"), value)
+
+	err = store.Move("pending", 1, "done")
+	require.Error(t, err)
+}
@@ -0,0 +1,170 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"sync"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// EventType tells what kind of change an Event is about.
+type EventType int
+
+const (
+	// EventSet is sent when a record was stored.
+	EventSet EventType = iota + 1
+	// EventDel is sent when a record was deleted (or has expired).
+	EventDel
+	// EventCorruption is sent (to the watchers of all buckets) when scrubbing found a corruption, see WithScrub.
+	EventCorruption
+)
+
+// Event describes one change of a record, as it is delivered by Watch.
+type Event struct {
+	Err    error // the corruption, only for EventCorruption
+	Bucket string
+	Value  []byte // the new value, nil for EventDel
+	Type   EventType
+	Key    int
+}
+
+// watcher delivers the events of one subscription, in the order of the changes.
+type watcher struct {
+	events chan Event
+	wake   chan struct{} // signals that there are queued events
+	done   chan struct{} // closed when the subscription stops
+	bucket string        // empty for all buckets
+	queue  []Event
+	mu     sync.Mutex
+	once   sync.Once
+}
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+Watch returns a channel with the changes (Set and Del) of the records in a bucket,
+and a function to stop watching, which closes the channel.
+An empty bucket watches all the buckets.
+Events are queued, so a slow reader never blocks the writes and never misses an event.
+Close stops all the watchers.
+*/
+func (fdb *DB) Watch(bucket string) (<-chan Event, func()) {
+	wtc := &watcher{
+		bucket: bucket,
+		events: make(chan Event),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	fdb.watchMu.Lock()
+	if fdb.watchers == nil {
+		fdb.watchers = map[*watcher]struct{}{}
+	}
+
+	fdb.watchers[wtc] = struct{}{}
+	fdb.watchMu.Unlock()
+
+	go wtc.run()
+
+	return wtc.events, func() {
+		fdb.watchMu.Lock()
+		delete(fdb.watchers, wtc)
+		fdb.watchMu.Unlock()
+
+		wtc.stop()
+	}
+}
+
+/*
+notify queues the event for every watcher of the bucket.
+The caller must hold the write lock, so the events are queued in the order of the changes.
+*/
+func (fdb *DB) notify(event Event) {
+	fdb.watchMu.Lock()
+	defer fdb.watchMu.Unlock()
+
+	for wtc := range fdb.watchers {
+		if wtc.bucket == "" || wtc.bucket == event.Bucket {
+			wtc.push(event)
+		}
+	}
+}
+
+/*
+stopWatchers stops all the watchers.
+*/
+func (fdb *DB) stopWatchers() {
+	fdb.watchMu.Lock()
+	watchers := fdb.watchers
+	fdb.watchers = nil
+	fdb.watchMu.Unlock()
+
+	for wtc := range watchers {
+		wtc.stop()
+	}
+}
+
+/*
+push adds the event to the queue, and wakes up the delivery.
+*/
+func (wtc *watcher) push(event Event) {
+	wtc.mu.Lock()
+	wtc.queue = append(wtc.queue, event)
+	wtc.mu.Unlock()
+
+	select {
+	case wtc.wake <- struct{}{}:
+	default:
+	}
+}
+
+/*
+pop takes the oldest event from the queue.
+*/
+func (wtc *watcher) pop() (Event, bool) {
+	wtc.mu.Lock()
+	defer wtc.mu.Unlock()
+
+	if len(wtc.queue) == 0 {
+		return Event{}, false
+	}
+
+	event := wtc.queue[0]
+	wtc.queue[0] = Event{}
+	wtc.queue = wtc.queue[1:]
+
+	return event, true
+}
+
+/*
+run delivers the queued events to the channel, until the watcher is stopped.
+*/
+func (wtc *watcher) run() {
+	defer close(wtc.events)
+
+	for {
+		select {
+		case <-wtc.done:
+			return
+		case <-wtc.wake:
+		}
+
+		for event, ok := wtc.pop(); ok; event, ok = wtc.pop() {
+			select {
+			case wtc.events <- event:
+			case <-wtc.done:
+				return
+			}
+		}
+	}
+}
+
+/*
+stop ends the delivery, it can be called more than once.
+*/
+func (wtc *watcher) stop() {
+	wtc.once.Do(func() {
+		close(wtc.done)
+	})
+}
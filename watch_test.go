@@ -0,0 +1,83 @@
+package fastdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Watch(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	events, stop := store.Watch("text")
+	all, stopAll := store.Watch("")
+
+	defer stopAll()
+
+	err = store.Set("text", 1, []byte("one"))
+	require.NoError(t, err)
+
+	err = store.Set("other", 1, []byte("other"))
+	require.NoError(t, err)
+
+	err = store.SetMulti("text", map[int][]byte{2: []byte("two"), 3: []byte("three")})
+	require.NoError(t, err)
+
+	_, err = store.Del("text", 1)
+	require.NoError(t, err)
+
+	expected := []fastdb.Event{
+		{Type: fastdb.EventSet, Bucket: "text", Key: 1, Value: []byte("one")},
+		{Type: fastdb.EventSet, Bucket: "text", Key: 2, Value: []byte("two")},
+		{Type: fastdb.EventSet, Bucket: "text", Key: 3, Value: []byte("three")},
+		{Type: fastdb.EventDel, Bucket: "text", Key: 1},
+	}
+
+	for _, want := range expected {
+		assert.Equal(t, want, receive(t, events))
+	}
+
+	assert.Equal(t, "text", receive(t, all).Bucket)
+	assert.Equal(t, "other", receive(t, all).Bucket)
+
+	stop()
+	stop()
+
+	_, ok := <-events
+	assert.False(t, ok)
+
+	err = store.Close()
+	require.NoError(t, err)
+}
+
+func Test_Watch_close(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	events, stop := store.Watch("text")
+
+	err = store.Close()
+	require.NoError(t, err)
+
+	_, ok := <-events
+	assert.False(t, ok)
+
+	stop()
+}
+
+func receive(t *testing.T, events <-chan fastdb.Event) fastdb.Event {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		require.FailNow(t, "no event received")
+	}
+
+	return fastdb.Event{}
+}
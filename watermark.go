@@ -0,0 +1,125 @@
+package fastdb
+
+/* ------------------------------- Imports --------------------------- */
+
+import (
+	"errors"
+	"fmt"
+)
+
+/* ---------------------- Constants/Types/Variables ------------------ */
+
+// Watermark holds the record counts of a bucket at which to warn, and at which to refuse new records.
+type Watermark struct {
+	Warn  int // the number of records from which a WatermarkAlert is sent, 0 for none
+	Limit int // the maximum number of records, new records are refused with ErrBucketFull, 0 for none
+}
+
+// WatermarkAlert tells that a bucket reached its Warn mark, or its Limit.
+type WatermarkAlert struct {
+	Bucket  string
+	Records int  // the number of records in the bucket
+	Limit   bool // true when the bucket is full, false when it reached the Warn mark
+}
+
+// WatermarkFunc is called when a bucket reaches a mark of its Watermark.
+type WatermarkFunc func(alert WatermarkAlert)
+
+// watermarkState holds the watermark of a bucket, and the level the bucket reached (see level).
+type watermarkState struct {
+	mark  Watermark
+	level int
+}
+
+const (
+	levelBelow = iota
+	levelWarn
+	levelFull
+)
+
+// ErrBucketFull is returned for a write that would exceed the Limit of the Watermark of the bucket.
+var ErrBucketFull = errors.New("bucket is full")
+
+/* -------------------------- Methods/Functions ---------------------- */
+
+/*
+SetWatermark sets the record counts of a bucket at which to warn (see OnWatermark),
+and at which new records are refused (updating records is still possible).
+A zero Watermark removes it. Watermarks aren't persisted.
+*/
+func (fdb *DB) SetWatermark(bucket string, mark Watermark) {
+	defer fdb.lockUnlock()()
+
+	if mark == (Watermark{}) {
+		delete(fdb.watermarks, bucket)
+
+		return
+	}
+
+	if fdb.watermarks == nil {
+		fdb.watermarks = map[string]*watermarkState{}
+	}
+
+	fdb.watermarks[bucket] = &watermarkState{mark: mark}
+
+	// a bucket that is already over a mark, is reported right away
+	fdb.checkWatermark(bucket)
+}
+
+/*
+OnWatermark adds a function that is called when a bucket reaches the Warn mark or the Limit of its
+Watermark. It is called once per mark, until the bucket went below the mark again.
+The hooks are called while the database is locked, so they can't use the database.
+*/
+func (fdb *DB) OnWatermark(hook WatermarkFunc) {
+	defer fdb.lockUnlock()()
+
+	fdb.hooks.watermark = append(fdb.hooks.watermark, hook)
+}
+
+/*
+checkLimit returns ErrBucketFull if adding records to the bucket would exceed its Limit.
+The caller must hold the write lock.
+*/
+func (fdb *DB) checkLimit(bucket string, added int) error {
+	state, found := fdb.watermarks[bucket]
+	if !found || state.mark.Limit <= 0 || added <= 0 {
+		return nil
+	}
+
+	if len(fdb.keys[bucket])+added > state.mark.Limit {
+		return fmt.Errorf("bucket (%s) has %d records, the limit is %d: %w",
+			bucket, len(fdb.keys[bucket]), state.mark.Limit, ErrBucketFull)
+	}
+
+	return nil
+}
+
+/*
+checkWatermark calls the watermark hooks when the bucket reached a higher mark than before.
+The caller must hold the write lock.
+*/
+func (fdb *DB) checkWatermark(bucket string) {
+	state, found := fdb.watermarks[bucket]
+	if !found {
+		return
+	}
+
+	records := len(fdb.keys[bucket])
+	level := levelBelow
+
+	switch {
+	case state.mark.Limit > 0 && records >= state.mark.Limit:
+		level = levelFull
+	case state.mark.Warn > 0 && records >= state.mark.Warn:
+		level = levelWarn
+	}
+
+	if level > state.level {
+		for _, hook := range fdb.hooks.watermark {
+			hook(WatermarkAlert{Bucket: bucket, Records: records, Limit: level == levelFull})
+		}
+	}
+
+	state.level = level
+}
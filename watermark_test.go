@@ -0,0 +1,86 @@
+package fastdb_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/marcelloh/fastdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SetWatermark(t *testing.T) {
+	store, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	defer func() {
+		err = store.Close()
+		require.NoError(t, err)
+	}()
+
+	var alerts []fastdb.WatermarkAlert
+
+	store.OnWatermark(func(alert fastdb.WatermarkAlert) {
+		alerts = append(alerts, alert)
+	})
+
+	store.SetWatermark("queue", fastdb.Watermark{Warn: 3, Limit: 5})
+
+	for key := 1; key <= 5; key++ {
+		err = store.Set("queue", key, []byte("item"))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []fastdb.WatermarkAlert{
+		{Bucket: "queue", Records: 3},
+		{Bucket: "queue", Records: 5, Limit: true},
+	}, alerts)
+
+	// new records are refused, but existing ones can still be changed
+	err = store.Set("queue", 6, []byte("item"))
+	require.ErrorIs(t, err, fastdb.ErrBucketFull)
+
+	_, err = store.Insert("queue", []byte("item"))
+	require.ErrorIs(t, err, fastdb.ErrBucketFull)
+
+	err = store.SetMulti("queue", map[int][]byte{5: []byte("new"), 6: []byte("item")})
+	require.ErrorIs(t, err, fastdb.ErrBucketFull)
+
+	other, err := fastdb.Open(memory)
+	require.NoError(t, err)
+
+	err = other.Set("queue", 7, []byte("item"))
+	require.NoError(t, err)
+
+	snapshot := &bytes.Buffer{}
+	err = other.Snapshot(snapshot)
+	require.NoError(t, err)
+
+	err = store.Restore(snapshot, fastdb.KeepIncoming)
+	require.ErrorIs(t, err, fastdb.ErrBucketFull)
+
+	err = store.Set("queue", 5, []byte("changed"))
+	require.NoError(t, err)
+
+	assert.Len(t, alerts, 2)
+
+	// after going below the marks, they are reported again
+	_, err = store.DeleteRange("queue", 1, 4)
+	require.NoError(t, err)
+
+	err = store.SetMulti("queue", map[int][]byte{1: []byte("item"), 2: []byte("item")})
+	require.NoError(t, err)
+
+	require.Len(t, alerts, 3)
+	assert.Equal(t, fastdb.WatermarkAlert{Bucket: "queue", Records: 3}, alerts[2])
+
+	// a bucket that is already over the mark, is reported right away
+	store.SetWatermark("queue", fastdb.Watermark{Warn: 2})
+	require.Len(t, alerts, 4)
+
+	store.SetWatermark("queue", fastdb.Watermark{})
+
+	err = store.Set("queue", 10, []byte("item"))
+	require.NoError(t, err)
+	assert.Len(t, alerts, 4)
+}